@@ -1,6 +1,10 @@
 package main
 
 import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_time "github.com/sharovik/devbot/internal/service/time"
@@ -8,12 +12,19 @@ import (
 	"github.com/sharovik/devbot/internal/service/schedule"
 
 	"github.com/sharovik/devbot/internal/service/definedevents"
+	"github.com/sharovik/devbot/internal/service/definitions"
+	"github.com/sharovik/devbot/internal/service/executor"
 
 	"github.com/sharovik/devbot/internal/config"
 
 	"github.com/sharovik/devbot/internal/container"
 	"github.com/sharovik/devbot/internal/log"
 	"github.com/sharovik/devbot/internal/service/message"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/message/deduplication"
+	"github.com/sharovik/devbot/internal/service/phrasebook"
+	"github.com/sharovik/devbot/internal/service/shutdown"
+	"github.com/sharovik/devbot/internal/service/timezone"
 )
 
 func init() {
@@ -23,14 +34,31 @@ func init() {
 	}
 
 	container.C = cnt
+	if err = phrasebook.InitS(container.C.Config.Phrasebook); err != nil {
+		log.Logger().AddError(err).Str("path", container.C.Config.Phrasebook.Path).Msg("Failed to load the phrasebooks. The built-in phrasebook will be used")
+	}
+
 	definedevents.InitializeDefinedEvents()
+	if err = definitions.InitS(container.C.Config.ScenariosPath, container.C.DefinedEvents); err != nil {
+		log.Logger().AddError(err).Str("path", container.C.Config.ScenariosPath).Msg("Failed to load the scenario definitions")
+	}
+
 	message.InitService()
+	conversation.InitS(container.C.Dictionary.GetDBClient())
+	deduplication.InitS(
+		container.C.Dictionary.GetDBClient(),
+		time.Duration(container.C.Config.MessagesAPIConfig.DeduplicationTTL)*time.Second,
+		container.C.Config.MessagesAPIConfig.DeduplicationPersisted,
+	)
+	executor.InitS(container.C.Config.EventsExecutor)
 	schedule.InitS(container.C.Config, container.C.Dictionary.GetDBClient(), container.C.DefinedEvents)
+	timezone.InitS(container.C.Dictionary.GetDBClient(), container.C.MessageClient)
 }
 
 const (
 	maximumRetries      = 4
 	delayBetweenRetries = time.Second * 600 //10 minutes
+	cancelGracePeriod   = time.Second * 3
 )
 
 var (
@@ -47,6 +75,10 @@ func run() error {
 
 	for {
 		if err := message.S.InitWebSocketReceiver(); err != nil {
+			if errors.Is(err, message.ErrInputClosed) || errors.Is(err, message.ErrReceiverStopped) {
+				return nil
+			}
+
 			log.Logger().AddError(err).Msg("Error received during application run")
 
 			if numberOfRetries >= maximumRetries {
@@ -71,7 +103,11 @@ func run() error {
 
 			log.Logger().Debug().Msg("Triggered retry")
 			if container.C.Config.GetAppEnv() != config.EnvironmentTesting {
-				time.Sleep(time.Duration(numberOfRetries) * time.Minute)
+				select {
+				case <-shutdown.S.Stopping():
+					return nil
+				case <-time.After(time.Duration(numberOfRetries) * time.Minute):
+				}
 			}
 
 			continue
@@ -87,8 +123,34 @@ func main() {
 	})
 
 	log.Logger().StartMessage("DevBot")
-	if err := run(); err != nil {
-		log.Logger().AddError(err).Msg("Application was interrupted by an error")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	finished := make(chan error, 1)
+	go func() {
+		finished <- run()
+	}()
+
+	select {
+	case err := <-finished:
+		if err != nil {
+			log.Logger().AddError(err).Msg("Application was interrupted by an error")
+		}
+	case sig := <-signals:
+		log.Logger().Info().Str("signal", sig.String()).Msg("Received stop signal")
+	}
+
+	//We stop receiving the new messages and wait for the events, which are running right now
+	shutdown.S.Stop()
+	if err := shutdown.S.Wait(time.Duration(container.C.Config.ShutdownTimeout) * time.Second); err != nil {
+		log.Logger().AddError(err).Msg("The running events were not finished in time. Cancelling them.")
+
+		//The cancelled events need a bit of time to write their results
+		shutdown.S.Cancel()
+		if err = shutdown.S.Wait(cancelGracePeriod); err != nil {
+			log.Logger().AddError(err).Msg("Failed to wait for the cancelled events")
+		}
 	}
 
 	container.C.Terminate()
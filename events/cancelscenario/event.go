@@ -1,9 +1,12 @@
 package cancelscenario
 
 import (
+	"fmt"
+
 	"github.com/sharovik/devbot/internal/database"
 	"github.com/sharovik/devbot/internal/helper"
 	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
 
 	"github.com/sharovik/devbot/internal/container"
 	"github.com/sharovik/devbot/internal/dto"
@@ -40,15 +43,37 @@ func (e EventStruct) Alias() string {
 
 // Execute method which is called by message processor
 func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
-	channel := extractChannelName(message.OriginalMessage.Text)
-	if channel == "" {
+	target := extractChannelName(message.OriginalMessage.Text)
+	if target == "" {
 		message.Text = "Please specify the channel name."
 		return message, nil
 	}
 
+	//The conversation of the current message is skipped, because this event is running in it
+	currentKey := conversation.NewKey(message)
+	isTarget := func(key conversation.Key) bool {
+		return key != currentKey && (key.Channel == target || key.User == target)
+	}
+
+	stoppedConversations := 0
+	for key := range conversation.S.List() {
+		if isTarget(key) {
+			conversation.S.Finalise(key)
+			stoppedConversations++
+		}
+	}
+
+	cancelledEvents := conversation.CancelMatching(isTarget)
+
+	log.Logger().Info().
+		Str("target", target).
+		Int("stopped_conversations", stoppedConversations).
+		Int("cancelled_events", cancelledEvents).
+		Msg("Conversations stopped")
+
 	//This answer will be show once the event get triggered.
 	//Leave message.Text empty, once you need to not show the message, once this event get triggered.
-	message.Text = "Done."
+	message.Text = fmt.Sprintf("Done. Stopped conversations: %d, cancelled running events: %d.", stoppedConversations, cancelledEvents)
 	return message, nil
 }
 
@@ -50,7 +50,7 @@ func (e EventStruct) Alias() string {
 
 // Execute method which is called by message processor
 func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
-	currentConversation := conversation.GetConversation(message.Channel)
+	currentConversation := conversation.S.Get(conversation.NewKey(message))
 
 	whatToWrite := ""
 	whereToWrite := ""
@@ -0,0 +1,288 @@
+package learnquestion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service"
+	"github.com/sharovik/devbot/internal/service/learning"
+	"github.com/sharovik/devbot/internal/service/message"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+)
+
+const (
+	//EventName the name of the event
+	EventName = "learnquestion"
+
+	//EventVersion the version of the event
+	EventVersion = "1.0.0"
+
+	helpMessage = "When the learning mode is enabled and I don't know what to answer to the trainer, I ask how I should react on that message.\nAsk me `learned questions` and I will show you the latest learned questions."
+
+	reviewQuestion = "learned questions"
+	reviewLimit    = 10
+
+	textAnswer     = "text"
+	negativeAnswer = "no"
+	positiveAnswer = "yes"
+
+	targetQuestion = "I don't know what to answer to `%s`. Should I learn it?\nPlease, write the alias of the event, which I should trigger, `text` if I should answer with a plain text, or `no` if I should not learn it. You can find the aliases in the `events list`."
+	answerQuestion = "What should I answer to `%s`?"
+	regexQuestion  = "Should I also generate the regex `%s` for this question? Please, answer yes or no"
+)
+
+// Stages of the learning dialog
+const (
+	stageTarget = iota
+	stageAnswer
+	stageRegex
+)
+
+// EventStruct the struct for the event object. It will be used for initialisation of the event in defined-events.go file.
+type EventStruct struct {
+}
+
+// lessonState the state of the learning dialog in the conversation
+type lessonState struct {
+	stage  int
+	lesson learning.Lesson
+}
+
+var (
+	// Event - object which is ready to use
+	Event = EventStruct{}
+
+	mu      sync.Mutex
+	lessons = map[conversation.Key]*lessonState{}
+)
+
+// Help retrieves the help message
+func (e EventStruct) Help() string {
+	return helpMessage
+}
+
+// Alias retrieves the event alias
+func (e EventStruct) Alias() string {
+	return EventName
+}
+
+// Execute method which is called by message processor
+func (e EventStruct) Execute(msg dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	if msg.DictionaryMessage.Question == reviewQuestion {
+		return review(msg)
+	}
+
+	//That is the learned question with the plain text answer. The answer was already sent, so there is nothing to do
+	if msg.DictionaryMessage.QuestionID != 0 {
+		msg.Text = ""
+		return msg, nil
+	}
+
+	key := conversation.NewKey(msg)
+	if !container.C.Config.IsTrainer(key.User) {
+		msg.Text = "Sorry, only the trainers can teach me."
+		return msg, nil
+	}
+
+	state := takeState(key)
+	conv := conversation.S.Get(key)
+	if state == nil || len(conv.Scenario.RequiredVariables) == 0 {
+		return start(msg)
+	}
+
+	answer := strings.TrimSpace(conv.Scenario.RequiredVariables[0].Value)
+	switch state.stage {
+	case stageTarget:
+		switch alias := strings.ToLower(answer); {
+		case alias == negativeAnswer:
+			msg.Text = "Ok, I will not learn it."
+			return msg, nil
+		case alias == textAnswer:
+			state.stage = stageAnswer
+			return ask(msg, state, fmt.Sprintf(answerQuestion, state.lesson.Question), database.VariableRules{})
+		case alias == EventName || container.C.DefinedEvents[alias] == nil:
+			msg.Text = fmt.Sprintf("I don't know the `%s` event, so I will not learn it. Please, have a look on the `events list` and try again.", answer)
+			return msg, nil
+		default:
+			state.lesson.Alias = alias
+		}
+	case stageAnswer:
+		state.lesson.Alias = EventName
+		state.lesson.Answer = answer
+	case stageRegex:
+		if answer == positiveAnswer {
+			state.lesson.Regex = learning.GenerateRegex(state.lesson.Question)
+		}
+
+		return learn(msg, state.lesson)
+	}
+
+	state.stage = stageRegex
+	if regex := learning.GenerateRegex(state.lesson.Question); regex != "" {
+		return ask(msg, state, fmt.Sprintf(regexQuestion, regex), database.VariableRules{Type: database.VariableTypeBool})
+	}
+
+	return learn(msg, state.lesson)
+}
+
+func start(msg dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	key := conversation.NewKey(msg)
+	question := learning.CleanQuestion(msg.OriginalMessage.Text)
+	if question == "" {
+		msg.Text = ""
+		return msg, nil
+	}
+
+	return ask(msg, &lessonState{
+		stage: stageTarget,
+		lesson: learning.Lesson{
+			Question: question,
+			User:     key.User,
+			Channel:  key.Channel,
+		},
+	}, fmt.Sprintf(targetQuestion, question), database.VariableRules{})
+}
+
+// ask triggers the learning scenario with the selected question. The answer, which satisfies the rules, will be received in the next event execution
+func ask(msg dto.BaseChatMessage, state *lessonState, question string, rules database.VariableRules) (dto.BaseChatMessage, error) {
+	eventID, err := container.C.Dictionary.FindEventByAlias(EventName)
+	if err != nil {
+		msg.Text = "Failed to prepare the learning scenario. Try again later. Sorry."
+		return msg, err
+	}
+
+	scenario, err := service.PrepareEventScenario(eventID, EventName)
+	if err != nil || scenario.ID == 0 {
+		msg.Text = "Failed to prepare the learning scenario. Try again later. Sorry."
+		return msg, err
+	}
+
+	scenario.RequiredVariables = []database.ScenarioVariable{
+		{
+			Name:          "answer",
+			Question:      question,
+			VariableRules: rules,
+		},
+	}
+
+	key := conversation.NewKey(msg)
+	saveState(key, state)
+
+	if err = message.TriggerScenario(key, scenario, false); err != nil {
+		takeState(key)
+		msg.Text = "Failed to ask the learning question. Try again later. Sorry."
+		return msg, err
+	}
+
+	msg.Text = ""
+	return msg, nil
+}
+
+func learn(msg dto.BaseChatMessage, lesson learning.Lesson) (dto.BaseChatMessage, error) {
+	entry, err := learning.Learn(lesson)
+	if errors.Is(err, learning.ErrUnknownEvent) {
+		msg.Text = fmt.Sprintf("The `%s` event is not installed, so I cannot learn it.", lesson.Alias)
+		return msg, nil
+	} else if err != nil {
+		msg.Text = "Failed to learn the question. Try again later. Sorry."
+		return msg, err
+	}
+
+	log.Logger().Info().
+		Int64("question_id", entry.QuestionID).
+		Str("question", entry.Question).
+		Str("event_alias", entry.Alias).
+		Str("regex", entry.Regex).
+		Str("user", entry.User).
+		Msg("New question learned")
+
+	msg.Text = fmt.Sprintf("Done. Now I will %s, when I receive `%s`.", describe(entry), entry.Question)
+	return msg, nil
+}
+
+func review(msg dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	entries, err := learning.Latest(reviewLimit)
+	if err != nil {
+		msg.Text = "Failed to get the learned questions. Try again later. Sorry."
+		return msg, err
+	}
+
+	if len(entries) == 0 {
+		msg.Text = "I haven't learned any question yet."
+		return msg, nil
+	}
+
+	msg.Text = "Here are the latest learned questions:"
+	for _, entry := range entries {
+		msg.Text += fmt.Sprintf("\n`%s` - I %s. Learned from <@%s> on %s",
+			entry.Question,
+			describe(entry),
+			entry.User,
+			entry.Created.In(container.C.Config.GetTimezone()).Format("2006-01-02 15:04"),
+		)
+
+		if entry.Regex != "" {
+			msg.Text += fmt.Sprintf(" with the regex `%s`", entry.Regex)
+		}
+	}
+
+	return msg, nil
+}
+
+func describe(entry learning.Entry) string {
+	if entry.Alias == EventName {
+		return fmt.Sprintf("answer `%s`", entry.Answer)
+	}
+
+	return fmt.Sprintf("trigger the `%s` event", entry.Alias)
+}
+
+func saveState(key conversation.Key, state *lessonState) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	lessons[key] = state
+}
+
+// takeState retrieves the state of the learning dialog and removes it, so the stopped dialog is not continued
+func takeState(key conversation.Key) *lessonState {
+	mu.Lock()
+	defer mu.Unlock()
+
+	state := lessons[key]
+	delete(lessons, key)
+
+	return state
+}
+
+// Install method for installation of event
+func (e EventStruct) Install() error {
+	log.Logger().Debug().
+		Str("event_name", EventName).
+		Str("event_version", EventVersion).
+		Msg("Triggered event installation")
+
+	return container.C.Dictionary.InstallNewEventScenario(database.EventScenario{
+		EventName:    EventName,
+		EventVersion: EventVersion,
+		Questions: []database.Question{
+			{
+				Question:      reviewQuestion,
+				Answer:        "Let me check",
+				QuestionRegex: "(?i)(learned questions)",
+				QuestionGroup: "",
+			},
+		},
+	})
+}
+
+// Update for event update actions
+func (e EventStruct) Update() error {
+	return nil
+}
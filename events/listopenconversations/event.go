@@ -41,16 +41,23 @@ func (e EventStruct) Alias() string {
 
 // Execute method which is called by message processor
 func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
-	currentConversations := conversation.GetCurrentConversations()
+	currentConversations := conversation.S.List()
 	if len(currentConversations) == 0 {
 		message.Text = "There is no open conversations."
 		return message, nil
 	}
 
 	message.Text = "Here is the list:"
-	for _, conv := range conversation.GetCurrentConversations() {
+	for key, conv := range currentConversations {
 		message.Text += "\n-------"
-		message.Text += fmt.Sprintf("\nScenario #%d was triggered in <@%s> chat", conv.ScenarioID, conv.LastQuestion.Channel)
+		message.Text += fmt.Sprintf("\nScenario #%d was triggered in <@%s> chat", conv.ScenarioID, key.Channel)
+		if key.User != "" {
+			message.Text += fmt.Sprintf(" by <@%s>", key.User)
+		}
+
+		if key.ThreadTS != "" {
+			message.Text += fmt.Sprintf(" in thread `%s`", key.ThreadTS)
+		}
 		if len(conv.Scenario.RequiredVariables) == 0 {
 			message.Text += "\nAnd there is no answers received yet for that scenario."
 		} else {
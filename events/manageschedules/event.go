@@ -0,0 +1,306 @@
+package manageschedules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/helper"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/schedule"
+	"github.com/sharovik/devbot/internal/service/timezone"
+)
+
+const (
+	//EventName the name of the event
+	EventName = "manageschedules"
+
+	//EventVersion the version of the event
+	EventVersion = "1.0.0"
+
+	helpMessage = "I can manage the scheduled events:" +
+		"\n```list schedules``` shows your schedules, ```list channel schedules``` shows the schedules of the current channel;" +
+		"\n```show schedule {id}``` shows the schedule details, ```show schedule {id} runs``` shows its latest runs;" +
+		"\n```pause schedule {id}``` and ```resume schedule {id}``` stop and continue the schedule triggering;" +
+		"\n```delete schedule {id}``` removes the schedule;" +
+		"\n```change schedule {id} to {time_string}``` changes the time of the schedule, like ```change schedule 1 to cron \"30 9 * * 1-5\"```;" +
+		"\n```change schedule {id} misfire {policy}``` changes the way, how the runs missed while I was offline are handled. The policies: `skip`, `run_once`, `run_all`." +
+		"\nOnly the author of the schedule can change it."
+
+	commandRegex = `(?is)(list|show|pause|resume|delete|change)\s+(my\s+|channel\s+)?schedules?(?:\s+#?(\d+))?(?:\s+(?:to\s+)?(.+))?`
+
+	//The times are shown with the time zone abbreviation, because the schedule and the user may have different time zones
+	dateFormat = "2006-01-02 15:04 MST"
+
+	//The number of the latest runs, which are shown
+	runsLimit = 10
+)
+
+// EventStruct the struct for the event object. It will be used for initialisation of the event in defined-events.go file.
+type EventStruct struct {
+}
+
+// Event - object which is ready to use
+var Event = EventStruct{}
+
+// Help retrieves the help message
+func (e EventStruct) Help() string {
+	return helpMessage
+}
+
+// Alias retrieves the event alias
+func (e EventStruct) Alias() string {
+	return EventName
+}
+
+// Execute method which is called by message processor
+func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	matches := helper.FindMatches(commandRegex, message.OriginalMessage.Text)
+	command := strings.ToLower(matches["1"])
+	if command == "" {
+		message.Text = helpMessage
+		return message, nil
+	}
+
+	//The list of schedules doesn't need the ID
+	if matches["3"] == "" {
+		if command != "list" && command != "show" {
+			message.Text = fmt.Sprintf("Please, specify the schedule ID, like ```%s schedule 1```. Write ```list schedules``` to see the IDs.", command)
+			return message, nil
+		}
+
+		return listSchedules(message, strings.TrimSpace(strings.ToLower(matches["2"])) == "channel")
+	}
+
+	id, err := strconv.Atoi(matches["3"])
+	if err != nil {
+		message.Text = fmt.Sprintf("`%s` is not the schedule ID.", matches["3"])
+		return message, nil
+	}
+
+	var (
+		item     schedule.Item
+		user     = message.OriginalMessage.User
+		location = timezone.S.Location(user)
+	)
+
+	switch command {
+	case "list", "show":
+		if strings.EqualFold(strings.TrimSpace(matches["4"]), "runs") {
+			return showRuns(message, id, location)
+		}
+
+		return showSchedule(message, id, location)
+	case "pause":
+		item, err = schedule.S.Pause(id, user)
+	case "resume":
+		item, err = schedule.S.Resume(id, user)
+	case "delete":
+		item, err = schedule.S.Delete(id, user)
+	case "change":
+		if fields := strings.Fields(matches["4"]); len(fields) == 2 && strings.EqualFold(fields[0], "misfire") {
+			if !schedule.IsMisfirePolicy(strings.ToLower(fields[1])) {
+				message.Text = fmt.Sprintf("I don't know the misfire policy `%s`. Please, use one of: `%s`, `%s`, `%s`.", fields[1], schedule.MisfireSkip, schedule.MisfireRunOnce, schedule.MisfireRunAll)
+				return message, nil
+			}
+
+			item, err = schedule.S.SetMisfirePolicy(id, user, strings.ToLower(fields[1]))
+			break
+		}
+
+		executeAt, parseErr := new(schedule.ExecuteAt).In(location).FromString(matches["4"])
+		if parseErr != nil || executeAt.IsEmpty() {
+			message.Text = "Please, specify the new time, like ```change schedule 1 to in 2 hours``` or ```change schedule 1 to cron \"30 9 * * 1-5\"```."
+			if parseErr != nil {
+				message.Text = fmt.Sprintf("I cannot recognise the time: %s.\n%s", parseErr, message.Text)
+			}
+
+			return message, nil
+		}
+
+		item, err = schedule.S.Reschedule(id, user, executeAt)
+	}
+
+	if err != nil {
+		message.Text = failureText(id, err)
+		if errors.Is(err, schedule.ErrNotFound) || errors.Is(err, schedule.ErrForbidden) || errors.Is(err, schedule.ErrFinished) {
+			return message, nil
+		}
+
+		return message, err
+	}
+
+	switch command {
+	case "delete":
+		message.Text = fmt.Sprintf("The schedule #%d of `%s` event is deleted.", item.ID, item.ReactionType)
+	default:
+		message.Text = fmt.Sprintf("Done. %s", describe(item, location))
+	}
+
+	return message, nil
+}
+
+func listSchedules(message dto.BaseChatMessage, isChannel bool) (dto.BaseChatMessage, error) {
+	filter := schedule.Filter{Author: message.OriginalMessage.User}
+	if isChannel {
+		filter = schedule.Filter{Channel: message.Channel}
+	}
+
+	location := timezone.S.Location(message.OriginalMessage.User)
+	items, err := schedule.S.List(filter)
+	if err != nil {
+		message.Text = "Failed to retrieve the schedules."
+		return message, err
+	}
+
+	if len(items) == 0 {
+		message.Text = "There are no schedules."
+		return message, nil
+	}
+
+	message.Text = "Here is the list:"
+	for _, item := range items {
+		message.Text += fmt.Sprintf("\n* %s", describe(item, location))
+	}
+
+	return message, nil
+}
+
+func showSchedule(message dto.BaseChatMessage, id int, location *time.Location) (dto.BaseChatMessage, error) {
+	item, err := getVisibleSchedule(message, id)
+	if err != nil {
+		message.Text = failureText(id, err)
+		if errors.Is(err, schedule.ErrNotFound) {
+			return message, nil
+		}
+
+		return message, err
+	}
+
+	message.Text = describe(item, location)
+	message.Text += fmt.Sprintf("\nAuthor: <@%s>", item.Author)
+	message.Text += fmt.Sprintf("\nChannel: <#%s>", item.Channel)
+	message.Text += fmt.Sprintf("\nMissed runs: `%s`", item.ActualMisfirePolicy())
+	message.Text += fmt.Sprintf("\nTime zone: `%s`", scheduleLocation(item))
+	if item.Variables != "" {
+		message.Text += fmt.Sprintf("\nAnswers: `%s`", strings.ReplaceAll(item.Variables, schedule.VariablesDelimiter, "`, `"))
+	}
+
+	return message, nil
+}
+
+func showRuns(message dto.BaseChatMessage, id int, location *time.Location) (dto.BaseChatMessage, error) {
+	if _, err := getVisibleSchedule(message, id); err != nil {
+		message.Text = failureText(id, err)
+		if errors.Is(err, schedule.ErrNotFound) {
+			return message, nil
+		}
+
+		return message, err
+	}
+
+	runs, err := schedule.S.Runs(id, runsLimit)
+	if err != nil {
+		message.Text = "Failed to retrieve the runs of the schedule."
+		return message, err
+	}
+
+	if len(runs) == 0 {
+		message.Text = fmt.Sprintf("The schedule #%d was not triggered yet.", id)
+		return message, nil
+	}
+
+	message.Text = fmt.Sprintf("The latest runs of the schedule #%d, the times are in `%s` time zone:", id, location)
+	for _, run := range runs {
+		run.ScheduledAt, run.StartedAt, run.RetryAt = run.ScheduledAt.In(location), run.StartedAt.In(location), run.RetryAt.In(location)
+		message.Text += fmt.Sprintf("\n* %s", run.String())
+	}
+
+	return message, nil
+}
+
+// getVisibleSchedule retrieves the schedule, which can be seen in the current channel. The schedules of the other channels are visible only to their authors
+func getVisibleSchedule(message dto.BaseChatMessage, id int) (schedule.Item, error) {
+	item, err := schedule.S.Get(id)
+	if err != nil {
+		return schedule.Item{}, err
+	}
+
+	if item.Channel != message.Channel && !item.CanBeChangedBy(message.OriginalMessage.User) {
+		return schedule.Item{}, schedule.ErrNotFound
+	}
+
+	return item, nil
+}
+
+// describe retrieves the short description of the schedule. The next run is shown in the time zone of the reader
+func describe(item schedule.Item, location *time.Location) string {
+	text := fmt.Sprintf("#%d `%s` %s", item.ID, item.ReactionType, item.ExecuteAt.String())
+	if itemLocation := scheduleLocation(item); itemLocation.String() != location.String() {
+		text += fmt.Sprintf(" %s", itemLocation)
+	}
+	switch {
+	case item.IsPaused():
+		return text + ", paused"
+	case item.IsFinished():
+		return text + ", finished"
+	}
+
+	next := item.NextRun()
+	if next.IsZero() {
+		return text + ", never runs"
+	}
+
+	return fmt.Sprintf("%s, next run %s", text, next.In(location).Format(dateFormat))
+}
+
+// scheduleLocation retrieves the time zone, in which the time of the schedule is set
+func scheduleLocation(item schedule.Item) *time.Location {
+	if item.ExecuteAt.Location == nil {
+		return timezone.Default()
+	}
+
+	return item.ExecuteAt.Location
+}
+
+func failureText(id int, err error) string {
+	switch {
+	case errors.Is(err, schedule.ErrNotFound):
+		return fmt.Sprintf("I cannot find the schedule #%d.", id)
+	case errors.Is(err, schedule.ErrForbidden), errors.Is(err, schedule.ErrFinished):
+		return fmt.Sprintf("Sorry, %s.", err)
+	default:
+		return fmt.Sprintf("Failed to change the schedule #%d.\nReason: %s", id, err)
+	}
+}
+
+// Install method for installation of event
+func (e EventStruct) Install() error {
+	log.Logger().Debug().
+		Str("event_name", EventName).
+		Str("event_version", EventVersion).
+		Msg("Triggered event installation")
+
+	return container.C.Dictionary.InstallNewEventScenario(database.EventScenario{
+		EventName:    EventName,
+		EventVersion: EventVersion,
+		Questions: []database.Question{
+			{
+				Question:      "list schedules",
+				Answer:        "Give me a sec.",
+				QuestionRegex: `(?i)((?:list|show|pause|resume|delete|change)\s+(?:my\s+|channel\s+)?schedules?\b)`,
+				QuestionGroup: "",
+			},
+		},
+	})
+}
+
+// Update for event update actions
+func (e EventStruct) Update() error {
+	return nil
+}
@@ -1,6 +1,7 @@
 package repeatevent
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/sharovik/devbot/internal/database"
 	"github.com/sharovik/devbot/internal/dto"
 	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/dto/event"
 	"github.com/sharovik/devbot/internal/log"
 	"github.com/sharovik/orm/clients"
 	cdto "github.com/sharovik/orm/dto"
@@ -49,6 +51,11 @@ func (e EventStruct) Alias() string {
 
 // Execute method which is called by message processor
 func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	return e.ExecuteContext(context.Background(), message)
+}
+
+// ExecuteContext method which is called by message processor. The context is passed to the repeated event, so it can be cancelled as well
+func (e EventStruct) ExecuteContext(ctx context.Context, message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
 	executedEvent, err := lastExecutedEvent(message)
 	if err != nil {
 		message.Text = "Failed to fetch the last executed events for that channel."
@@ -60,7 +67,7 @@ func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage,
 		return message, nil
 	}
 
-	answer, err := triggerScenario(executedEvent)
+	answer, err := triggerScenario(ctx, executedEvent)
 	if err != nil {
 		message.Text = fmt.Sprintf("Failed to execute the event.\n```%s```", err)
 		return message, err
@@ -181,7 +188,7 @@ func getEventAliasByID(eventID int) (string, error) {
 	return item.Items()[0].GetField("alias").Value.(string), nil
 }
 
-func triggerScenario(item cdto.ModelInterface) (dto.BaseChatMessage, error) {
+func triggerScenario(ctx context.Context, item cdto.ModelInterface) (dto.BaseChatMessage, error) {
 	eventAlias, err := getEventAliasByID(item.GetField("event_id").Value.(int))
 	if err != nil {
 		return dto.BaseChatMessage{}, err
@@ -190,6 +197,7 @@ func triggerScenario(item cdto.ModelInterface) (dto.BaseChatMessage, error) {
 	var (
 		variables []string
 		channel   = item.GetField("channel").Value.(string)
+		user      = item.GetField("user").Value.(string)
 	)
 
 	if item.GetField("variables").Value.(string) != "" {
@@ -212,19 +220,21 @@ func triggerScenario(item cdto.ModelInterface) (dto.BaseChatMessage, error) {
 			ReactionType: eventAlias,
 		}
 
-		conversation.AddConversation(scenario, dto.BaseChatMessage{
+		conversation.S.Add(scenario, dto.BaseChatMessage{
 			Channel:           channel,
 			Text:              item.GetField("command").Value.(string),
 			AsUser:            false,
 			Ts:                time.Now(),
 			DictionaryMessage: dmAnswer,
-			OriginalMessage:   dto.BaseOriginalMessage{},
+			OriginalMessage: dto.BaseOriginalMessage{
+				User: user,
+			},
 		})
 
-		return container.C.DefinedEvents[eventAlias].Execute(conversation.GetConversation(channel).LastQuestion)
+		return event.WithContext(container.C.DefinedEvents[eventAlias]).ExecuteContext(ctx, conversation.S.Get(conversation.Key{Channel: channel, User: user}).LastQuestion)
 	}
 
-	return container.C.DefinedEvents[eventAlias].Execute(dto.BaseChatMessage{
+	return event.WithContext(container.C.DefinedEvents[eventAlias]).ExecuteContext(ctx, dto.BaseChatMessage{
 		Channel: channel,
 		Text:    item.GetField("command").Value.(string),
 		AsUser:  false,
@@ -237,7 +247,7 @@ func triggerScenario(item cdto.ModelInterface) (dto.BaseChatMessage, error) {
 		},
 		OriginalMessage: dto.BaseOriginalMessage{
 			Text:  item.GetField("command").Value.(string),
-			User:  item.GetField("user").Value.(string),
+			User:  user,
 			Files: nil,
 		},
 	})
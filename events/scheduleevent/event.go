@@ -3,6 +3,7 @@ package scheduleevent
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/sharovik/devbot/internal/container"
 	"github.com/sharovik/devbot/internal/database"
@@ -13,6 +14,7 @@ import (
 	"github.com/sharovik/devbot/internal/service/message"
 	"github.com/sharovik/devbot/internal/service/message/conversation"
 	"github.com/sharovik/devbot/internal/service/schedule"
+	"github.com/sharovik/devbot/internal/service/timezone"
 )
 
 const (
@@ -22,11 +24,16 @@ const (
 	//EventVersion the version of the event
 	EventVersion = "1.0.0"
 
-	supportedTimeFormats = "`YYYY-mm-dd HH:ii`; `DD days`; `HH hours`; `ii minutes`"
+	supportedTimeFormats = "`YYYY-mm-dd HH:ii`; `in 1h30m`; `tomorrow at 10am`; `next friday 17:00`; `on november 2`; `every monday at 9:10`; `cron \"30 9 * * 1-5\"`; `cron @daily`"
 
 	helpMessage = "Ask me `schedule event {event_name} {time_string}` and I will schedule the event. I may ask you the requested event questions. \nThe use-case scenario: you triggered the event, and you want to repeat it, but in a few hours. In that case, ask me: `schedule event {event_name} in 2 hours`. The event will be executed in 2 hours from current time" +
+		"\nTo repeat the event by the cron expression, ask me: `schedule event {event_name} cron \"30 9 * * 1-5\"`. The expression contains 5 fields: minute, hour, day of month, month and day of week. Use `1#1` in the day of week for the first Monday of the month, or the macros like `@hourly`, `@daily`, `@weekly` and `@monthly`." +
+		"\nAdd `misfire skip`, `misfire run_once` or `misfire run_all` to the time to select, how the runs missed while I was offline are handled." +
 		"\nSupported time formats: " + supportedTimeFormats + ".\nMake sure target event is configured OR does have the scenario questions."
 
+	//The misfire policy can be added to the time, like `cron @daily misfire run_once`
+	misfireRegex = `(?i)(?:^|\s)misfire\s+(skip|run_once|run_all)\b`
+
 	questionTime       = "When we need to trigger this event? Supported formats: " + supportedTimeFormats
 	questionEventAlias = "Which event I need to execute?\nPlease, provide the event alias(use events list command to get it)."
 )
@@ -36,14 +43,42 @@ type EventStruct struct {
 }
 
 type requestedScenario struct {
-	Scenario  database.EventScenario
-	ExecuteAt schedule.ExecuteAt
+	Scenario      database.EventScenario
+	ExecuteAt     schedule.ExecuteAt
+	MisfirePolicy string
+}
+
+// requestedScenarios the scenarios, which wait for the answers before they are scheduled. The events are executed concurrently, so the access is guarded
+type requestedScenarios struct {
+	mu        sync.Mutex
+	scenarios map[conversation.Key]requestedScenario
+}
+
+func (r *requestedScenarios) get(key conversation.Key) requestedScenario {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.scenarios[key]
+}
+
+func (r *requestedScenarios) set(key conversation.Key, scenario requestedScenario) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.scenarios[key] = scenario
+}
+
+func (r *requestedScenarios) delete(key conversation.Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.scenarios, key)
 }
 
 // Event - object which is ready to use
 var (
-	Event              = EventStruct{}
-	requestedScenarios = map[string]requestedScenario{}
+	Event     = EventStruct{}
+	requested = &requestedScenarios{scenarios: map[conversation.Key]requestedScenario{}}
 )
 
 // Help retrieves the help message
@@ -59,9 +94,10 @@ func (e EventStruct) Alias() string {
 // Execute method which is called by message processor
 func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
 
+	key := conversation.NewKey(message)
+
 	//We schedule the scenario
-	if requestedScenarios[message.Channel].Scenario.ID != 0 {
-		rScenario := requestedScenarios[message.Channel]
+	if rScenario := requested.get(key); rScenario.Scenario.ID != 0 {
 		executeAt := rScenario.ExecuteAt
 		if executeAt.IsEmpty() {
 			message.Text = "Failed to schedule scenario"
@@ -70,7 +106,7 @@ func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage,
 			return message, nil
 		}
 
-		delete(requestedScenarios, message.Channel)
+		requested.delete(key)
 
 		if err := scheduleRequestedScenario(rScenario, message, executeAt); err != nil {
 			message.Text = "Failed to schedule scenario"
@@ -91,7 +127,7 @@ func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage,
 			return message, err
 		}
 
-		if err = askScenarioQuestions(scenarioID, message.Channel); err != nil {
+		if err = askScenarioQuestions(scenarioID, key); err != nil {
 			message.Text = "Failed to trigger the main questions for the schedule scenario"
 			return message, err
 		}
@@ -105,7 +141,7 @@ func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage,
 	scheduleTime := getScheduleTime(message)
 	//if event type is defined, we ask questions from that event to collect the answers and use them during schedule.
 	if eventType != "" {
-		rScenario, forceSchedule, err := askEventQuestions(eventType, message.Channel, scheduleTime)
+		rScenario, forceSchedule, err := askEventQuestions(eventType, key, scheduleTime, getMisfirePolicy(message))
 		if err != nil {
 			message.Text = "I cannot ask you the questions from that event. Please, try again."
 			return message, err
@@ -143,26 +179,27 @@ func isAllVariablesDefined(message dto.BaseChatMessage) bool {
 func scheduleRequestedScenario(rScenario requestedScenario, message dto.BaseChatMessage, scheduleTime schedule.ExecuteAt) error {
 	var variables []string
 
-	for _, value := range conversation.GetConversation(message.Channel).Scenario.RequiredVariables {
+	for _, value := range conversation.S.Get(conversation.NewKey(message)).Scenario.RequiredVariables {
 		variables = append(variables, value.Value)
 	}
 
 	item := schedule.Item{
-		Author:       message.OriginalMessage.User,
-		Channel:      message.Channel,
-		ScenarioID:   rScenario.Scenario.ID,
-		EventID:      rScenario.Scenario.EventID,
-		ReactionType: rScenario.Scenario.EventName,
-		Variables:    strings.Join(variables, schedule.VariablesDelimiter),
-		Scenario:     rScenario.Scenario,
-		ExecuteAt:    scheduleTime,
-		IsRepeatable: rScenario.ExecuteAt.IsRepeatable,
+		Author:        message.OriginalMessage.User,
+		Channel:       message.Channel,
+		ScenarioID:    rScenario.Scenario.ID,
+		EventID:       rScenario.Scenario.EventID,
+		ReactionType:  rScenario.Scenario.EventName,
+		Variables:     strings.Join(variables, schedule.VariablesDelimiter),
+		Scenario:      rScenario.Scenario,
+		ExecuteAt:     scheduleTime,
+		IsRepeatable:  rScenario.ExecuteAt.IsRepeatable,
+		MisfirePolicy: rScenario.MisfirePolicy,
 	}
 
 	return schedule.S.Schedule(item)
 }
 
-func askEventQuestions(eventType string, channel string, scheduleTime schedule.ExecuteAt) (rScenario requestedScenario, forceSchedule bool, err error) {
+func askEventQuestions(eventType string, key conversation.Key, scheduleTime schedule.ExecuteAt, misfirePolicy string) (rScenario requestedScenario, forceSchedule bool, err error) {
 	eventID, err := container.C.Dictionary.FindEventByAlias(eventType)
 	if err != nil {
 		return
@@ -181,17 +218,18 @@ func askEventQuestions(eventType string, channel string, scheduleTime schedule.E
 	}
 
 	if len(scenario.RequiredVariables) > 0 {
-		if err = message.TriggerScenario(channel, scenario, false); err != nil {
+		if err = message.TriggerScenario(key, scenario, false); err != nil {
 			return
 		}
 
 		//We change back the event type of scenario to the original one, to make sure we schedule the right event
 		scenario.EventName = eventType
 
-		requestedScenarios[channel] = requestedScenario{
-			Scenario:  scenario,
-			ExecuteAt: scheduleTime,
-		}
+		requested.set(key, requestedScenario{
+			Scenario:      scenario,
+			ExecuteAt:     scheduleTime,
+			MisfirePolicy: misfirePolicy,
+		})
 
 		return
 	}
@@ -201,19 +239,20 @@ func askEventQuestions(eventType string, channel string, scheduleTime schedule.E
 
 	//We schedule this event right away, because it does not have any questions/required variables to ask
 	return requestedScenario{
-		Scenario:  scenario,
-		ExecuteAt: scheduleTime,
+		Scenario:      scenario,
+		ExecuteAt:     scheduleTime,
+		MisfirePolicy: misfirePolicy,
 	}, true, nil
 }
 
-func askScenarioQuestions(scenarioID int64, channel string) error {
+func askScenarioQuestions(scenarioID int64, key conversation.Key) error {
 	//We prepare the scenario, with our event name, to make sure we execute the right at the end
 	scenario, err := service.PrepareScenario(scenarioID, EventName)
 	if err != nil {
 		return err
 	}
 
-	if err = message.TriggerScenario(channel, scenario, false); err != nil {
+	if err = message.TriggerScenario(key, scenario, false); err != nil {
 		return err
 	}
 
@@ -221,7 +260,7 @@ func askScenarioQuestions(scenarioID int64, channel string) error {
 }
 
 func getReactionType(message dto.BaseChatMessage) (eventType string) {
-	conv := conversation.GetConversation(message.Channel)
+	conv := conversation.S.Get(conversation.NewKey(message))
 
 	//If we already have opened conversation, we will try to get the answer from the required variables
 	if conv.Scenario.ID != int64(0) {
@@ -238,7 +277,7 @@ func getReactionType(message dto.BaseChatMessage) (eventType string) {
 }
 
 func getScheduleTime(message dto.BaseChatMessage) schedule.ExecuteAt {
-	conv := conversation.GetConversation(message.Channel)
+	conv := conversation.S.Get(conversation.NewKey(message))
 
 	text := message.OriginalMessage.Text
 	//If we already have opened conversation, we will try to get the answer from the required variables
@@ -251,7 +290,8 @@ func getScheduleTime(message dto.BaseChatMessage) schedule.ExecuteAt {
 		}
 	}
 
-	r, err := new(schedule.ExecuteAt).FromString(text)
+	//The time is parsed in the time zone of the user, so `every day at 9:00` means 9:00 of the user
+	r, err := new(schedule.ExecuteAt).In(timezone.S.Location(message.OriginalMessage.User)).FromString(text)
 	if err != nil {
 		log.Logger().AddError(err).Msg("Failed to parse time string")
 		return schedule.ExecuteAt{}
@@ -260,6 +300,23 @@ func getScheduleTime(message dto.BaseChatMessage) schedule.ExecuteAt {
 	return r
 }
 
+// getMisfirePolicy retrieves the misfire policy from the same text as the schedule time. The empty policy means the default one
+func getMisfirePolicy(message dto.BaseChatMessage) string {
+	conv := conversation.S.Get(conversation.NewKey(message))
+
+	text := message.OriginalMessage.Text
+	if conv.Scenario.ID != int64(0) {
+		for _, variable := range conv.Scenario.RequiredVariables {
+			if questionTime == variable.Question {
+				text = variable.Value
+				break
+			}
+		}
+	}
+
+	return strings.ToLower(helper.FindMatches(misfireRegex, text)["1"])
+}
+
 // Install method for installation of event
 func (e EventStruct) Install() error {
 	log.Logger().Debug().
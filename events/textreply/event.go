@@ -0,0 +1,83 @@
+package textreply
+
+import (
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/definitions"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+)
+
+const (
+	//EventName the name of the event
+	EventName = definitions.ReplyEventAlias
+
+	//EventVersion the version of the event
+	EventVersion = "1.0.0"
+
+	helpMessage = "I answer with the replies of the scenarios, which are defined in the YAML files."
+)
+
+// EventStruct the struct for the event object. It will be used for initialisation of the event in defined-events.go file.
+type EventStruct struct {
+}
+
+// Event - object which is ready to use
+var Event = EventStruct{}
+
+// Help retrieves the help message
+func (e EventStruct) Help() string {
+	return helpMessage
+}
+
+// Alias retrieves the event alias
+func (e EventStruct) Alias() string {
+	return EventName
+}
+
+// Execute method which is called by message processor
+func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	currentConversation := conversation.S.Get(conversation.NewKey(message))
+
+	//The reply of the scenario without variables was already sent as the answer of the question
+	if len(currentConversation.Scenario.RequiredVariables) == 0 {
+		message.Text = ""
+		return message, nil
+	}
+
+	definition, ok := definitions.S.FindByScenarioID(message.DictionaryMessage.ScenarioID)
+	if !ok {
+		log.Logger().Warn().
+			Int64("scenario_id", message.DictionaryMessage.ScenarioID).
+			Msg("The scenario definition is not loaded")
+
+		message.Text = "Sorry, I don't know how to reply to this scenario anymore."
+		return message, nil
+	}
+
+	reply, err := definition.Render(definition.Values(currentConversation.Scenario.RequiredVariables))
+	if err != nil {
+		message.Text = "Failed to prepare the reply. Sorry."
+		return message, err
+	}
+
+	message.Text = reply
+	return message, nil
+}
+
+// Install method for installation of event. The scenarios of the event are synced from the definitions files
+func (e EventStruct) Install() error {
+	log.Logger().Debug().
+		Str("event_name", EventName).
+		Str("event_version", EventVersion).
+		Msg("Triggered event installation")
+
+	_, err := container.C.Dictionary.InsertEvent(EventName, EventVersion)
+
+	return err
+}
+
+// Update for event update actions
+func (e EventStruct) Update() error {
+	return nil
+}
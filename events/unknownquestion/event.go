@@ -1,10 +1,12 @@
 package unknownquestion
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/sharovik/devbot/internal/database"
 	"github.com/sharovik/devbot/internal/service"
@@ -36,10 +38,34 @@ const (
 type EventStruct struct {
 }
 
+// selectedEvents the aliases of the events, which wait for the confirmation of the user. The events are executed concurrently, so the access is guarded
+type selectedEvents struct {
+	mu      sync.Mutex
+	aliases map[conversation.Key]string
+}
+
+func (s *selectedEvents) set(key conversation.Key, alias string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aliases[key] = alias
+}
+
+// take returns the selected alias and removes it, so the same confirmation cannot be used twice
+func (s *selectedEvents) take(key conversation.Key) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias := s.aliases[key]
+	delete(s.aliases, key)
+
+	return alias
+}
+
 var (
 	// Event - object which is ready to use
-	Event                 = EventStruct{}
-	selectedConversations = map[string]string{}
+	Event    = EventStruct{}
+	selected = &selectedEvents{aliases: map[conversation.Key]string{}}
 )
 
 // Help retrieves the help message
@@ -54,18 +80,23 @@ func (e EventStruct) Alias() string {
 
 // Execute method which is called by message processor
 func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
-	conv := conversation.GetConversation(message.Channel)
-	if len(conv.Scenario.RequiredVariables) > 0 && selectedConversations[message.Channel] != "" {
-		selected := selectedConversations[message.Channel]
-		delete(selectedConversations, message.Channel)
+	return e.ExecuteContext(context.Background(), message)
+}
 
-		if getAnswer(message) {
-			return triggerSelectedScenarioQuestion(message, selected)
-		}
+// ExecuteContext method which is called by message processor. The potential events are searched word by word, so the search stops once the context is done
+func (e EventStruct) ExecuteContext(ctx context.Context, message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	key := conversation.NewKey(message)
+	conv := conversation.S.Get(key)
+	if len(conv.Scenario.RequiredVariables) > 0 {
+		if alias := selected.take(key); alias != "" {
+			if getAnswer(message) {
+				return triggerSelectedScenarioQuestion(message, alias)
+			}
 
-		message.Text = "I've got a negative answer from you. I will not trigger this scenario. Perhaps, you may use `events list` command, to see all events."
+			message.Text = "I've got a negative answer from you. I will not trigger this scenario. Perhaps, you may use `events list` command, to see all events."
 
-		return message, nil
+			return message, nil
+		}
 	}
 
 	textStr, err := extractRequestString(message.OriginalMessage.Text)
@@ -77,7 +108,11 @@ func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage,
 
 	words := strings.Split(textStr, " ")
 
-	var foundResults, cleanItems = findResults(words)
+	foundResults, cleanItems, err := findResults(ctx, words)
+	if err != nil {
+		message.Text = ""
+		return message, err
+	}
 
 	switch len(foundResults) {
 	case 0:
@@ -99,19 +134,14 @@ func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage,
 }
 
 func getAnswer(message dto.BaseChatMessage) (result bool) {
-	conv := conversation.GetConversation(message.Channel)
+	conv := conversation.S.Get(conversation.NewKey(message))
 
-	//If we already have opened conversation, we will try to get the answer from the required variables
+	//If we already have opened conversation, we will try to get the answer from the required variables.
+	//The answer of the bool variable is already normalised to yes or no
 	if conv.Scenario.ID != int64(0) {
 		for _, variable := range conv.Scenario.RequiredVariables {
-			if variable.Value != "" {
-				answer := strings.ToLower(variable.Value)
-				switch answer {
-				case "yes":
-					return true
-				default:
-					return false
-				}
+			if variable.IsAnswered() {
+				return variable.Value == "yes"
 			}
 		}
 	}
@@ -139,7 +169,7 @@ func triggerSelectedScenarioQuestion(msg dto.BaseChatMessage, eventAlias string)
 		scenario.ID = 0
 	}
 
-	if err = message.TriggerScenario(msg.Channel, scenario, false); err != nil {
+	if err = message.TriggerScenario(conversation.NewKey(msg), scenario, false); err != nil {
 		msg.Text = "Failed to trigger selected scenario. Try again later. Sorry."
 
 		return msg, err
@@ -167,13 +197,14 @@ func triggerPotentialScenarioQuestion(msg dto.BaseChatMessage, item cdto.ModelIn
 
 	scenario.RequiredVariables = []database.ScenarioVariable{
 		{
-			Question: fmt.Sprintf("Do you want me to trigger the `%s` event (later you can ask `%s --help` for more details)?\nPlease, answer yes or no", item.GetField("alias").Value.(string), item.GetField("question").Value.(string)),
+			Question:      fmt.Sprintf("Do you want me to trigger the `%s` event (later you can ask `%s --help` for more details)?\nPlease, answer yes or no", item.GetField("alias").Value.(string), item.GetField("question").Value.(string)),
+			VariableRules: database.VariableRules{Type: database.VariableTypeBool},
 		},
 	}
 
-	selectedConversations[msg.Channel] = item.GetField("alias").Value.(string)
+	selected.set(conversation.NewKey(msg), item.GetField("alias").Value.(string))
 
-	if err = message.TriggerScenario(msg.Channel, scenario, false); err != nil {
+	if err = message.TriggerScenario(conversation.NewKey(msg), scenario, false); err != nil {
 		msg.Text = "Failed to ask scenario questions"
 
 		return msg, err
@@ -202,7 +233,7 @@ func implodeDatabaseResults(items []cdto.ModelInterface) string {
 	return result
 }
 
-func findResults(words []string) (foundResults []string, cleanItems []cdto.ModelInterface) {
+func findResults(ctx context.Context, words []string) (foundResults []string, cleanItems []cdto.ModelInterface, err error) {
 	var processedEvents = map[string]string{}
 
 	for _, word := range words {
@@ -210,6 +241,10 @@ func findResults(words []string) (foundResults []string, cleanItems []cdto.Model
 			continue
 		}
 
+		if err = ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
 		wordRes, err := findPotentialEvents(word)
 		if err != nil {
 			log.Logger().AddError(err).
@@ -272,9 +307,10 @@ func (e EventStruct) Install() error {
 		},
 		RequiredVariables: []database.ScenarioVariable{
 			{
-				Name:     "answer",
-				Value:    "",
-				Question: scenarioShouldITriggerThis,
+				Name:          "answer",
+				Value:         "",
+				Question:      scenarioShouldITriggerThis,
+				VariableRules: database.VariableRules{Type: database.VariableTypeBool},
 			},
 		},
 	})
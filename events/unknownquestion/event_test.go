@@ -0,0 +1,120 @@
+package unknownquestion
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/client"
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func initTestDictionary(t *testing.T) *database.Dictionary {
+	return database.NewDictionary(test.InitDatabase(t,
+		databasedto.EventModel,
+		databasedto.QuestionsRegexModel,
+		databasedto.ScenariosModel,
+		databasedto.QuestionsModel,
+	))
+}
+
+func TestExecuteContext_ParallelFlows(t *testing.T) {
+	d := initTestDictionary(t)
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				BotName: "devbot",
+				Type:    config.MessagesAPITypeConsole,
+			},
+		},
+		Dictionary:    d,
+		MessageClient: client.NewConsoleClient(io.Discard, "devbot", "developer", "console"),
+	}
+	conversation.S = conversation.NewMemoryStore()
+
+	assert.NoError(t, Event.Install())
+	assert.NoError(t, d.InstallNewEventScenario(database.EventScenario{
+		EventName: "deploy",
+		Questions: []database.Question{
+			{Question: "deploy", Answer: "Deploying"},
+		},
+	}))
+
+	const negativeAnswer = "I've got a negative answer from you. I will not trigger this scenario. Perhaps, you may use `events list` command, to see all events."
+
+	//Every flow asks the questions in several threads first and answers them afterwards, so the answers of both flows are processed at the same time
+	const threads = 20
+
+	users := []string{"U1", "U2"}
+
+	var asked, wg sync.WaitGroup
+	asked.Add(len(users))
+	results := make(chan string, len(users)*threads)
+	for _, user := range users {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+
+			var messages []dto.BaseChatMessage
+			for i := 0; i < threads; i++ {
+				msg := dto.BaseChatMessage{
+					Channel:  "C1",
+					ThreadTS: fmt.Sprintf("%d", i),
+					OriginalMessage: dto.BaseOriginalMessage{
+						Text:     "similar questions deploy",
+						User:     user,
+						Channel:  "C1",
+						ThreadTS: fmt.Sprintf("%d", i),
+					},
+				}
+
+				_, err := Event.ExecuteContext(context.Background(), msg)
+				assert.NoError(t, err)
+
+				key := conversation.NewKey(msg)
+				assert.Len(t, conversation.S.Get(key).Scenario.RequiredVariables, 1)
+				conversation.S.SetVariable(key, 0, "no")
+
+				msg.OriginalMessage.Text = "no"
+				messages = append(messages, msg)
+			}
+
+			asked.Done()
+			asked.Wait()
+
+			for _, msg := range messages {
+				result, err := Event.ExecuteContext(context.Background(), msg)
+				assert.NoError(t, err)
+
+				results <- fmt.Sprintf("%s:%s", user, result.Text)
+			}
+		}(user)
+	}
+
+	wg.Wait()
+	close(results)
+
+	counts := map[string]int{}
+	for text := range results {
+		counts[text]++
+	}
+
+	assert.Equal(t, map[string]int{
+		"U1:" + negativeAnswer: threads,
+		"U2:" + negativeAnswer: threads,
+	}, counts)
+}
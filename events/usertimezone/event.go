@@ -0,0 +1,112 @@
+package usertimezone
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/helper"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/timezone"
+)
+
+const (
+	//EventName the name of the event
+	EventName = "usertimezone"
+
+	//EventVersion the version of the event
+	EventVersion = "1.0.0"
+
+	helpMessage = "I use your time zone to schedule the events and to show the times:" +
+		"\n```my timezone``` shows the time zone, which I use for you;" +
+		"\n```set my timezone to Europe/Berlin``` selects your time zone, it has the priority over the time zone of your chat profile;" +
+		"\n```reset my timezone``` removes the selected time zone, so the time zone of your chat profile is used again." +
+		"\nThe schedules keep the time zone, which was used during their creation."
+
+	commandRegex = `(?i)(?:(set|reset)\s+)?my\s+time\s?zone(?:\s+(?:to\s+)?([\w/+-]+))?`
+
+	dateFormat = "2006-01-02 15:04 MST"
+)
+
+// EventStruct the struct for the event object. It will be used for initialisation of the event in defined-events.go file.
+type EventStruct struct {
+}
+
+// Event - object which is ready to use
+var Event = EventStruct{}
+
+// Help retrieves the help message
+func (e EventStruct) Help() string {
+	return helpMessage
+}
+
+// Alias retrieves the event alias
+func (e EventStruct) Alias() string {
+	return EventName
+}
+
+// Execute method which is called by message processor
+func (e EventStruct) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	matches := helper.FindMatches(commandRegex, message.OriginalMessage.Text)
+	user := message.OriginalMessage.User
+
+	switch strings.ToLower(matches["1"]) {
+	case "set":
+		if matches["2"] == "" {
+			message.Text = "Please, specify the time zone, like ```set my timezone to Europe/Berlin```."
+			return message, nil
+		}
+
+		location, err := timezone.S.Select(user, matches["2"])
+		if err != nil {
+			message.Text = fmt.Sprintf("Sorry, I cannot select this time zone.\nReason: %s", err)
+			return message, nil
+		}
+
+		message.Text = fmt.Sprintf("Done. Your time zone is `%s`, the current time is %s.", location, timezone.Now(location).Format(dateFormat))
+	case "reset":
+		if err := timezone.S.Reset(user); err != nil {
+			message.Text = "Failed to reset your time zone."
+			return message, err
+		}
+
+		location := timezone.S.Location(user)
+		message.Text = fmt.Sprintf("Done. Now I use `%s` time zone for you, the current time is %s.", location, timezone.Now(location).Format(dateFormat))
+	default:
+		location := timezone.S.Location(user)
+		message.Text = fmt.Sprintf("I use `%s` time zone for you, the current time is %s.", location, timezone.Now(location).Format(dateFormat))
+		if selected, _ := timezone.S.Selected(user); selected == nil {
+			message.Text += "\nYou can select your own time zone by ```set my timezone to Europe/Berlin```."
+		}
+	}
+
+	return message, nil
+}
+
+// Install method for installation of event
+func (e EventStruct) Install() error {
+	log.Logger().Debug().
+		Str("event_name", EventName).
+		Str("event_version", EventVersion).
+		Msg("Triggered event installation")
+
+	return container.C.Dictionary.InstallNewEventScenario(database.EventScenario{
+		EventName:    EventName,
+		EventVersion: EventVersion,
+		Questions: []database.Question{
+			{
+				Question:      "my timezone",
+				Answer:        "Give me a sec.",
+				QuestionRegex: `(?i)((?:set\s+|reset\s+)?my\s+time\s?zone\b)`,
+				QuestionGroup: "",
+			},
+		},
+	})
+}
+
+// Update for event update actions
+func (e EventStruct) Update() error {
+	return nil
+}
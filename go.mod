@@ -10,6 +10,7 @@ require (
 	github.com/sharovik/orm v1.2.5
 	github.com/stretchr/testify v1.9.0
 	golang.org/x/net v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -21,5 +22,4 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.22 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+// ConsoleClient the messages client for the local terminal. The messages are printed into the Output instead of sending to the messages API
+type ConsoleClient struct {
+	BaseMessageClient
+
+	//Output the writer for the bot messages. If it is not set, os.Stdout will be used
+	Output io.Writer
+
+	BotName   string
+	UserID    string
+	ChannelID string
+
+	mu *sync.Mutex
+}
+
+// NewConsoleClient creates the console client for selected output, bot name, user and channel
+func NewConsoleClient(output io.Writer, botName string, userID string, channelID string) ConsoleClient {
+	return ConsoleClient{
+		Output:    output,
+		BotName:   botName,
+		UserID:    userID,
+		ChannelID: channelID,
+		mu:        &sync.Mutex{},
+	}
+}
+
+// AttachFileTo method prints the information about attached file
+func (client ConsoleClient) AttachFileTo(channel string, pathToFile string, filename string) ([]byte, int, error) {
+	if _, err := os.Stat(pathToFile); err != nil {
+		return nil, 0, err
+	}
+
+	response := []byte(fmt.Sprintf("[file %s: %s]", filename, pathToFile))
+	if err := client.print(channel, "", string(response)); err != nil {
+		return nil, 0, err
+	}
+
+	return response, http.StatusOK, nil
+}
+
+// SendMessage method prints the message into the output
+func (client ConsoleClient) SendMessage(message dto.BaseChatMessage) (resp dto.BaseResponseInterface, status int, err error) {
+	if err = client.print(message.Channel, message.ThreadTS, message.Text); err != nil {
+		return resp, 0, err
+	}
+
+	dtoResponse := dto.BaseResponse{}
+	dtoResponse.SetByteResponse([]byte(message.Text))
+
+	return &dtoResponse, http.StatusOK, nil
+}
+
+// GetConversationsList method returns the console channel
+func (client ConsoleClient) GetConversationsList() (dto.SlackResponseConversationsList, int, error) {
+	return dto.SlackResponseConversationsList{
+		Ok: true,
+		Channels: []dto.Channel{
+			{
+				ID:        client.ChannelID,
+				Name:      client.ChannelID,
+				IsChannel: true,
+				IsMember:  true,
+			},
+		},
+	}, http.StatusOK, nil
+}
+
+// GetUsersList method returns the bot and the console user
+func (client ConsoleClient) GetUsersList() (dto.SlackResponseUsersList, int, error) {
+	result, err := client.GetUsersListPaged("")
+
+	return result, http.StatusOK, err
+}
+
+// GetUsersListPaged method returns the bot and the console user. There is only one page
+func (client ConsoleClient) GetUsersListPaged(cursor string) (result dto.SlackResponseUsersList, err error) {
+	return dto.SlackResponseUsersList{
+		Ok: true,
+		Members: []dto.SlackMember{
+			{
+				ID:       client.BotName,
+				Name:     client.BotName,
+				RealName: client.BotName,
+				IsBot:    true,
+				Profile:  dto.Profile{RealName: client.BotName},
+			},
+			{
+				ID:       client.UserID,
+				Name:     client.UserID,
+				RealName: client.UserID,
+				Profile:  dto.Profile{RealName: client.UserID},
+			},
+		},
+	}, nil
+}
+
+func (client ConsoleClient) print(channel string, threadTS string, text string) error {
+	if client.mu != nil {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+	}
+
+	output := client.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	prefix := client.BotName
+	if channel != "" && channel != client.ChannelID {
+		prefix = fmt.Sprintf("%s in #%s", prefix, channel)
+	}
+
+	if threadTS != "" {
+		prefix = fmt.Sprintf("%s (thread %s)", prefix, threadTS)
+	}
+
+	_, err := fmt.Fprintf(output, "%s: %s\n", prefix, text)
+
+	return err
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleClient_SendMessage(t *testing.T) {
+	var output bytes.Buffer
+	c := NewConsoleClient(&output, "devbot", "developer", "console")
+
+	response, status, err := c.SendMessage(dto.BaseChatMessage{Channel: "console", Text: "Hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []byte("Hello"), response.GetByteResponse())
+
+	_, _, err = c.SendMessage(dto.BaseChatMessage{Channel: "release", ThreadTS: "1", Text: "Released"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "devbot: Hello\ndevbot in #release (thread 1): Released\n", output.String())
+}
+
+func TestConsoleClient_AttachFileTo(t *testing.T) {
+	var output bytes.Buffer
+	c := NewConsoleClient(&output, "devbot", "developer", "console")
+
+	filePath := path.Join(t.TempDir(), "report.txt")
+	assert.NoError(t, os.WriteFile(filePath, []byte("Report"), 0644))
+
+	_, status, err := c.AttachFileTo("console", filePath, "report.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "devbot: [file report.txt: "+filePath+"]\n", output.String())
+
+	_, _, err = c.AttachFileTo("console", path.Join(t.TempDir(), "missing.txt"), "missing.txt")
+	assert.Error(t, err)
+}
+
+func TestConsoleClient_GetUsersList(t *testing.T) {
+	c := NewConsoleClient(nil, "devbot", "developer", "console")
+
+	users, _, err := c.GetUsersList()
+	assert.NoError(t, err)
+	assert.Len(t, users.Members, 2)
+	assert.Equal(t, "devbot", users.Members[0].Profile.RealName)
+	assert.Equal(t, "developer", users.Members[1].ID)
+
+	channels, _, err := c.GetConversationsList()
+	assert.NoError(t, err)
+	assert.Len(t, channels.Channels, 1)
+	assert.Equal(t, "console", channels.Channels[0].ID)
+}
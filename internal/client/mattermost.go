@@ -0,0 +1,264 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+)
+
+const (
+	//MattermostUsersPerPage the number of users, which will be requested per one page
+	MattermostUsersPerPage = 200
+)
+
+// MattermostClient client for Mattermost API v4 calls. The HTTPClient base url should point to the API root, Example: https://mattermost.example.com/api/v4
+type MattermostClient struct {
+	BaseMessageClient
+}
+
+// AttachFileTo method uploads the file and posts it into specific channel
+func (client MattermostClient) AttachFileTo(channel string, pathToFile string, filename string) ([]byte, int, error) {
+	log.Logger().StartMessage("Mattermost attachment request")
+
+	log.Logger().Debug().
+		Str("channel", channel).
+		Str("file_path", pathToFile).
+		Str("filename", filename).
+		Msg("Received parameters")
+
+	var buf bytes.Buffer
+
+	writer := multipart.NewWriter(&buf)
+	if err := writer.WriteField("channel_id", channel); err != nil {
+		return nil, 0, err
+	}
+
+	part, err := writer.CreateFormFile("files", filename)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file, err := os.Open(pathToFile)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer file.Close()
+
+	if _, err = io.Copy(part, file); err != nil {
+		return nil, 0, err
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	response, statusCode, err := client.HTTPClient.Post("/files", buf, map[string]string{
+		"Content-Type": writer.FormDataContentType(),
+	})
+	if err = checkMattermostResponse(response, statusCode, err); err != nil {
+		return response, statusCode, err
+	}
+
+	var upload dto.MattermostResponseFileUpload
+	if err = json.Unmarshal(response, &upload); err != nil {
+		return response, statusCode, err
+	}
+
+	var fileIDs []string
+	for _, info := range upload.FileInfos {
+		fileIDs = append(fileIDs, info.ID)
+	}
+
+	byteStr, err := json.Marshal(dto.MattermostRequestCreatePost{
+		ChannelID: channel,
+		FileIDs:   fileIDs,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	response, statusCode, err = client.HTTPClient.Post("/posts", byteStr, map[string]string{})
+	if err = checkMattermostResponse(response, statusCode, err); err != nil {
+		return response, statusCode, err
+	}
+
+	log.Logger().FinishMessage("Mattermost attachment request")
+
+	return response, statusCode, nil
+}
+
+// SendMessage method creates the post in the message channel. If the message has ThreadTS, the post will be created as the reply to that thread
+func (client MattermostClient) SendMessage(message dto.BaseChatMessage) (resp dto.BaseResponseInterface, status int, err error) {
+	log.Logger().Debug().Interface("message", message).Msg("Start posts create")
+	byteStr, err := json.Marshal(dto.MattermostRequestCreatePost{
+		ChannelID: message.Channel,
+		Message:   message.Text,
+		RootID:    message.ThreadTS,
+	})
+	if err != nil {
+		return resp, 0, err
+	}
+
+	response, statusCode, err := client.HTTPClient.Post("/posts", byteStr, map[string]string{})
+	if err = checkMattermostResponse(response, statusCode, err); err != nil {
+		log.Logger().AddError(err).
+			RawJSON("response", response).
+			Int("status_code", statusCode).
+			Msg("Failed send message")
+		return resp, statusCode, err
+	}
+
+	var dtoResponse dto.MattermostResponsePost
+	if err = json.Unmarshal(response, &dtoResponse); err != nil {
+		return resp, statusCode, err
+	}
+
+	log.Logger().Debug().Interface("message", message).Msg("Finish posts create")
+	dtoResponse.SetByteResponse(response)
+
+	return &dtoResponse, statusCode, nil
+}
+
+// GetConversationsList method returns the channels of the bot user from all teams
+func (client MattermostClient) GetConversationsList() (dto.SlackResponseConversationsList, int, error) {
+	response, statusCode, err := client.HTTPClient.Get("/users/me/channels", map[string]string{})
+	if err = checkMattermostResponse(response, statusCode, err); err != nil {
+		return dto.SlackResponseConversationsList{}, statusCode, err
+	}
+
+	var channels dto.MattermostResponseChannelsList
+	if err = json.Unmarshal(response, &channels); err != nil {
+		return dto.SlackResponseConversationsList{}, statusCode, err
+	}
+
+	result := dto.SlackResponseConversationsList{Ok: true}
+	for _, channel := range channels {
+		c := dto.Channel{
+			ID:             channel.ID,
+			Name:           channel.Name,
+			NameNormalized: channel.DisplayName,
+			IsChannel:      channel.Type == dto.MattermostChannelTypeOpen || channel.Type == dto.MattermostChannelTypePrivate,
+			IsPrivate:      channel.Type == dto.MattermostChannelTypePrivate,
+			IsIm:           channel.Type == dto.MattermostChannelTypeDirect,
+			IsMpim:         channel.Type == dto.MattermostChannelTypeGroup,
+			IsArchived:     channel.DeleteAt != 0,
+			Creator:        channel.CreatorID,
+			IsMember:       true,
+		}
+		c.Purpose.Value = channel.Purpose
+		c.Topic.Value = channel.Header
+
+		result.Channels = append(result.Channels, c)
+	}
+
+	return result, statusCode, nil
+}
+
+// GetUsersList method returns all users of the Mattermost server
+func (client MattermostClient) GetUsersList() (dto.SlackResponseUsersList, int, error) {
+	result := dto.SlackResponseUsersList{Ok: true}
+
+	cursor := ""
+	for {
+		page, err := client.GetUsersListPaged(cursor)
+		if err != nil {
+			return dto.SlackResponseUsersList{}, 0, err
+		}
+
+		result.Members = append(result.Members, page.Members...)
+		if page.ResponseMetadata.NextCursor == "" {
+			break
+		}
+
+		cursor = page.ResponseMetadata.NextCursor
+	}
+
+	return result, http.StatusOK, nil
+}
+
+// GetUsersListPaged method returns the users list page. The cursor is the number of page, the empty cursor means first page
+func (client MattermostClient) GetUsersListPaged(cursor string) (result dto.SlackResponseUsersList, err error) {
+	page := 0
+	if cursor != "" {
+		if page, err = strconv.Atoi(cursor); err != nil {
+			return dto.SlackResponseUsersList{}, fmt.Errorf("invalid users list cursor `%s`", cursor)
+		}
+	}
+
+	response, statusCode, err := client.HTTPClient.Get("/users", map[string]string{
+		"page":     strconv.Itoa(page),
+		"per_page": strconv.Itoa(MattermostUsersPerPage),
+	})
+	if err = checkMattermostResponse(response, statusCode, err); err != nil {
+		return dto.SlackResponseUsersList{}, err
+	}
+
+	var users dto.MattermostResponseUsersList
+	if err = json.Unmarshal(response, &users); err != nil {
+		return dto.SlackResponseUsersList{}, err
+	}
+
+	result.Ok = true
+	for _, user := range users {
+		realName := user.Username
+		if user.FirstName != "" || user.LastName != "" {
+			realName = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+		}
+
+		//The members without the time zone have the nil one, like in Slack API
+		var tz interface{}
+		if name := user.Timezone.Name(); name != "" {
+			tz = name
+		}
+
+		result.Members = append(result.Members, dto.SlackMember{
+			ID:       user.ID,
+			Name:     user.Username,
+			RealName: realName,
+			Tz:       tz,
+			Deleted:  user.DeleteAt != 0,
+			IsBot:    user.IsBot,
+			Profile: dto.Profile{
+				RealName:    realName,
+				DisplayName: user.Nickname,
+				FirstName:   user.FirstName,
+				LastName:    user.LastName,
+				Email:       user.Email,
+			},
+		})
+	}
+
+	if len(users) == MattermostUsersPerPage {
+		result.ResponseMetadata.NextCursor = strconv.Itoa(page + 1)
+	}
+
+	return result, nil
+}
+
+// checkMattermostResponse retrieves the error of the request or the error message of the unsuccessful Mattermost API response
+func checkMattermostResponse(response []byte, statusCode int, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices {
+		return nil
+	}
+
+	var dtoError dto.MattermostResponseError
+	if err = json.Unmarshal(response, &dtoError); err != nil || dtoError.Message == "" {
+		return fmt.Errorf("unexpected mattermost API response status code %d", statusCode)
+	}
+
+	return errors.New(dtoError.Message)
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const mattermostTestToken = "test-token"
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+// fakeMattermostServer the local fake of Mattermost API v4, which keeps the created posts and uploaded files
+type fakeMattermostServer struct {
+	*httptest.Server
+	posts []dto.MattermostRequestCreatePost
+	files map[string]string
+	users dto.MattermostResponseUsersList
+}
+
+func newFakeMattermostServer(t *testing.T) *fakeMattermostServer {
+	s := &fakeMattermostServer{
+		files: map[string]string{},
+	}
+
+	for i := 0; i < MattermostUsersPerPage+1; i++ {
+		s.users = append(s.users, dto.MattermostUser{
+			ID:       fmt.Sprintf("user%d", i),
+			Username: fmt.Sprintf("user%d", i),
+		})
+	}
+
+	s.users[1].Timezone = dto.MattermostTimezone{UseAutomaticTimezone: "true", AutomaticTimezone: "Europe/Berlin", ManualTimezone: "Asia/Tokyo"}
+	s.users[2].Timezone = dto.MattermostTimezone{UseAutomaticTimezone: "false", AutomaticTimezone: "Europe/Berlin", ManualTimezone: "Asia/Tokyo"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/posts", func(w http.ResponseWriter, r *http.Request) {
+		var post dto.MattermostRequestCreatePost
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&post))
+
+		if post.ChannelID == "unknown" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"id":"api.context.permissions.app_error","message":"You do not have the appropriate permissions.","status_code":403}`))
+			return
+		}
+
+		s.posts = append(s.posts, post)
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(dto.MattermostResponsePost{
+			ID:        fmt.Sprintf("post%d", len(s.posts)),
+			ChannelID: post.ChannelID,
+			RootID:    post.RootID,
+			Message:   post.Message,
+			FileIDs:   post.FileIDs,
+		})
+	})
+	mux.HandleFunc("/api/v4/files", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseMultipartForm(1024*1024))
+
+		file, header, err := r.FormFile("files")
+		assert.NoError(t, err)
+
+		content, err := io.ReadAll(file)
+		assert.NoError(t, err)
+
+		fileID := fmt.Sprintf("file%d", len(s.files))
+		s.files[fileID] = string(content)
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(dto.MattermostResponseFileUpload{
+			FileInfos: []dto.MattermostFileInfo{{ID: fileID, Name: header.Filename}},
+		})
+	})
+	mux.HandleFunc("/api/v4/users/me/channels", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(dto.MattermostResponseChannelsList{
+			{ID: "channel1", Name: "town-square", DisplayName: "Town Square", Type: dto.MattermostChannelTypeOpen},
+			{ID: "channel2", Name: "secret", DisplayName: "Secret", Type: dto.MattermostChannelTypePrivate},
+			{ID: "channel3", Name: "user1__bot", Type: dto.MattermostChannelTypeDirect},
+		})
+	})
+	mux.HandleFunc("/api/v4/users", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+
+		result := dto.MattermostResponseUsersList{}
+		for i := page * perPage; i < len(s.users) && i < (page+1)*perPage; i++ {
+			result = append(result, s.users[i])
+		}
+
+		_ = json.NewEncoder(w).Encode(result)
+	})
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+mattermostTestToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"id":"api.context.session_expired.app_error","message":"Invalid or expired session, please login again.","status_code":401}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *fakeMattermostServer) client(token string) MattermostClient {
+	h := HTTPClient{
+		Client:     s.Server.Client(),
+		OAuthToken: token,
+		BaseURL:    s.URL + "/api/v4",
+	}
+
+	mc := MattermostClient{}
+	mc.HTTPClient = &h
+
+	return mc
+}
+
+func TestMattermostClient_SendMessage(t *testing.T) {
+	server := newFakeMattermostServer(t)
+
+	response, status, err := server.client(mattermostTestToken).SendMessage(dto.BaseChatMessage{
+		Channel:  "channel1",
+		Text:     "Hello world",
+		ThreadTS: "root1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "post1", response.(*dto.MattermostResponsePost).ID)
+	assert.NotEmpty(t, response.GetByteResponse())
+
+	assert.Len(t, server.posts, 1)
+	assert.Equal(t, dto.MattermostRequestCreatePost{
+		ChannelID: "channel1",
+		Message:   "Hello world",
+		RootID:    "root1",
+	}, server.posts[0])
+
+	_, status, err = server.client(mattermostTestToken).SendMessage(dto.BaseChatMessage{
+		Channel: "unknown",
+		Text:    "Hello world",
+	})
+	assert.EqualError(t, err, "You do not have the appropriate permissions.")
+	assert.Equal(t, http.StatusForbidden, status)
+
+	_, status, err = server.client("wrong-token").SendMessage(dto.BaseChatMessage{
+		Channel: "channel1",
+		Text:    "Hello world",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Len(t, server.posts, 1)
+}
+
+func TestMattermostClient_GetConversationsList(t *testing.T) {
+	server := newFakeMattermostServer(t)
+
+	result, status, err := server.client(mattermostTestToken).GetConversationsList()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, result.Ok)
+	assert.Len(t, result.Channels, 3)
+
+	assert.Equal(t, "channel1", result.Channels[0].ID)
+	assert.Equal(t, "town-square", result.Channels[0].Name)
+	assert.True(t, result.Channels[0].IsChannel)
+	assert.False(t, result.Channels[0].IsPrivate)
+
+	assert.True(t, result.Channels[1].IsChannel)
+	assert.True(t, result.Channels[1].IsPrivate)
+
+	assert.False(t, result.Channels[2].IsChannel)
+	assert.True(t, result.Channels[2].IsIm)
+}
+
+func TestMattermostClient_GetUsersList(t *testing.T) {
+	server := newFakeMattermostServer(t)
+	c := server.client(mattermostTestToken)
+
+	page, err := c.GetUsersListPaged("")
+	assert.NoError(t, err)
+	assert.Len(t, page.Members, MattermostUsersPerPage)
+	assert.Equal(t, "1", page.ResponseMetadata.NextCursor)
+
+	page, err = c.GetUsersListPaged(page.ResponseMetadata.NextCursor)
+	assert.NoError(t, err)
+	assert.Len(t, page.Members, 1)
+	assert.Equal(t, "", page.ResponseMetadata.NextCursor)
+
+	_, err = c.GetUsersListPaged("wrong")
+	assert.Error(t, err)
+
+	result, status, err := c.GetUsersList()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, result.Members, MattermostUsersPerPage+1)
+	assert.Equal(t, "user0", result.Members[0].ID)
+	assert.Equal(t, "user0", result.Members[0].Name)
+	assert.Equal(t, "user0", result.Members[0].Profile.RealName)
+	assert.Nil(t, result.Members[0].Tz)
+	assert.Equal(t, "Europe/Berlin", result.Members[1].Tz)
+	assert.Equal(t, "Asia/Tokyo", result.Members[2].Tz)
+}
+
+func TestMattermostClient_AttachFileTo(t *testing.T) {
+	server := newFakeMattermostServer(t)
+
+	filePath := path.Join(t.TempDir(), "report.txt")
+	assert.NoError(t, os.WriteFile(filePath, []byte("Report content"), 0644))
+
+	_, status, err := server.client(mattermostTestToken).AttachFileTo("channel1", filePath, "report.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, status)
+
+	assert.Equal(t, "Report content", server.files["file0"])
+	assert.Len(t, server.posts, 1)
+	assert.Equal(t, "channel1", server.posts[0].ChannelID)
+	assert.Equal(t, []string{"file0"}, server.posts[0].FileIDs)
+
+	_, _, err = server.client(mattermostTestToken).AttachFileTo("channel1", path.Join(t.TempDir(), "missing.txt"), "missing.txt")
+	assert.Error(t, err)
+}
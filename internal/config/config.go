@@ -24,6 +24,25 @@ type MessagesAPIConfig struct {
 	MainChannelAlias string
 	MainChannelID    string
 	Type             string
+
+	//ReceiverMode the way how the messages are received from Slack. Supported: socket, http
+	ReceiverMode string
+
+	//SigningSecret the Slack application signing secret, which is used for the requests verification in http receiver mode
+	SigningSecret string
+
+	//HTTPAddress the address, on which the http receiver listens the Events API requests. Example: :8080
+	HTTPAddress string
+
+	//DeduplicationTTL the time in seconds, during which the redelivered messages are dropped
+	DeduplicationTTL int64
+
+	//DeduplicationPersisted enables the storing of processed messages identifiers in the database
+	DeduplicationPersisted bool
+
+	//ConsoleUserID and ConsoleChannelID are used by console messages API type as the author and the channel of the typed messages
+	ConsoleUserID    string
+	ConsoleChannelID string
 }
 
 // BitBucketConfig struct for bitbucket config
@@ -51,6 +70,57 @@ type HTTPClient struct {
 	InsecureSkipVerify  bool
 }
 
+// EventsExecutorConfig the configuration for the events execution
+type EventsExecutorConfig struct {
+	//Workers the number of events, which can be executed at the same time. If it is not specified, the default value is used
+	Workers int
+
+	//QueueSize the number of events, which can wait for the execution. Once the queue is full, the new events are rejected. If it is not specified, the default value is used
+	QueueSize int
+
+	//ConcurrencyLimits the maximum number of events with the same alias, which can be executed at the same time
+	ConcurrencyLimits map[string]int
+
+	//DefaultTimeout the time in seconds, after which the event execution is cancelled. If it is not specified, the default value is used
+	DefaultTimeout int
+
+	//Timeouts the execution timeouts in seconds of the events by their alias. They override the timeouts, which are defined by the events
+	Timeouts map[string]int
+}
+
+// MatchingConfig the configuration for the matching of received messages with the dictionary questions
+type MatchingConfig struct {
+	//Threshold the minimal confidence from 0 to 1 of the matched question. If it is not specified, the default value is used
+	Threshold float64
+
+	//AmbiguityMargin the difference between the confidences of the best questions, below which the user is asked to choose one of them. If it is not specified, the default value is used
+	AmbiguityMargin float64
+
+	//CacheTTL the time in seconds, after which the questions are loaded again, so the changes of the other processes, like install or update, are used
+	CacheTTL int
+}
+
+// PhrasebookConfig the configuration of the phrases, which the bot uses in the conversations
+type PhrasebookConfig struct {
+	//Path the file or the directory with the YAML phrasebooks. Only the built-in English phrasebook is used, when the path is empty
+	Path string
+
+	//DefaultLocale the locale of the channels and users, which don't have the selected locale. If it is not specified, English is used
+	DefaultLocale string
+
+	//Locales the locales of the channels and users by their IDs. The locale of the user overrides the locale of the channel
+	Locales map[string]string
+}
+
+// SchedulesConfig the configuration of the scheduled events execution
+type SchedulesConfig struct {
+	//Retries the number of the retries of the scheduled event, which execution failed. The failed events are not retried by default
+	Retries int
+
+	//RetryBackoff the time in minutes before the first retry. Each next retry waits twice longer. If it is not specified, the default value is used
+	RetryBackoff int
+}
+
 // Config configuration object
 type Config struct {
 	appEnv            string
@@ -62,6 +132,23 @@ type Config struct {
 	Database          clients.DatabaseConfig
 	HTTPClient        HTTPClient
 	LogConfig         log.Config
+
+	//ShutdownTimeout the time in seconds, during which the running events are awaited before the application stop
+	ShutdownTimeout int64
+
+	EventsExecutor EventsExecutorConfig
+
+	Matching MatchingConfig
+
+	//LearningTrainers the users, who can teach the bot in the learning mode. Nobody can teach the bot, when the list is empty
+	LearningTrainers []string
+
+	//ScenariosPath the file or the directory with the YAML scenario definitions. The definitions are not loaded, when the path is empty
+	ScenariosPath string
+
+	Phrasebook PhrasebookConfig
+
+	Schedules SchedulesConfig
 }
 
 // cfg variable which contains initialised Config
@@ -98,6 +185,27 @@ const (
 	//EnvWebAPIOAuthToken env variable for message web api oauth token.
 	EnvWebAPIOAuthToken = "MESSAGES_API_WEB_API_OAUTH_TOKEN"
 
+	//EnvReceiverMode env variable for the messages receiver mode
+	EnvReceiverMode = "MESSAGES_API_RECEIVER_MODE"
+
+	//EnvSigningSecret env variable for the signing secret of the Events API requests
+	EnvSigningSecret = "MESSAGES_API_SIGNING_SECRET"
+
+	//EnvHTTPAddress env variable for the listen address of the http receiver
+	EnvHTTPAddress = "MESSAGES_API_HTTP_ADDRESS"
+
+	//EnvDeduplicationTTL env variable for the time in seconds, during which the redelivered messages are dropped
+	EnvDeduplicationTTL = "MESSAGES_API_DEDUPLICATION_TTL"
+
+	//EnvDeduplicationPersisted env variable, which enables the storing of processed messages identifiers in the database
+	EnvDeduplicationPersisted = "MESSAGES_API_DEDUPLICATION_PERSISTED"
+
+	//EnvConsoleUserID env variable for the user ID, which is used as the author of messages in console messages API type
+	EnvConsoleUserID = "MESSAGES_API_CONSOLE_USER_ID"
+
+	//EnvConsoleChannelID env variable for the channel ID, which is used for messages in console messages API type
+	EnvConsoleChannelID = "MESSAGES_API_CONSOLE_CHANNEL_ID"
+
 	//DatabaseConnection env variable for database connection type
 	DatabaseConnection = "DATABASE_CONNECTION"
 
@@ -144,6 +252,54 @@ const (
 	//learningEnabled enables or disables the learning mode. If enabled, the bot will try to ask in the main channel, how to react on that message.
 	learningEnabled = "LEARNING_MODE_ENABLED"
 
+	//EnvLearningTrainers env variable for the IDs of the users separated by comma, who can teach the bot in the learning mode
+	EnvLearningTrainers = "LEARNING_MODE_TRAINERS"
+
+	//EnvScenariosPath env variable for the file or the directory with the YAML scenario definitions
+	EnvScenariosPath = "SCENARIOS_PATH"
+
+	//EnvPhrasebookPath env variable for the file or the directory with the YAML phrasebooks
+	EnvPhrasebookPath = "PHRASEBOOK_PATH"
+
+	//EnvPhrasebookDefaultLocale env variable for the locale of the channels and users, which don't have the selected locale
+	EnvPhrasebookDefaultLocale = "PHRASEBOOK_DEFAULT_LOCALE"
+
+	//EnvPhrasebookLocales env variable for the locales of the channels and users separated by comma. Example: C0LAN2Q65:de,U0LAN0Z89:en
+	EnvPhrasebookLocales = "PHRASEBOOK_LOCALES"
+
+	//EnvShutdownTimeout env variable for the time in seconds, during which the running events are awaited before the application stop
+	EnvShutdownTimeout = "SHUTDOWN_TIMEOUT"
+
+	//EnvEventsWorkers env variable for the number of events, which can be executed at the same time
+	EnvEventsWorkers = "EVENTS_WORKERS"
+
+	//EnvEventsQueueSize env variable for the number of events, which can wait for the execution
+	EnvEventsQueueSize = "EVENTS_QUEUE_SIZE"
+
+	//EnvEventsConcurrencyLimits env variable for the concurrency limits of the events separated by comma. Example: bitbucketrelease:1,scheduleevent:2
+	EnvEventsConcurrencyLimits = "EVENTS_CONCURRENCY_LIMITS"
+
+	//EnvEventsDefaultTimeout env variable for the time in seconds, after which the event execution is cancelled
+	EnvEventsDefaultTimeout = "EVENTS_DEFAULT_TIMEOUT"
+
+	//EnvEventsTimeouts env variable for the execution timeouts in seconds of the events separated by comma. Example: bitbucketrelease:600
+	EnvEventsTimeouts = "EVENTS_TIMEOUTS"
+
+	//EnvMatchingThreshold env variable for the minimal confidence from 0 to 1 of the matched question
+	EnvMatchingThreshold = "MATCHING_THRESHOLD"
+
+	//EnvMatchingAmbiguityMargin env variable for the difference between the confidences of the best questions, below which the user is asked to choose one of them
+	EnvMatchingAmbiguityMargin = "MATCHING_AMBIGUITY_MARGIN"
+
+	//EnvMatchingCacheTTL env variable for the time in seconds, after which the questions are loaded again
+	EnvMatchingCacheTTL = "MATCHING_CACHE_TTL"
+
+	//EnvSchedulesRetries env variable for the number of the retries of the failed scheduled event
+	EnvSchedulesRetries = "SCHEDULES_RETRIES"
+
+	//EnvSchedulesRetryBackoff env variable for the time in minutes before the first retry of the failed scheduled event
+	EnvSchedulesRetryBackoff = "SCHEDULES_RETRY_BACKOFF"
+
 	envLogOutput            = "LOG_OUTPUT"
 	envLogLevel             = "LOG_LEVEL"
 	envLogFieldContext      = "LOG_FIELD_CONTEXT"
@@ -153,10 +309,29 @@ const (
 	//MessagesAPITypeSlack message messages API type
 	MessagesAPITypeSlack = "slack"
 
+	//MessagesAPITypeMattermost the Mattermost messages API type
+	MessagesAPITypeMattermost = "mattermost"
+
+	//ReceiverModeSocket the messages are received through the websocket connection. For Slack it is the Socket Mode
+	ReceiverModeSocket = "socket"
+
+	//ReceiverModeHTTP the messages are received by the http server from the Slack Events API requests
+	ReceiverModeHTTP = "http"
+
+	//MessagesAPITypeConsole the local terminal messages API type. Can be used for the events development without the real messages API
+	MessagesAPITypeConsole = "console"
+
 	defaultMainChannelAlias       = "general"
 	defaultBotName                = "devbot"
+	defaultReceiverMode           = ReceiverModeSocket
+	defaultHTTPAddress            = ":8080"
+	defaultConsoleUserID          = "developer"
+	defaultConsoleChannelID       = "console"
 	defaultMessagesAPIType        = MessagesAPITypeSlack
 	defaultDatabaseConnection     = "sqlite"
+	defaultShutdownTimeout        = 25
+	defaultSchedulesRetryBackoff  = 1
+	defaultMatchingCacheTTL       = 60
 	defaultEnvFilePath            = "./.env"
 	defaultEnvFileRootProjectPath = "./../../.env"
 
@@ -192,6 +367,13 @@ func Init() (Config, error) {
 		cfg = Config{
 			appEnv:            os.Getenv(envAppEnv),
 			LearningEnabled:   getBoolValue(learningEnabled),
+			LearningTrainers:  PrepareListValues(os.Getenv(EnvLearningTrainers)),
+			ScenariosPath:     os.Getenv(EnvScenariosPath),
+			Phrasebook:        initPhrasebookConfig(),
+			Schedules:         initSchedulesConfig(),
+			ShutdownTimeout:   initShutdownTimeout(),
+			EventsExecutor:    initEventsExecutorConfig(),
+			Matching:          initMatchingConfig(),
 			MessagesAPIConfig: initMessagesAPIConfig(),
 			BitBucketConfig:   initBitbucketConfig(),
 			initialised:       true,
@@ -217,6 +399,9 @@ func loadSecrets(cfg Config) Config {
 		cfg.BitBucketConfig.ClientSecret = secrets.BitBucketClientSecret
 		cfg.MessagesAPIConfig.OAuthToken = secrets.MessagesAPIOAuthToken
 		cfg.MessagesAPIConfig.WebAPIOAuthToken = secrets.MessagesAPIWebAPIOAuthToken
+		if secrets.MessagesAPISigningSecret != "" {
+			cfg.MessagesAPIConfig.SigningSecret = secrets.MessagesAPISigningSecret
+		}
 	}
 
 	return cfg
@@ -245,6 +430,52 @@ func initHTTPClientConfig() (c HTTPClient, err error) {
 	}, nil
 }
 
+func initShutdownTimeout() int64 {
+	if os.Getenv(EnvShutdownTimeout) == "" {
+		return defaultShutdownTimeout
+	}
+
+	timeout, err := strconv.ParseInt(os.Getenv(EnvShutdownTimeout), 10, 64)
+	if err != nil || timeout <= 0 {
+		return defaultShutdownTimeout
+	}
+
+	return timeout
+}
+
+func initEventsExecutorConfig() EventsExecutorConfig {
+	return EventsExecutorConfig{
+		Workers:           getPositiveIntValue(EnvEventsWorkers, 0),
+		QueueSize:         getPositiveIntValue(EnvEventsQueueSize, 0),
+		ConcurrencyLimits: PrepareAliasValues(os.Getenv(EnvEventsConcurrencyLimits)),
+		DefaultTimeout:    getPositiveIntValue(EnvEventsDefaultTimeout, 0),
+		Timeouts:          PrepareAliasValues(os.Getenv(EnvEventsTimeouts)),
+	}
+}
+
+func initPhrasebookConfig() PhrasebookConfig {
+	return PhrasebookConfig{
+		Path:          os.Getenv(EnvPhrasebookPath),
+		DefaultLocale: os.Getenv(EnvPhrasebookDefaultLocale),
+		Locales:       PrepareMapValues(os.Getenv(EnvPhrasebookLocales)),
+	}
+}
+
+func initSchedulesConfig() SchedulesConfig {
+	return SchedulesConfig{
+		Retries:      getPositiveIntValue(EnvSchedulesRetries, 0),
+		RetryBackoff: getPositiveIntValue(EnvSchedulesRetryBackoff, defaultSchedulesRetryBackoff),
+	}
+}
+
+func initMatchingConfig() MatchingConfig {
+	return MatchingConfig{
+		Threshold:       getRatioValue(EnvMatchingThreshold),
+		AmbiguityMargin: getRatioValue(EnvMatchingAmbiguityMargin),
+		CacheTTL:        getPositiveIntValue(EnvMatchingCacheTTL, defaultMatchingCacheTTL),
+	}
+}
+
 func initBitbucketConfig() BitBucketConfig {
 	return BitBucketConfig{
 		ClientID:                     os.Getenv(BitBucketClientID),
@@ -276,15 +507,47 @@ func initMessagesAPIConfig() MessagesAPIConfig {
 		messagesAPIType = os.Getenv(envMessagesAPIType)
 	}
 
+	receiverMode := defaultReceiverMode
+	if os.Getenv(EnvReceiverMode) != "" {
+		receiverMode = os.Getenv(EnvReceiverMode)
+	}
+
+	httpAddress := defaultHTTPAddress
+	if os.Getenv(EnvHTTPAddress) != "" {
+		httpAddress = os.Getenv(EnvHTTPAddress)
+	}
+
+	var deduplicationTTL int64
+	if os.Getenv(EnvDeduplicationTTL) != "" {
+		deduplicationTTL, _ = strconv.ParseInt(os.Getenv(EnvDeduplicationTTL), 10, 64)
+	}
+
+	consoleUserID := defaultConsoleUserID
+	if os.Getenv(EnvConsoleUserID) != "" {
+		consoleUserID = os.Getenv(EnvConsoleUserID)
+	}
+
+	consoleChannelID := defaultConsoleChannelID
+	if os.Getenv(EnvConsoleChannelID) != "" {
+		consoleChannelID = os.Getenv(EnvConsoleChannelID)
+	}
+
 	return MessagesAPIConfig{
-		BaseURL:          os.Getenv(EnvBaseURL),
-		OAuthToken:       oAuthToken,
-		WebAPIOAuthToken: webAPIOAuthToken,
-		MainChannelAlias: mainChannelAlias,
-		MainChannelID:    os.Getenv(EnvMainChannelID),
-		BotUserID:        os.Getenv(EnvUserID),
-		BotName:          botName,
-		Type:             messagesAPIType,
+		BaseURL:                os.Getenv(EnvBaseURL),
+		OAuthToken:             oAuthToken,
+		WebAPIOAuthToken:       webAPIOAuthToken,
+		MainChannelAlias:       mainChannelAlias,
+		MainChannelID:          os.Getenv(EnvMainChannelID),
+		BotUserID:              os.Getenv(EnvUserID),
+		BotName:                botName,
+		Type:                   messagesAPIType,
+		ReceiverMode:           receiverMode,
+		SigningSecret:          os.Getenv(EnvSigningSecret),
+		HTTPAddress:            httpAddress,
+		DeduplicationTTL:       deduplicationTTL,
+		DeduplicationPersisted: getBoolValue(EnvDeduplicationPersisted),
+		ConsoleUserID:          consoleUserID,
+		ConsoleChannelID:       consoleChannelID,
 	}
 }
 
@@ -385,6 +648,83 @@ func PrepareBitBucketReviewers(reviewers string) []BitBucketReviewer {
 	return result
 }
 
+// PrepareAliasValues parses the positive numbers of the events in format alias:number separated by comma. It is used for the concurrency limits and timeouts
+func PrepareAliasValues(values string) map[string]int {
+	result := map[string]int{}
+	for _, value := range strings.Split(values, ",") {
+		aliasInfo := strings.Split(strings.TrimSpace(value), ":")
+		if len(aliasInfo) != 2 || aliasInfo[0] == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(aliasInfo[1])
+		if err != nil || number <= 0 {
+			continue
+		}
+
+		result[aliasInfo[0]] = number
+	}
+
+	return result
+}
+
+// PrepareMapValues parses the values in format key:value separated by comma. The entries with the empty key or value are skipped
+func PrepareMapValues(values string) map[string]string {
+	result := map[string]string{}
+	for _, value := range strings.Split(values, ",") {
+		entry := strings.SplitN(strings.TrimSpace(value), ":", 2)
+		if len(entry) != 2 || entry[0] == "" || entry[1] == "" {
+			continue
+		}
+
+		result[entry[0]] = entry[1]
+	}
+
+	return result
+}
+
+// PrepareListValues parses the values separated by comma. The empty values are skipped
+func PrepareListValues(values string) []string {
+	var result []string
+	for _, value := range strings.Split(values, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
+
+// IsTrainer returns true, when the user can teach the bot in the learning mode
+func (c Config) IsTrainer(userID string) bool {
+	for _, trainer := range c.LearningTrainers {
+		if trainer == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getPositiveIntValue(field string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(field))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getRatioValue parses the number from 0 to 1. Returns 0, if the value is not valid
+func getRatioValue(field string) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(field), 64)
+	if err != nil || value <= 0 || value > 1 {
+		return 0
+	}
+
+	return value
+}
+
 func getBoolValue(field string) bool {
 	res := false
 	if os.Getenv(field) == "true" || os.Getenv(field) == "1" {
@@ -18,3 +18,48 @@ func TestInit(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, true, c.initialised)
 }
+
+func TestPrepareAliasValues(t *testing.T) {
+	assert.Equal(t, map[string]int{}, PrepareAliasValues(""))
+	assert.Equal(t, map[string]int{
+		"bitbucketrelease": 1,
+		"scheduleevent":    2,
+	}, PrepareAliasValues("bitbucketrelease:1, scheduleevent:2,wrong,negative:-1,:3,text:a"))
+}
+
+func TestPrepareMapValues(t *testing.T) {
+	assert.Equal(t, map[string]string{}, PrepareMapValues(""))
+	assert.Equal(t, map[string]string{
+		"C0LAN2Q65": "de",
+		"U0LAN0Z89": "en",
+	}, PrepareMapValues("C0LAN2Q65:de, U0LAN0Z89:en,wrong,:fr,U1:"))
+}
+
+func TestGetRatioValue(t *testing.T) {
+	for value, expected := range map[string]float64{
+		"":     0,
+		"text": 0,
+		"0":    0,
+		"-0.5": 0,
+		"1.5":  0,
+		"0.75": 0.75,
+		"1":    1,
+	} {
+		t.Setenv(EnvMatchingThreshold, value)
+		assert.Equal(t, expected, getRatioValue(EnvMatchingThreshold), value)
+	}
+}
+
+func TestPrepareListValues(t *testing.T) {
+	assert.Empty(t, PrepareListValues(""))
+	assert.Equal(t, []string{"U1", "U2"}, PrepareListValues(" U1, ,U2,"))
+}
+
+func TestConfig_IsTrainer(t *testing.T) {
+	cfg := Config{LearningTrainers: []string{"U1", "U2"}}
+
+	assert.True(t, cfg.IsTrainer("U2"))
+	assert.False(t, cfg.IsTrainer("U3"))
+	assert.False(t, cfg.IsTrainer(""))
+	assert.False(t, Config{}.IsTrainer("U1"))
+}
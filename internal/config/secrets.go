@@ -21,6 +21,9 @@ type SecretConfigValues struct {
 	//MessagesAPIWebAPIOAuthToken slack web-oauth token
 	MessagesAPIWebAPIOAuthToken string `json:"MESSAGES_API_WEB_API_OAUTH_TOKEN"`
 
+	//MessagesAPISigningSecret slack signing secret for the Events API requests verification
+	MessagesAPISigningSecret string `json:"MESSAGES_API_SIGNING_SECRET"`
+
 	//BitBucketClientID the client id for bitbucket api
 	BitBucketClientID string `json:"BITBUCKET_CLIENT_ID"`
 
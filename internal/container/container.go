@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"net/http"
+	"os"
 	"time"
 
 	_time "github.com/sharovik/devbot/internal/service/time"
@@ -84,7 +85,7 @@ func (c *Main) Terminate() {
 }
 
 func (c *Main) loadDictionary() error {
-	dictionary := new(database.Dictionary)
+	dictionary := &database.Dictionary{Matching: c.Config.Matching}
 	if err := dictionary.InitDatabaseConnection(c.Config.Database); err != nil {
 		return err
 	}
@@ -106,6 +107,23 @@ func (c *Main) initMessageClient() client.MessageClientInterface {
 		sc.HTTPClient = &h
 
 		return sc
+	case config.MessagesAPITypeMattermost:
+		h := initHttpClient()
+
+		h.SetOauthToken(c.Config.MessagesAPIConfig.OAuthToken)
+		h.SetBaseURL(c.Config.MessagesAPIConfig.BaseURL)
+
+		mc := client.MattermostClient{}
+		mc.HTTPClient = &h
+
+		return mc
+	case config.MessagesAPITypeConsole:
+		return client.NewConsoleClient(
+			os.Stdout,
+			c.Config.MessagesAPIConfig.BotName,
+			c.Config.MessagesAPIConfig.ConsoleUserID,
+			c.Config.MessagesAPIConfig.ConsoleChannelID,
+		)
 	default:
 		panic(errors.New("unknown messages API type"))
 	}
@@ -2,6 +2,8 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"regexp"
 	"strings"
 
 	"github.com/sharovik/orm/clients"
@@ -26,9 +28,35 @@ type BaseDatabaseInterface interface {
 	InsertEvent(alias string, version string) (int64, error)
 	FindRegex(regex string) (int64, error)
 	InsertQuestionRegex(questionRegex string, questionRegexGroup string) (int64, error)
+	UpdateQuestionRegexPriority(regexID int64, priority int) error
+
+	//UpdateQuestionRegexGroup sets the name of the regex group, which value is used in the answer
+	UpdateQuestionRegexGroup(regexID int64, questionRegexGroup string) error
+
+	//UpdateQuestion sets the answer and the regex of the question
+	UpdateQuestion(questionID int64, answer string, regexID int64) error
+
+	//UpdateScenarioEvent moves the scenario to the other event
+	UpdateScenarioEvent(scenarioID int64, eventID int64) error
+
+	//UpdateEventVersion sets the installed version of the event
+	UpdateEventVersion(eventID int64, version string) error
+
 	GetAllRegex() (map[int64]string, error)
 	GetQuestionsByScenarioID(scenarioID int64, isVariable bool) (result []QuestionObject, err error)
 
+	//InsertVariable inserts the variable question of the scenario with its validation rules
+	InsertVariable(scenarioID int64, variable ScenarioVariable) (int64, error)
+
+	//UpdateVariableRules updates the validation rules of the variable question
+	UpdateVariableRules(questionID int64, rules VariableRules) error
+
+	//UpdateScenarioConfirmation enables or disables the confirmation of the answers before the scenario event execution
+	UpdateScenarioConfirmation(scenarioID int64, confirmation bool) error
+
+	//IsScenarioConfirmationRequired checks if the answers of the scenario should be confirmed before the event execution
+	IsScenarioConfirmationRequired(scenarioID int64) (bool, error)
+
 	//RunMigrations Should be used for custom event migrations loading
 	RunMigrations(path string) error
 	IsMigrationAlreadyExecuted(name string) (bool, error)
@@ -49,6 +77,79 @@ type QuestionObject struct {
 	Answer       string
 	ReactionType string
 	IsVariable   bool
+
+	//Rules the validation rules of the variable question
+	Rules VariableRules
+}
+
+// Types of the scenario variables answers
+const (
+	VariableTypeString  = "string"
+	VariableTypeInt     = "int"
+	VariableTypeBool    = "bool"
+	VariableTypeEnum    = "enum"
+	VariableTypeDate    = "date"
+	VariableTypeChannel = "channel"
+	VariableTypeUser    = "user"
+	VariableTypeURL     = "url"
+	VariableTypeRegex   = "regex"
+)
+
+// VariableRules the rules, by which the answer of the scenario variable is validated before it is set to the variable
+type VariableRules struct {
+	//Type the type of the answer. The string type is used by default
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+
+	//Choices the allowed answers of the enum variable
+	Choices []string `json:"choices,omitempty" yaml:"choices,omitempty"`
+
+	//Pattern the regex, which should match the answer of the regex variable
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	//Optional the variable can be skipped, then the Default value is used
+	Optional bool `json:"optional,omitempty" yaml:"optional,omitempty"`
+
+	//Default the value of the skipped optional variable
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+
+	//Transitions select the next variable by the answer. The first matched transition is used. If there is no matched transition, the next variable of the list is asked
+	Transitions []Transition `json:"transitions,omitempty" yaml:"transitions,omitempty"`
+}
+
+// IsEmpty checks if the rules are not defined, so any answer is accepted and the next variable of the list is asked
+func (r VariableRules) IsEmpty() bool {
+	return (r.Type == "" || r.Type == VariableTypeString) && len(r.Choices) == 0 && r.Pattern == "" && !r.Optional && r.Default == "" && len(r.Transitions) == 0
+}
+
+// Transition the conditional jump from the answered variable to the next one. The transition without conditions is matched by any answer
+type Transition struct {
+	//Equals the answer should be equal to this value, case insensitive. For the enum variable it is one of the choices
+	Equals string `json:"equals,omitempty" yaml:"equals,omitempty"`
+
+	//Matches the regex, which should match the answer
+	Matches string `json:"matches,omitempty" yaml:"matches,omitempty"`
+
+	//Next the question of the variable, which is asked next
+	Next string `json:"next,omitempty" yaml:"next,omitempty"`
+
+	//End the scenario is finished, the rest of variables are not asked
+	End bool `json:"end,omitempty" yaml:"end,omitempty"`
+}
+
+// IsMatched checks if the transition is selected by the answer
+func (t Transition) IsMatched(value string) bool {
+	if t.Equals != "" && !strings.EqualFold(t.Equals, value) {
+		return false
+	}
+
+	if t.Matches != "" {
+		matched, err := regexp.MatchString(t.Matches, value)
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
 }
 
 // ScenarioVariable similar to the Question, but here is a different concept. Question object is used as the main entrypoint for the scenario execution,
@@ -58,6 +159,16 @@ type ScenarioVariable struct {
 	Name     string
 	Value    string
 	Question string
+
+	VariableRules
+
+	//Answered the answer was received. The skipped optional variable can be answered with the empty value
+	Answered bool `json:",omitempty"`
+}
+
+// IsAnswered checks if the answer for the variable was received
+func (v ScenarioVariable) IsAnswered() bool {
+	return v.Answered || v.Value != ""
 }
 
 // EventScenario the main object of event scenario. It will be used during scenarios installation process, via `Install` method call.
@@ -82,6 +193,9 @@ type EventScenario struct {
 
 	//RequiredVariables required variables list we've expecting for this scenario
 	RequiredVariables []ScenarioVariable
+
+	//Confirmation the user is asked to confirm the answers of the required variables before the event execution
+	Confirmation bool
 }
 
 // VariablesToString converts the variables to the string.
@@ -101,19 +215,74 @@ type Question struct {
 	Answer        string
 	QuestionRegex string
 	QuestionGroup string
+
+	//QuestionPriority the priority of the QuestionRegex. When the message matches several regexes, the regex with higher priority is used
+	QuestionPriority int
 }
 
-// GetUnAnsweredQuestion retrieves unanswered question from the list of questions of the scenario
+// GetUnAnsweredQuestion retrieves the question of the variable, which should be asked next. Empty string is returned, when the scenario is finished
 func (e *EventScenario) GetUnAnsweredQuestion() string {
-	for _, variable := range e.RequiredVariables {
-		if variable.Value != "" {
+	index, ok := e.NextVariable()
+	if !ok {
+		return ""
+	}
+
+	return e.RequiredVariables[index].Question
+}
+
+// NextVariable retrieves the index of the variable, which should be asked next. The variables are walked from the first one by the transitions of the answered variables,
+// so the variables of the other branches stay unanswered. False is returned, when the scenario is finished
+func (e *EventScenario) NextVariable() (int, bool) {
+	_, index, ok := e.walk()
+
+	return index, ok
+}
+
+// AnsweredVariables retrieves the indexes of the answered variables in the order, in which they were asked. The answers of the other branches are not included
+func (e *EventScenario) AnsweredVariables() []int {
+	path, _, _ := e.walk()
+
+	return path
+}
+
+// walk walks the variables by the transitions of the answered variables. It retrieves the walked answered variables and the variable, which should be asked next
+func (e *EventScenario) walk() (path []int, next int, ok bool) {
+	//The visited variables are not walked again, so the loop of transitions finishes the scenario
+	visited := map[int]bool{}
+	for index := 0; index >= 0 && index < len(e.RequiredVariables) && !visited[index]; index = e.nextIndex(index) {
+		if !e.RequiredVariables[index].IsAnswered() {
+			return path, index, true
+		}
+
+		visited[index] = true
+		path = append(path, index)
+	}
+
+	return path, 0, false
+}
+
+// nextIndex retrieves the index of the variable, which follows the answered variable. -1 is returned, when the scenario is finished
+func (e *EventScenario) nextIndex(index int) int {
+	variable := e.RequiredVariables[index]
+	for _, transition := range variable.Transitions {
+		if !transition.IsMatched(variable.Value) {
 			continue
 		}
 
-		return variable.Question
+		if transition.End {
+			return -1
+		}
+
+		for i, v := range e.RequiredVariables {
+			if v.Question == transition.Next {
+				return i
+			}
+		}
+
+		return -1
 	}
 
-	return ""
+	return index + 1
 }
 
 func installNewEventScenario(d BaseDatabaseInterface, scenario EventScenario) error {
@@ -139,15 +308,34 @@ func installNewEventScenario(d BaseDatabaseInterface, scenario EventScenario) er
 		return err
 	}
 
+	if scenario.Confirmation {
+		if err = d.UpdateScenarioConfirmation(scenarioID, true); err != nil {
+			return err
+		}
+	}
+
 	for _, q := range scenario.Questions {
 		_, err = d.InsertQuestion(q.Question, q.Answer, scenarioID, q.QuestionRegex, q.QuestionRegex, false)
 		if err != nil {
 			return err
 		}
+
+		if q.QuestionRegex == "" || q.QuestionPriority == 0 {
+			continue
+		}
+
+		regexID, err := d.FindRegex(q.QuestionRegex)
+		if err != nil {
+			return err
+		}
+
+		if err = d.UpdateQuestionRegexPriority(regexID, q.QuestionPriority); err != nil {
+			return err
+		}
 	}
 
 	for _, v := range scenario.RequiredVariables {
-		_, err = d.InsertQuestion("", v.Question, scenarioID, "", "", true)
+		_, err = d.InsertVariable(scenarioID, v)
 		if err != nil {
 			return err
 		}
@@ -163,6 +351,7 @@ func getQuestionsByScenarioID(d BaseDatabaseInterface, scenarioID int64, isVaria
 			"questions.question",
 			"questions.answer",
 			"questions.is_variable",
+			"questions.variable_rules",
 			"events.alias",
 		}).
 		From(&cdto.BaseModel{TableName: "questions"}).
@@ -206,12 +395,20 @@ func getQuestionsByScenarioID(d BaseDatabaseInterface, scenarioID int64, isVaria
 			isVar = true
 		}
 
+		var rules VariableRules
+		if data, ok := item.GetField("variable_rules").Value.(string); ok && data != "" {
+			if err = json.Unmarshal([]byte(data), &rules); err != nil {
+				return result, err
+			}
+		}
+
 		result = append(result, QuestionObject{
 			ID:           int64(item.GetField("id").Value.(int)),
 			Question:     item.GetField("question").Value.(string),
 			Answer:       item.GetField("answer").Value.(string),
 			ReactionType: item.GetField("alias").Value.(string),
 			IsVariable:   isVar,
+			Rules:        rules,
 		})
 	}
 
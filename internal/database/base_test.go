@@ -0,0 +1,112 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func branchingScenario() EventScenario {
+	return EventScenario{RequiredVariables: []ScenarioVariable{
+		{
+			Question: "Which repository?",
+			VariableRules: VariableRules{Transitions: []Transition{
+				{Equals: "monorepo", Next: "Which service?"},
+				{Next: "Which branch?"},
+			}},
+		},
+		{
+			Question: "Which service?",
+			VariableRules: VariableRules{Transitions: []Transition{
+				{Matches: `^legacy-`, End: true},
+				{Next: "Which environment?"},
+			}},
+		},
+		{Question: "Which branch?"},
+		{Question: "Which environment?"},
+	}}
+}
+
+func answer(scenario *EventScenario, value string) {
+	index, _ := scenario.NextVariable()
+	scenario.RequiredVariables[index].Value = value
+	scenario.RequiredVariables[index].Answered = true
+}
+
+func TestEventScenario_NextVariable(t *testing.T) {
+	scenario := branchingScenario()
+	assert.Equal(t, "Which repository?", scenario.GetUnAnsweredQuestion())
+
+	answer(&scenario, "Monorepo")
+	assert.Equal(t, "Which service?", scenario.GetUnAnsweredQuestion())
+
+	answer(&scenario, "billing")
+	assert.Equal(t, "Which environment?", scenario.GetUnAnsweredQuestion())
+
+	answer(&scenario, "staging")
+	_, ok := scenario.NextVariable()
+	assert.False(t, ok)
+	assert.Empty(t, scenario.GetUnAnsweredQuestion())
+	assert.False(t, scenario.RequiredVariables[2].IsAnswered())
+
+	//The other branch
+	scenario = branchingScenario()
+	answer(&scenario, "devbot")
+	assert.Equal(t, "Which branch?", scenario.GetUnAnsweredQuestion())
+
+	answer(&scenario, "master")
+	assert.Equal(t, "Which environment?", scenario.GetUnAnsweredQuestion())
+
+	//The transition finishes the scenario
+	scenario = branchingScenario()
+	answer(&scenario, "monorepo")
+	answer(&scenario, "legacy-api")
+	assert.Empty(t, scenario.GetUnAnsweredQuestion())
+}
+
+func TestEventScenario_NextVariableLinear(t *testing.T) {
+	scenario := EventScenario{RequiredVariables: []ScenarioVariable{
+		{Question: "First?"},
+		{Question: "Second?"},
+	}}
+
+	assert.Equal(t, "First?", scenario.GetUnAnsweredQuestion())
+
+	answer(&scenario, "")
+	assert.Equal(t, "Second?", scenario.GetUnAnsweredQuestion())
+
+	answer(&scenario, "answer")
+	assert.Empty(t, scenario.GetUnAnsweredQuestion())
+
+	assert.Empty(t, (&EventScenario{}).GetUnAnsweredQuestion())
+}
+
+func TestEventScenario_NextVariableLoop(t *testing.T) {
+	scenario := EventScenario{RequiredVariables: []ScenarioVariable{
+		{Question: "First?", Value: "a", VariableRules: VariableRules{Transitions: []Transition{{Next: "Second?"}}}},
+		{Question: "Second?", Value: "b", VariableRules: VariableRules{Transitions: []Transition{{Next: "First?"}}}},
+		{Question: "Third?"},
+	}}
+
+	_, ok := scenario.NextVariable()
+	assert.False(t, ok)
+}
+
+func TestEventScenario_AnsweredVariables(t *testing.T) {
+	scenario := branchingScenario()
+	assert.Empty(t, scenario.AnsweredVariables())
+
+	answer(&scenario, "monorepo")
+	answer(&scenario, "billing")
+	assert.Equal(t, []int{0, 1}, scenario.AnsweredVariables())
+
+	//The answer of the other branch is not walked
+	scenario.RequiredVariables[2].Value = "master"
+	scenario.RequiredVariables[2].Answered = true
+	answer(&scenario, "staging")
+	assert.Equal(t, []int{0, 1, 3}, scenario.AnsweredVariables())
+
+	//The changed answer selects the other branch
+	scenario.RequiredVariables[0].Value = "devbot"
+	assert.Equal(t, []int{0, 2, 3}, scenario.AnsweredVariables())
+}
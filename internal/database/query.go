@@ -0,0 +1,17 @@
+package database
+
+import (
+	cquery "github.com/sharovik/orm/query"
+)
+
+// WhereEquals retrieves the condition, which selects the rows with the field equal to the value. The value is bound to the query
+func WhereEquals(field string, value interface{}) cquery.Where {
+	return cquery.Where{
+		First:    field,
+		Operator: "=",
+		Second: cquery.Bind{
+			Field: field,
+			Value: value,
+		},
+	}
+}
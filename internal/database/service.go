@@ -2,13 +2,20 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/sharovik/devbot/internal/config"
 	"github.com/sharovik/devbot/internal/dto"
 	"github.com/sharovik/devbot/internal/dto/databasedto"
 	"github.com/sharovik/devbot/internal/helper"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/matcher"
+	_time "github.com/sharovik/devbot/internal/service/time"
 	"github.com/sharovik/orm/clients"
 	cdto "github.com/sharovik/orm/dto"
 	cquery "github.com/sharovik/orm/query"
@@ -17,6 +24,34 @@ import (
 // Dictionary the sqlite dictionary object
 type Dictionary struct {
 	db clients.BaseClientInterface
+
+	//Matching the configuration of the received messages matching with the questions
+	Matching config.MatchingConfig
+
+	mu      sync.RWMutex
+	answers *answersCache
+}
+
+// answersCache the questions, which are prepared for the matching. It is reset, when the dictionary is changed by this process,
+// and it expires after the cache TTL, so the changes of the other processes, like install, update or dictionary import, are loaded as well
+type answersCache struct {
+	answers    map[int64]dto.DictionaryMessage
+	candidates []matcher.Candidate
+	loadedAt   time.Time
+}
+
+// isExpired checks if the questions should be loaded again
+func (c *answersCache) isExpired(ttl int) bool {
+	if ttl <= 0 {
+		return false
+	}
+
+	return _time.Service.Now().Sub(c.loadedAt) >= time.Duration(ttl)*time.Second
+}
+
+// NewDictionary creates the dictionary, which uses the already initialised database connection
+func NewDictionary(db clients.BaseClientInterface) *Dictionary {
+	return &Dictionary{db: db}
 }
 
 // GetDBClient method returns the client connection
@@ -37,28 +72,37 @@ func (d *Dictionary) CloseDatabaseConnection() error {
 	return d.db.Disconnect()
 }
 
-// FindAnswer used for searching of message in the database
+// FindAnswer used for searching of message in the database. All questions are scored by the matcher and the best one is used as the answer.
+// If the message matches several questions almost equally, the answer contains only the suggestions, from which the user should choose
 func (d *Dictionary) FindAnswer(message string) (dto.DictionaryMessage, error) {
-	var (
-		dmAnswer dto.DictionaryMessage
-		regexID  int64
-		err      error
-	)
-
-	//We do that because it can be that we can parse this question by available regex. If so, it will help main query to find the answer for this message
-	regexID, err = d.parsedByAvailableRegex(message)
+	cache, err := d.getAnswersCache()
 	if err != nil {
 		return dto.DictionaryMessage{}, err
 	}
 
-	dmAnswer, err = d.answerByQuestionString(message, regexID)
-	if err != nil {
-		return dto.DictionaryMessage{}, err
+	result := matcher.New(d.Matching).Find(message, cache.candidates)
+	if result.Best == nil {
+		return dto.DictionaryMessage{}, nil
 	}
 
+	if result.IsAmbiguous() {
+		suggestions := []string{result.Best.Text}
+		for _, alternative := range result.Alternatives {
+			suggestions = append(suggestions, alternative.Text)
+		}
+
+		return dto.DictionaryMessage{
+			Confidence:  result.Best.Score,
+			Suggestions: suggestions,
+		}, nil
+	}
+
+	dmAnswer := cache.answers[result.Best.ID]
+	dmAnswer.Confidence = result.Best.Score
+
 	//Finally we parse data by using selected regex in our question
-	if dmAnswer.Regex != "" {
-		matches := helper.FindMatches(dmAnswer.Regex, message)
+	if result.Best.Regex != nil {
+		matches := helper.FindRegexpMatches(result.Best.Regex, message)
 
 		if len(matches) != 0 && dmAnswer.MainGroupIndexInRegex != "" && matches[dmAnswer.MainGroupIndexInRegex] != "" {
 			dmAnswer.Answer = fmt.Sprintf(dmAnswer.Answer, matches[dmAnswer.MainGroupIndexInRegex])
@@ -68,24 +112,46 @@ func (d *Dictionary) FindAnswer(message string) (dto.DictionaryMessage, error) {
 	return dmAnswer, nil
 }
 
-func (d *Dictionary) parsedByAvailableRegex(question string) (int64, error) {
-	availableRegex, err := d.GetAllRegex()
-	if err != nil {
-		return int64(0), err
+// getAnswersCache method retrieves the prepared questions. The questions are loaded from the database again, once the dictionary is changed or the cache is expired
+func (d *Dictionary) getAnswersCache() (*answersCache, error) {
+	d.mu.RLock()
+	cache := d.answers
+	d.mu.RUnlock()
+
+	if cache != nil && !cache.isExpired(d.Matching.CacheTTL) {
+		return cache, nil
 	}
 
-	for regexID, regex := range availableRegex {
-		matches := helper.FindMatches(regex, question)
-		if len(matches) != 0 {
-			return regexID, nil
-		}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.answers != nil && !d.answers.isExpired(d.Matching.CacheTTL) {
+		return d.answers, nil
+	}
+
+	cache, err := d.loadAnswersCache()
+	if err != nil {
+		return nil, err
 	}
 
-	return 0, nil
+	d.answers = cache
+
+	log.Logger().Debug().
+		Int("questions", len(cache.candidates)).
+		Msg("Questions loaded for matching")
+
+	return cache, nil
+}
+
+// resetAnswersCache method resets the prepared questions, so they are loaded again during the next search
+func (d *Dictionary) resetAnswersCache() {
+	d.mu.Lock()
+	d.answers = nil
+	d.mu.Unlock()
 }
 
-// answerByQuestionString method retrieves the answer data by selected question string
-func (d *Dictionary) answerByQuestionString(questionText string, regexID int64) (dto.DictionaryMessage, error) {
+// loadAnswersCache method retrieves all questions, which can be matched with the received message, and prepares them for the matching
+func (d *Dictionary) loadAnswersCache() (*answersCache, error) {
 	query := new(clients.Query).
 		Select([]interface{}{
 			"scenarios.id",
@@ -95,6 +161,7 @@ func (d *Dictionary) answerByQuestionString(questionText string, regexID int64)
 			"questions.question",
 			"questions_regex.regex as question_regex",
 			"questions_regex.regex_group as question_regex_group",
+			"questions_regex.priority as question_regex_priority",
 			"events.alias",
 		}).From(&cdto.BaseModel{TableName: "questions"}).
 		Join(cquery.Join{
@@ -114,70 +181,77 @@ func (d *Dictionary) answerByQuestionString(questionText string, regexID int64)
 			With:      cquery.Reference{Table: "scenarios", Key: "event_id"},
 			Condition: "=",
 			Type:      cquery.LeftJoinType,
-		})
-
-	if regexID != 0 {
-		query.Where(cquery.Where{
-			First:    "questions.regex_id",
-			Operator: "=",
-			Second: cquery.Bind{
-				Field: "regex_id",
-				Value: regexID,
-			},
-		})
-	} else {
-		query.Where(cquery.Where{
-			First:    "questions.question",
-			Operator: "LIKE",
-			Second: cquery.Bind{
-				Field: "question",
-				Value: questionText + "%",
-			},
-		})
-	}
+		}).
+		OrderBy("questions.id", cquery.OrderDirectionAsc)
 
-	query.
-		OrderBy("questions.id", cquery.OrderDirectionAsc).
-		Limit(cquery.Limit{From: 0, To: 1})
+	cache := &answersCache{answers: map[int64]dto.DictionaryMessage{}, loadedAt: _time.Service.Now()}
 
 	res, err := d.db.Execute(query)
-
 	if err == sql.ErrNoRows {
-		return dto.DictionaryMessage{}, nil
+		return cache, nil
 	} else if err != nil {
-		return dto.DictionaryMessage{}, err
+		return nil, err
 	}
 
-	if len(res.Items()) == 0 {
-		return dto.DictionaryMessage{}, nil
-	}
+	for _, item := range res.Items() {
+		var (
+			question string
+			r        string
+			rg       string
+			alias    string
+			priority int
+		)
 
-	//We take first item and use it as the result
-	item := res.Items()[0]
+		if item.GetField("question").Value != nil {
+			question = item.GetField("question").Value.(string)
+		}
 
-	var (
-		r  string
-		rg string
-	)
+		if item.GetField("question_regex").Value != nil {
+			r = item.GetField("question_regex").Value.(string)
+		}
 
-	if item.GetField("question_regex").Value != nil {
-		r = item.GetField("question_regex").Value.(string)
-	}
+		//The variables questions don't have the question text and regex, so they cannot be matched
+		if question == "" && r == "" {
+			continue
+		}
+
+		if item.GetField("question_regex_group").Value != nil {
+			rg = item.GetField("question_regex_group").Value.(string)
+		}
+
+		if item.GetField("alias").Value != nil {
+			alias = item.GetField("alias").Value.(string)
+		}
 
-	if item.GetField("question_regex_group").Value != nil {
-		rg = item.GetField("question_regex_group").Value.(string)
+		if item.GetField("question_regex_priority").Value != nil {
+			priority = item.GetField("question_regex_priority").Value.(int)
+		}
+
+		questionID := int64(item.GetField("question_id").Value.(int))
+		scenarioID := int64(item.GetField("id").Value.(int))
+
+		candidate, err := matcher.NewCandidate(questionID, scenarioID, question, r, priority)
+		if err != nil {
+			log.Logger().AddError(err).
+				Int64("question_id", questionID).
+				Str("regex", r).
+				Msg("Failed to compile the question regex")
+		}
+
+		cache.candidates = append(cache.candidates, candidate)
+		cache.answers[questionID] = dto.DictionaryMessage{
+			ScenarioID:            scenarioID,
+			EventID:               int64(item.GetField("event_id").Value.(int)),
+			Answer:                item.GetField("answer").Value.(string),
+			QuestionID:            questionID,
+			Question:              question,
+			Regex:                 r,
+			MainGroupIndexInRegex: rg,
+			ReactionType:          alias,
+		}
 	}
 
-	return dto.DictionaryMessage{
-		ScenarioID:            int64(item.GetField("id").Value.(int)),
-		EventID:               int64(item.GetField("event_id").Value.(int)),
-		Answer:                item.GetField("answer").Value.(string),
-		QuestionID:            int64(item.GetField("question_id").Value.(int)),
-		Question:              item.GetField("question").Value.(string),
-		Regex:                 r,
-		MainGroupIndexInRegex: rg,
-		ReactionType:          item.GetField("alias").Value.(string),
-	}, nil
+	return cache, nil
 }
 
 // InsertScenario used for scenario creation
@@ -201,6 +275,8 @@ func (d *Dictionary) InsertScenario(name string, eventID int64) (int64, error) {
 		return 0, err
 	}
 
+	d.resetAnswersCache()
+
 	return res.LastInsertID(), nil
 }
 
@@ -342,6 +418,8 @@ func (d *Dictionary) InsertEvent(alias string, version string) (int64, error) {
 		return 0, err
 	}
 
+	d.resetAnswersCache()
+
 	return res.LastInsertID(), nil
 }
 
@@ -401,9 +479,111 @@ func (d *Dictionary) InsertQuestion(question string, answer string, scenarioID i
 		return 0, err
 	}
 
+	d.resetAnswersCache()
+
 	return res.LastInsertID(), nil
 }
 
+// InsertVariable inserts the variable question of the scenario. The rules are stored in JSON format, only when they are defined
+func (d *Dictionary) InsertVariable(scenarioID int64, variable ScenarioVariable) (int64, error) {
+	questionID, err := d.InsertQuestion("", variable.Question, scenarioID, "", "", true)
+	if err != nil || variable.VariableRules.IsEmpty() {
+		return questionID, err
+	}
+
+	if err = d.UpdateVariableRules(questionID, variable.VariableRules); err != nil {
+		return 0, err
+	}
+
+	return questionID, nil
+}
+
+// UpdateVariableRules updates the validation rules of the variable question. The empty rules are removed
+func (d *Dictionary) UpdateVariableRules(questionID int64, rules VariableRules) error {
+	var value interface{}
+	if !rules.IsEmpty() {
+		data, err := json.Marshal(rules)
+		if err != nil {
+			return err
+		}
+
+		value = string(data)
+	}
+
+	_, err := d.db.Execute(new(clients.Query).
+		Update(&cdto.BaseModel{
+			TableName: "questions",
+			Fields: []interface{}{
+				cdto.ModelField{
+					Name:  "variable_rules",
+					Value: value,
+				},
+			},
+		}).
+		Where(cquery.Where{
+			First:    "id",
+			Operator: "=",
+			Second: cquery.Bind{
+				Field: "id",
+				Value: questionID,
+			},
+		}))
+
+	return err
+}
+
+// UpdateScenarioConfirmation enables or disables the confirmation of the answers before the scenario event execution
+func (d *Dictionary) UpdateScenarioConfirmation(scenarioID int64, confirmation bool) error {
+	_, err := d.db.Execute(new(clients.Query).
+		Update(&cdto.BaseModel{
+			TableName: "scenarios",
+			Fields: []interface{}{
+				cdto.ModelField{
+					Name:  "confirmation",
+					Value: confirmation,
+				},
+			},
+		}).
+		Where(cquery.Where{
+			First:    "id",
+			Operator: "=",
+			Second: cquery.Bind{
+				Field: "id",
+				Value: scenarioID,
+			},
+		}))
+
+	return err
+}
+
+// IsScenarioConfirmationRequired checks if the answers of the scenario should be confirmed before the event execution
+func (d *Dictionary) IsScenarioConfirmationRequired(scenarioID int64) (bool, error) {
+	res, err := d.db.Execute(new(clients.Query).
+		Select([]interface{}{"confirmation"}).
+		From(&cdto.BaseModel{TableName: "scenarios"}).
+		Where(cquery.Where{
+			First:    "id",
+			Operator: "=",
+			Second: cquery.Bind{
+				Field: "id",
+				Value: scenarioID,
+			},
+		}))
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if len(res.Items()) == 0 {
+		return false, nil
+	}
+
+	confirmation, _ := res.Items()[0].GetField("confirmation").Value.(int)
+
+	return confirmation == 1, nil
+}
+
 // FindRegex search regex by regex string
 func (d *Dictionary) FindRegex(regex string) (int64, error) {
 	query := new(clients.Query).
@@ -453,9 +633,94 @@ func (d *Dictionary) InsertQuestionRegex(questionRegex string, questionRegexGrou
 		return 0, err
 	}
 
+	d.resetAnswersCache()
+
 	return res.LastInsertID(), nil
 }
 
+// UpdateQuestionRegexPriority method sets the priority of the regex. The regex with higher priority is used first, when several regexes match the message
+func (d *Dictionary) UpdateQuestionRegexPriority(regexID int64, priority int) error {
+	query := new(clients.Query).
+		Update(&cdto.BaseModel{
+			TableName: "questions_regex",
+			Fields: []interface{}{
+				cdto.ModelField{
+					Name:  "priority",
+					Value: priority,
+				},
+			},
+		}).
+		Where(cquery.Where{
+			First:    "id",
+			Operator: "=",
+			Second: cquery.Bind{
+				Field: "id",
+				Value: regexID,
+			},
+		})
+	if _, err := d.db.Execute(query); err != nil {
+		return err
+	}
+
+	d.resetAnswersCache()
+
+	return nil
+}
+
+// UpdateQuestionRegexGroup method sets the name of the regex group, which value is used in the answer
+func (d *Dictionary) UpdateQuestionRegexGroup(regexID int64, questionRegexGroup string) error {
+	return d.updateByID("questions_regex", regexID, cdto.ModelField{Name: "regex_group", Value: questionRegexGroup})
+}
+
+// UpdateQuestion method sets the answer and the regex of the question. The question is matched without the regex, when the regexID is 0
+func (d *Dictionary) UpdateQuestion(questionID int64, answer string, regexID int64) error {
+	var regexValue interface{}
+	if regexID != 0 {
+		regexValue = regexID
+	}
+
+	return d.updateByID("questions", questionID,
+		cdto.ModelField{Name: "answer", Value: answer},
+		cdto.ModelField{Name: "regex_id", Value: regexValue},
+	)
+}
+
+// UpdateScenarioEvent method moves the scenario to the other event
+func (d *Dictionary) UpdateScenarioEvent(scenarioID int64, eventID int64) error {
+	return d.updateByID("scenarios", scenarioID, cdto.ModelField{Name: "event_id", Value: eventID})
+}
+
+// UpdateEventVersion method sets the installed version of the event
+func (d *Dictionary) UpdateEventVersion(eventID int64, version string) error {
+	return d.updateByID("events", eventID, cdto.ModelField{Name: "installed_version", Value: version})
+}
+
+// updateByID method updates the fields of the dictionary record and resets the prepared questions
+func (d *Dictionary) updateByID(table string, id int64, fields ...cdto.ModelField) error {
+	model := cdto.BaseModel{TableName: table}
+	for _, field := range fields {
+		model.AddModelField(field)
+	}
+
+	_, err := d.db.Execute(new(clients.Query).
+		Update(&model).
+		Where(cquery.Where{
+			First:    "id",
+			Operator: "=",
+			Second: cquery.Bind{
+				Field: "id",
+				Value: id,
+			},
+		}))
+	if err != nil {
+		return err
+	}
+
+	d.resetAnswersCache()
+
+	return nil
+}
+
 // GetAllRegex method retrieves all available regexs
 func (d *Dictionary) GetAllRegex() (res map[int64]string, err error) {
 	rows, err := d.db.Execute(new(clients.Query).Select(databasedto.QuestionsRegexModel.GetColumns()).From(&cdto.BaseModel{TableName: "questions_regex"}))
@@ -516,6 +781,9 @@ func (d *Dictionary) RunMigrations(pathToFiles string) error {
 			return err
 		}
 
+		//The migration can change the questions
+		d.resetAnswersCache()
+
 		if err := d.MarkMigrationExecuted(file); err != nil {
 			return err
 		}
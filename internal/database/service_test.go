@@ -0,0 +1,184 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/devbot/test"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func initTestDictionary(tb testing.TB) *Dictionary {
+	return NewDictionary(test.InitDatabase(tb,
+		databasedto.EventModel,
+		databasedto.QuestionsRegexModel,
+		databasedto.ScenariosModel,
+		databasedto.QuestionsModel,
+	))
+}
+
+func TestDictionary_FindAnswerPriority(t *testing.T) {
+	d := initTestDictionary(t)
+
+	assert.NoError(t, d.InstallNewEventScenario(EventScenario{
+		EventName: "deploy",
+		Questions: []Question{
+			{Question: "deploy", Answer: "Deploying", QuestionRegex: "(?i)deploy"},
+		},
+	}))
+	assert.NoError(t, d.InstallNewEventScenario(EventScenario{
+		EventName: "deploystaging",
+		Questions: []Question{
+			{Question: "deploy staging", Answer: "Deploying staging", QuestionRegex: "(?i)deploy (staging|stage)"},
+		},
+	}))
+
+	//Both regexes match the message, so the more similar question is used
+	for i := 0; i < 5; i++ {
+		answer, err := d.FindAnswer("please deploy staging")
+		assert.NoError(t, err)
+		assert.Equal(t, "deploystaging", answer.ReactionType)
+		assert.Equal(t, 1.0, answer.Confidence)
+	}
+
+	//The regex with higher priority wins
+	regexID, err := d.FindRegex("(?i)deploy")
+	assert.NoError(t, err)
+	assert.NoError(t, d.UpdateQuestionRegexPriority(regexID, 10))
+
+	answer, err := d.FindAnswer("please deploy staging")
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy", answer.ReactionType)
+
+	assert.NoError(t, d.InstallNewEventScenario(EventScenario{
+		EventName: "deployproduction",
+		Questions: []Question{
+			{Question: "deploy production", Answer: "Deploying production", QuestionRegex: "(?i)deploy (production|prod)", QuestionPriority: 20},
+		},
+	}))
+
+	answer, err = d.FindAnswer("please deploy prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "deployproduction", answer.ReactionType)
+}
+
+func TestDictionary_FindAnswerCache(t *testing.T) {
+	d := initTestDictionary(t)
+
+	answer, err := d.FindAnswer("who are you?")
+	assert.NoError(t, err)
+	assert.True(t, answer.IsEmpty())
+	assert.NotNil(t, d.answers)
+
+	//The new question resets the cache, so it can be found right away
+	assert.NoError(t, d.InstallNewEventScenario(EventScenario{
+		EventName: "whoami",
+		Questions: []Question{
+			{Question: "who are you?", Answer: "I'm devbot", QuestionRegex: "(?i)who are you"},
+		},
+	}))
+	assert.Nil(t, d.answers)
+
+	answer, err = d.FindAnswer("Who are you?")
+	assert.NoError(t, err)
+	assert.Equal(t, "I'm devbot", answer.Answer)
+	assert.Equal(t, "whoami", answer.ReactionType)
+
+	//The invalid regex doesn't break the matching of other questions
+	_, err = d.InsertQuestion("broken", "Broken", 1, "(?i)(broken", "", false)
+	assert.NoError(t, err)
+
+	answer, err = d.FindAnswer("who are you?")
+	assert.NoError(t, err)
+	assert.Equal(t, "whoami", answer.ReactionType)
+}
+
+func TestDictionary_FindAnswerCacheTTL(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	_time.Service.Clock = func() time.Time {
+		return now
+	}
+	defer func() {
+		_time.Service.Clock = nil
+	}()
+
+	d := initTestDictionary(t)
+	d.Matching = config.MatchingConfig{CacheTTL: 60}
+
+	assert.NoError(t, d.InstallNewEventScenario(EventScenario{
+		EventName: "whoami",
+		Questions: []Question{
+			{Question: "who are you?", Answer: "I'm devbot", QuestionRegex: "(?i)who are you"},
+		},
+	}))
+
+	answer, err := d.FindAnswer("who are you?")
+	assert.NoError(t, err)
+	assert.Equal(t, "I'm devbot", answer.Answer)
+
+	//The answer is changed by the other process, so the cache is not reset
+	_, err = d.GetDBClient().Execute(new(clients.Query).
+		Update(&cdto.BaseModel{TableName: "questions", Fields: []interface{}{cdto.ModelField{Name: "answer", Value: "I'm devbot v2"}}}))
+	assert.NoError(t, err)
+
+	answer, err = d.FindAnswer("who are you?")
+	assert.NoError(t, err)
+	assert.Equal(t, "I'm devbot", answer.Answer)
+
+	//The expired cache is loaded again
+	now = now.Add(time.Minute)
+	answer, err = d.FindAnswer("who are you?")
+	assert.NoError(t, err)
+	assert.Equal(t, "I'm devbot v2", answer.Answer)
+
+	//The changes of this process reset the cache right away
+	assert.NoError(t, d.UpdateQuestion(answer.QuestionID, "I'm devbot v3", 0))
+	assert.Nil(t, d.answers)
+
+	answer, err = d.FindAnswer("who are you?")
+	assert.NoError(t, err)
+	assert.Equal(t, "I'm devbot v3", answer.Answer)
+}
+
+func BenchmarkDictionary_FindAnswer(b *testing.B) {
+	d := initTestDictionary(b)
+
+	for i := 0; i < 3000; i++ {
+		assert.NoError(b, d.InstallNewEventScenario(EventScenario{
+			EventName: fmt.Sprintf("event%d", i),
+			Questions: []Question{
+				{
+					Question:      fmt.Sprintf("run command number %d", i),
+					Answer:        "Ok",
+					QuestionRegex: fmt.Sprintf(`(?i)^run command number %d$`, i),
+				},
+			},
+		}))
+	}
+
+	for name, message := range map[string]string{
+		"regex": "run command number 1500",
+		"typo":  "rnu comand number 1500",
+		"none":  "what is the weather today",
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := d.FindAnswer(message); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
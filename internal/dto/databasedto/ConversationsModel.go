@@ -0,0 +1,48 @@
+package databasedto
+
+import "github.com/sharovik/orm/dto"
+
+// ConversationsStruct the struct for conversations model
+type ConversationsStruct struct {
+	dto.BaseModel
+}
+
+// ConversationsModel the model for conversations table, where the open conversations are stored
+var ConversationsModel = New(
+	"conversations",
+	[]interface{}{
+		dto.ModelField{
+			Name:   "channel",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:    "user",
+			Type:    dto.VarcharColumnType,
+			Length:  255,
+			Default: "",
+		},
+		dto.ModelField{
+			Name:    "thread_ts",
+			Type:    dto.VarcharColumnType,
+			Length:  255,
+			Default: "",
+		},
+		dto.ModelField{
+			Name: "conversation",
+			Type: dto.VarcharColumnType,
+		},
+		dto.ModelField{
+			Name:   "updated",
+			Type:   dto.IntegerColumnType,
+			Length: 11,
+		},
+	},
+	dto.ModelField{
+		Name:          "id",
+		Type:          dto.IntegerColumnType,
+		AutoIncrement: true,
+		IsPrimaryKey:  true,
+	},
+	&ConversationsStruct{},
+)
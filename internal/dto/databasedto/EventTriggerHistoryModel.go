@@ -51,6 +51,18 @@ var EventTriggerHistoryModel = New(
 			Type:   dto.IntegerColumnType,
 			Length: 11,
 		},
+		dto.ModelField{
+			Name:    "status",
+			Type:    dto.VarcharColumnType,
+			Length:  255,
+			Default: "success",
+		},
+		dto.ModelField{
+			Name:       "error",
+			Type:       dto.VarcharColumnType,
+			Length:     255,
+			IsNullable: true,
+		},
 	},
 	dto.ModelField{
 		Name:          "id",
@@ -0,0 +1,62 @@
+package databasedto
+
+import "github.com/sharovik/orm/dto"
+
+// LearnedQuestionsStruct the struct for learned questions model
+type LearnedQuestionsStruct struct {
+	dto.BaseModel
+}
+
+// LearnedQuestionsModel the model for learned_questions table, where the questions learned in the learning mode are stored for the review
+var LearnedQuestionsModel = New(
+	"learned_questions",
+	[]interface{}{
+		dto.ModelField{
+			Name: "question_id",
+			Type: dto.IntegerColumnType,
+		},
+		dto.ModelField{
+			Name:   "question",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:   "answer",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:   "alias",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:       "regex",
+			Type:       dto.VarcharColumnType,
+			Length:     255,
+			IsNullable: true,
+		},
+		dto.ModelField{
+			Name:   "user",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:   "channel",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:   "created",
+			Type:   dto.IntegerColumnType,
+			Length: 11,
+		},
+	},
+	dto.ModelField{
+		Name:          "id",
+		Type:          dto.IntegerColumnType,
+		AutoIncrement: true,
+		IsPrimaryKey:  true,
+	},
+	&LearnedQuestionsStruct{},
+)
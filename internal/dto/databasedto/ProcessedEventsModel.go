@@ -0,0 +1,32 @@
+package databasedto
+
+import "github.com/sharovik/orm/dto"
+
+// ProcessedEventsStruct the struct for processed events model
+type ProcessedEventsStruct struct {
+	dto.BaseModel
+}
+
+// ProcessedEventsModel the model for processed_events table, where the identifiers of already processed messages are stored
+var ProcessedEventsModel = New(
+	"processed_events",
+	[]interface{}{
+		dto.ModelField{
+			Name:   "event_key",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:   "created",
+			Type:   dto.IntegerColumnType,
+			Length: 11,
+		},
+	},
+	dto.ModelField{
+		Name:          "id",
+		Type:          dto.IntegerColumnType,
+		AutoIncrement: true,
+		IsPrimaryKey:  true,
+	},
+	&ProcessedEventsStruct{},
+)
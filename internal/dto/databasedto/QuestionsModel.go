@@ -37,6 +37,11 @@ var QuestionsModel = New(
 			Type:       dto.BooleanColumnType,
 			IsNullable: true,
 		},
+		dto.ModelField{
+			Name:       "variable_rules",
+			Type:       dto.VarcharColumnType,
+			IsNullable: true,
+		},
 	},
 	dto.ModelField{
 		Name:          "id",
@@ -21,6 +21,11 @@ var QuestionsRegexModel = New(
 			Type:   dto.VarcharColumnType,
 			Length: 255,
 		},
+		dto.ModelField{
+			Name:    "priority",
+			Type:    dto.IntegerColumnType,
+			Default: 0,
+		},
 	},
 	dto.ModelField{
 		Name:          "id",
@@ -21,6 +21,11 @@ var ScenariosModel = New(
 			Type:       dto.IntegerColumnType,
 			IsNullable: true,
 		},
+		dto.ModelField{
+			Name:       "confirmation",
+			Type:       dto.BooleanColumnType,
+			IsNullable: true,
+		},
 	},
 	dto.ModelField{
 		Name:          "id",
@@ -0,0 +1,60 @@
+package databasedto
+
+import "github.com/sharovik/orm/dto"
+
+// ScheduleRunsStruct the struct for schedule runs model
+type ScheduleRunsStruct struct {
+	dto.BaseModel
+}
+
+// ScheduleRunsModel the model for schedule_runs table, where each attempt of the scheduled event execution is stored
+var ScheduleRunsModel = New(
+	"schedule_runs",
+	[]interface{}{
+		dto.ModelField{
+			Name: "schedule_id",
+			Type: dto.IntegerColumnType,
+		},
+		dto.ModelField{
+			Name:   "scheduled_at",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:   "started_at",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name: "duration",
+			Type: dto.IntegerColumnType,
+		},
+		dto.ModelField{
+			Name: "attempt",
+			Type: dto.IntegerColumnType,
+		},
+		dto.ModelField{
+			Name:   "status",
+			Type:   dto.VarcharColumnType,
+			Length: 32,
+		},
+		dto.ModelField{
+			Name:       "error",
+			Type:       dto.VarcharColumnType,
+			IsNullable: true,
+		},
+		dto.ModelField{
+			Name:       "retry_at",
+			Type:       dto.VarcharColumnType,
+			Length:     255,
+			IsNullable: true,
+		},
+	},
+	dto.ModelField{
+		Name:          "id",
+		Type:          dto.IntegerColumnType,
+		AutoIncrement: true,
+		IsPrimaryKey:  true,
+	},
+	&ScheduleRunsStruct{},
+)
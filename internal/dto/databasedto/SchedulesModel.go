@@ -44,6 +44,36 @@ var SchedulesModel = New(
 			Type:   dto.VarcharColumnType,
 			Length: 255,
 		},
+		dto.ModelField{
+			Name:       "cron",
+			Type:       dto.VarcharColumnType,
+			Length:     255,
+			IsNullable: true,
+		},
+		dto.ModelField{
+			Name:       "status",
+			Type:       dto.VarcharColumnType,
+			Length:     32,
+			IsNullable: true,
+		},
+		dto.ModelField{
+			Name:       "misfire_policy",
+			Type:       dto.VarcharColumnType,
+			Length:     32,
+			IsNullable: true,
+		},
+		dto.ModelField{
+			Name:       "last_run_at",
+			Type:       dto.VarcharColumnType,
+			Length:     255,
+			IsNullable: true,
+		},
+		dto.ModelField{
+			Name:       "timezone",
+			Type:       dto.VarcharColumnType,
+			Length:     64,
+			IsNullable: true,
+		},
 		dto.ModelField{
 			Name:    "is_repeatable",
 			Type:    dto.BooleanColumnType,
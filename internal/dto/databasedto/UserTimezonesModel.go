@@ -0,0 +1,32 @@
+package databasedto
+
+import "github.com/sharovik/orm/dto"
+
+// UserTimezonesStruct the struct for user timezones model
+type UserTimezonesStruct struct {
+	dto.BaseModel
+}
+
+// UserTimezonesModel the model for user_timezones table, where the time zones selected by the users are stored
+var UserTimezonesModel = New(
+	"user_timezones",
+	[]interface{}{
+		dto.ModelField{
+			Name:   "user",
+			Type:   dto.VarcharColumnType,
+			Length: 255,
+		},
+		dto.ModelField{
+			Name:   "timezone",
+			Type:   dto.VarcharColumnType,
+			Length: 64,
+		},
+	},
+	dto.ModelField{
+		Name:          "id",
+		Type:          dto.IntegerColumnType,
+		AutoIncrement: true,
+		IsPrimaryKey:  true,
+	},
+	&UserTimezonesStruct{},
+)
@@ -11,4 +11,25 @@ type DictionaryMessage struct {
 	MainGroupIndexInRegex string
 	ReactionType          string
 	IsHelpTriggered       bool
+
+	//Confidence the confidence from 0 to 1 of the match between the received message and the question
+	Confidence float64
+
+	//Suggestions the questions, from which the user should choose, because the received message matches all of them almost equally
+	Suggestions []string
+}
+
+// IsEmpty returns true, when the dictionary message doesn't contain any data
+func (m DictionaryMessage) IsEmpty() bool {
+	return m.ScenarioID == 0 &&
+		m.Question == "" &&
+		m.QuestionID == 0 &&
+		m.EventID == 0 &&
+		m.Regex == "" &&
+		m.Answer == "" &&
+		m.MainGroupIndexInRegex == "" &&
+		m.ReactionType == "" &&
+		!m.IsHelpTriggered &&
+		m.Confidence == 0 &&
+		len(m.Suggestions) == 0
 }
@@ -0,0 +1,84 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+// ContextEventInterface the interface for events, which support the cancellation. The context is cancelled once the event timed out,
+// the user stopped the conversation or the application is stopping
+type ContextEventInterface interface {
+	DefinedEventInterface
+
+	//ExecuteContext The main execution method, which should stop the work and return, once the context is done
+	ExecuteContext(ctx context.Context, message dto.BaseChatMessage) (dto.BaseChatMessage, error)
+}
+
+// TimeoutEventInterface the interface for events, which define their own default execution timeout
+type TimeoutEventInterface interface {
+	//Timeout returns the maximum duration of the event execution
+	Timeout() time.Duration
+}
+
+// WithContext returns the context-aware version of the event. The events, which don't implement ContextEventInterface,
+// are wrapped by the adapter, which stops waiting for the result once the context is done
+func WithContext(e DefinedEventInterface) ContextEventInterface {
+	if contextEvent, ok := e.(ContextEventInterface); ok {
+		return contextEvent
+	}
+
+	return contextAdapter{e}
+}
+
+type detachHandlerKey struct{}
+
+// WithDetachHandler returns the context with the handler, which is called once the adapter stops waiting for the event, which is still running.
+// The function returned by the handler is called, once the event actually finished
+func WithDetachHandler(ctx context.Context, handler func() (finished func())) context.Context {
+	return context.WithValue(ctx, detachHandlerKey{}, handler)
+}
+
+// contextAdapter the adapter for the events, which implement only Execute method.
+// The event cannot be interrupted, so it continues in background, but its result is ignored once the context is done
+type contextAdapter struct {
+	DefinedEventInterface
+}
+
+type executionResult struct {
+	message dto.BaseChatMessage
+	err     error
+}
+
+// ExecuteContext runs the Execute method of the event and waits for the result until the context is done
+func (a contextAdapter) ExecuteContext(ctx context.Context, message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	result := make(chan executionResult, 1)
+	go func() {
+		//The panic in this goroutine cannot be recovered by the caller, so it is returned as the error
+		defer func() {
+			if r := recover(); r != nil {
+				result <- executionResult{message: message, err: NewPanicError(r)}
+			}
+		}()
+
+		answer, err := a.Execute(message)
+		result <- executionResult{message: answer, err: err}
+	}()
+
+	select {
+	case res := <-result:
+		return res.message, res.err
+	case <-ctx.Done():
+		//The event is still running, so the caller is notified, once it actually finished
+		if handler, ok := ctx.Value(detachHandlerKey{}).(func() func()); ok {
+			finished := handler()
+			go func() {
+				<-result
+				finished()
+			}()
+		}
+
+		return message, ctx.Err()
+	}
+}
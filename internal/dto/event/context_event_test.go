@@ -0,0 +1,117 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+type legacyEvent struct {
+	delay time.Duration
+	err   error
+	panic bool
+}
+
+func (e legacyEvent) Help() string   { return "" }
+func (e legacyEvent) Alias() string  { return "legacy" }
+func (e legacyEvent) Install() error { return nil }
+func (e legacyEvent) Update() error  { return nil }
+
+func (e legacyEvent) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	time.Sleep(e.delay)
+	if e.panic {
+		panic("something went wrong")
+	}
+
+	message.Text = "Done"
+
+	return message, e.err
+}
+
+type contextEvent struct {
+	legacyEvent
+}
+
+func (e contextEvent) ExecuteContext(ctx context.Context, message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	<-ctx.Done()
+	message.Text = "Stopped"
+
+	return message, ctx.Err()
+}
+
+func TestWithContext(t *testing.T) {
+	e := contextEvent{}
+	assert.Equal(t, e, WithContext(e))
+	assert.IsType(t, contextAdapter{}, WithContext(legacyEvent{}))
+}
+
+func TestContextAdapter_ExecuteContext(t *testing.T) {
+	answer, err := WithContext(legacyEvent{}).ExecuteContext(context.Background(), dto.BaseChatMessage{Text: "Hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Done", answer.Text)
+
+	expectedErr := errors.New("failed")
+	_, err = WithContext(legacyEvent{err: expectedErr}).ExecuteContext(context.Background(), dto.BaseChatMessage{})
+	assert.ErrorIs(t, err, expectedErr)
+
+	//The slow event result is ignored, once the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	answer, err = WithContext(legacyEvent{delay: time.Second}).ExecuteContext(ctx, dto.BaseChatMessage{Text: "Hello"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "Hello", answer.Text)
+}
+
+func TestContextAdapter_ExecuteContextDetached(t *testing.T) {
+	detached := false
+	finished := make(chan struct{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	ctx = WithDetachHandler(ctx, func() func() {
+		detached = true
+		return func() {
+			close(finished)
+		}
+	})
+
+	_, err := WithContext(legacyEvent{delay: time.Millisecond * 50}).ExecuteContext(ctx, dto.BaseChatMessage{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, detached)
+
+	//The finish is reported, once the event actually returned
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("the finish of the detached event was not reported")
+	}
+}
+
+func TestContextEvent_ExecuteContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	answer, err := WithContext(contextEvent{}).ExecuteContext(ctx, dto.BaseChatMessage{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "Stopped", answer.Text)
+}
+
+func TestContextAdapter_ExecuteContextPanic(t *testing.T) {
+	answer, err := WithContext(legacyEvent{panic: true}).ExecuteContext(context.Background(), dto.BaseChatMessage{Text: "Hello"})
+
+	var panicErr *PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "something went wrong", panicErr.Value)
+	assert.Contains(t, string(panicErr.Stack), "legacyEvent.Execute")
+	assert.Equal(t, "the event execution panicked: something went wrong", err.Error())
+	assert.Equal(t, "Hello", answer.Text)
+
+	panicErr.ReferenceID = "abc123"
+	assert.Equal(t, "the event execution panicked (reference ID: abc123): something went wrong", err.Error())
+}
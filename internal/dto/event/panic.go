@@ -0,0 +1,34 @@
+package event
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError the error, which is returned, once the event execution panicked
+type PanicError struct {
+	//ReferenceID the identifier of the failure, which can be shared with the user and found in the logs
+	ReferenceID string
+
+	//Value the value, which was passed to the panic
+	Value interface{}
+
+	//Stack the stack trace of the goroutine at the moment of the panic
+	Stack []byte
+}
+
+// NewPanicError creates the error from the recovered value. Should be called in the deferred function, so the stack trace points to the panic
+func NewPanicError(value interface{}) *PanicError {
+	return &PanicError{
+		Value: value,
+		Stack: debug.Stack(),
+	}
+}
+
+func (e *PanicError) Error() string {
+	if e.ReferenceID == "" {
+		return fmt.Sprintf("the event execution panicked: %v", e.Value)
+	}
+
+	return fmt.Sprintf("the event execution panicked (reference ID: %s): %v", e.ReferenceID, e.Value)
+}
@@ -0,0 +1,9 @@
+package dto
+
+// MattermostRequestCreatePost request object for the posts create endpoint
+type MattermostRequestCreatePost struct {
+	ChannelID string   `json:"channel_id"`
+	Message   string   `json:"message"`
+	RootID    string   `json:"root_id,omitempty"`
+	FileIDs   []string `json:"file_ids,omitempty"`
+}
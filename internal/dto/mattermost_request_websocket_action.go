@@ -0,0 +1,8 @@
+package dto
+
+// MattermostRequestWebsocketAction the action request, which is sent into the Mattermost websocket connection. Example: authentication_challenge
+type MattermostRequestWebsocketAction struct {
+	Seq    int               `json:"seq"`
+	Action string            `json:"action"`
+	Data   map[string]string `json:"data"`
+}
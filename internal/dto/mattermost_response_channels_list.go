@@ -0,0 +1,32 @@
+package dto
+
+const (
+	//MattermostChannelTypeOpen the type of public channel
+	MattermostChannelTypeOpen = "O"
+
+	//MattermostChannelTypePrivate the type of private channel
+	MattermostChannelTypePrivate = "P"
+
+	//MattermostChannelTypeDirect the type of direct messages channel
+	MattermostChannelTypeDirect = "D"
+
+	//MattermostChannelTypeGroup the type of group messages channel
+	MattermostChannelTypeGroup = "G"
+)
+
+// MattermostChannel the channel object of Mattermost API
+type MattermostChannel struct {
+	ID          string `json:"id"`
+	TeamID      string `json:"team_id"`
+	Type        string `json:"type"`
+	DisplayName string `json:"display_name"`
+	Name        string `json:"name"`
+	Header      string `json:"header"`
+	Purpose     string `json:"purpose"`
+	CreatorID   string `json:"creator_id"`
+	CreateAt    int64  `json:"create_at"`
+	DeleteAt    int64  `json:"delete_at"`
+}
+
+// MattermostResponseChannelsList the list of MattermostChannel objects
+type MattermostResponseChannelsList []MattermostChannel
@@ -0,0 +1,9 @@
+package dto
+
+// MattermostResponseError the error object, which Mattermost API returns for failed requests
+type MattermostResponseError struct {
+	ID         string `json:"id"`
+	Message    string `json:"message"`
+	StatusCode int    `json:"status_code"`
+	RequestID  string `json:"request_id"`
+}
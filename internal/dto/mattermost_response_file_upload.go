@@ -0,0 +1,16 @@
+package dto
+
+// MattermostFileInfo the information about uploaded file
+type MattermostFileInfo struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Extension string `json:"extension"`
+	Size      int    `json:"size"`
+	MimeType  string `json:"mime_type"`
+}
+
+// MattermostResponseFileUpload response object of files upload endpoint
+type MattermostResponseFileUpload struct {
+	FileInfos []MattermostFileInfo `json:"file_infos"`
+	ClientIDs []string             `json:"client_ids"`
+}
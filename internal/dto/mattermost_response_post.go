@@ -0,0 +1,33 @@
+package dto
+
+// MattermostResponsePost the post object of Mattermost API. It is used as response of posts create endpoint and inside the websocket posted event
+type MattermostResponsePost struct {
+	BaseResponse
+	ID        string                 `json:"id"`
+	CreateAt  int64                  `json:"create_at"`
+	UpdateAt  int64                  `json:"update_at"`
+	UserID    string                 `json:"user_id"`
+	ChannelID string                 `json:"channel_id"`
+	RootID    string                 `json:"root_id"`
+	Message   string                 `json:"message"`
+	Type      string                 `json:"type"`
+	FileIDs   []string               `json:"file_ids"`
+	Props     map[string]interface{} `json:"props"`
+}
+
+// IsFromBot retrieves true if the post was sent by the bot account
+func (r MattermostResponsePost) IsFromBot() bool {
+	fromBot, _ := r.Props["from_bot"].(string)
+
+	return fromBot == "true"
+}
+
+// SetByteResponse sets the byte response
+func (r *MattermostResponsePost) SetByteResponse(response []byte) {
+	r.ByteResponse = response
+}
+
+// GetByteResponse returns the byte response
+func (r *MattermostResponsePost) GetByteResponse() []byte {
+	return r.ByteResponse
+}
@@ -0,0 +1,35 @@
+package dto
+
+// MattermostUser the user object of Mattermost API
+type MattermostUser struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Nickname  string `json:"nickname"`
+	Email     string `json:"email"`
+	Locale    string `json:"locale"`
+	IsBot     bool   `json:"is_bot"`
+	DeleteAt  int64  `json:"delete_at"`
+
+	Timezone MattermostTimezone `json:"timezone"`
+}
+
+// MattermostTimezone the time zone settings of the Mattermost user. The values are strings, even the flag
+type MattermostTimezone struct {
+	UseAutomaticTimezone string `json:"useAutomaticTimezone"`
+	AutomaticTimezone    string `json:"automaticTimezone"`
+	ManualTimezone       string `json:"manualTimezone"`
+}
+
+// Name retrieves the name of the selected time zone. The empty name is returned, when the time zone is not set
+func (t MattermostTimezone) Name() string {
+	if t.UseAutomaticTimezone == "true" {
+		return t.AutomaticTimezone
+	}
+
+	return t.ManualTimezone
+}
+
+// MattermostResponseUsersList the list of MattermostUser objects
+type MattermostResponseUsersList []MattermostUser
@@ -0,0 +1,29 @@
+package dto
+
+// MattermostResponseWebsocketEvent the event object received from the Mattermost websocket connection
+type MattermostResponseWebsocketEvent struct {
+	Event string `json:"event"`
+	Data  struct {
+		ChannelDisplayName string `json:"channel_display_name"`
+		ChannelName        string `json:"channel_name"`
+		ChannelType        string `json:"channel_type"`
+		SenderName         string `json:"sender_name"`
+		TeamID             string `json:"team_id"`
+
+		//Mentions the JSON encoded list of mentioned user IDs
+		Mentions string `json:"mentions"`
+
+		//Post the JSON encoded MattermostResponsePost object
+		Post string `json:"post"`
+	} `json:"data"`
+	Broadcast struct {
+		ChannelID string `json:"channel_id"`
+		TeamID    string `json:"team_id"`
+		UserID    string `json:"user_id"`
+	} `json:"broadcast"`
+	Seq int `json:"seq"`
+
+	//Status and SeqReply are filled for the replies on the sent actions
+	Status   string `json:"status"`
+	SeqReply int    `json:"seq_reply"`
+}
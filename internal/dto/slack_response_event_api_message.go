@@ -44,6 +44,9 @@ type SlackResponseEventAPIMessage struct {
 		TeamID             string `json:"team_id"`
 		Token              string `json:"token"`
 		Type               string `json:"type"`
+
+		//Challenge is received only in url_verification request of Events API
+		Challenge string `json:"challenge,omitempty"`
 	} `json:"payload"`
 	RetryAttempt int    `json:"retry_attempt"`
 	RetryReason  string `json:"retry_reason"`
@@ -146,6 +146,11 @@ func FindMatches(regex string, subject string) map[string]string {
 		return map[string]string{}
 	}
 
+	return FindRegexpMatches(re, subject)
+}
+
+// FindRegexpMatches method does the same as FindMatches, but uses already compiled regex
+func FindRegexpMatches(re *regexp.Regexp, subject string) map[string]string {
 	matches := re.FindStringSubmatch(subject)
 	result := make(map[string]string)
 
@@ -88,9 +88,22 @@ func Init(config Config) error {
 		initialized: true,
 	}
 
+	loggerInstance.setGlobals()
+
 	return nil
 }
 
+// setGlobals sets the global zerolog configuration. It is done once during the initialisation, because the logger is used from the different goroutines
+func (l *LoggerInstance) setGlobals() {
+	l.setLogLevel()
+
+	zerolog.TimestampFieldName = "@timestamp"
+	zerolog.LevelFieldName = l.config.FieldLevelName
+	zerolog.ErrorFieldName = l.config.FieldErrorMessage
+	zerolog.TimeFieldFormat = "2006-01-02T15:04:05.000000"
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+}
+
 // Refresh refreshes the logger instance
 func Refresh() {
 	loggerInstance = LoggerInstance{}
@@ -140,7 +153,6 @@ func (l *LoggerInstance) AddError(err error) *zerolog.Event {
 
 // DefaultContext method which returns Logger with default context
 func (l *LoggerInstance) DefaultContext() *zerolog.Logger {
-	l.setLogLevel()
 	var context = zerolog.Context{}
 	switch l.config.Env {
 	case appEnvTesting:
@@ -150,12 +162,6 @@ func (l *LoggerInstance) DefaultContext() *zerolog.Logger {
 		context = l.getOutput()
 	}
 
-	zerolog.TimestampFieldName = "@timestamp"
-	zerolog.LevelFieldName = l.config.FieldLevelName
-	zerolog.ErrorFieldName = l.config.FieldErrorMessage
-	zerolog.TimeFieldFormat = "2006-01-02T15:04:05.000000"
-	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-
 	logger := context.
 		Interface(l.config.FieldContext, l.context).
 		Logger()
@@ -1,8 +1,15 @@
 package analiser
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/phrasebook"
 	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/devbot/internal/service/timezone"
+	"github.com/sharovik/devbot/internal/service/variables"
 
 	"github.com/sharovik/devbot/internal/container"
 	"github.com/sharovik/devbot/internal/database"
@@ -13,38 +20,70 @@ import (
 
 // Message the message object, from which we will generate the dto.DictionaryMessage
 type Message struct {
-	Channel string
-	User    string
-	Text    string
+	Channel  string
+	User     string
+	ThreadTS string
+	Text     string
+}
+
+// ConversationKey retrieves the key of the conversation, to which this message belongs
+func (m Message) ConversationKey() conversation.Key {
+	return conversation.Key{
+		Channel:  m.Channel,
+		User:     m.User,
+		ThreadTS: m.ThreadTS,
+	}
 }
 
 // GetDmAnswer retrieves the Dictionary Message Answer
 func GetDmAnswer(message Message) (dmAnswer dto.DictionaryMessage, err error) {
-	//Now we need to check if there was already opened conversation for this channel
+	//Now we need to check if there was already opened conversation for this user in this channel or thread
 	//If so, then we need to get the Answer from this scenario
-	openConversation := conversation.GetConversation(message.Channel)
+	openConversation := conversation.S.Get(message.ConversationKey())
+	book := phrasebook.For(message.Channel, message.User)
 
 	//If that was a stop word, we need to cancel the conversation
-	IsScenarioStopTriggered := conversation.IsScenarioStopTriggered(message.Text)
+	IsScenarioStopTriggered := conversation.IsScenarioStopTriggered(message.ConversationKey(), message.Text)
 	if IsScenarioStopTriggered {
+		answer := book.Phrase(phrasebook.Stopped)
+
+		//The running events of this conversation are cancelled as well
+		if conversation.Cancel(message.ConversationKey()) > 0 {
+			answer = book.Phrase(phrasebook.EventStopped)
+		}
+
 		dmAnswer = dto.DictionaryMessage{
 			ScenarioID:            0,
 			EventID:               0,
-			Answer:                "Ok, no more questions!",
+			Answer:                answer,
 			QuestionID:            0,
 			Question:              message.Text,
 			Regex:                 "",
 			MainGroupIndexInRegex: "",
 			ReactionType:          "text",
 		}
-		conversation.FinaliseConversation(message.Channel)
+		conversation.S.Finalise(message.ConversationKey())
 
 		return dmAnswer, nil
 	}
 
 	//If there was a scenario triggered for this conversation, we trigger the scenario handling logic
 	if openConversation.ScenarioID != 0 {
-		setAnswerToVariable(message.Text, &openConversation)
+		if command, number := conversation.ParseControlCommand(message.ConversationKey(), message.Text); command != "" {
+			return controlConversation(message, openConversation, command, number)
+		}
+
+		if openConversation.AwaitingConfirmation {
+			return confirmConversation(message, openConversation)
+		}
+
+		if invalidAnswer := setAnswerToVariable(message.Text, &openConversation); invalidAnswer != nil {
+			//The same question is asked again with the explanation, why the answer is not accepted
+			dmAnswer, err = generateDmForConversation(message, openConversation)
+			dmAnswer.Answer = fmt.Sprintf("%s\n%s", invalidAnswer, dmAnswer.Answer)
+
+			return dmAnswer, err
+		}
 
 		return generateDmForConversation(message, openConversation)
 	}
@@ -54,6 +93,13 @@ func GetDmAnswer(message Message) (dmAnswer dto.DictionaryMessage, err error) {
 		return dto.DictionaryMessage{}, err
 	}
 
+	//The message is similar to several questions, so we ask the user to choose one of them
+	if len(dmAnswer.Suggestions) > 0 {
+		dmAnswer.Answer = suggestionsAnswer(book, dmAnswer.Suggestions)
+
+		return dmAnswer, nil
+	}
+
 	questions, err := getVariableQuestionsByScenarioID(dmAnswer.ScenarioID)
 	//if we don't have an error here, then we can proceed with the questions preparing for scenarios
 	if err != nil {
@@ -72,28 +118,45 @@ func GetDmAnswer(message Message) (dmAnswer dto.DictionaryMessage, err error) {
 		return dmAnswer, nil
 	}
 
-	//If the questions amount is more than 1, we need to start the conversation algorithm
+	//If the scenario has the variable questions, we need to start the conversation algorithm
 	if len(questions) > 1 && !isHelpAnswerTriggered {
 		scenario := database.EventScenario{}
 		SetScenarioQuestions(&scenario, questions)
-		conversation.AddConversation(scenario, dto.BaseChatMessage{
+
+		scenario.Confirmation, err = container.C.Dictionary.IsScenarioConfirmationRequired(dmAnswer.ScenarioID)
+		if err != nil {
+			log.Logger().AddError(err).Int64("scenario_id", dmAnswer.ScenarioID).Msg("Failed to check the scenario confirmation")
+		}
+
+		conversation.S.Add(scenario, dto.BaseChatMessage{
 			Channel:           message.Channel,
 			Text:              message.Text,
 			AsUser:            false,
+			ThreadTS:          message.ThreadTS,
 			Ts:                _time.Service.Now(),
 			DictionaryMessage: dmAnswer,
 			OriginalMessage: dto.BaseOriginalMessage{
-				Text: message.Text,
-				User: message.User,
+				Text:     message.Text,
+				User:     message.User,
+				ThreadTS: message.ThreadTS,
 			},
 		})
 
-		dmAnswer.Answer = scenario.GetUnAnsweredQuestion()
+		dmAnswer.Answer = variables.NextPrompt(book, scenario)
 	}
 
 	return dmAnswer, nil
 }
 
+func suggestionsAnswer(book *phrasebook.Phrasebook, suggestions []string) string {
+	var list []string
+	for _, suggestion := range suggestions {
+		list = append(list, fmt.Sprintf("`%s`", suggestion))
+	}
+
+	return book.Phrase(phrasebook.Suggestions, strings.Join(list, "\n"))
+}
+
 func SetScenarioQuestions(scenario *database.EventScenario, questions []database.QuestionObject) {
 	for _, q := range questions {
 		scenario.Questions = append(scenario.Questions, database.Question{
@@ -103,7 +166,8 @@ func SetScenarioQuestions(scenario *database.EventScenario, questions []database
 
 		if q.IsVariable {
 			scenario.RequiredVariables = append(scenario.RequiredVariables, database.ScenarioVariable{
-				Question: q.Answer,
+				Question:      q.Answer,
+				VariableRules: q.Rules,
 			})
 		}
 	}
@@ -127,19 +191,33 @@ func getVariableQuestionsByScenarioID(scenarioID int64) (result []database.Quest
 	return result, err
 }
 
-func setAnswerToVariable(answer string, openConversation *conversation.Conversation) {
-	if len(openConversation.Scenario.RequiredVariables) == 0 {
-		return
+// setAnswerToVariable validates the answer by the rules of the variable, which was asked, and sets the normalised value to it.
+// The variables are asked one by one, so the received message is the answer to the next variable of the scenario
+func setAnswerToVariable(answer string, openConversation *conversation.Conversation) error {
+	index, ok := openConversation.Scenario.NextVariable()
+	if !ok {
+		return nil
 	}
 
-	for i, variable := range openConversation.Scenario.RequiredVariables {
-		if variable.Value != "" || openConversation.LastQuestion.Text != variable.Question {
-			continue
-		}
+	variable := openConversation.Scenario.RequiredVariables[index]
 
-		openConversation.Scenario.RequiredVariables[i].Value = answer
-		return
+	//Only the dates depend on the time zone of the user
+	var location *time.Location
+	if variable.Type == database.VariableTypeDate {
+		location = timezone.S.Location(openConversation.User)
 	}
+
+	book := phrasebook.For(openConversation.Channel, openConversation.User)
+	value, err := variables.Parse(book, location, variable, answer)
+	if err != nil {
+		return err
+	}
+
+	openConversation.Scenario.RequiredVariables[index].Value = value
+	openConversation.Scenario.RequiredVariables[index].Answered = true
+	conversation.S.SetVariable(openConversation.Key(), index, value)
+
+	return nil
 }
 
 func generateDmForConversation(message Message, openConversation conversation.Conversation) (dto.DictionaryMessage, error) {
@@ -147,27 +225,38 @@ func generateDmForConversation(message Message, openConversation conversation.Co
 		return dto.DictionaryMessage{}, nil
 	}
 
-	for _, variable := range openConversation.Scenario.RequiredVariables {
-		if variable.Value != "" {
-			continue
-		}
+	book := phrasebook.For(message.Channel, message.User)
 
-		dmAnswer := dto.DictionaryMessage{
+	//The next variable is selected by the transitions of the answered variables
+	if index, ok := openConversation.Scenario.NextVariable(); ok {
+		return dto.DictionaryMessage{
 			ScenarioID:   openConversation.ScenarioID,
 			EventID:      openConversation.EventID,
-			Answer:       variable.Question,
+			Answer:       variables.Prompt(book, openConversation.Scenario.RequiredVariables[index]),
 			ReactionType: openConversation.ReactionType,
-		}
+		}, nil
+	}
 
-		return dmAnswer, nil
+	//The answers of the branches, which were left after the changed answer, are not used anymore
+	resetUnusedVariables(&openConversation)
+
+	if openConversation.Scenario.Confirmation {
+		conversation.S.SetAwaitingConfirmation(message.ConversationKey(), true)
+
+		return dto.DictionaryMessage{
+			ScenarioID:   openConversation.ScenarioID,
+			EventID:      openConversation.EventID,
+			Answer:       confirmationPrompt(book, openConversation),
+			ReactionType: openConversation.ReactionType,
+		}, nil
 	}
 
-	conversation.MarkAsReadyEventToBeExecuted(message.Channel)
+	conversation.S.MarkReady(message.ConversationKey())
 
 	return dto.DictionaryMessage{
 		ScenarioID:   openConversation.ScenarioID,
 		EventID:      openConversation.ScenarioID,
-		Answer:       "Ok",
+		Answer:       book.Phrase(phrasebook.Ok),
 		ReactionType: openConversation.ReactionType,
 	}, nil
 }
@@ -0,0 +1,120 @@
+package analiser
+
+import (
+	"fmt"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/phrasebook"
+	"github.com/sharovik/devbot/internal/service/variables"
+)
+
+// controlConversation handles the conversation control command. The answers, which should be changed, are removed and their questions are asked again
+func controlConversation(message Message, openConversation conversation.Conversation, command string, number int) (dto.DictionaryMessage, error) {
+	var (
+		answered = openConversation.Scenario.AnsweredVariables()
+		book     = phrasebook.For(message.Channel, message.User)
+		notice   string
+	)
+
+	switch command {
+	case phrasebook.CommandBack:
+		if len(answered) == 0 {
+			notice = book.Phrase(phrasebook.NoPreviousQuestion)
+			break
+		}
+
+		resetVariable(&openConversation, answered[len(answered)-1])
+	case phrasebook.CommandChange:
+		if number < 1 || number > len(answered) {
+			notice = book.Phrase(phrasebook.UnknownAnswerNumber, number, book.ReviewCommand())
+			break
+		}
+
+		resetVariable(&openConversation, answered[number-1])
+	case phrasebook.CommandReview:
+		//The confirmation question already contains the answers
+		if openConversation.AwaitingConfirmation {
+			break
+		}
+
+		notice = book.Phrase(phrasebook.NoAnswers)
+		if len(answered) > 0 {
+			notice = book.Phrase(phrasebook.Answers, variables.Review(book, openConversation.Scenario))
+		}
+	}
+
+	dmAnswer, err := generateDmForConversation(message, openConversation)
+	if notice != "" {
+		dmAnswer.Answer = fmt.Sprintf("%s\n%s", notice, dmAnswer.Answer)
+	}
+
+	return dmAnswer, err
+}
+
+// confirmConversation handles the answer to the confirmation question. The event is executed only after the positive answer
+func confirmConversation(message Message, openConversation conversation.Conversation) (dto.DictionaryMessage, error) {
+	book := phrasebook.For(message.Channel, message.User)
+	answer, err := variables.Parse(book, nil, database.ScenarioVariable{
+		VariableRules: database.VariableRules{Type: database.VariableTypeBool},
+	}, message.Text)
+	if invalidAnswer := err; invalidAnswer != nil {
+		//The confirmation question is asked again
+		dmAnswer, err := generateDmForConversation(message, openConversation)
+		dmAnswer.Answer = fmt.Sprintf("%s\n%s", invalidAnswer, dmAnswer.Answer)
+
+		return dmAnswer, err
+	}
+
+	if answer == "no" {
+		conversation.S.Finalise(message.ConversationKey())
+
+		return dto.DictionaryMessage{
+			Answer:       book.Phrase(phrasebook.Declined),
+			Question:     message.Text,
+			ReactionType: "text",
+		}, nil
+	}
+
+	conversation.S.MarkReady(message.ConversationKey())
+
+	return dto.DictionaryMessage{
+		ScenarioID:   openConversation.ScenarioID,
+		EventID:      openConversation.EventID,
+		Answer:       book.Phrase(phrasebook.Ok),
+		ReactionType: openConversation.ReactionType,
+	}, nil
+}
+
+func confirmationPrompt(book *phrasebook.Phrasebook, openConversation conversation.Conversation) string {
+	return book.Phrase(phrasebook.Confirmation, openConversation.ReactionType, variables.Review(book, openConversation.Scenario), book.ChangeCommand())
+}
+
+// resetVariable removes the answer of the variable, so its question is asked again. The changed answers should be confirmed again
+func resetVariable(openConversation *conversation.Conversation, index int) {
+	key := openConversation.Key()
+
+	openConversation.Scenario.RequiredVariables[index].Value = ""
+	openConversation.Scenario.RequiredVariables[index].Answered = false
+	conversation.S.ResetVariable(key, index)
+
+	if openConversation.AwaitingConfirmation {
+		openConversation.AwaitingConfirmation = false
+		conversation.S.SetAwaitingConfirmation(key, false)
+	}
+}
+
+// resetUnusedVariables removes the answers of the variables, which are not walked by the transitions of the finished scenario
+func resetUnusedVariables(openConversation *conversation.Conversation) {
+	walked := map[int]bool{}
+	for _, index := range openConversation.Scenario.AnsweredVariables() {
+		walked[index] = true
+	}
+
+	for index, variable := range openConversation.Scenario.RequiredVariables {
+		if variable.IsAnswered() && !walked[index] {
+			resetVariable(openConversation, index)
+		}
+	}
+}
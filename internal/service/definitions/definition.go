@@ -0,0 +1,304 @@
+package definitions
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto/event"
+	"github.com/sharovik/devbot/internal/service/variables"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	//ReplyEventAlias the alias of the event, which sends the templated reply of the scenario definition
+	ReplyEventAlias = "textreply"
+
+	defaultAnswer = "Ok"
+)
+
+var variableNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// file the structure of the scenario definitions file
+type file struct {
+	Scenarios []Definition `yaml:"scenarios"`
+}
+
+// Definition the scenario, which is described in the YAML file
+type Definition struct {
+	//Name the unique name of the scenario
+	Name string `yaml:"name"`
+
+	//Event the alias of the event, which is triggered by the scenario. Cannot be used together with Reply
+	Event string `yaml:"event"`
+
+	//Reply the text/template of the reply. The answers of the variables are available by their names, like {{.branch}}
+	Reply string `yaml:"reply"`
+
+	//Answer the text, which is sent before the event execution. If it is not specified, the default answer is used
+	Answer string `yaml:"answer"`
+
+	//Questions the trigger phrases of the scenario
+	Questions []Question `yaml:"questions"`
+
+	//Variables the questions, which are asked in the defined order before the event execution. The transitions of the variables refer to the names of the next variables
+	Variables []Variable `yaml:"variables"`
+
+	//Confirmation the user confirms the answers of the variables before the event execution
+	Confirmation bool `yaml:"confirmation"`
+
+	//File the path of the file, where the scenario is defined
+	File string `yaml:"-"`
+}
+
+// Question the trigger phrase of the scenario
+type Question struct {
+	Question string `yaml:"question"`
+	Regex    string `yaml:"regex"`
+	Priority int    `yaml:"priority"`
+}
+
+// Variable the question, which is asked before the event execution. The answer is validated by the rules
+type Variable struct {
+	Name     string `yaml:"name"`
+	Question string `yaml:"question"`
+
+	database.VariableRules `yaml:",inline"`
+}
+
+// Load reads the scenario definitions from the YAML file or from the *.yaml and *.yml files of the directory sorted by name
+func Load(path string) (definitions []Definition, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files = nil
+		for _, entry := range entries {
+			extension := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (extension == ".yaml" || extension == ".yml") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		//The unknown fields are rejected, so the typos in the definitions are not ignored
+		var parsed file
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(&parsed); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+
+		for _, definition := range parsed.Scenarios {
+			definition.File = f
+			definitions = append(definitions, definition)
+		}
+	}
+
+	return definitions, nil
+}
+
+// Validate checks the definitions and returns all found problems
+func Validate(definitions []Definition, definedEvents map[string]event.DefinedEventInterface) error {
+	var (
+		errs  []error
+		names = map[string]bool{}
+	)
+	for _, definition := range definitions {
+		for _, problem := range definition.problems(definedEvents) {
+			errs = append(errs, fmt.Errorf("%s: scenario %q: %s", definition.File, definition.Name, problem))
+		}
+
+		if definition.Name != "" && names[definition.Name] {
+			errs = append(errs, fmt.Errorf("%s: scenario %q: the name is already used by the other scenario", definition.File, definition.Name))
+		}
+
+		names[definition.Name] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+func (d Definition) problems(definedEvents map[string]event.DefinedEventInterface) (problems []string) {
+	if d.Name == "" {
+		problems = append(problems, "the name cannot be empty")
+	}
+
+	switch {
+	case d.Event == "" && d.Reply == "":
+		problems = append(problems, "the event or the reply should be specified")
+	case d.Event != "" && d.Reply != "":
+		problems = append(problems, "the event and the reply cannot be used together")
+	case definedEvents[d.target()] == nil:
+		problems = append(problems, fmt.Sprintf("the event %s is not defined", d.target()))
+	}
+
+	if len(d.Questions) == 0 {
+		problems = append(problems, "at least one question should be specified")
+	}
+
+	for _, q := range d.Questions {
+		if strings.TrimSpace(q.Question) == "" {
+			problems = append(problems, "the question cannot be empty")
+		}
+
+		if _, err := regexp.Compile(q.Regex); err != nil {
+			problems = append(problems, fmt.Sprintf("the regex of the question %q is invalid: %s", q.Question, err))
+		}
+	}
+
+	var (
+		names     = map[string]bool{}
+		questions = map[string]bool{}
+	)
+	for _, v := range d.Variables {
+		if !variableNameRegex.MatchString(v.Name) {
+			problems = append(problems, fmt.Sprintf("the variable name %q should contain only letters, digits and underscores", v.Name))
+		}
+
+		if strings.TrimSpace(v.Question) == "" {
+			problems = append(problems, fmt.Sprintf("the question of the variable %q cannot be empty", v.Name))
+		}
+
+		if err := variables.Check(v.VariableRules); err != nil {
+			problems = append(problems, fmt.Sprintf("the variable %q is invalid: %s", v.Name, err))
+		}
+
+		//The answers are matched with the variables by the questions, so they should be unique as well
+		if names[v.Name] || questions[v.Question] {
+			problems = append(problems, fmt.Sprintf("the variable %q is defined twice", v.Name))
+		}
+
+		names[v.Name] = true
+		questions[v.Question] = true
+	}
+
+	if d.Confirmation && len(d.Variables) == 0 {
+		problems = append(problems, "the confirmation requires at least one variable")
+	}
+
+	if list, err := d.scenarioVariables(); err != nil {
+		problems = append(problems, err.Error())
+	} else if err = variables.CheckTransitions(list); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if d.Reply != "" {
+		//Every variable is set during the validation, so only the unknown variables and the syntax errors are reported
+		values := map[string]string{}
+		for _, v := range d.Variables {
+			values[v.Name] = v.Name
+		}
+
+		if _, err := d.Render(values); err != nil {
+			problems = append(problems, fmt.Sprintf("the reply is invalid: %s", err))
+		}
+	}
+
+	return problems
+}
+
+// scenarioVariables converts the variables to the scenario variables. The transitions of the definition refer to the variable names,
+// whereas the scenario variables are identified by the questions
+func (d Definition) scenarioVariables() (list []database.ScenarioVariable, err error) {
+	questions := map[string]string{}
+	for _, v := range d.Variables {
+		questions[v.Name] = v.Question
+	}
+
+	for _, v := range d.Variables {
+		variable := database.ScenarioVariable{Name: v.Name, Question: v.Question, VariableRules: v.VariableRules}
+		variable.Transitions = nil
+		for _, transition := range v.Transitions {
+			if transition.Next != "" {
+				question, ok := questions[transition.Next]
+				if !ok {
+					return nil, fmt.Errorf("the next variable %q of the variable %q is not defined", transition.Next, v.Name)
+				}
+
+				transition.Next = question
+			}
+
+			variable.Transitions = append(variable.Transitions, transition)
+		}
+
+		list = append(list, variable)
+	}
+
+	return list, nil
+}
+
+// target returns the alias of the event, which is triggered by the scenario
+func (d Definition) target() string {
+	if d.Reply != "" {
+		return ReplyEventAlias
+	}
+
+	return d.Event
+}
+
+// answer returns the answer of the scenario questions. The reply without variables is sent as the answer, so it doesn't wait for the event execution
+func (d Definition) answer() string {
+	if d.Reply != "" && len(d.Variables) == 0 {
+		if reply, err := d.Render(nil); err == nil {
+			return reply
+		}
+	}
+
+	if d.Answer != "" {
+		return d.Answer
+	}
+
+	return defaultAnswer
+}
+
+// Render executes the reply template with the values of the variables
+func (d Definition) Render(values map[string]string) (string, error) {
+	tmpl, err := template.New(d.Name).Option("missingkey=error").Parse(d.Reply)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, values); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Values returns the answers of the conversation variables by the names of the definition variables
+func (d Definition) Values(variables []database.ScenarioVariable) map[string]string {
+	values := map[string]string{}
+	for _, v := range d.Variables {
+		for _, answered := range variables {
+			if answered.Question == v.Question {
+				values[v.Name] = answered.Value
+				break
+			}
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,245 @@
+package definitions
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/dto/event"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDefinitions = `scenarios:
+  - name: greeting
+    questions:
+      - question: say hello
+        regex: (?i)say hello
+        priority: 2
+    variables:
+      - name: person
+        question: Whom should I greet?
+    reply: "Hello, {{.person}}!"
+  - name: office hours
+    questions:
+      - question: office hours
+    reply: From 9 to 18
+  - name: deploy to environment
+    event: deploy
+    answer: Let's deploy
+    confirmation: true
+    questions:
+      - question: deploy please
+    variables:
+      - name: branch
+        question: Which branch?
+        transitions:
+          - equals: master
+            end: true
+      - name: environment
+        question: Which environment?
+        type: enum
+        choices: [staging, production]
+        optional: true
+        default: staging
+`
+
+type testEvent struct {
+	alias string
+}
+
+func (e testEvent) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	return message, nil
+}
+
+func (e testEvent) Install() error {
+	_, err := container.C.Dictionary.InsertEvent(e.alias, "1.0.0")
+
+	return err
+}
+
+func (e testEvent) Update() error {
+	return nil
+}
+
+func (e testEvent) Alias() string {
+	return e.alias
+}
+
+func (e testEvent) Help() string {
+	return ""
+}
+
+var testEvents = map[string]event.DefinedEventInterface{
+	ReplyEventAlias: testEvent{alias: ReplyEventAlias},
+	"deploy":        testEvent{alias: "deploy"},
+}
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func initTestDictionary(t *testing.T) {
+	container.C = container.Main{Dictionary: database.NewDictionary(test.InitDatabase(t,
+		databasedto.EventModel,
+		databasedto.QuestionsRegexModel,
+		databasedto.ScenariosModel,
+		databasedto.QuestionsModel,
+	))}
+}
+
+func writeDefinitions(t *testing.T, dir string, name string, content string) {
+	assert.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0o600))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeDefinitions(t, dir, "b.yml", testDefinitions)
+	writeDefinitions(t, dir, "a.yaml", "scenarios:\n  - name: first\n    reply: First\n    questions:\n      - question: first\n")
+	writeDefinitions(t, dir, "readme.md", "not a definition")
+
+	definitions, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Len(t, definitions, 4)
+	assert.Equal(t, "first", definitions[0].Name)
+	assert.Equal(t, path.Join(dir, "a.yaml"), definitions[0].File)
+	assert.Equal(t, Definition{
+		Name:      "greeting",
+		Reply:     "Hello, {{.person}}!",
+		Questions: []Question{{Question: "say hello", Regex: "(?i)say hello", Priority: 2}},
+		Variables: []Variable{{Name: "person", Question: "Whom should I greet?"}},
+		File:      path.Join(dir, "b.yml"),
+	}, definitions[1])
+
+	//The typos in the field names are reported
+	writeDefinitions(t, dir, "c.yaml", "scenarios:\n  - name: typo\n    replly: Hi\n")
+	_, err = Load(dir)
+	assert.Error(t, err)
+
+	_, err = Load(path.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	valid := Definition{
+		Name:      "greeting",
+		Reply:     "Hello, {{.person}}!",
+		Questions: []Question{{Question: "say hello"}},
+		Variables: []Variable{{Name: "person", Question: "Whom should I greet?"}},
+	}
+	assert.NoError(t, Validate([]Definition{valid}, testEvents))
+
+	cases := map[string]func(d *Definition){
+		"empty name":          func(d *Definition) { d.Name = "" },
+		"no target":           func(d *Definition) { d.Reply = "" },
+		"both targets":        func(d *Definition) { d.Event = "deploy" },
+		"unknown variable":    func(d *Definition) { d.Reply = "Hello, {{.name}}!" },
+		"invalid template":    func(d *Definition) { d.Reply = "Hello, {{.person}" },
+		"no questions":        func(d *Definition) { d.Questions = nil },
+		"invalid regex":       func(d *Definition) { d.Questions[0].Regex = "(?i" },
+		"invalid variable":    func(d *Definition) { d.Variables[0].Name = "the person" },
+		"empty variable":      func(d *Definition) { d.Variables[0].Question = "" },
+		"duplicated variable": func(d *Definition) { d.Variables = append(d.Variables, d.Variables[0]) },
+		"invalid rules":       func(d *Definition) { d.Variables[0].Type = "float" },
+		"unknown next": func(d *Definition) {
+			d.Variables[0].Transitions = []database.Transition{{Next: "unknown"}}
+		},
+		"loop": func(d *Definition) {
+			d.Variables[0].Transitions = []database.Transition{{Next: "person"}}
+		},
+		"confirmation without variables": func(d *Definition) {
+			d.Reply = "Hello!"
+			d.Variables = nil
+			d.Confirmation = true
+		},
+		"unknown event": func(d *Definition) {
+			d.Reply = ""
+			d.Event = "unknown"
+		},
+	}
+
+	for name, modify := range cases {
+		definition := valid
+		definition.Questions = append([]Question{}, valid.Questions...)
+		definition.Variables = append([]Variable{}, valid.Variables...)
+		modify(&definition)
+
+		assert.Error(t, Validate([]Definition{definition}, testEvents), name)
+	}
+
+	assert.Error(t, Validate([]Definition{valid, valid}, testEvents))
+	assert.Error(t, Validate([]Definition{valid}, map[string]event.DefinedEventInterface{}))
+}
+
+func TestInitS(t *testing.T) {
+	initTestDictionary(t)
+
+	dir := t.TempDir()
+	writeDefinitions(t, dir, "scenarios.yaml", testDefinitions)
+
+	//The events should be installed before the sync
+	assert.Error(t, InitS(dir, testEvents))
+	assert.NoError(t, testEvents["deploy"].Install())
+	assert.NoError(t, testEvents[ReplyEventAlias].Install())
+
+	assert.NoError(t, InitS(dir, testEvents))
+
+	answer, err := container.C.Dictionary.FindAnswer("say hello")
+	assert.NoError(t, err)
+	assert.Equal(t, ReplyEventAlias, answer.ReactionType)
+	assert.Equal(t, "Ok", answer.Answer)
+
+	definition, ok := S.FindByScenarioID(answer.ScenarioID)
+	assert.True(t, ok)
+	assert.Equal(t, "greeting", definition.Name)
+
+	reply, err := definition.Render(definition.Values([]database.ScenarioVariable{
+		{Question: "Whom should I greet?", Value: "John"},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello, John!", reply)
+
+	//The reply without variables is the answer of the question
+	answer, err = container.C.Dictionary.FindAnswer("office hours")
+	assert.NoError(t, err)
+	assert.Equal(t, "From 9 to 18", answer.Answer)
+
+	answer, err = container.C.Dictionary.FindAnswer("deploy please")
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy", answer.ReactionType)
+	assert.Equal(t, "Let's deploy", answer.Answer)
+
+	variables, err := container.C.Dictionary.GetQuestionsByScenarioID(answer.ScenarioID, true)
+	assert.NoError(t, err)
+	assert.Len(t, variables, 2)
+	assert.Equal(t, database.VariableRules{
+		Type:     database.VariableTypeEnum,
+		Choices:  []string{"staging", "production"},
+		Optional: true,
+		Default:  "staging",
+	}, variables[1].Rules)
+	assert.Equal(t, []database.Transition{{Equals: "master", End: true}}, variables[0].Rules.Transitions)
+
+	confirmation, err := container.C.Dictionary.IsScenarioConfirmationRequired(answer.ScenarioID)
+	assert.NoError(t, err)
+	assert.True(t, confirmation)
+
+	//The sync is idempotent
+	assert.NoError(t, InitS(dir, testEvents))
+	variables, err = container.C.Dictionary.GetQuestionsByScenarioID(answer.ScenarioID, true)
+	assert.NoError(t, err)
+	assert.Len(t, variables, 2)
+
+	//The invalid definitions are not synced
+	writeDefinitions(t, dir, "invalid.yaml", "scenarios:\n  - name: broken\n    reply: Hi\n")
+	assert.Error(t, InitS(dir, testEvents))
+	_, ok = S.FindByScenarioID(answer.ScenarioID)
+	assert.False(t, ok)
+
+	assert.NoError(t, InitS("", testEvents))
+}
@@ -0,0 +1,133 @@
+package definitions
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/dto/event"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/dictionary"
+	"github.com/sharovik/orm/clients"
+)
+
+// Service the service of the loaded scenario definitions
+type Service struct {
+	scenarios map[int64]Definition
+}
+
+// S the initialised service
+var S = Service{scenarios: map[int64]Definition{}}
+
+// InitS loads the scenario definitions from the path, validates them and syncs them into the dictionary.
+// The invalid definitions are not synced at all, so the dictionary is never updated partially
+func InitS(path string, definedEvents map[string]event.DefinedEventInterface) error {
+	S = Service{scenarios: map[int64]Definition{}}
+	if path == "" {
+		return nil
+	}
+
+	definitions, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	if err = Validate(definitions, definedEvents); err != nil {
+		return err
+	}
+
+	changes, err := syncDictionary(definitions)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		log.Logger().Info().Str("change", change.String()).Msg("Scenario definition synced")
+	}
+
+	ids, err := scenarioIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range definitions {
+		S.scenarios[ids[definition.Name]] = definition
+	}
+
+	log.Logger().Info().Str("path", path).Int("scenarios", len(definitions)).Msg("Scenario definitions loaded")
+
+	return nil
+}
+
+// FindByScenarioID returns the loaded definition of the scenario
+func (s Service) FindByScenarioID(scenarioID int64) (Definition, bool) {
+	definition, ok := s.scenarios[scenarioID]
+
+	return definition, ok
+}
+
+// syncDictionary imports the definitions into the dictionary. The events are installed by their Install method,
+// so the definitions can be attached only to the installed events
+func syncDictionary(definitions []Definition) ([]dictionary.Change, error) {
+	doc := dictionary.Document{Version: dictionary.DocumentVersion}
+	events := map[string]int{}
+	for _, definition := range definitions {
+		alias := definition.target()
+		if _, ok := events[alias]; !ok {
+			eventID, err := container.C.Dictionary.FindEventByAlias(alias)
+			if err != nil {
+				return nil, err
+			}
+
+			if eventID == 0 {
+				return nil, fmt.Errorf("the event %s is not installed. Please, install it first", alias)
+			}
+
+			events[alias] = len(doc.Events)
+			doc.Events = append(doc.Events, dictionary.Event{Alias: alias})
+		}
+
+		scenario := dictionary.Scenario{Name: definition.Name, Confirmation: definition.Confirmation}
+		//The regex is used as the regex group, the same way as during the installation of the event scenarios
+		for _, q := range definition.Questions {
+			scenario.Questions = append(scenario.Questions, dictionary.Question{
+				Question:   q.Question,
+				Answer:     definition.answer(),
+				Regex:      q.Regex,
+				RegexGroup: q.Regex,
+				Priority:   q.Priority,
+			})
+		}
+
+		//The definitions are already validated, so the variables can be converted
+		list, _ := definition.scenarioVariables()
+		for _, v := range list {
+			scenario.Variables = append(scenario.Variables, dictionary.Variable{Question: v.Question, VariableRules: v.VariableRules})
+		}
+
+		doc.Events[events[alias]].Scenarios = append(doc.Events[events[alias]].Scenarios, scenario)
+	}
+
+	return dictionary.Import(doc, false)
+}
+
+func scenarioIDs() (map[string]int64, error) {
+	ids := map[string]int64{}
+	res, err := container.C.Dictionary.GetDBClient().Execute(new(clients.Query).
+		Select([]interface{}{"id", "name"}).
+		From(databasedto.ScenariosModel))
+	if err == sql.ErrNoRows {
+		return ids, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, item := range res.Items() {
+		name, _ := item.GetField("name").Value.(string)
+		id, _ := item.GetField("id").Value.(int)
+		ids[name] = int64(id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,306 @@
+package dictionary
+
+import (
+	"testing"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func initTestDictionary(t *testing.T) *database.Dictionary {
+	return database.NewDictionary(test.InitDatabase(t,
+		databasedto.EventModel,
+		databasedto.QuestionsRegexModel,
+		databasedto.ScenariosModel,
+		databasedto.QuestionsModel,
+	))
+}
+
+func installTestScenarios(t *testing.T, d *database.Dictionary) {
+	assert.NoError(t, d.InstallNewEventScenario(database.EventScenario{
+		EventName:    "deploy",
+		EventVersion: "1.0.0",
+		Questions: []database.Question{
+			{Question: "deploy", Answer: "Deploying", QuestionRegex: "(?i)deploy", QuestionPriority: 5},
+			{Question: "deploy to %s", Answer: "Deploying to %s", QuestionRegex: "(?i)deploy to (.+)", QuestionGroup: "1"},
+		},
+		RequiredVariables: []database.ScenarioVariable{
+			{Question: "Which branch?"},
+			{Question: "Which environment?", VariableRules: database.VariableRules{Type: database.VariableTypeEnum, Choices: []string{"staging", "production"}}},
+		},
+		Confirmation: true,
+	}))
+	assert.NoError(t, d.InstallNewEventScenario(database.EventScenario{
+		EventName:    "deploy",
+		ScenarioName: "rollback",
+		EventVersion: "1.0.0",
+		Questions: []database.Question{
+			{Question: "rollback", Answer: "Rolling back"},
+		},
+	}))
+	assert.NoError(t, d.InstallNewEventScenario(database.EventScenario{
+		EventName:    "about",
+		EventVersion: "2.1.0",
+		Questions: []database.Question{
+			{Question: "who are you?", Answer: "I'm devbot", QuestionRegex: "(?i)who are you"},
+		},
+	}))
+}
+
+func TestExportImport(t *testing.T) {
+	source := initTestDictionary(t)
+	installTestScenarios(t, source)
+
+	container.C = container.Main{Dictionary: source}
+	doc, err := Export()
+	assert.NoError(t, err)
+
+	assert.Equal(t, Document{
+		Version: DocumentVersion,
+		Events: []Event{
+			{
+				Alias:   "about",
+				Version: "2.1.0",
+				Scenarios: []Scenario{
+					{
+						Name: "about",
+						Questions: []Question{
+							{Question: "who are you?", Answer: "I'm devbot", Regex: "(?i)who are you", RegexGroup: "(?i)who are you"},
+						},
+					},
+				},
+			},
+			{
+				Alias:   "deploy",
+				Version: "1.0.0",
+				Scenarios: []Scenario{
+					{
+						Name: "deploy",
+						Questions: []Question{
+							{Question: "deploy", Answer: "Deploying", Regex: "(?i)deploy", RegexGroup: "(?i)deploy", Priority: 5},
+							{Question: "deploy to %s", Answer: "Deploying to %s", Regex: "(?i)deploy to (.+)", RegexGroup: "(?i)deploy to (.+)"},
+						},
+						Variables: []Variable{
+							{Question: "Which branch?"},
+							{Question: "Which environment?", VariableRules: database.VariableRules{Type: database.VariableTypeEnum, Choices: []string{"staging", "production"}}},
+						},
+						Confirmation: true,
+					},
+					{
+						Name: "rollback",
+						Questions: []Question{
+							{Question: "rollback", Answer: "Rolling back"},
+						},
+					},
+				},
+			},
+		},
+	}, doc)
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		data, err := Marshal(doc, format)
+		assert.NoError(t, err)
+
+		parsed, err := Unmarshal(data, format)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, parsed, format)
+	}
+
+	container.C = container.Main{Dictionary: initTestDictionary(t)}
+
+	//The dry run doesn't change the database
+	changes, err := Import(doc, true)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 11)
+	assert.Equal(t, "+ event about", changes[0].String())
+	assert.Equal(t, "+ question about/about \"who are you?\"", changes[2].String())
+
+	empty, err := Export()
+	assert.NoError(t, err)
+	assert.Empty(t, empty.Events)
+
+	changes, err = Import(doc, false)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 11)
+
+	imported, err := Export()
+	assert.NoError(t, err)
+	assert.Equal(t, doc, imported)
+
+	answer, err := container.C.Dictionary.FindAnswer("please deploy to staging")
+	assert.NoError(t, err)
+	assert.Equal(t, "Deploying", answer.Answer)
+
+	//The import is idempotent
+	changes, err = Import(doc, false)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	//The empty version keeps the installed version
+	doc.Events[0].Version = ""
+	changes, err = Import(doc, false)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestImport_Update(t *testing.T) {
+	d := initTestDictionary(t)
+	installTestScenarios(t, d)
+	container.C = container.Main{Dictionary: d}
+
+	doc, err := Export()
+	assert.NoError(t, err)
+
+	doc.Events[0].Version = "2.2.0"
+	doc.Events[0].Scenarios[0].Questions[0].Answer = "I'm devbot, your assistant"
+	doc.Events[0].Scenarios[0].Questions[0].Regex = ""
+	doc.Events[1].Scenarios[0].Confirmation = false
+	doc.Events[1].Scenarios[0].Questions[0].Priority = 10
+	doc.Events[1].Scenarios[0].Variables[1].Optional = true
+	doc.Events[1].Scenarios[0].Variables[1].Default = "staging"
+	doc.Events[1].Scenarios[0].Variables = append(doc.Events[1].Scenarios[0].Variables, Variable{
+		Question:      "Are you sure?",
+		VariableRules: database.VariableRules{Type: database.VariableTypeBool},
+	})
+	doc.Events[1].Scenarios = append(doc.Events[1].Scenarios, Scenario{
+		Name:      "status",
+		Questions: []Question{{Question: "deploy status", Answer: "Checking"}},
+	})
+
+	//The scenario is moved to the other event. It was installed before the scenario of that event, so it goes first
+	doc.Events[0].Scenarios = append([]Scenario{doc.Events[1].Scenarios[1]}, doc.Events[0].Scenarios...)
+	doc.Events[1].Scenarios = append(doc.Events[1].Scenarios[:1], doc.Events[1].Scenarios[2:]...)
+
+	var expected = []string{
+		`~ event about: version "2.1.0" -> "2.2.0"`,
+		`~ scenario about/rollback: event "deploy" -> "about"`,
+		`~ question about/about "who are you?": answer "I'm devbot" -> "I'm devbot, your assistant", regex "(?i)who are you" -> ""`,
+		`~ scenario deploy/deploy: confirmation true -> false`,
+		`~ question deploy/deploy "deploy": priority 5 -> 10`,
+		`~ variable deploy/deploy "Which environment?": rules {"type":"enum","choices":["staging","production"]} -> {"type":"enum","choices":["staging","production"],"optional":true,"default":"staging"}`,
+		`+ variable deploy/deploy "Are you sure?"`,
+		`+ scenario deploy/status`,
+		`+ question deploy/status "deploy status"`,
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		changes, err := Import(doc, dryRun)
+		assert.NoError(t, err)
+
+		var actual []string
+		for _, change := range changes {
+			actual = append(actual, change.String())
+		}
+		assert.Equal(t, expected, actual)
+	}
+
+	//The question without regex doesn't have the regex group
+	doc.Events[0].Scenarios[1].Questions[0].RegexGroup = ""
+
+	imported, err := Export()
+	assert.NoError(t, err)
+	assert.Equal(t, doc, imported)
+
+	answer, err := d.FindAnswer("who are you")
+	assert.NoError(t, err)
+	assert.Equal(t, "I'm devbot, your assistant", answer.Answer)
+	assert.Empty(t, answer.Regex)
+}
+
+func TestImport_Invalid(t *testing.T) {
+	d := initTestDictionary(t)
+	installTestScenarios(t, d)
+	container.C = container.Main{Dictionary: d}
+
+	expected, err := Export()
+	assert.NoError(t, err)
+
+	//The invalid entry is at the end of the document, so the valid entries before it are not written either
+	doc, err := Export()
+	assert.NoError(t, err)
+
+	doc.Events[0].Version = "2.2.0"
+	doc.Events = append(doc.Events, Event{Alias: "status", Scenarios: []Scenario{{
+		Name:      "status",
+		Questions: []Question{{Question: "status of %s", Answer: "Checking %s", Regex: "(?i)status of (.+"}},
+	}}})
+
+	changes, err := Import(doc, false)
+	assert.Error(t, err)
+	assert.Empty(t, changes)
+
+	actual, err := Export()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestDocument_Validate(t *testing.T) {
+	assert.NoError(t, Document{Version: DocumentVersion}.Validate())
+	assert.Error(t, Document{Version: 2}.Validate())
+	assert.Error(t, Document{Version: DocumentVersion, Events: []Event{{Alias: ""}}}.Validate())
+	assert.Error(t, Document{Version: DocumentVersion, Events: []Event{{Alias: "a"}, {Alias: "a"}}}.Validate())
+	assert.Error(t, Document{Version: DocumentVersion, Events: []Event{
+		{Alias: "a", Scenarios: []Scenario{{Name: "main"}}},
+		{Alias: "b", Scenarios: []Scenario{{Name: "main"}}},
+	}}.Validate())
+
+	assert.Error(t, Document{Version: DocumentVersion, Events: []Event{
+		{Alias: "a", Scenarios: []Scenario{{Name: "main", Variables: []Variable{
+			{Question: "Which one?", VariableRules: database.VariableRules{Type: database.VariableTypeEnum}},
+		}}}},
+	}}.Validate())
+	assert.Error(t, Document{Version: DocumentVersion, Events: []Event{
+		{Alias: "a", Scenarios: []Scenario{{Name: "main", Variables: []Variable{
+			{Question: "Which one?", VariableRules: database.VariableRules{Transitions: []database.Transition{{Next: "Unknown?"}}}},
+		}}}},
+	}}.Validate())
+
+	assert.Error(t, Document{Version: DocumentVersion, Events: []Event{
+		{Alias: "a", Scenarios: []Scenario{{Name: "main", Questions: []Question{
+			{Question: "deploy", Answer: "Deploying", Regex: "(?i)deploy (.+"},
+		}}}},
+	}}.Validate())
+
+	_, err := Unmarshal([]byte("version: 2\nevents: []\n"), FormatYAML)
+	assert.Error(t, err)
+
+	_, err = Unmarshal([]byte("{}"), "xml")
+	assert.Error(t, err)
+}
+
+func TestVariable_Marshal(t *testing.T) {
+	doc := Document{Version: DocumentVersion, Events: []Event{
+		{Alias: "deploy", Scenarios: []Scenario{{Name: "deploy", Variables: []Variable{
+			{Question: "Which branch?"},
+			{Question: "How many?", VariableRules: database.VariableRules{Type: database.VariableTypeInt, Optional: true, Default: "1"}},
+		}}}},
+	}}
+
+	data, err := Marshal(doc, FormatYAML)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "- Which branch?\n")
+	assert.Contains(t, string(data), "- question: How many?\n")
+
+	data, err = Marshal(doc, FormatJSON)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"Which branch?",`)
+	assert.Contains(t, string(data), `"question": "How many?",`)
+
+	parsed, err := Unmarshal(data, FormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, doc, parsed)
+}
+
+func TestFormatByPath(t *testing.T) {
+	assert.Equal(t, FormatJSON, FormatByPath("dictionary.JSON"))
+	assert.Equal(t, FormatYAML, FormatByPath("dictionary.yml"))
+	assert.Equal(t, FormatYAML, FormatByPath("dictionary"))
+}
@@ -0,0 +1,222 @@
+package dictionary
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/service/variables"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	//DocumentVersion the version of the document structure. The documents of other versions cannot be imported
+	DocumentVersion = 1
+
+	//FormatYAML the YAML format of the document
+	FormatYAML = "yaml"
+
+	//FormatJSON the JSON format of the document
+	FormatJSON = "json"
+)
+
+// Document the exported dictionary. The records are identified by the event aliases, the scenario names and the question texts,
+// so the document can be imported to the database of the other bot, where the identifiers are different
+type Document struct {
+	Version int     `json:"version" yaml:"version"`
+	Events  []Event `json:"events" yaml:"events"`
+}
+
+// Event the event of the dictionary
+type Event struct {
+	Alias string `json:"alias" yaml:"alias"`
+
+	//Version the installed version of the event. The empty version doesn't change the version of the existing event during the import
+	Version string `json:"version" yaml:"version"`
+
+	Scenarios []Scenario `json:"scenarios,omitempty" yaml:"scenarios,omitempty"`
+}
+
+// Scenario the scenario of the event. The first scenario of the event is the main one
+type Scenario struct {
+	Name      string     `json:"name" yaml:"name"`
+	Questions []Question `json:"questions,omitempty" yaml:"questions,omitempty"`
+
+	//Variables the questions, which are asked before the event execution
+	Variables []Variable `json:"variables,omitempty" yaml:"variables,omitempty"`
+
+	//Confirmation the answers of the variables are confirmed by the user before the event execution
+	Confirmation bool `json:"confirmation,omitempty" yaml:"confirmation,omitempty"`
+}
+
+// Variable the variable question of the scenario. The variable without rules is written as the plain question
+type Variable struct {
+	Question string `json:"question" yaml:"question"`
+
+	database.VariableRules `yaml:",inline"`
+}
+
+// variableObject the variable with rules. It is used to avoid the recursion of the custom marshalling
+type variableObject Variable
+
+// MarshalYAML writes the variable without rules as the plain question
+func (v Variable) MarshalYAML() (interface{}, error) {
+	if v.VariableRules.IsEmpty() {
+		return v.Question, nil
+	}
+
+	return variableObject(v), nil
+}
+
+// UnmarshalYAML reads the variable from the plain question or from the object with rules
+func (v *Variable) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*v = Variable{}
+		return node.Decode(&v.Question)
+	}
+
+	return node.Decode((*variableObject)(v))
+}
+
+// MarshalJSON writes the variable without rules as the plain question
+func (v Variable) MarshalJSON() ([]byte, error) {
+	if v.VariableRules.IsEmpty() {
+		return json.Marshal(v.Question)
+	}
+
+	return json.Marshal(variableObject(v))
+}
+
+// UnmarshalJSON reads the variable from the plain question or from the object with rules
+func (v *Variable) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*v = Variable{}
+		return json.Unmarshal(data, &v.Question)
+	}
+
+	return json.Unmarshal(data, (*variableObject)(v))
+}
+
+// Question the question of the scenario
+type Question struct {
+	Question   string `json:"question" yaml:"question"`
+	Answer     string `json:"answer" yaml:"answer"`
+	Regex      string `json:"regex,omitempty" yaml:"regex,omitempty"`
+	RegexGroup string `json:"regex_group,omitempty" yaml:"regex_group,omitempty"`
+	Priority   int    `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+// FormatByPath returns the format of the document by the file extension. YAML is used by default
+func FormatByPath(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return FormatJSON
+	}
+
+	return FormatYAML
+}
+
+// Marshal converts the document to the selected format
+func Marshal(doc Document, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case FormatYAML:
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("the %s format is not supported", format)
+}
+
+// Unmarshal parses the document of the selected format and validates it
+func Unmarshal(data []byte, format string) (doc Document, err error) {
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &doc)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		err = fmt.Errorf("the %s format is not supported", format)
+	}
+
+	if err != nil {
+		return doc, err
+	}
+
+	return doc, doc.Validate()
+}
+
+// Validate checks that the document can be imported. Every entry is checked, so the import does not stop in the middle because of the invalid entry
+func (d Document) Validate() error {
+	if d.Version != DocumentVersion {
+		return fmt.Errorf("the document version %d is not supported, the supported version is %d", d.Version, DocumentVersion)
+	}
+
+	var (
+		aliases   = map[string]bool{}
+		scenarios = map[string]bool{}
+	)
+	for _, event := range d.Events {
+		if event.Alias == "" {
+			return fmt.Errorf("the event alias cannot be empty")
+		}
+
+		if aliases[event.Alias] {
+			return fmt.Errorf("the event %s is defined twice", event.Alias)
+		}
+
+		aliases[event.Alias] = true
+
+		for _, scenario := range event.Scenarios {
+			if scenario.Name == "" {
+				return fmt.Errorf("the scenario name of the event %s cannot be empty", event.Alias)
+			}
+
+			//The scenario names are unique in the database
+			if scenarios[scenario.Name] {
+				return fmt.Errorf("the scenario %s is defined twice", scenario.Name)
+			}
+
+			scenarios[scenario.Name] = true
+
+			for _, question := range scenario.Questions {
+				if question.Regex == "" {
+					continue
+				}
+
+				if _, err := regexp.Compile(question.Regex); err != nil {
+					return fmt.Errorf("the regex of the question %q of the scenario %s is invalid: %w", question.Question, scenario.Name, err)
+				}
+			}
+
+			var list []database.ScenarioVariable
+			for _, variable := range scenario.Variables {
+				if err := variables.Check(variable.VariableRules); err != nil {
+					return fmt.Errorf("the variable %q of the scenario %s is invalid: %w", variable.Question, scenario.Name, err)
+				}
+
+				list = append(list, database.ScenarioVariable{Question: variable.Question, VariableRules: variable.VariableRules})
+			}
+
+			if err := variables.CheckTransitions(list); err != nil {
+				return fmt.Errorf("the scenario %s is invalid: %w", scenario.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
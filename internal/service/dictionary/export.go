@@ -0,0 +1,207 @@
+package dictionary
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	cquery "github.com/sharovik/orm/query"
+)
+
+type storedEvent struct {
+	id      int64
+	alias   string
+	version string
+}
+
+type storedScenario struct {
+	id           int64
+	eventID      int64
+	name         string
+	confirmation bool
+}
+
+type storedQuestion struct {
+	id         int64
+	scenarioID int64
+	regexID    int64
+	question   string
+	answer     string
+	isVariable bool
+	rules      database.VariableRules
+}
+
+type storedRegex struct {
+	id       int64
+	regex    string
+	group    string
+	priority int
+}
+
+// snapshot the current state of the dictionary tables. The records are sorted by the identifiers
+type snapshot struct {
+	events    []storedEvent
+	scenarios []storedScenario
+	questions []storedQuestion
+	regexes   map[int64]storedRegex
+}
+
+// Export retrieves the full dictionary. The events are sorted by the alias, the scenarios and questions keep the order of the installation
+func Export() (Document, error) {
+	s, err := loadSnapshot()
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc := Document{Version: DocumentVersion}
+	for _, e := range s.events {
+		event := Event{
+			Alias:   e.alias,
+			Version: e.version,
+		}
+
+		for _, sc := range s.scenarios {
+			if sc.eventID != e.id {
+				continue
+			}
+
+			scenario := Scenario{Name: sc.name, Confirmation: sc.confirmation}
+			for _, q := range s.questions {
+				if q.scenarioID != sc.id {
+					continue
+				}
+
+				if q.isVariable {
+					scenario.Variables = append(scenario.Variables, Variable{Question: q.answer, VariableRules: q.rules})
+					continue
+				}
+
+				regex := s.regexes[q.regexID]
+				scenario.Questions = append(scenario.Questions, Question{
+					Question:   q.question,
+					Answer:     q.answer,
+					Regex:      regex.regex,
+					RegexGroup: regex.group,
+					Priority:   regex.priority,
+				})
+			}
+
+			event.Scenarios = append(event.Scenarios, scenario)
+		}
+
+		doc.Events = append(doc.Events, event)
+	}
+
+	sort.SliceStable(doc.Events, func(i, j int) bool {
+		return doc.Events[i].Alias < doc.Events[j].Alias
+	})
+
+	return doc, nil
+}
+
+func loadSnapshot() (s snapshot, err error) {
+	s.regexes = map[int64]storedRegex{}
+
+	items, err := selectAll(databasedto.EventModel)
+	if err != nil {
+		return s, err
+	}
+
+	for _, item := range items {
+		s.events = append(s.events, storedEvent{
+			id:      int64(item.GetField("id").Value.(int)),
+			alias:   item.GetField("alias").Value.(string),
+			version: stringValue(item, "installed_version"),
+		})
+	}
+
+	if items, err = selectAll(databasedto.ScenariosModel); err != nil {
+		return s, err
+	}
+
+	for _, item := range items {
+		s.scenarios = append(s.scenarios, storedScenario{
+			id:           int64(item.GetField("id").Value.(int)),
+			eventID:      intValue(item, "event_id"),
+			name:         item.GetField("name").Value.(string),
+			confirmation: intValue(item, "confirmation") == 1,
+		})
+	}
+
+	if items, err = selectAll(databasedto.QuestionsModel); err != nil {
+		return s, err
+	}
+
+	for _, item := range items {
+		question := storedQuestion{
+			id:         int64(item.GetField("id").Value.(int)),
+			scenarioID: intValue(item, "scenario_id"),
+			regexID:    intValue(item, "regex_id"),
+			question:   stringValue(item, "question"),
+			answer:     stringValue(item, "answer"),
+			isVariable: intValue(item, "is_variable") == 1,
+		}
+
+		if rules := stringValue(item, "variable_rules"); rules != "" {
+			if err = json.Unmarshal([]byte(rules), &question.rules); err != nil {
+				return s, err
+			}
+		}
+
+		s.questions = append(s.questions, question)
+	}
+
+	if items, err = selectAll(databasedto.QuestionsRegexModel); err != nil {
+		return s, err
+	}
+
+	for _, item := range items {
+		regex := storedRegex{
+			id:       int64(item.GetField("id").Value.(int)),
+			regex:    item.GetField("regex").Value.(string),
+			group:    stringValue(item, "regex_group"),
+			priority: int(intValue(item, "priority")),
+		}
+		s.regexes[regex.id] = regex
+	}
+
+	return s, nil
+}
+
+func selectAll(model cdto.ModelInterface) ([]cdto.ModelInterface, error) {
+	var columns = []interface{}{model.GetPrimaryKey().Name}
+	for _, field := range model.GetColumns() {
+		if f, ok := field.(cdto.ModelField); ok && f.Name != model.GetPrimaryKey().Name {
+			columns = append(columns, f.Name)
+		}
+	}
+
+	res, err := container.C.Dictionary.GetDBClient().Execute(new(clients.Query).
+		Select(columns).
+		From(model).
+		OrderBy(model.GetPrimaryKey().Name, cquery.OrderDirectionAsc))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return res.Items(), nil
+}
+
+func stringValue(item cdto.ModelInterface, field string) string {
+	value, _ := item.GetField(field).Value.(string)
+
+	return value
+}
+
+func intValue(item cdto.ModelInterface, field string) int64 {
+	value, _ := item.GetField(field).Value.(int)
+
+	return int64(value)
+}
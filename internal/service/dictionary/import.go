@@ -0,0 +1,391 @@
+package dictionary
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+)
+
+const (
+	//ActionCreate the record is created
+	ActionCreate = "create"
+
+	//ActionUpdate the record is updated
+	ActionUpdate = "update"
+)
+
+// Change the difference between the document and the database
+type Change struct {
+	//Action the action, which is applied to the record
+	Action string
+
+	//Target the type of the record: event, scenario, question or variable
+	Target string
+
+	//Key the human readable identifier of the record
+	Key string
+
+	//Details the changed fields of the updated record
+	Details []string
+}
+
+// String returns the change in the diff format
+func (c Change) String() string {
+	if c.Action == ActionCreate {
+		return fmt.Sprintf("+ %s %s", c.Target, c.Key)
+	}
+
+	return fmt.Sprintf("~ %s %s: %s", c.Target, c.Key, strings.Join(c.Details, ", "))
+}
+
+// Import applies the document to the database and returns the applied changes. The records, which are already the same, are not changed,
+// so the same document can be imported many times. The records, which are not in the document, are kept.
+// The whole document is validated before the first change is written, so the invalid entry does not leave the dictionary partly imported.
+// During the dry run the changes are only calculated. The running bots load the changed questions, once their questions cache is expired
+func Import(doc Document, dryRun bool) (changes []Change, err error) {
+	if err = doc.Validate(); err != nil {
+		return nil, err
+	}
+
+	s, err := loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	im := importer{snapshot: s, dryRun: dryRun}
+	for _, event := range doc.Events {
+		if err = im.importEvent(event); err != nil {
+			return im.changes, err
+		}
+	}
+
+	return im.changes, nil
+}
+
+type importer struct {
+	snapshot
+	dryRun  bool
+	changes []Change
+}
+
+func (im *importer) change(change Change) {
+	im.changes = append(im.changes, change)
+}
+
+func (im *importer) importEvent(event Event) (err error) {
+	var stored *storedEvent
+	for i := range im.events {
+		if im.events[i].alias == event.Alias {
+			stored = &im.events[i]
+			break
+		}
+	}
+
+	var eventID int64
+	switch {
+	case stored == nil:
+		im.change(Change{Action: ActionCreate, Target: "event", Key: event.Alias})
+		if !im.dryRun {
+			if eventID, err = container.C.Dictionary.InsertEvent(event.Alias, event.Version); err != nil {
+				return err
+			}
+		}
+	case event.Version != "" && stored.version != event.Version:
+		eventID = stored.id
+		im.change(Change{
+			Action:  ActionUpdate,
+			Target:  "event",
+			Key:     event.Alias,
+			Details: []string{fmt.Sprintf("version %q -> %q", stored.version, event.Version)},
+		})
+		if !im.dryRun {
+			if err = container.C.Dictionary.UpdateEventVersion(eventID, event.Version); err != nil {
+				return err
+			}
+		}
+	default:
+		eventID = stored.id
+	}
+
+	for _, scenario := range event.Scenarios {
+		if err = im.importScenario(event.Alias, eventID, scenario); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (im *importer) importScenario(alias string, eventID int64, scenario Scenario) (err error) {
+	var stored *storedScenario
+	for i := range im.scenarios {
+		if im.scenarios[i].name == scenario.Name {
+			stored = &im.scenarios[i]
+			break
+		}
+	}
+
+	var (
+		scenarioID int64
+		key        = fmt.Sprintf("%s/%s", alias, scenario.Name)
+	)
+	if stored == nil {
+		im.change(Change{Action: ActionCreate, Target: "scenario", Key: key})
+		if !im.dryRun {
+			if scenarioID, err = container.C.Dictionary.InsertScenario(scenario.Name, eventID); err != nil {
+				return err
+			}
+
+			if scenario.Confirmation {
+				if err = container.C.Dictionary.UpdateScenarioConfirmation(scenarioID, true); err != nil {
+					return err
+				}
+			}
+		}
+	} else {
+		scenarioID = stored.id
+		if err = im.updateScenario(alias, key, eventID, *stored, scenario); err != nil {
+			return err
+		}
+	}
+
+	for _, question := range scenario.Questions {
+		if err = im.importQuestion(key, scenarioID, stored != nil, question); err != nil {
+			return err
+		}
+	}
+
+	for _, variable := range scenario.Variables {
+		if err = im.importVariable(key, scenarioID, stored != nil, variable); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateScenario moves the stored scenario to the event of the document and updates its confirmation
+func (im *importer) updateScenario(alias string, key string, eventID int64, stored storedScenario, scenario Scenario) (err error) {
+	var (
+		details        []string
+		isMoved        = stored.eventID != eventID
+		isConfirmation = stored.confirmation != scenario.Confirmation
+	)
+
+	//The scenario belongs to the other event in the database
+	if isMoved {
+		details = append(details, fmt.Sprintf("event %q -> %q", im.eventAlias(stored.eventID), alias))
+	}
+
+	if isConfirmation {
+		details = append(details, fmt.Sprintf("confirmation %t -> %t", stored.confirmation, scenario.Confirmation))
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+
+	im.change(Change{Action: ActionUpdate, Target: "scenario", Key: key, Details: details})
+	if im.dryRun {
+		return nil
+	}
+
+	if isMoved {
+		if err = container.C.Dictionary.UpdateScenarioEvent(stored.id, eventID); err != nil {
+			return err
+		}
+	}
+
+	if isConfirmation {
+		return container.C.Dictionary.UpdateScenarioConfirmation(stored.id, scenario.Confirmation)
+	}
+
+	return nil
+}
+
+func (im *importer) importVariable(scenarioKey string, scenarioID int64, isStoredScenario bool, variable Variable) (err error) {
+	var stored *storedQuestion
+	if isStoredScenario {
+		stored = im.findQuestion(scenarioID, true, variable.Question)
+	}
+
+	key := fmt.Sprintf("%s %q", scenarioKey, variable.Question)
+	if stored == nil {
+		im.change(Change{Action: ActionCreate, Target: "variable", Key: key})
+		if !im.dryRun {
+			_, err = container.C.Dictionary.InsertVariable(scenarioID, database.ScenarioVariable{
+				Question:      variable.Question,
+				VariableRules: variable.VariableRules,
+			})
+		}
+
+		return err
+	}
+
+	current, expected := describeRules(stored.rules), describeRules(variable.VariableRules)
+	if current == expected {
+		return nil
+	}
+
+	im.change(Change{
+		Action:  ActionUpdate,
+		Target:  "variable",
+		Key:     key,
+		Details: []string{fmt.Sprintf("rules %s -> %s", current, expected)},
+	})
+	if im.dryRun {
+		return nil
+	}
+
+	return container.C.Dictionary.UpdateVariableRules(stored.id, variable.VariableRules)
+}
+
+func (im *importer) importQuestion(scenarioKey string, scenarioID int64, isStoredScenario bool, question Question) (err error) {
+	var stored *storedQuestion
+	if isStoredScenario {
+		stored = im.findQuestion(scenarioID, false, question.Question)
+	}
+
+	key := fmt.Sprintf("%s %q", scenarioKey, question.Question)
+	if stored == nil {
+		im.change(Change{Action: ActionCreate, Target: "question", Key: key})
+		if im.dryRun {
+			return nil
+		}
+
+		if _, err = container.C.Dictionary.InsertQuestion(question.Question, question.Answer, scenarioID, question.Regex, question.RegexGroup, false); err != nil {
+			return err
+		}
+
+		_, err = im.updateRegex(question)
+		return err
+	}
+
+	var (
+		regex   = im.regexes[stored.regexID]
+		details []string
+	)
+	if stored.answer != question.Answer {
+		details = append(details, fmt.Sprintf("answer %q -> %q", stored.answer, question.Answer))
+	}
+
+	if regex.regex != question.Regex {
+		details = append(details, fmt.Sprintf("regex %q -> %q", regex.regex, question.Regex))
+	}
+
+	//The regex group and priority are the properties of the regex, so they are compared only if the regex exists
+	if question.Regex != "" {
+		if current := im.findRegex(question.Regex); current != nil {
+			regex = *current
+		} else {
+			regex = storedRegex{}
+		}
+
+		if regex.group != question.RegexGroup {
+			details = append(details, fmt.Sprintf("regex group %q -> %q", regex.group, question.RegexGroup))
+		}
+
+		if regex.priority != question.Priority {
+			details = append(details, fmt.Sprintf("priority %d -> %d", regex.priority, question.Priority))
+		}
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+
+	im.change(Change{Action: ActionUpdate, Target: "question", Key: key, Details: details})
+	if im.dryRun {
+		return nil
+	}
+
+	regexID, err := im.updateRegex(question)
+	if err != nil {
+		return err
+	}
+
+	return container.C.Dictionary.UpdateQuestion(stored.id, question.Answer, regexID)
+}
+
+// updateRegex creates the regex of the question, if it doesn't exist, and updates its group and priority
+func (im *importer) updateRegex(question Question) (int64, error) {
+	if question.Regex == "" {
+		return 0, nil
+	}
+
+	regexID, err := container.C.Dictionary.FindRegex(question.Regex)
+	if err != nil {
+		return 0, err
+	}
+
+	if regexID == 0 {
+		if regexID, err = container.C.Dictionary.InsertQuestionRegex(question.Regex, question.RegexGroup); err != nil {
+			return 0, err
+		}
+	} else if err = container.C.Dictionary.UpdateQuestionRegexGroup(regexID, question.RegexGroup); err != nil {
+		return 0, err
+	}
+
+	if err = container.C.Dictionary.UpdateQuestionRegexPriority(regexID, question.Priority); err != nil {
+		return 0, err
+	}
+
+	//The same regex can be used by the next questions of the document
+	im.regexes[regexID] = storedRegex{
+		id:       regexID,
+		regex:    question.Regex,
+		group:    question.RegexGroup,
+		priority: question.Priority,
+	}
+
+	return regexID, nil
+}
+
+// findQuestion finds the question of the scenario by the text. The variables are identified by their questions, which are stored as the answer
+func (im *importer) findQuestion(scenarioID int64, isVariable bool, text string) *storedQuestion {
+	for i, q := range im.questions {
+		if q.scenarioID != scenarioID || q.isVariable != isVariable {
+			continue
+		}
+
+		if (isVariable && q.answer == text) || (!isVariable && q.question == text) {
+			return &im.questions[i]
+		}
+	}
+
+	return nil
+}
+
+func (im *importer) findRegex(regex string) *storedRegex {
+	for _, r := range im.regexes {
+		if r.regex == regex {
+			return &r
+		}
+	}
+
+	return nil
+}
+
+func (im *importer) eventAlias(eventID int64) string {
+	for _, e := range im.events {
+		if e.id == eventID {
+			return e.alias
+		}
+	}
+
+	return ""
+}
+
+// describeRules retrieves the rules in the JSON format, so they can be compared and shown in the changes
+func describeRules(rules database.VariableRules) string {
+	if rules.IsEmpty() {
+		return "none"
+	}
+
+	data, _ := json.Marshal(rules)
+
+	return string(data)
+}
@@ -0,0 +1,378 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/event"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/shutdown"
+	_time "github.com/sharovik/devbot/internal/service/time"
+)
+
+var (
+	// ErrQueueFull the error, which is returned, once there is no free space in the queue for the new event
+	ErrQueueFull = errors.New("the events queue is full")
+
+	// ErrEventTimeout the error, which is returned, once the event was not finished during its timeout
+	ErrEventTimeout = errors.New("the event execution timed out")
+
+	// ErrEventCancelled the error, which is returned, once the event was cancelled by the user or by the application stop
+	ErrEventCancelled = errors.New("the event execution was cancelled")
+)
+
+const (
+	//DefaultWorkers the default number of events, which can be executed at the same time
+	DefaultWorkers = 10
+
+	//DefaultQueueSize the default number of events, which can wait for the execution
+	DefaultQueueSize = 100
+
+	//DefaultTimeout the default time, after which the event execution is cancelled
+	DefaultTimeout = time.Minute * 5
+)
+
+// Service the events execution engine. The events are executed by the fixed number of workers.
+// The events, which cannot be executed right now, wait in the bounded queue
+type Service struct {
+	mu       sync.Mutex
+	once     sync.Once
+	workers  int
+	queue    chan *job
+	limits   map[string]int
+	running  map[string]int
+	deferred map[string][]*job
+	pending  int
+	size     int
+
+	defaultTimeout time.Duration
+	timeouts       map[string]time.Duration
+}
+
+type job struct {
+	alias    string
+	fn       func()
+	enqueued time.Time
+}
+
+// S the events executor object
+var S = New(config.EventsExecutorConfig{})
+
+// InitS initialise the events executor
+func InitS(cfg config.EventsExecutorConfig) {
+	S = New(cfg)
+}
+
+// New creates the events executor. The workers are started with the first submitted event.
+// If the number of workers or queue size is not specified, the default value is used
+func New(cfg config.EventsExecutorConfig) *Service {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+
+	limits := map[string]int{}
+	for alias, limit := range cfg.ConcurrencyLimits {
+		limits[alias] = limit
+	}
+
+	defaultTimeout := DefaultTimeout
+	if cfg.DefaultTimeout > 0 {
+		defaultTimeout = time.Duration(cfg.DefaultTimeout) * time.Second
+	}
+
+	timeouts := map[string]time.Duration{}
+	for alias, timeout := range cfg.Timeouts {
+		timeouts[alias] = time.Duration(timeout) * time.Second
+	}
+
+	return &Service{
+		workers:        workers,
+		queue:          make(chan *job, size),
+		limits:         limits,
+		running:        map[string]int{},
+		deferred:       map[string][]*job{},
+		size:           size,
+		defaultTimeout: defaultTimeout,
+		timeouts:       timeouts,
+	}
+}
+
+func (s *Service) startWorkers() {
+	log.Logger().Debug().
+		Int("workers", s.workers).
+		Int("queue_size", s.size).
+		Interface("concurrency_limits", s.limits).
+		Msg("Start events executor")
+
+	for i := 0; i < s.workers; i++ {
+		go func() {
+			for j := range s.queue {
+				s.execute(j)
+			}
+		}()
+	}
+}
+
+// Submit puts the function of the event into the queue. If the queue is full, the ErrQueueFull error is returned
+func (s *Service) Submit(alias string, fn func()) error {
+	s.once.Do(s.startWorkers)
+
+	s.mu.Lock()
+	if s.pending >= s.size {
+		depth := s.pending
+		s.mu.Unlock()
+
+		log.Logger().Warn().
+			Str("alias", alias).
+			Int("queue_depth", depth).
+			Msg("The events queue is full. Rejecting the event.")
+
+		return ErrQueueFull
+	}
+
+	s.pending++
+	depth := s.pending
+	s.mu.Unlock()
+
+	//The queued events should be finished before the application stop
+	shutdown.S.Track()
+
+	s.queue <- &job{
+		alias:    alias,
+		fn:       fn,
+		enqueued: _time.Service.Now(),
+	}
+
+	log.Logger().Debug().
+		Str("alias", alias).
+		Int("queue_depth", depth).
+		Msg("Event queued")
+
+	return nil
+}
+
+// execute runs the job, if the concurrency limit of the event allows it. Otherwise, the job is deferred
+// and it will be executed by the worker, which finishes the running job with the same alias
+func (s *Service) execute(j *job) {
+	s.mu.Lock()
+	if limit := s.limits[j.alias]; limit > 0 && s.running[j.alias] >= limit {
+		s.deferred[j.alias] = append(s.deferred[j.alias], j)
+		s.mu.Unlock()
+
+		log.Logger().Debug().
+			Str("alias", j.alias).
+			Int("concurrency_limit", limit).
+			Msg("Concurrency limit reached. Event execution deferred.")
+
+		return
+	}
+
+	s.start(j)
+	s.mu.Unlock()
+
+	for j != nil {
+		s.run(j)
+		j = s.finish(j.alias)
+	}
+}
+
+// finish frees the slot of the alias and starts the oldest deferred job of the alias, if there is one
+func (s *Service) finish(alias string) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running[alias]--
+	next := s.popDeferred(alias)
+	if next != nil {
+		s.start(next)
+	}
+
+	return next
+}
+
+// detach keeps the slot of the alias and the application stop waiting for the event, which is still running after its context is done.
+// The returned function frees them once the event actually finished
+func (s *Service) detach(alias string) func() {
+	s.mu.Lock()
+	s.running[alias]++
+	s.mu.Unlock()
+
+	shutdown.S.Track()
+
+	log.Logger().Warn().
+		Str("alias", alias).
+		Msg("The event does not support the cancellation. It keeps running in background.")
+
+	return func() {
+		shutdown.S.End()
+
+		log.Logger().Info().
+			Str("alias", alias).
+			Msg("Event finished in background")
+
+		//The freed slot is used by the deferred job of the alias
+		for j := s.finish(alias); j != nil; j = s.finish(j.alias) {
+			s.run(j)
+		}
+	}
+}
+
+// start marks the job as running. Should be called under the lock
+func (s *Service) start(j *job) {
+	s.running[j.alias]++
+	s.pending--
+
+	log.Logger().Info().
+		Str("alias", j.alias).
+		Dur("wait_time", _time.Service.Now().Sub(j.enqueued)).
+		Int("queue_depth", s.pending).
+		Int("running", s.running[j.alias]).
+		Msg("Event execution started")
+}
+
+// popDeferred takes the oldest deferred job of the alias. Should be called under the lock
+func (s *Service) popDeferred(alias string) *job {
+	if len(s.deferred[alias]) == 0 {
+		return nil
+	}
+
+	j := s.deferred[alias][0]
+	s.deferred[alias] = s.deferred[alias][1:]
+	if len(s.deferred[alias]) == 0 {
+		delete(s.deferred, alias)
+	}
+
+	return j
+}
+
+func (s *Service) run(j *job) {
+	defer shutdown.S.End()
+
+	//The panic of the job should not stop the worker
+	defer func() {
+		if r := recover(); r != nil {
+			log.Logger().Error().
+				Str("alias", j.alias).
+				Interface("panic", r).
+				Str("stack", string(debug.Stack())).
+				Msg("Event job panicked")
+		}
+	}()
+
+	started := _time.Service.Now()
+	j.fn()
+
+	log.Logger().Info().
+		Str("alias", j.alias).
+		Dur("execution_time", _time.Service.Now().Sub(started)).
+		Msg("Event execution finished")
+}
+
+// QueueDepth returns the number of events, which are waiting for the execution
+func (s *Service) QueueDepth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pending
+}
+
+// Timeout returns the execution timeout of the event. The timeout from the configuration has the priority over the timeout, which is defined by the event
+func (s *Service) Timeout(e event.DefinedEventInterface) time.Duration {
+	if timeout, ok := s.timeouts[e.Alias()]; ok {
+		return timeout
+	}
+
+	if timeoutEvent, ok := e.(event.TimeoutEventInterface); ok && timeoutEvent.Timeout() > 0 {
+		return timeoutEvent.Timeout()
+	}
+
+	return s.defaultTimeout
+}
+
+// ExecuteEvent executes the event for the conversation. The context of the event is cancelled once the event timed out,
+// the conversation was stopped by the user or the running events were cancelled during the application stop
+func (s *Service) ExecuteEvent(key conversation.Key, e event.DefinedEventInterface, message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	timeout := s.Timeout(e)
+
+	ctx, cancel := context.WithTimeout(shutdown.S.Context(), timeout)
+	defer cancel()
+
+	ctx, release := conversation.WithCancel(ctx, key)
+	defer release()
+
+	ctx = event.WithDetachHandler(ctx, func() func() {
+		return s.detach(e.Alias())
+	})
+
+	answer, err := execute(ctx, e, message)
+
+	var panicErr *event.PanicError
+	if errors.As(err, &panicErr) {
+		panicErr.ReferenceID = newReferenceID()
+
+		log.Logger().Error().
+			Str("alias", e.Alias()).
+			Str("conversation", key.String()).
+			Str("reference_id", panicErr.ReferenceID).
+			Interface("panic", panicErr.Value).
+			Str("stack", string(panicErr.Stack)).
+			Msg("Event execution panicked")
+
+		return answer, err
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		log.Logger().Warn().
+			Str("alias", e.Alias()).
+			Dur("timeout", timeout).
+			Msg("Event execution timed out")
+
+		return answer, fmt.Errorf("%w after %s", ErrEventTimeout, timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		log.Logger().Info().
+			Str("alias", e.Alias()).
+			Str("conversation", key.String()).
+			Msg("Event execution cancelled")
+
+		return answer, ErrEventCancelled
+	}
+
+	return answer, err
+}
+
+// execute runs the event with the context. The panic of the event is returned as event.PanicError
+func execute(ctx context.Context, e event.DefinedEventInterface, message dto.BaseChatMessage) (answer dto.BaseChatMessage, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			answer, err = message, event.NewPanicError(r)
+		}
+	}()
+
+	return event.WithContext(e).ExecuteContext(ctx, message)
+}
+
+// newReferenceID generates the identifier of the failure, by which the failure can be found in the logs
+func newReferenceID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return _time.Service.Now().Format("20060102150405.000000")
+	}
+
+	return hex.EncodeToString(b)
+}
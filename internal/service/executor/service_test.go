@@ -0,0 +1,350 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/event"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/shutdown"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+// concurrencyCounter tracks the maximum number of functions, which were running at the same time
+type concurrencyCounter struct {
+	running int64
+	maximum int64
+}
+
+func (c *concurrencyCounter) run(duration time.Duration) {
+	running := atomic.AddInt64(&c.running, 1)
+	for {
+		maximum := atomic.LoadInt64(&c.maximum)
+		if running <= maximum || atomic.CompareAndSwapInt64(&c.maximum, maximum, running) {
+			break
+		}
+	}
+
+	time.Sleep(duration)
+	atomic.AddInt64(&c.running, -1)
+}
+
+func TestNew(t *testing.T) {
+	s := New(config.EventsExecutorConfig{})
+	assert.Equal(t, DefaultWorkers, s.workers)
+	assert.Equal(t, DefaultQueueSize, s.size)
+
+	s = New(config.EventsExecutorConfig{
+		Workers:           2,
+		QueueSize:         5,
+		ConcurrencyLimits: map[string]int{"bitbucketrelease": 1},
+	})
+	assert.Equal(t, 2, s.workers)
+	assert.Equal(t, 5, s.size)
+	assert.Equal(t, map[string]int{"bitbucketrelease": 1}, s.limits)
+}
+
+func TestService_SubmitWorkers(t *testing.T) {
+	var (
+		s       = New(config.EventsExecutorConfig{Workers: 3, QueueSize: 20})
+		counter concurrencyCounter
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		assert.NoError(t, s.Submit("example", func() {
+			defer wg.Done()
+			counter.run(time.Millisecond * 20)
+		}))
+	}
+
+	wg.Wait()
+	assert.Equal(t, int64(3), atomic.LoadInt64(&counter.maximum))
+	assert.Equal(t, 0, s.QueueDepth())
+}
+
+func TestService_SubmitConcurrencyLimit(t *testing.T) {
+	var (
+		s = New(config.EventsExecutorConfig{
+			Workers:           4,
+			QueueSize:         20,
+			ConcurrencyLimits: map[string]int{"bitbucketrelease": 1},
+		})
+		limited   concurrencyCounter
+		unlimited concurrencyCounter
+		wg        sync.WaitGroup
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		assert.NoError(t, s.Submit("bitbucketrelease", func() {
+			defer wg.Done()
+			limited.run(time.Millisecond * 20)
+		}))
+		assert.NoError(t, s.Submit("example", func() {
+			defer wg.Done()
+			unlimited.run(time.Millisecond * 50)
+		}))
+	}
+
+	wg.Wait()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&limited.maximum))
+	assert.Greater(t, atomic.LoadInt64(&unlimited.maximum), int64(1))
+	assert.Equal(t, 0, s.QueueDepth())
+}
+
+func TestService_SubmitQueueFull(t *testing.T) {
+	var (
+		s       = New(config.EventsExecutorConfig{Workers: 1, QueueSize: 1})
+		started = make(chan struct{})
+		release = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+
+	wg.Add(2)
+	assert.NoError(t, s.Submit("example", func() {
+		defer wg.Done()
+		close(started)
+		<-release
+	}))
+	<-started
+
+	assert.NoError(t, s.Submit("example", func() {
+		defer wg.Done()
+	}))
+	assert.Equal(t, 1, s.QueueDepth())
+
+	assert.ErrorIs(t, s.Submit("example", func() {}), ErrQueueFull)
+
+	close(release)
+	wg.Wait()
+}
+
+func TestService_SubmitShutdown(t *testing.T) {
+	shutdown.S = shutdown.New()
+	defer func() {
+		shutdown.S = shutdown.New()
+	}()
+
+	var (
+		s        = New(config.EventsExecutorConfig{Workers: 1, QueueSize: 5})
+		finished int64
+	)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.Submit("example", func() {
+			time.Sleep(time.Millisecond * 20)
+			atomic.AddInt64(&finished, 1)
+		}))
+	}
+
+	//The queued events are awaited during the application stop
+	shutdown.S.Stop()
+	assert.NoError(t, shutdown.S.Wait(time.Second))
+	assert.Equal(t, int64(3), atomic.LoadInt64(&finished))
+}
+
+type fakeEvent struct {
+	alias   string
+	timeout time.Duration
+	started chan struct{}
+	panic   bool
+}
+
+func (e fakeEvent) Help() string   { return "" }
+func (e fakeEvent) Alias() string  { return e.alias }
+func (e fakeEvent) Install() error { return nil }
+func (e fakeEvent) Update() error  { return nil }
+
+func (e fakeEvent) Timeout() time.Duration {
+	return e.timeout
+}
+
+func (e fakeEvent) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	message.Text = "Done"
+
+	return message, nil
+}
+
+func (e fakeEvent) ExecuteContext(ctx context.Context, message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	if e.panic {
+		var variables []string
+		message.Text = variables[1]
+	}
+
+	if e.started == nil {
+		return e.Execute(message)
+	}
+
+	close(e.started)
+	<-ctx.Done()
+
+	return message, ctx.Err()
+}
+
+func TestService_Timeout(t *testing.T) {
+	s := New(config.EventsExecutorConfig{
+		DefaultTimeout: 60,
+		Timeouts:       map[string]int{"bitbucketrelease": 900},
+	})
+
+	assert.Equal(t, time.Minute, s.Timeout(fakeEvent{alias: "example"}))
+	assert.Equal(t, time.Second*30, s.Timeout(fakeEvent{alias: "example", timeout: time.Second * 30}))
+	assert.Equal(t, time.Second*900, s.Timeout(fakeEvent{alias: "bitbucketrelease", timeout: time.Second * 30}))
+
+	assert.Equal(t, DefaultTimeout, New(config.EventsExecutorConfig{}).Timeout(fakeEvent{alias: "example"}))
+}
+
+func TestService_ExecuteEvent(t *testing.T) {
+	s := New(config.EventsExecutorConfig{})
+	key := conversation.Key{Channel: "C1", User: "U1"}
+
+	answer, err := s.ExecuteEvent(key, fakeEvent{alias: "example"}, dto.BaseChatMessage{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Done", answer.Text)
+
+	_, err = s.ExecuteEvent(key, fakeEvent{alias: "example", timeout: time.Millisecond * 10, started: make(chan struct{})}, dto.BaseChatMessage{})
+	assert.ErrorIs(t, err, ErrEventTimeout)
+	assert.EqualError(t, err, "the event execution timed out after 10ms")
+}
+
+func TestService_ExecuteEventCancelled(t *testing.T) {
+	s := New(config.EventsExecutorConfig{})
+	key := conversation.Key{Channel: "C2", User: "U2"}
+
+	e := fakeEvent{alias: "example", started: make(chan struct{})}
+	go func() {
+		<-e.started
+		conversation.Cancel(key)
+	}()
+
+	_, err := s.ExecuteEvent(key, e, dto.BaseChatMessage{})
+	assert.ErrorIs(t, err, ErrEventCancelled)
+}
+
+func TestService_ExecuteEventShutdown(t *testing.T) {
+	shutdown.S = shutdown.New()
+	defer func() {
+		shutdown.S = shutdown.New()
+	}()
+
+	s := New(config.EventsExecutorConfig{})
+
+	e := fakeEvent{alias: "example", started: make(chan struct{})}
+	go func() {
+		<-e.started
+		shutdown.S.Cancel()
+	}()
+
+	_, err := s.ExecuteEvent(conversation.Key{Channel: "C3"}, e, dto.BaseChatMessage{})
+	assert.ErrorIs(t, err, ErrEventCancelled)
+}
+
+func TestService_ExecuteEventPanic(t *testing.T) {
+	s := New(config.EventsExecutorConfig{})
+
+	_, err := s.ExecuteEvent(conversation.Key{Channel: "C4"}, fakeEvent{alias: "example", panic: true}, dto.BaseChatMessage{})
+
+	var panicErr *event.PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Len(t, panicErr.ReferenceID, 12)
+	assert.Contains(t, panicErr.Error(), "index out of range")
+	assert.Contains(t, string(panicErr.Stack), "fakeEvent.ExecuteContext")
+}
+
+func TestService_SubmitPanic(t *testing.T) {
+	s := New(config.EventsExecutorConfig{Workers: 1})
+
+	assert.NoError(t, s.Submit("example", func() {
+		panic("something went wrong")
+	}))
+
+	//The worker survives the panic and executes the next job
+	done := make(chan struct{})
+	assert.NoError(t, s.Submit("example", func() {
+		close(done)
+	}))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("The job was not executed after the panic")
+	}
+}
+
+// legacyEvent the event, which implements only Execute method, so it cannot be interrupted
+type legacyEvent struct {
+	alias   string
+	release chan struct{}
+}
+
+func (e legacyEvent) Help() string   { return "" }
+func (e legacyEvent) Alias() string  { return e.alias }
+func (e legacyEvent) Install() error { return nil }
+func (e legacyEvent) Update() error  { return nil }
+
+func (e legacyEvent) Timeout() time.Duration {
+	return time.Millisecond * 10
+}
+
+func (e legacyEvent) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	<-e.release
+
+	return message, nil
+}
+
+func TestService_ExecuteEventDetached(t *testing.T) {
+	shutdown.S = shutdown.New()
+	defer func() {
+		shutdown.S = shutdown.New()
+	}()
+
+	var (
+		s = New(config.EventsExecutorConfig{
+			Workers:           2,
+			ConcurrencyLimits: map[string]int{"bitbucketrelease": 1},
+		})
+		e        = legacyEvent{alias: "bitbucketrelease", release: make(chan struct{})}
+		timedOut = make(chan error, 1)
+		next     = make(chan struct{})
+	)
+
+	assert.NoError(t, s.Submit(e.alias, func() {
+		_, err := s.ExecuteEvent(conversation.Key{Channel: "C5"}, e, dto.BaseChatMessage{})
+		timedOut <- err
+	}))
+	assert.ErrorIs(t, <-timedOut, ErrEventTimeout)
+
+	//The timed out event is still running, so the next event with the same alias waits and the application stop waits as well
+	assert.NoError(t, s.Submit(e.alias, func() {
+		close(next)
+	}))
+
+	select {
+	case <-next:
+		t.Fatal("The next event was started, while the timed out event is still running")
+	case <-time.After(time.Millisecond * 50):
+	}
+	assert.Error(t, shutdown.S.Wait(time.Millisecond*10))
+
+	close(e.release)
+
+	select {
+	case <-next:
+	case <-time.After(time.Second):
+		t.Fatal("The next event was not started after the timed out event finished")
+	}
+	assert.NoError(t, shutdown.S.Wait(time.Second))
+}
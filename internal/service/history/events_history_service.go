@@ -15,7 +15,14 @@ import (
 )
 
 const (
+	//StatusSuccess the status of the successful event execution
+	StatusSuccess = "success"
+
+	//StatusFailed the status of the failed event execution
+	StatusFailed = "failed"
+
 	variablesSeparator = ";"
+	maxFailureLength   = 255
 	ignoredRepeatEvent = "repeatevent"
 )
 
@@ -25,50 +32,52 @@ func RememberEventExecution(msg dto.BaseChatMessage) {
 		return
 	}
 
+	remember(msg, StatusSuccess, "")
+}
+
+// RememberEventFailure method for store the failed event execution in the history. The failure contains the error reference, which was sent to the user
+func RememberEventFailure(msg dto.BaseChatMessage, failure string) {
+	if len(failure) > maxFailureLength {
+		failure = failure[:maxFailureLength]
+	}
+
+	remember(msg, StatusFailed, failure)
+}
+
+func remember(msg dto.BaseChatMessage, status string, failure string) {
+	conv := conversation.S.Get(conversation.NewKey(msg))
+
 	command := msg.OriginalMessage.Text
-	if conversation.GetConversation(msg.Channel).Question != "" {
-		command = conversation.GetConversation(msg.Channel).Question
+	if conv.Question != "" {
+		command = conv.Question
 	}
 
 	var variables []string
-	for _, variable := range conversation.GetConversation(msg.Channel).Scenario.RequiredVariables {
+	for _, variable := range conv.Scenario.RequiredVariables {
 		variables = append(variables, variable.Value)
 	}
 
-	item := databasedto.EventTriggerHistoryModel
-	item.RemoveModelField("id")
-	item.AddModelField(cdto.ModelField{
-		Name:  "event_id",
-		Value: msg.DictionaryMessage.EventID,
-	})
-	item.AddModelField(cdto.ModelField{
-		Name:  "scenario_id",
-		Value: msg.DictionaryMessage.ScenarioID,
-	})
-	item.AddModelField(cdto.ModelField{
-		Name:  "user",
-		Value: msg.OriginalMessage.User,
-	})
-	item.AddModelField(cdto.ModelField{
-		Name:  "channel",
-		Value: msg.Channel,
-	})
-	item.AddModelField(cdto.ModelField{
-		Name:  "command",
-		Value: command,
-	})
-	item.AddModelField(cdto.ModelField{
-		Name:  "variables",
-		Value: strings.Join(variables, variablesSeparator),
-	})
-	item.AddModelField(cdto.ModelField{
-		Name:  "last_question_id",
-		Value: conversation.GetConversation(msg.Channel).LastQuestion.DictionaryMessage.QuestionID,
-	})
-	item.AddModelField(cdto.ModelField{
-		Name:  "created",
-		Value: _time.Service.Now().Unix(),
-	})
+	var failureValue interface{}
+	if failure != "" {
+		failureValue = failure
+	}
+
+	//The events are executed concurrently, so every entry gets its own model
+	item := &cdto.BaseModel{
+		TableName: databasedto.EventTriggerHistoryModel.GetTableName(),
+		Fields: []interface{}{
+			cdto.ModelField{Name: "event_id", Value: msg.DictionaryMessage.EventID},
+			cdto.ModelField{Name: "scenario_id", Value: msg.DictionaryMessage.ScenarioID},
+			cdto.ModelField{Name: "user", Value: msg.OriginalMessage.User},
+			cdto.ModelField{Name: "channel", Value: msg.Channel},
+			cdto.ModelField{Name: "command", Value: command},
+			cdto.ModelField{Name: "variables", Value: strings.Join(variables, variablesSeparator)},
+			cdto.ModelField{Name: "last_question_id", Value: conv.LastQuestion.DictionaryMessage.QuestionID},
+			cdto.ModelField{Name: "created", Value: _time.Service.Now().Unix()},
+			cdto.ModelField{Name: "status", Value: status},
+			cdto.ModelField{Name: "error", Value: failureValue},
+		},
+	}
 
 	c := container.C.Dictionary.GetDBClient()
 
@@ -0,0 +1,127 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/test"
+	"github.com/sharovik/orm/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+// fakeDictionary the dictionary, which returns only the database client
+type fakeDictionary struct {
+	database.BaseDatabaseInterface
+	db clients.BaseClientInterface
+}
+
+func (d fakeDictionary) GetDBClient() clients.BaseClientInterface {
+	return d.db
+}
+
+func TestRememberEventFailure(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.EventTriggerHistoryModel)
+	container.C = container.Main{Dictionary: fakeDictionary{db: db}}
+
+	msg := dto.BaseChatMessage{
+		Channel: "C1",
+		DictionaryMessage: dto.DictionaryMessage{
+			EventID:      1,
+			ReactionType: "example",
+		},
+		OriginalMessage: dto.BaseOriginalMessage{
+			Text: "hello",
+			User: "U1",
+		},
+	}
+
+	RememberEventExecution(msg)
+	RememberEventFailure(msg, "the event execution panicked (reference ID: abc123): "+strings.Repeat("a", 300))
+	RememberEventExecution(msg)
+
+	res, err := db.Execute(new(clients.Query).
+		Select([]interface{}{"status", "error"}).
+		From(databasedto.EventTriggerHistoryModel).
+		OrderBy("id", "ASC"))
+	assert.NoError(t, err)
+	assert.Len(t, res.Items(), 3)
+
+	success := res.Items()[0].GetField("status").Value
+	assert.Equal(t, StatusSuccess, success)
+
+	failure := res.Items()[1]
+	assert.Equal(t, StatusFailed, failure.GetField("status").Value)
+
+	failureText := failure.GetField("error").Value.(string)
+	assert.Len(t, failureText, maxFailureLength)
+	assert.True(t, strings.HasPrefix(failureText, "the event execution panicked (reference ID: abc123)"))
+
+	//The failure of the previous execution is not copied to the next entry
+	assert.Equal(t, StatusSuccess, res.Items()[2].GetField("status").Value)
+	assert.Nil(t, res.Items()[2].GetField("error").Value)
+}
+
+func TestRemember_Concurrent(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.EventTriggerHistoryModel)
+	container.C = container.Main{Dictionary: fakeDictionary{db: db}}
+
+	const executions = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < executions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			msg := dto.BaseChatMessage{
+				Channel: "C1",
+				DictionaryMessage: dto.DictionaryMessage{
+					EventID:      1,
+					ReactionType: "example",
+				},
+				OriginalMessage: dto.BaseOriginalMessage{
+					Text: "hello",
+					User: fmt.Sprintf("U%d", i),
+				},
+			}
+
+			if i%2 == 0 {
+				RememberEventExecution(msg)
+				return
+			}
+
+			RememberEventFailure(msg, fmt.Sprintf("failure of U%d", i))
+		}(i)
+	}
+
+	wg.Wait()
+
+	res, err := db.Execute(new(clients.Query).
+		Select([]interface{}{"user", "status", "error"}).
+		From(databasedto.EventTriggerHistoryModel))
+	assert.NoError(t, err)
+	assert.Len(t, res.Items(), executions)
+
+	//The status and the error of every entry belong to its own execution
+	for _, item := range res.Items() {
+		user := item.GetField("user").Value.(string)
+		if item.GetField("status").Value == StatusSuccess {
+			assert.Nil(t, item.GetField("error").Value, user)
+			continue
+		}
+
+		assert.Equal(t, StatusFailed, item.GetField("status").Value, user)
+		assert.Equal(t, "failure of "+user, item.GetField("error").Value, user)
+	}
+}
@@ -0,0 +1,235 @@
+package learning
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/service"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	cquery "github.com/sharovik/orm/query"
+)
+
+// defaultAnswer the answer of the learned question, when the answer of the event cannot be reused
+const defaultAnswer = "Ok"
+
+var (
+	//ErrUnknownEvent the error, which is returned when the question is learned for the event, which is not installed
+	ErrUnknownEvent = errors.New("the event is not installed")
+
+	//ErrEmptyQuestion the error, which is returned when the question doesn't contain any words
+	ErrEmptyQuestion = errors.New("the question is empty")
+
+	mentionRegex = regexp.MustCompile(`<@[^>]*>`)
+)
+
+// Lesson the question, which the trainer teaches the bot
+type Lesson struct {
+	//Question the text of the learned question
+	Question string
+
+	//Alias the alias of the event, which is triggered by the learned question
+	Alias string
+
+	//Answer the text, which is sent on the learned question. If it is empty, the answer of the event questions is used
+	Answer string
+
+	//Regex the regex of the learned question. Can be empty
+	Regex string
+
+	//User the trainer, who taught the bot
+	User string
+
+	//Channel the channel, where the bot was taught
+	Channel string
+}
+
+// Entry the learned question, which is stored for the review
+type Entry struct {
+	Lesson
+
+	//QuestionID the identifier of the question in the dictionary
+	QuestionID int64
+
+	//Created the time, when the question was learned
+	Created time.Time
+}
+
+// Learn stores the question of the lesson in the dictionary and records it for the review.
+// The question is added to the first scenario of the event, so it triggers the event as the other questions of this scenario
+func Learn(lesson Lesson) (entry Entry, err error) {
+	lesson.Question = CleanQuestion(lesson.Question)
+	if lesson.Question == "" {
+		return entry, ErrEmptyQuestion
+	}
+
+	eventID, err := container.C.Dictionary.FindEventByAlias(lesson.Alias)
+	if err != nil {
+		return entry, err
+	}
+
+	if eventID == 0 {
+		return entry, ErrUnknownEvent
+	}
+
+	scenario, err := service.PrepareEventScenario(eventID, lesson.Alias)
+	if err != nil {
+		return entry, err
+	}
+
+	if scenario.ID == 0 {
+		return entry, ErrUnknownEvent
+	}
+
+	if lesson.Answer == "" {
+		if lesson.Answer, err = scenarioAnswer(scenario.ID); err != nil {
+			return entry, err
+		}
+	}
+
+	questionID, err := container.C.Dictionary.InsertQuestion(lesson.Question, lesson.Answer, scenario.ID, lesson.Regex, "", false)
+	if err != nil {
+		return entry, err
+	}
+
+	entry = Entry{
+		Lesson:     lesson,
+		QuestionID: questionID,
+		Created:    _time.Service.Now(),
+	}
+
+	return entry, remember(entry)
+}
+
+// Latest retrieves the latest learned questions starting from the newest one
+func Latest(limit int) (result []Entry, err error) {
+	q := new(clients.Query).
+		Select(databasedto.LearnedQuestionsModel.GetColumns()).
+		From(databasedto.LearnedQuestionsModel).
+		OrderBy("id", cquery.OrderDirectionDesc).
+		Limit(cquery.Limit{From: 0, To: int64(limit)})
+
+	res, err := container.C.Dictionary.GetDBClient().Execute(q)
+	if err == sql.ErrNoRows {
+		return result, nil
+	} else if err != nil {
+		return result, err
+	}
+
+	for _, item := range res.Items() {
+		entry := Entry{
+			Lesson: Lesson{
+				Question: item.GetField("question").Value.(string),
+				Answer:   item.GetField("answer").Value.(string),
+				Alias:    item.GetField("alias").Value.(string),
+				User:     item.GetField("user").Value.(string),
+				Channel:  item.GetField("channel").Value.(string),
+			},
+			QuestionID: int64(item.GetField("question_id").Value.(int)),
+			Created:    time.Unix(int64(item.GetField("created").Value.(int)), 0),
+		}
+
+		if regex, ok := item.GetField("regex").Value.(string); ok {
+			entry.Regex = regex
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+// CleanQuestion removes the mentions and extra spaces from the text of the question
+func CleanQuestion(text string) string {
+	return strings.Join(strings.Fields(mentionRegex.ReplaceAllString(text, " ")), " ")
+}
+
+// GenerateRegex generates the case-insensitive regex, which matches the whole question. The punctuation and the mention of the bot are ignored
+func GenerateRegex(question string) string {
+	words := strings.FieldsFunc(CleanQuestion(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+
+	return `(?i)^\s*(?:<@[^>]*>)?\W*` + strings.Join(words, `\W+`) + `\W*$`
+}
+
+// scenarioAnswer retrieves the answer of the scenario questions, so the learned question is answered the same way.
+// The answers with the placeholders are skipped, because the learned question doesn't have the regex groups for them
+func scenarioAnswer(scenarioID int64) (string, error) {
+	questions, err := container.C.Dictionary.GetQuestionsByScenarioID(scenarioID, false)
+	if err != nil {
+		return "", err
+	}
+
+	for _, question := range questions {
+		if question.IsVariable || question.Question == "" || question.Answer == "" || strings.Contains(question.Answer, "%") {
+			continue
+		}
+
+		return question.Answer, nil
+	}
+
+	return defaultAnswer, nil
+}
+
+func remember(entry Entry) error {
+	var item = cdto.BaseModel{
+		TableName: databasedto.LearnedQuestionsModel.GetTableName(),
+		Fields: []interface{}{
+			cdto.ModelField{
+				Name:  "question_id",
+				Value: entry.QuestionID,
+			},
+			cdto.ModelField{
+				Name:  "question",
+				Value: entry.Question,
+			},
+			cdto.ModelField{
+				Name:  "answer",
+				Value: entry.Answer,
+			},
+			cdto.ModelField{
+				Name:  "alias",
+				Value: entry.Alias,
+			},
+			cdto.ModelField{
+				Name:  "user",
+				Value: entry.User,
+			},
+			cdto.ModelField{
+				Name:  "channel",
+				Value: entry.Channel,
+			},
+			cdto.ModelField{
+				Name:  "created",
+				Value: entry.Created.Unix(),
+			},
+		},
+	}
+
+	if entry.Regex != "" {
+		item.AddModelField(cdto.ModelField{
+			Name:  "regex",
+			Value: entry.Regex,
+		})
+	}
+
+	_, err := container.C.Dictionary.GetDBClient().Execute(new(clients.Query).Insert(&item))
+
+	return err
+}
@@ -0,0 +1,99 @@
+package learning
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func initTestDictionary(t *testing.T) *database.Dictionary {
+	return database.NewDictionary(test.InitDatabase(t,
+		databasedto.EventModel,
+		databasedto.QuestionsRegexModel,
+		databasedto.ScenariosModel,
+		databasedto.QuestionsModel,
+		databasedto.LearnedQuestionsModel,
+	))
+}
+
+func TestLearn(t *testing.T) {
+	d := initTestDictionary(t)
+	container.C = container.Main{Dictionary: d}
+
+	assert.NoError(t, d.InstallNewEventScenario(database.EventScenario{
+		EventName: "deploy",
+		Questions: []database.Question{
+			{Question: "deploy %s", Answer: "Deploying %s", QuestionRegex: "(?i)deploy (.+)", QuestionGroup: "1"},
+			{Question: "deploy", Answer: "Deploying"},
+		},
+	}))
+
+	entry, err := Learn(Lesson{
+		Question: "<@BOT> ship it",
+		Alias:    "deploy",
+		Regex:    GenerateRegex("ship it"),
+		User:     "U1",
+		Channel:  "C1",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, entry.QuestionID)
+	assert.Equal(t, "ship it", entry.Question)
+	assert.Equal(t, "Deploying", entry.Answer)
+
+	answer, err := d.FindAnswer("Ship it!")
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy", answer.ReactionType)
+	assert.Equal(t, "Deploying", answer.Answer)
+
+	_, err = Learn(Lesson{Question: "say hi", Alias: "greeting", Answer: "Hi"})
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+
+	_, err = Learn(Lesson{Question: "<@BOT> ", Alias: "deploy"})
+	assert.ErrorIs(t, err, ErrEmptyQuestion)
+
+	_, err = Learn(Lesson{Question: "release", Alias: "deploy", Answer: "Releasing", User: "U2", Channel: "C2"})
+	assert.NoError(t, err)
+
+	entries, err := Latest(10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "release", entries[0].Question)
+	assert.Equal(t, "Releasing", entries[0].Answer)
+	assert.Empty(t, entries[0].Regex)
+	assert.Equal(t, "U2", entries[0].User)
+	assert.Equal(t, entry.QuestionID, entries[1].QuestionID)
+	assert.Equal(t, entry.Regex, entries[1].Regex)
+	assert.Equal(t, entry.Created.Unix(), entries[1].Created.Unix())
+
+	entries, err = Latest(1)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestGenerateRegex(t *testing.T) {
+	assert.Empty(t, GenerateRegex(""))
+	assert.Empty(t, GenerateRegex("<@BOT> ?!"))
+
+	regex := GenerateRegex("<@BOT> What's the (build) status?")
+	assert.Equal(t, `(?i)^\s*(?:<@[^>]*>)?\W*What\W+s\W+the\W+build\W+status\W*$`, regex)
+
+	re := regexp.MustCompile(regex)
+	assert.True(t, re.MatchString("what's the build status"))
+	assert.True(t, re.MatchString("<@BOT> WHAT S THE BUILD STATUS ???"))
+	assert.False(t, re.MatchString("what's the build status of master"))
+	assert.False(t, re.MatchString("so what's the build status"))
+}
+
+func TestCleanQuestion(t *testing.T) {
+	assert.Equal(t, "ship it now", CleanQuestion(" <@BOT>  ship it <@U1> now "))
+}
@@ -0,0 +1,374 @@
+package matcher
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sharovik/devbot/internal/config"
+)
+
+const (
+	//DefaultThreshold the default minimal confidence of the match. The candidates with lower score are ignored
+	DefaultThreshold = 0.7
+
+	//DefaultAmbiguityMargin the default difference between the scores of the best candidates, below which the match is ambiguous
+	DefaultAmbiguityMargin = 0.05
+
+	//tokenWeight and editWeight the weights of the token overlap and the edit distance in the similarity score
+	tokenWeight = 0.7
+	editWeight  = 0.3
+
+	//minTokenSimilarity the minimal similarity of two words, which are counted as the same word with a typo
+	minTokenSimilarity = 0.75
+
+	//maxSuggestions the maximum number of candidates, which are suggested to the user for the ambiguous match
+	maxSuggestions = 3
+)
+
+var mentionRegex = regexp.MustCompile(`<@[^>]*>`)
+
+// Candidate the question, which can be matched with the message
+type Candidate struct {
+	//ID the identifier of the candidate. It is used as the last sorting criteria, so the result is always the same
+	ID int64
+
+	//Group the identifier of the candidates group. The candidates of the same group are not ambiguous between each other. For example, the questions of the same scenario
+	Group int64
+
+	//Text the text of the question
+	Text string
+
+	//Regex the compiled regular expression of the question
+	Regex *regexp.Regexp
+
+	//Priority the priority of the question regex. The candidate with higher priority is selected first, when several candidates have the same score
+	Priority int
+
+	prepared *text
+}
+
+// NewCandidate creates the candidate with compiled regex and normalised text, so they are not prepared again for each message.
+// If the regex cannot be compiled, the candidate without regex is returned together with the error
+func NewCandidate(id int64, group int64, question string, regex string, priority int) (Candidate, error) {
+	candidate := Candidate{
+		ID:       id,
+		Group:    group,
+		Text:     question,
+		Priority: priority,
+	}
+
+	prepared := newText(normalise(question))
+	candidate.prepared = &prepared
+
+	if regex == "" {
+		return candidate, nil
+	}
+
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return candidate, err
+	}
+
+	candidate.Regex = re
+
+	return candidate, nil
+}
+
+// Match the scored candidate
+type Match struct {
+	Candidate
+
+	//Score the confidence of the match from 0 to 1
+	Score float64
+
+	//Similarity the text similarity of the message and the candidate from 0 to 1
+	Similarity float64
+
+	//Exact true, when the normalised message is the same as the normalised candidate text
+	Exact bool
+
+	//RegexHit true, when the regex of the candidate matches the message
+	RegexHit bool
+}
+
+// Result the result of the matching
+type Result struct {
+	//Best the best match. Nil, when there is no candidate with the score above the threshold
+	Best *Match
+
+	//Alternatives the candidates of the other groups, which have almost the same score as the best match. Not empty only for the ambiguous match
+	Alternatives []Match
+}
+
+// IsAmbiguous returns true, when the user should choose between the best match and the alternatives
+func (r Result) IsAmbiguous() bool {
+	return r.Best != nil && len(r.Alternatives) > 0
+}
+
+// Matcher the engine, which scores all candidates and selects the best one
+type Matcher struct {
+	threshold       float64
+	ambiguityMargin float64
+}
+
+// New creates the matcher. If the threshold or ambiguity margin is not specified, the default value is used
+func New(cfg config.MatchingConfig) Matcher {
+	threshold := cfg.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+
+	ambiguityMargin := cfg.AmbiguityMargin
+	if ambiguityMargin <= 0 {
+		ambiguityMargin = DefaultAmbiguityMargin
+	}
+
+	return Matcher{
+		threshold:       threshold,
+		ambiguityMargin: ambiguityMargin,
+	}
+}
+
+// Rank scores all candidates and returns them sorted from the best to the worst. The candidates with the same score are sorted
+// by the priority, then the exact match goes first, then the more similar candidate. The last criteria is the ID, so the order is always the same
+func (m Matcher) Rank(message string, candidates []Candidate) []Match {
+	var (
+		s           scorer
+		messageText = newText(normalise(message))
+		matches     = make([]Match, 0, len(candidates))
+	)
+
+	for _, candidate := range candidates {
+		candidateText := candidate.prepared
+		if candidateText == nil {
+			prepared := newText(normalise(candidate.Text))
+			candidateText = &prepared
+		}
+
+		match := Match{
+			Candidate:  candidate,
+			Similarity: s.similarity(messageText, *candidateText),
+		}
+
+		match.Exact = messageText.value != "" && messageText.value == candidateText.value
+		match.RegexHit = candidate.Regex != nil && candidate.Regex.MatchString(message)
+
+		match.Score = match.Similarity
+		if match.Exact || match.RegexHit {
+			match.Score = 1
+		}
+
+		matches = append(matches, match)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+
+		if matches[i].Priority != matches[j].Priority {
+			return matches[i].Priority > matches[j].Priority
+		}
+
+		if matches[i].Exact != matches[j].Exact {
+			return matches[i].Exact
+		}
+
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+
+		return matches[i].ID < matches[j].ID
+	})
+
+	return matches
+}
+
+// Find selects the best candidate for the message. The exact and regex matches are always confident.
+// The fuzzy match is ambiguous, when the candidates of other groups have almost the same score
+func (m Matcher) Find(message string, candidates []Candidate) Result {
+	matches := m.Rank(message, candidates)
+	if len(matches) == 0 || matches[0].Score < m.threshold {
+		return Result{}
+	}
+
+	best := matches[0]
+	result := Result{Best: &best}
+	if best.Exact || best.RegexHit {
+		return result
+	}
+
+	var groups = map[int64]bool{best.Group: true}
+	for _, match := range matches[1:] {
+		if match.Score < m.threshold || best.Score-match.Score > m.ambiguityMargin || len(result.Alternatives) == maxSuggestions-1 {
+			break
+		}
+
+		if groups[match.Group] {
+			continue
+		}
+
+		groups[match.Group] = true
+		result.Alternatives = append(result.Alternatives, match)
+	}
+
+	return result
+}
+
+// Similarity returns the similarity of two normalised texts from 0 to 1. It combines the overlap of the words, which ignores the words order,
+// with the edit distance of the whole texts
+func Similarity(a string, b string) float64 {
+	return new(scorer).similarity(newText(a), newText(b))
+}
+
+// text the normalised text, which is split to the characters and words once, so it can be compared with many other texts
+type text struct {
+	value string
+	runes []rune
+	words [][]rune
+}
+
+func newText(value string) text {
+	t := text{
+		value: value,
+		runes: []rune(value),
+	}
+
+	for _, word := range strings.Fields(value) {
+		t.words = append(t.words, []rune(word))
+	}
+
+	return t
+}
+
+// scorer calculates the similarity of the texts. It reuses the memory between the calculations, so it should not be used concurrently
+type scorer struct {
+	beforePrevious []int
+	previous       []int
+	current        []int
+	used           []bool
+}
+
+func (s *scorer) similarity(a text, b text) float64 {
+	if a.value == "" || b.value == "" {
+		return 0
+	}
+
+	if a.value == b.value {
+		return 1
+	}
+
+	return tokenWeight*s.tokenOverlap(a.words, b.words) + editWeight*s.editSimilarity(a.runes, b.runes)
+}
+
+// tokenOverlap returns the Dice coefficient of two lists of words. The words with typos are counted proportionally to their similarity
+func (s *scorer) tokenOverlap(a [][]rune, b [][]rune) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	if cap(s.used) < len(b) {
+		s.used = make([]bool, len(b))
+	}
+
+	s.used = s.used[:len(b)]
+	for i := range s.used {
+		s.used[i] = false
+	}
+
+	var overlap float64
+	for _, wordA := range a {
+		bestIndex, bestSimilarity := -1, 0.0
+		for i, wordB := range b {
+			if s.used[i] || !canBeSimilar(len(wordA), len(wordB)) {
+				continue
+			}
+
+			if similarity := s.editSimilarity(wordA, wordB); similarity >= minTokenSimilarity && similarity > bestSimilarity {
+				bestIndex, bestSimilarity = i, similarity
+			}
+		}
+
+		if bestIndex != -1 {
+			s.used[bestIndex] = true
+			overlap += bestSimilarity
+		}
+	}
+
+	return 2 * overlap / float64(len(a)+len(b))
+}
+
+// canBeSimilar returns false, when the difference of the words lengths is too big for the words to be counted as the same word with a typo
+func canBeSimilar(a int, b int) bool {
+	diff, maxLen := a-b, a
+	if diff < 0 {
+		diff, maxLen = -diff, b
+	}
+
+	return 1-float64(diff)/float64(maxLen) >= minTokenSimilarity
+}
+
+// editSimilarity returns the similarity of two strings based on the edit distance
+func (s *scorer) editSimilarity(a []rune, b []rune) float64 {
+	maxLen := len(a)
+	if len(b) > maxLen {
+		maxLen = len(b)
+	}
+
+	if maxLen == 0 {
+		return 1
+	}
+
+	return 1 - float64(s.editDistance(a, b))/float64(maxLen)
+}
+
+// editDistance returns the number of single character edits and transpositions of two adjacent characters,
+// which are required to change one string into the other
+func (s *scorer) editDistance(a []rune, b []rune) int {
+	//Only the last three rows of the distances matrix are needed
+	if cap(s.current) < len(b)+1 {
+		s.beforePrevious = make([]int, len(b)+1)
+		s.previous = make([]int, len(b)+1)
+		s.current = make([]int, len(b)+1)
+	}
+
+	beforePrevious, previous, current := s.beforePrevious[:len(b)+1], s.previous[:len(b)+1], s.current[:len(b)+1]
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], beforePrevious[j-2]+1)
+			}
+		}
+
+		beforePrevious, previous, current = previous, current, beforePrevious
+	}
+
+	return previous[len(b)]
+}
+
+// normalise removes the mentions and punctuation from the text and converts it to the lower case
+func normalise(text string) string {
+	text = mentionRegex.ReplaceAllString(text, " ")
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+
+		return ' '
+	}, text)
+
+	return strings.Join(strings.Fields(text), " ")
+}
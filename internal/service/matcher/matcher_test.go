@@ -0,0 +1,158 @@
+package matcher
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+var candidates = []Candidate{
+	{ID: 1, Group: 1, Text: "events list", Regex: regexp.MustCompile("(?i)(events list)")},
+	{ID: 2, Group: 2, Text: "start conversation"},
+	{ID: 3, Group: 3, Text: "stop conversation"},
+	{ID: 4, Group: 4, Text: "write a message", Regex: regexp.MustCompile(`(?i)write a message (?P<message>.+)`)},
+	{ID: 5, Group: 5, Text: "schedule event"},
+	{ID: 6, Group: 5, Text: "schedule events"},
+}
+
+func TestNew(t *testing.T) {
+	m := New(config.MatchingConfig{})
+	assert.Equal(t, DefaultThreshold, m.threshold)
+	assert.Equal(t, DefaultAmbiguityMargin, m.ambiguityMargin)
+
+	m = New(config.MatchingConfig{Threshold: 0.9, AmbiguityMargin: 0.1})
+	assert.Equal(t, 0.9, m.threshold)
+	assert.Equal(t, 0.1, m.ambiguityMargin)
+}
+
+func TestMatcher_Find(t *testing.T) {
+	m := New(config.MatchingConfig{})
+
+	cases := map[string]int64{
+		"events list":                     1,
+		"Events list?":                    1,
+		"<@U0LAN0Z89> start conversation": 2,
+		"evnts list":                      1,
+		"list events":                     1,
+		"strat conversation":              2,
+		"write a message hello world":     4,
+		"schedule event":                  5,
+	}
+
+	for message, expected := range cases {
+		result := m.Find(message, candidates)
+		if assert.NotNil(t, result.Best, message) {
+			assert.Equal(t, expected, result.Best.ID, message)
+			assert.False(t, result.IsAmbiguous(), message)
+			assert.GreaterOrEqual(t, result.Best.Score, DefaultThreshold, message)
+		}
+	}
+
+	for _, message := range []string{"", "hello", "what is the weather today"} {
+		assert.Nil(t, m.Find(message, candidates).Best, message)
+	}
+}
+
+func TestMatcher_FindConfidence(t *testing.T) {
+	m := New(config.MatchingConfig{})
+
+	assert.Equal(t, 1.0, m.Find("events list", candidates).Best.Score)
+	assert.Equal(t, 1.0, m.Find("write a message hello", candidates).Best.Score)
+
+	typo := m.Find("evnts list", candidates).Best.Score
+	assert.Less(t, typo, 1.0)
+	assert.Greater(t, typo, m.Find("list events", candidates).Best.Score)
+}
+
+func TestMatcher_FindAmbiguous(t *testing.T) {
+	m := New(config.MatchingConfig{})
+
+	deploy := []Candidate{
+		{ID: 1, Group: 1, Text: "deploy app"},
+		{ID: 2, Group: 2, Text: "deploy api"},
+		{ID: 3, Group: 3, Text: "events list"},
+	}
+
+	m = New(config.MatchingConfig{Threshold: 0.6})
+	result := m.Find("deploy", deploy)
+	assert.True(t, result.IsAmbiguous())
+	assert.Equal(t, int64(1), result.Best.ID)
+	assert.Len(t, result.Alternatives, 1)
+	assert.Equal(t, int64(2), result.Alternatives[0].ID)
+
+	//The questions of the same group are not ambiguous
+	result = m.Find("schedule evnt", candidates)
+	assert.False(t, result.IsAmbiguous())
+	assert.Equal(t, int64(5), result.Best.ID)
+
+	//The typo in the message is not ambiguous, because the other question is less similar
+	result = m.Find("deploy apps", deploy)
+	assert.False(t, result.IsAmbiguous())
+	assert.Equal(t, int64(1), result.Best.ID)
+}
+
+func TestMatcher_RankDeterministic(t *testing.T) {
+	m := New(config.MatchingConfig{})
+	duplicates := []Candidate{
+		{ID: 3, Group: 3, Text: "hello"},
+		{ID: 1, Group: 1, Text: "hello"},
+		{ID: 2, Group: 2, Regex: regexp.MustCompile("(?i)hello")},
+	}
+
+	for i := 0; i < 10; i++ {
+		matches := m.Rank("Hello", duplicates)
+		assert.Equal(t, []int64{1, 3, 2}, []int64{matches[0].ID, matches[1].ID, matches[2].ID})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 0.0, Similarity("", "events list"))
+	assert.Equal(t, 1.0, Similarity("events list", "events list"))
+	assert.Equal(t, 0.0, Similarity("hello", "bye"))
+	assert.InDelta(t, 0.91, Similarity("evnts list", "events list"), 0.01)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, new(scorer).editDistance([]rune(""), []rune("")))
+	assert.Equal(t, 3, new(scorer).editDistance([]rune(""), []rune("abc")))
+	assert.Equal(t, 3, new(scorer).editDistance([]rune("kitten"), []rune("sitting")))
+	assert.Equal(t, 1, new(scorer).editDistance([]rune("start"), []rune("strat")))
+	assert.Equal(t, 1, new(scorer).editDistance([]rune("привет"), []rune("привт")))
+}
+
+func TestNormalise(t *testing.T) {
+	assert.Equal(t, "events list", normalise("  <@U0LAN0Z89>  Events,   LIST?! "))
+	assert.Equal(t, "potential event", normalise("potential_event"))
+}
+
+func TestNewCandidate(t *testing.T) {
+	candidate, err := NewCandidate(1, 2, "Events list?", "(?i)events list", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "events list", candidate.prepared.value)
+	assert.True(t, candidate.Regex.MatchString("Events List"))
+	assert.Equal(t, 5, candidate.Priority)
+
+	candidate, err = NewCandidate(1, 2, "events list", "(?i)(events list", 0)
+	assert.Error(t, err)
+	assert.Nil(t, candidate.Regex)
+	assert.Equal(t, "events list", candidate.Text)
+}
+
+func TestMatcher_RankPriority(t *testing.T) {
+	m := New(config.MatchingConfig{})
+	prioritised := []Candidate{
+		{ID: 1, Group: 1, Text: "deploy staging", Regex: regexp.MustCompile("(?i)deploy (staging|stage)")},
+		{ID: 2, Group: 2, Text: "deploy", Regex: regexp.MustCompile("(?i)deploy"), Priority: 10},
+	}
+
+	matches := m.Rank("deploy staging", prioritised)
+	assert.Equal(t, int64(2), matches[0].ID)
+	assert.True(t, matches[1].Exact)
+}
@@ -1,8 +1,14 @@
 package message
 
 import (
+	"errors"
+	"io"
+	"os"
+
 	"github.com/sharovik/devbot/internal/config"
 	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/shutdown"
 )
 
 // BaseServiceInterface base interface for messages APIs services
@@ -15,12 +21,45 @@ type BaseServiceInterface interface {
 // S message service object
 var S BaseServiceInterface
 
+// ErrInputClosed the error, which is returned by the receiver, once there will be no more messages. The application should be stopped without retries
+var ErrInputClosed = errors.New("messages input was closed")
+
+// ErrReceiverStopped the error, which is returned by the receiver, once the application is stopping. The application should be stopped without retries
+var ErrReceiverStopped = errors.New("messages receiver was stopped")
+
 // InitService initialize the events-api service
 func InitService() {
 	switch container.C.Config.MessagesAPIConfig.Type {
 	case config.MessagesAPITypeSlack:
 		S = SlackService{}
+	case config.MessagesAPITypeMattermost:
+		S = MattermostService{}
+	case config.MessagesAPITypeConsole:
+		S = ConsoleService{Input: os.Stdin}
 	default:
 		panic("The messages api type is not supported")
 	}
 }
+
+// closeOnStop closes the connection of the receiver, once the application is stopping. The returned function should be called, when the receiver is finished
+func closeOnStop(connection io.Closer) (release func()) {
+	finished := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+
+		select {
+		case <-shutdown.S.Stopping():
+			log.Logger().Info().Msg("Close the messages receiver connection")
+			if err := connection.Close(); err != nil {
+				log.Logger().AddError(err).Msg("Failed to close the messages receiver connection")
+			}
+		case <-finished:
+		}
+	}()
+
+	return func() {
+		close(finished)
+		<-watched
+	}
+}
@@ -0,0 +1,58 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/service/shutdown"
+)
+
+type fakeConnection struct {
+	closed chan struct{}
+}
+
+func (c fakeConnection) Close() error {
+	close(c.closed)
+
+	return nil
+}
+
+func TestCloseOnStop(t *testing.T) {
+	shutdown.S = shutdown.New()
+	defer func() {
+		shutdown.S = shutdown.New()
+	}()
+
+	connection := fakeConnection{closed: make(chan struct{})}
+	release := closeOnStop(connection)
+	defer release()
+
+	shutdown.S.Stop()
+
+	select {
+	case <-connection.closed:
+	case <-time.After(time.Second):
+		t.Fatal("The connection should be closed, once the application is stopping")
+	}
+}
+
+func TestCloseOnStopReleased(t *testing.T) {
+	shutdown.S = shutdown.New()
+	defer func() {
+		shutdown.S = shutdown.New()
+	}()
+
+	connection := fakeConnection{closed: make(chan struct{})}
+	release := closeOnStop(connection)
+	release()
+
+	//The connection of the finished receiver is not closed
+	shutdown.S.Stop()
+	time.Sleep(time.Millisecond * 50)
+
+	select {
+	case <-connection.closed:
+		t.Fatal("The connection should not be closed by the released receiver")
+	default:
+	}
+}
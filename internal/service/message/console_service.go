@@ -0,0 +1,150 @@
+package message
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/analiser"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/shutdown"
+	_time "github.com/sharovik/devbot/internal/service/time"
+)
+
+// ConsoleService struct of message service for the local terminal. Each line of the Input is processed as the message of the console user
+type ConsoleService struct {
+	Input io.Reader
+}
+
+// BeforeWSConnectionStart runs methods before the input reading start
+func (ConsoleService) BeforeWSConnectionStart() error {
+	if container.C.Config.MessagesAPIConfig.MainChannelID == "" {
+		container.C.Config.MessagesAPIConfig.MainChannelID = container.C.Config.MessagesAPIConfig.ConsoleChannelID
+	}
+
+	if container.C.Config.MessagesAPIConfig.BotUserID == "" {
+		container.C.Config.MessagesAPIConfig.BotUserID = container.C.Config.MessagesAPIConfig.BotName
+	}
+
+	log.Logger().AppendGlobalContext(map[string]interface{}{
+		"main_channel_id": container.C.Config.MessagesAPIConfig.MainChannelID,
+		"bot_user_id":     container.C.Config.MessagesAPIConfig.BotUserID,
+		"console_user_id": container.C.Config.MessagesAPIConfig.ConsoleUserID,
+	})
+
+	return nil
+}
+
+// InitWebSocketReceiver method reads the messages from the input, until the input will be closed
+func (s ConsoleService) InitWebSocketReceiver() error {
+	if err := s.BeforeWSConnectionStart(); err != nil {
+		log.Logger().AddError(err).Msg("Failed to prepare service for the input reading")
+		return err
+	}
+
+	if _, _, err := container.C.MessageClient.SendMessage(dto.BaseChatMessage{
+		Channel: container.C.Config.MessagesAPIConfig.ConsoleChannelID,
+		Text:    fmt.Sprintf("Hi %s! Type your message and press Enter. Close the input (Ctrl+D) to exit.", container.C.Config.MessagesAPIConfig.ConsoleUserID),
+	}); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(s.Input)
+	for scanner.Scan() {
+		message := dto.BaseOriginalMessage{
+			Text:    scanner.Text(),
+			User:    container.C.Config.MessagesAPIConfig.ConsoleUserID,
+			Channel: container.C.Config.MessagesAPIConfig.ConsoleChannelID,
+			Ts:      strconv.FormatInt(_time.Service.Now().UnixNano(), 10),
+			Type:    eventTypeMessage,
+		}
+
+		if !isValidMessage(MsgAttributes{
+			Type:    message.Type,
+			Channel: message.Channel,
+			Text:    message.Text,
+			User:    message.User,
+		}) {
+			continue
+		}
+
+		if !shutdown.S.Begin() {
+			return ErrReceiverStopped
+		}
+
+		if err := s.ProcessMessage(&message); err != nil {
+			log.Logger().AddError(err).Interface("message_object", &message).Msg("Can't check or answer to the message")
+		}
+
+		conversation.S.Expire()
+		shutdown.S.End()
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Logger().AddError(err).Msg("Something went wrong with the input reading")
+		return err
+	}
+
+	return ErrInputClosed
+}
+
+// ProcessMessage processes the message typed in the console
+func (s ConsoleService) ProcessMessage(msg interface{}) error {
+	message := msg.(*dto.BaseOriginalMessage)
+	log.Logger().Debug().
+		Str("text", message.Text).
+		Str("user", message.User).
+		Str("channel", message.Channel).
+		Msg("Message received")
+
+	//We need to trim the message before all checks
+	message.Text = strings.TrimSpace(message.Text)
+
+	dmAnswer, err := analiser.GetDmAnswer(analiser.Message{
+		Channel:  message.Channel,
+		User:     message.User,
+		ThreadTS: message.ThreadTS,
+		Text:     message.Text,
+	})
+	if err != nil {
+		log.Logger().AddError(err).Msg("Failed to get dictionary message answer")
+		return err
+	}
+
+	m, err := prepareAnswer(&dto.SlackResponseEventMessage{
+		Channel:  message.Channel,
+		ThreadTS: message.ThreadTS,
+		Text:     message.Text,
+		Ts:       message.Ts,
+		Type:     message.Type,
+		User:     message.User,
+	}, dmAnswer)
+	if err != nil {
+		log.Logger().AddError(err).Msg("Failed to analyse received message")
+		return err
+	}
+
+	if dmAnswer.IsEmpty() {
+		log.Logger().Debug().
+			Str("text", message.Text).
+			Str("user", message.User).
+			Msg("No answer found for the received message")
+	} else {
+		//We put a dictionary message into our message object,
+		// so later we can identify what kind of reaction will be executed
+		m.DictionaryMessage = dmAnswer
+	}
+
+	if err = TriggerAnswer(m, true); err != nil {
+		log.Logger().AddError(err).Msg("Failed trigger the answer")
+		return err
+	}
+
+	refreshPreparedMessages()
+	return nil
+}
@@ -0,0 +1,78 @@
+package message
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/client"
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/service/shutdown"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleService_InitWebSocketReceiver(t *testing.T) {
+	var output bytes.Buffer
+
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				BotName:          "devbot",
+				Type:             config.MessagesAPITypeConsole,
+				ConsoleUserID:    "developer",
+				ConsoleChannelID: "console",
+			},
+		},
+		MessageClient: client.NewConsoleClient(&output, "devbot", "developer", "console"),
+		Dictionary: fakeDictionary{answers: map[string]string{
+			"Hello bot": "Hello human",
+		}},
+	}
+
+	err := ConsoleService{Input: strings.NewReader("  Hello bot  \n\nsomething unknown\n")}.InitWebSocketReceiver()
+	assert.ErrorIs(t, err, ErrInputClosed)
+
+	assert.Equal(t, "devbot", container.C.Config.MessagesAPIConfig.BotUserID)
+	assert.Equal(t, "console", container.C.Config.MessagesAPIConfig.MainChannelID)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "Hi developer!")
+	assert.Equal(t, "devbot: Hello human", lines[1])
+	assert.Equal(t, "devbot: Hmmm", lines[2])
+}
+
+func TestConsoleService_InitWebSocketReceiverStopped(t *testing.T) {
+	var output bytes.Buffer
+
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				BotName:          "devbot",
+				Type:             config.MessagesAPITypeConsole,
+				ConsoleUserID:    "developer",
+				ConsoleChannelID: "console",
+			},
+		},
+		MessageClient: client.NewConsoleClient(&output, "devbot", "developer", "console"),
+		Dictionary: fakeDictionary{answers: map[string]string{
+			"Hello bot": "Hello human",
+		}},
+	}
+
+	shutdown.S = shutdown.New()
+	defer func() {
+		shutdown.S = shutdown.New()
+	}()
+
+	shutdown.S.Stop()
+
+	err := ConsoleService{Input: strings.NewReader("Hello bot\n")}.InitWebSocketReceiver()
+	assert.ErrorIs(t, err, ErrReceiverStopped)
+
+	//The message, which was received after the stop, is not processed
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "Hi developer!")
+}
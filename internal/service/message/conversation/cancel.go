@@ -0,0 +1,68 @@
+package conversation
+
+import (
+	"context"
+	"sync"
+)
+
+// runningEvents the cancel functions of the running events, grouped by the conversation key
+var runningEvents = struct {
+	mu      sync.Mutex
+	next    int
+	cancels map[Key]map[int]context.CancelFunc
+}{
+	cancels: map[Key]map[int]context.CancelFunc{},
+}
+
+// WithCancel returns the context of the event execution for the conversation. The context is cancelled once Cancel is called for this key.
+// The returned release function should be called, once the event execution is finished
+func WithCancel(parent context.Context, key Key) (ctx context.Context, release func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	runningEvents.mu.Lock()
+	id := runningEvents.next
+	runningEvents.next++
+	if runningEvents.cancels[key] == nil {
+		runningEvents.cancels[key] = map[int]context.CancelFunc{}
+	}
+
+	runningEvents.cancels[key][id] = cancel
+	runningEvents.mu.Unlock()
+
+	return ctx, func() {
+		runningEvents.mu.Lock()
+		delete(runningEvents.cancels[key], id)
+		if len(runningEvents.cancels[key]) == 0 {
+			delete(runningEvents.cancels, key)
+		}
+		runningEvents.mu.Unlock()
+
+		cancel()
+	}
+}
+
+// Cancel cancels the running events of the conversation and returns the number of cancelled events
+func Cancel(key Key) int {
+	return CancelMatching(func(k Key) bool {
+		return k == key
+	})
+}
+
+// CancelMatching cancels the running events of all conversations, which keys are matching, and returns the number of cancelled events
+func CancelMatching(match func(key Key) bool) (cancelled int) {
+	runningEvents.mu.Lock()
+	defer runningEvents.mu.Unlock()
+
+	for key, cancels := range runningEvents.cancels {
+		if !match(key) {
+			continue
+		}
+
+		for _, cancel := range cancels {
+			cancel()
+			cancelled++
+		}
+	}
+
+	return cancelled
+}
@@ -0,0 +1,52 @@
+package conversation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCancel(t *testing.T) {
+	key := Key{Channel: "C1", User: "U1"}
+	ctx, release := WithCancel(context.Background(), key)
+	defer release()
+
+	threadCtx, threadRelease := WithCancel(context.Background(), Key{Channel: "C1", User: "U1", ThreadTS: "1.1"})
+	defer threadRelease()
+
+	assert.Equal(t, 0, Cancel(Key{Channel: "C2", User: "U1"}))
+	assert.NoError(t, ctx.Err())
+
+	assert.Equal(t, 1, Cancel(key))
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.NoError(t, threadCtx.Err())
+}
+
+func TestCancelMatching(t *testing.T) {
+	first, firstRelease := WithCancel(context.Background(), Key{Channel: "C1", User: "U1"})
+	defer firstRelease()
+
+	second, secondRelease := WithCancel(context.Background(), Key{Channel: "C1", User: "U2", ThreadTS: "1.1"})
+	defer secondRelease()
+
+	other, otherRelease := WithCancel(context.Background(), Key{Channel: "C2", User: "U2"})
+	defer otherRelease()
+
+	assert.Equal(t, 2, CancelMatching(func(key Key) bool {
+		return key.Channel == "C1"
+	}))
+	assert.Error(t, first.Err())
+	assert.Error(t, second.Err())
+	assert.NoError(t, other.Err())
+}
+
+func TestWithCancelRelease(t *testing.T) {
+	key := Key{Channel: "C3", User: "U3"}
+	ctx, release := WithCancel(context.Background(), key)
+	release()
+
+	//The released context is cancelled and it is not tracked anymore
+	assert.Error(t, ctx.Err())
+	assert.Equal(t, 0, Cancel(key))
+}
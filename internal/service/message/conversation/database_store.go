@@ -0,0 +1,287 @@
+package conversation
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+// DatabaseStore the conversations store, which keeps the open conversations in the database, so the scenario progress survives the application restart.
+// The conversations are cached in the memory and each change is written through to the conversations table.
+type DatabaseStore struct {
+	mu     sync.Mutex
+	db     clients.BaseClientInterface
+	memory *MemoryStore
+}
+
+// NewDatabaseStore creates the new database conversations store for selected database client
+func NewDatabaseStore(db clients.BaseClientInterface) *DatabaseStore {
+	return &DatabaseStore{
+		db:     db,
+		memory: NewMemoryStore(),
+	}
+}
+
+// InitS loads the open conversations from the database and switches the conversations store to the database one.
+// If the conversations cannot be loaded, the in-memory store will be used.
+func InitS(db clients.BaseClientInterface) {
+	store := NewDatabaseStore(db)
+	if err := store.Load(); err != nil {
+		log.Logger().AddError(err).Msg("Failed to load open conversations from the database. In-memory store will be used")
+		return
+	}
+
+	S = store
+}
+
+// Load rehydrates the open conversations from the database. Expired and already answered conversations are removed from the database
+func (s *DatabaseStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := new(clients.Query).
+		Select(databasedto.ConversationsModel.GetColumns()).
+		From(databasedto.ConversationsModel)
+
+	res, err := s.db.Execute(q)
+	if err != nil {
+		return err
+	}
+
+	currentTime := _time.Service.Now()
+	for _, item := range res.Items() {
+		key := Key{
+			Channel:  item.GetField("channel").Value.(string),
+			User:     item.GetField("user").Value.(string),
+			ThreadTS: item.GetField("thread_ts").Value.(string),
+		}
+
+		var conv Conversation
+		if err = json.Unmarshal([]byte(item.GetField("conversation").Value.(string)), &conv); err != nil {
+			log.Logger().AddError(err).Str("key", key.String()).Msg("Failed to parse stored conversation")
+			s.delete(key)
+			continue
+		}
+
+		//We restore only the conversations, which are still waiting for the answers or for their confirmation
+		if isExpired(conv, currentTime) || conv.EventReadyToBeExecuted || (conv.Scenario.GetUnAnsweredQuestion() == "" && !conv.AwaitingConfirmation) {
+			s.delete(key)
+			continue
+		}
+
+		s.memory.set(key, conv)
+	}
+
+	log.Logger().Debug().Int("conversations", len(s.memory.List())).Msg("Open conversations loaded")
+
+	return nil
+}
+
+// Add opens the new conversation and stores it in the database
+func (s *DatabaseStore) Add(scenario database.EventScenario, message dto.BaseChatMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.Add(scenario, message)
+	s.save(NewKey(message))
+}
+
+// Get retrieves the conversation for selected key
+func (s *DatabaseStore) Get(key Key) Conversation {
+	return s.memory.Get(key)
+}
+
+// SetLastQuestion sets the last question to the current conversation
+func (s *DatabaseStore) SetLastQuestion(message dto.BaseChatMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.SetLastQuestion(message)
+	s.save(NewKey(message))
+}
+
+// SetVariable sets the value of the scenario required variable by its index
+func (s *DatabaseStore) SetVariable(key Key, index int, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.SetVariable(key, index, value)
+	s.save(key)
+}
+
+// ResetVariable removes the value of the scenario required variable by its index
+func (s *DatabaseStore) ResetVariable(key Key, index int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.ResetVariable(key, index)
+	s.save(key)
+}
+
+// SetAwaitingConfirmation marks the conversation as waiting for the confirmation of the answers
+func (s *DatabaseStore) SetAwaitingConfirmation(key Key, awaiting bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.SetAwaitingConfirmation(key, awaiting)
+	s.save(key)
+}
+
+// MarkReady marks the conversation event as ready to be executed
+func (s *DatabaseStore) MarkReady(key Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.MarkReady(key)
+	s.save(key)
+}
+
+// Finalise removes the conversation for selected key
+func (s *DatabaseStore) Finalise(key Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.memory.Finalise(key)
+	s.delete(key)
+}
+
+// List retrieves all open conversations
+func (s *DatabaseStore) List() map[Key]Conversation {
+	return s.memory.List()
+}
+
+// Expire removes the expired conversations from the memory and from the database
+func (s *DatabaseStore) Expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	currentTime := _time.Service.Now()
+	for key, conv := range s.memory.List() {
+		if !isExpired(conv, currentTime) {
+			continue
+		}
+
+		s.memory.Finalise(key)
+		s.delete(key)
+	}
+}
+
+// save writes the current state of the conversation into the database
+func (s *DatabaseStore) save(key Key) {
+	conv, ok := s.memory.lookup(key)
+	if !ok {
+		return
+	}
+
+	//There is nothing to restore for the conversations without scenario
+	if conv.ScenarioID == 0 {
+		return
+	}
+
+	data, err := json.Marshal(conv)
+	if err != nil {
+		log.Logger().AddError(err).Str("key", key.String()).Msg("Failed to encode the conversation")
+		return
+	}
+
+	//The row is updated in place, so the stored conversation is not lost, when the write fails
+	exists, err := s.exists(key)
+	if err != nil {
+		log.Logger().AddError(err).Str("key", key.String()).Msg("Failed to check the stored conversation")
+		return
+	}
+
+	fields := []interface{}{
+		cdto.ModelField{
+			Name:  "conversation",
+			Value: string(data),
+		},
+		cdto.ModelField{
+			Name:  "updated",
+			Value: _time.Service.Now().Unix(),
+		},
+	}
+
+	var q clients.QueryInterface
+	if exists {
+		q = new(clients.Query).
+			Update(&cdto.BaseModel{TableName: databasedto.ConversationsModel.GetTableName(), Fields: fields})
+		for _, where := range keyConditions(key) {
+			q = q.Where(where)
+		}
+	} else {
+		q = new(clients.Query).Insert(&cdto.BaseModel{
+			TableName: databasedto.ConversationsModel.GetTableName(),
+			Fields: append([]interface{}{
+				cdto.ModelField{
+					Name:  "channel",
+					Value: key.Channel,
+				},
+				cdto.ModelField{
+					Name:  "user",
+					Value: key.User,
+				},
+				cdto.ModelField{
+					Name:  "thread_ts",
+					Value: key.ThreadTS,
+				},
+			}, fields...),
+		})
+	}
+
+	if _, err = s.db.Execute(q); err != nil {
+		log.Logger().AddError(err).Str("key", key.String()).Msg("Failed to store the conversation")
+	}
+}
+
+// exists checks if the conversation is already stored in the database
+func (s *DatabaseStore) exists(key Key) (bool, error) {
+	q := new(clients.Query).
+		Select([]interface{}{"id"}).
+		From(databasedto.ConversationsModel)
+	for _, where := range keyConditions(key) {
+		q = q.Where(where)
+	}
+
+	res, err := s.db.Execute(q)
+	if err != nil {
+		return false, err
+	}
+
+	return len(res.Items()) > 0, nil
+}
+
+// delete removes the conversation from the database
+func (s *DatabaseStore) delete(key Key) {
+	q := new(clients.Query).
+		Delete().
+		From(databasedto.ConversationsModel)
+	for _, where := range keyConditions(key) {
+		q = q.Where(where)
+	}
+
+	if _, err := s.db.Execute(q); err != nil {
+		log.Logger().AddError(err).Str("key", key.String()).Msg("Failed to delete the conversation")
+	}
+}
+
+// keyConditions retrieves the conditions, which select the row of the conversation by its key
+func keyConditions(key Key) []query.Where {
+	fields := []string{"channel", "user", "thread_ts"}
+	values := []string{key.Channel, key.User, key.ThreadTS}
+
+	var conditions []query.Where
+	for i, field := range fields {
+		conditions = append(conditions, database.WhereEquals(field, values[i]))
+	}
+
+	return conditions
+}
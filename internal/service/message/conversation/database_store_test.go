@@ -0,0 +1,229 @@
+package conversation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/devbot/test"
+	"github.com/sharovik/orm/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func TestDatabaseStore_Load(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.ConversationsModel)
+	now := _time.Service.Now()
+
+	store := NewDatabaseStore(db)
+	scenario := database.EventScenario{
+		ID: 1,
+		RequiredVariables: []database.ScenarioVariable{
+			{Question: "What I need to write?"},
+			{Question: "Where I need to post this message?"},
+		},
+	}
+
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel: "_test_channel_",
+		Text:    "What I need to write?",
+		Ts:      now,
+		DictionaryMessage: dto.DictionaryMessage{
+			ScenarioID:   1,
+			ReactionType: "examplescenario",
+		},
+	})
+	store.SetVariable(Key{Channel: "_test_channel_"}, 0, "Hello world")
+	store.SetLastQuestion(dto.BaseChatMessage{
+		Channel: "_test_channel_",
+		Text:    "Where I need to post this message?",
+		Ts:      now,
+		DictionaryMessage: dto.DictionaryMessage{
+			ScenarioID:   1,
+			ReactionType: "examplescenario",
+		},
+	})
+
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel:           "_test_channel_expired_",
+		Ts:                now.Add(-openConversationTimeout),
+		DictionaryMessage: dto.DictionaryMessage{ScenarioID: 1},
+	})
+
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel:           "_test_channel_ready_",
+		Ts:                now,
+		DictionaryMessage: dto.DictionaryMessage{ScenarioID: 1},
+	})
+	store.MarkReady(Key{Channel: "_test_channel_ready_"})
+
+	//The answered conversation, which waits for the confirmation, is restored
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel:           "_test_channel_confirmation_",
+		Ts:                now,
+		DictionaryMessage: dto.DictionaryMessage{ScenarioID: 1},
+	})
+	store.SetVariable(Key{Channel: "_test_channel_confirmation_"}, 0, "Hello world")
+	store.SetVariable(Key{Channel: "_test_channel_confirmation_"}, 1, "#general")
+	store.SetAwaitingConfirmation(Key{Channel: "_test_channel_confirmation_"}, true)
+
+	store.Add(database.EventScenario{}, dto.BaseChatMessage{
+		Channel: "_test_channel_without_scenario_",
+		Ts:      now,
+	})
+
+	//The new store simulates the application restart
+	restored := NewDatabaseStore(db)
+	assert.NoError(t, restored.Load())
+
+	list := restored.List()
+	assert.Len(t, list, 2)
+	assert.True(t, list[Key{Channel: "_test_channel_confirmation_"}].AwaitingConfirmation)
+
+	conv := restored.Get(Key{Channel: "_test_channel_"})
+	assert.Equal(t, int64(1), conv.ScenarioID)
+	assert.Equal(t, "examplescenario", conv.ReactionType)
+	assert.Equal(t, "Where I need to post this message?", conv.LastQuestion.Text)
+	assert.Equal(t, now.Unix(), conv.LastQuestion.Ts.Unix())
+	assert.Len(t, conv.Scenario.RequiredVariables, 2)
+	assert.Equal(t, "Hello world", conv.Scenario.RequiredVariables[0].Value)
+	assert.Equal(t, "", conv.Scenario.RequiredVariables[1].Value)
+
+	//Expired and answered conversations should be removed from the database during the load
+	res, err := db.Execute(new(clients.Query).Select(databasedto.ConversationsModel.GetColumns()).From(databasedto.ConversationsModel))
+	assert.NoError(t, err)
+	assert.Len(t, res.Items(), 2)
+}
+
+func TestDatabaseStore_Finalise(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.ConversationsModel)
+
+	store := NewDatabaseStore(db)
+	store.Add(database.EventScenario{
+		RequiredVariables: []database.ScenarioVariable{
+			{Question: "What I need to write?"},
+		},
+	}, dto.BaseChatMessage{
+		Channel:           "_test_channel_",
+		Ts:                _time.Service.Now(),
+		DictionaryMessage: dto.DictionaryMessage{ScenarioID: 1},
+	})
+	assert.NotEmpty(t, store.Get(Key{Channel: "_test_channel_"}))
+
+	store.Finalise(Key{Channel: "_test_channel_"})
+	assert.Empty(t, store.Get(Key{Channel: "_test_channel_"}))
+
+	restored := NewDatabaseStore(db)
+	assert.NoError(t, restored.Load())
+	assert.Empty(t, restored.List())
+}
+
+func TestDatabaseStore_Expire(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.ConversationsModel)
+	now := _time.Service.Now()
+
+	store := NewDatabaseStore(db)
+	for channel, ts := range map[string]time.Time{
+		"_test_channel_":  now.Add(-openConversationTimeout),
+		"_test_channel2_": now,
+	} {
+		store.Add(database.EventScenario{
+			RequiredVariables: []database.ScenarioVariable{
+				{Question: "What I need to write?"},
+			},
+		}, dto.BaseChatMessage{
+			Channel:           channel,
+			Ts:                ts,
+			DictionaryMessage: dto.DictionaryMessage{ScenarioID: 1},
+		})
+	}
+
+	store.Expire()
+	assert.Empty(t, store.Get(Key{Channel: "_test_channel_"}))
+	assert.NotEmpty(t, store.Get(Key{Channel: "_test_channel2_"}))
+
+	res, err := db.Execute(new(clients.Query).Select(databasedto.ConversationsModel.GetColumns()).From(databasedto.ConversationsModel))
+	assert.NoError(t, err)
+	assert.Len(t, res.Items(), 1)
+}
+
+func TestDatabaseStore_LoadWithoutTable(t *testing.T) {
+	db := test.InitDatabase(t)
+
+	S = NewMemoryStore()
+	InitS(db)
+
+	assert.IsType(t, &MemoryStore{}, S)
+}
+
+func TestDatabaseStore_LoadParallelConversations(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.ConversationsModel)
+
+	store := NewDatabaseStore(db)
+	for _, message := range []dto.BaseChatMessage{
+		{Channel: "_test_channel_", OriginalMessage: dto.BaseOriginalMessage{User: "U1"}},
+		{Channel: "_test_channel_", OriginalMessage: dto.BaseOriginalMessage{User: "U2"}},
+		{Channel: "_test_channel_", ThreadTS: "1.1", OriginalMessage: dto.BaseOriginalMessage{User: "U1"}},
+	} {
+		message.Ts = _time.Service.Now()
+		message.DictionaryMessage = dto.DictionaryMessage{ScenarioID: 1}
+		store.Add(database.EventScenario{
+			RequiredVariables: []database.ScenarioVariable{
+				{Question: "What I need to write?"},
+			},
+		}, message)
+	}
+
+	store.Finalise(Key{Channel: "_test_channel_", User: "U2"})
+
+	restored := NewDatabaseStore(db)
+	assert.NoError(t, restored.Load())
+
+	list := restored.List()
+	assert.Len(t, list, 2)
+	assert.NotEmpty(t, list[Key{Channel: "_test_channel_", User: "U1"}])
+	assert.NotEmpty(t, list[Key{Channel: "_test_channel_", User: "U1", ThreadTS: "1.1"}])
+}
+
+func TestDatabaseStore_SaveUpdatesRow(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.ConversationsModel)
+
+	store := NewDatabaseStore(db)
+	store.Add(database.EventScenario{
+		RequiredVariables: []database.ScenarioVariable{
+			{Question: "What I need to write?"},
+			{Question: "Where I need to post this message?"},
+		},
+	}, dto.BaseChatMessage{
+		Channel:           "_test_channel_",
+		Ts:                _time.Service.Now(),
+		DictionaryMessage: dto.DictionaryMessage{ScenarioID: 1},
+	})
+
+	res, err := db.Execute(new(clients.Query).Select([]interface{}{"id"}).From(databasedto.ConversationsModel))
+	assert.NoError(t, err)
+	assert.Len(t, res.Items(), 1)
+	id := res.Items()[0].GetField("id").Value
+
+	store.SetVariable(Key{Channel: "_test_channel_"}, 0, "Hello")
+	store.SetVariable(Key{Channel: "_test_channel_"}, 0, "Hello world")
+
+	//The conversation row is updated in place, so it keeps the same id
+	res, err = db.Execute(new(clients.Query).Select([]interface{}{"id"}).From(databasedto.ConversationsModel))
+	assert.NoError(t, err)
+	assert.Len(t, res.Items(), 1)
+	assert.Equal(t, id, res.Items()[0].GetField("id").Value)
+
+	restored := NewDatabaseStore(db)
+	assert.NoError(t, restored.Load())
+	conv := restored.Get(Key{Channel: "_test_channel_"})
+	assert.Equal(t, "Hello world", conv.Scenario.RequiredVariables[0].Value)
+	assert.Equal(t, "", conv.Scenario.RequiredVariables[1].Value)
+}
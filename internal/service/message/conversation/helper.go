@@ -1,15 +1,11 @@
 package conversation
 
 import (
-	"fmt"
-	"regexp"
-	"strings"
 	"time"
 
-	_time "github.com/sharovik/devbot/internal/service/time"
-
 	"github.com/sharovik/devbot/internal/database"
 	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/service/phrasebook"
 )
 
 // Conversation the conversation object which contains the information about the scenario selected for the conversation and the last question asked by the customer.
@@ -19,110 +15,57 @@ type Conversation struct {
 	ScenarioQuestionID     int64
 	Question               string
 	Channel                string
+	User                   string
+	ThreadTS               string
 	EventReadyToBeExecuted bool
 	LastQuestion           dto.BaseChatMessage
 	ReactionType           string
 	Scenario               database.EventScenario
-}
-
-// currentConversations contains the list of current open conversations, where each key is a channel ID and the value is the last channel question
-var currentConversations = map[string]Conversation{}
 
-const openConversationTimeout = time.Second * 600
-
-// GetCurrentConversations returns the list of current open conversations
-func GetCurrentConversations() map[string]Conversation {
-	return currentConversations
+	//AwaitingConfirmation all variables are answered and the user is asked to confirm them before the event execution
+	AwaitingConfirmation bool
 }
 
-// AddConversation add the new conversation to the list of open conversations. This will be used for scenarios build
-func AddConversation(scenario database.EventScenario, message dto.BaseChatMessage) {
-	conversation := Conversation{
-		ScenarioID:         message.DictionaryMessage.ScenarioID,
-		EventID:            message.DictionaryMessage.EventID,
-		Question:           message.DictionaryMessage.Question,
-		ScenarioQuestionID: message.DictionaryMessage.QuestionID,
-		LastQuestion:       message,
-		ReactionType:       message.DictionaryMessage.ReactionType,
-		Scenario:           scenario,
-	}
-
-	currentConversations[message.Channel] = conversation
-}
-
-// SetLastQuestion sets the last question to the current conversation
-func SetLastQuestion(message dto.BaseChatMessage) {
-	conv := GetConversation(message.Channel)
-	conv.LastQuestion = message
-
-	currentConversations[message.Channel] = conv
-}
+const openConversationTimeout = time.Second * 600
 
-// GetConversation method retrieve the conversation for selected channel
-func GetConversation(channel string) Conversation {
-	if conversation, ok := currentConversations[channel]; ok {
-		return conversation
+// Key retrieves the key of the conversation
+func (c Conversation) Key() Key {
+	return Key{
+		Channel:  c.Channel,
+		User:     c.User,
+		ThreadTS: c.ThreadTS,
 	}
-
-	return Conversation{}
 }
 
-// MarkAsReadyEventToBeExecuted method set the conversation event ready to be executed
-func MarkAsReadyEventToBeExecuted(channel string) {
-	conversation := currentConversations[channel]
-	conversation.EventReadyToBeExecuted = true
+// isExpired checks if the conversation last question was asked before the open conversation timeout
+func isExpired(conv Conversation, currentTime time.Time) bool {
+	elapsed := time.Duration(currentTime.Sub(conv.LastQuestion.Ts).Nanoseconds())
 
-	currentConversations[channel] = conversation
+	return elapsed >= openConversationTimeout
 }
 
-// CleanUpExpiredMessages removes the messages from the CleanUpExpiredMessages map object, which are expired
-func CleanUpExpiredMessages() {
-	currentTime := _time.Service.Now()
-
-	for channel, conversation := range GetCurrentConversations() {
-		elapsed := time.Duration(currentTime.Sub(conversation.LastQuestion.Ts).Nanoseconds())
-		if elapsed >= openConversationTimeout {
-			FinaliseConversation(channel)
-		}
+// clone returns the copy of the conversation, which does not share the scenario slices with the original one
+func (c Conversation) clone() Conversation {
+	if c.Scenario.Questions != nil {
+		c.Scenario.Questions = append([]database.Question(nil), c.Scenario.Questions...)
 	}
-}
 
-// FinaliseConversation method delete the conversation for selected channel
-func FinaliseConversation(channel string) {
-	if _, ok := currentConversations[channel]; !ok {
-		return
+	if c.Scenario.RequiredVariables != nil {
+		c.Scenario.RequiredVariables = append([]database.ScenarioVariable(nil), c.Scenario.RequiredVariables...)
 	}
 
-	delete(currentConversations, channel)
+	return c
 }
 
-// getStopScenarioWords method returns the stop words, which will be used for identification if we need to stop the scenario.
-func getStopScenarioWords() []string {
-	var stopPhrases []string
-
-	for _, text := range []string{
-		"stop!",
-		"stop scenario!",
-		"exit",
-		"stop",
-		"cancel",
-	} {
-		modifiedText := fmt.Sprintf("(%s)", text)
-		stopPhrases = append(stopPhrases, modifiedText)
-	}
-
-	return stopPhrases
+// IsScenarioStopTriggered method checks if the scenario stop action was triggered. The stop phrases are taken from the phrasebook of the conversation user.
+// By default the message should contain only the stop phrase, optionally with the bot mention, so the commands like "stop conversation #channel" are not treated as the stop action
+func IsScenarioStopTriggered(key Key, text string) bool {
+	return phrasebook.For(key.Channel, key.User).IsStop(text)
 }
 
-// IsScenarioStopTriggered method checks if the scenario stop action was triggered
-func IsScenarioStopTriggered(text string) bool {
-	regexStr := fmt.Sprintf("(?i)%s", strings.Join(getStopScenarioWords(), "|"))
-	regex, err := regexp.Compile(regexStr)
-	if err != nil {
-		return false
-	}
-
-	matches := regex.FindStringSubmatch(text)
-
-	return len(matches) != 0
+// ParseControlCommand parses the conversation control command. The commands are taken from the phrasebook of the conversation user.
+// Like the stop phrase, the message should contain only the command, optionally with the bot mention.
+// The number of the answer is retrieved for the change command. Empty command is returned, when the message is not the control command
+func ParseControlCommand(key Key, text string) (command string, number int) {
+	return phrasebook.For(key.Channel, key.User).Command(text)
 }
@@ -2,129 +2,51 @@ package conversation
 
 import (
 	"testing"
-	"time"
 
-	_time "github.com/sharovik/devbot/internal/service/time"
-
-	"github.com/sharovik/devbot/internal/database"
-
-	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/service/phrasebook"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestGetCurrentConversations(t *testing.T) {
-	currentConversations["_test_channel_"] = Conversation{
-		ScenarioID:         0,
-		ScenarioQuestionID: 0,
-		LastQuestion: dto.BaseChatMessage{
-			Channel:           "_test_channel_",
-			Text:              "Testing",
-			AsUser:            false,
-			Ts:                time.Time{},
-			DictionaryMessage: dto.DictionaryMessage{},
-			OriginalMessage:   dto.BaseOriginalMessage{},
-		},
+func TestIsScenarioStopTriggered(t *testing.T) {
+	for _, text := range []string{
+		"stop",
+		"Stop!",
+		" cancel ",
+		"exit",
+		"stop scenario!",
+		"<@U0LAN0Z89> stop",
+	} {
+		assert.True(t, IsScenarioStopTriggered(Key{Channel: "C0LAN2Q65"}, text), text)
 	}
 
-	currentConversations["_test_channel2_"] = Conversation{
-		ScenarioID:         0,
-		ScenarioQuestionID: 0,
-		LastQuestion: dto.BaseChatMessage{
-			Channel:           "_test_channel2_",
-			Text:              "Testing",
-			AsUser:            false,
-			Ts:                time.Time{},
-			DictionaryMessage: dto.DictionaryMessage{},
-			OriginalMessage:   dto.BaseOriginalMessage{},
-		},
+	for _, text := range []string{
+		"stop conversation <#C0LAN2Q65>",
+		"what is next?",
+		"the release was cancelled",
+		"exit code 1",
+		"",
+	} {
+		assert.False(t, IsScenarioStopTriggered(Key{Channel: "C0LAN2Q65"}, text), text)
 	}
-
-	list := GetCurrentConversations()
-	assert.NotEmpty(t, list)
-	assert.NotEmpty(t, list["_test_channel_"])
-	assert.NotEmpty(t, list["_test_channel2_"])
-}
-
-func TestAddConversation(t *testing.T) {
-	scenario := database.EventScenario{}
-	AddConversation(scenario, dto.BaseChatMessage{
-		Channel:           "_test_channel_",
-		Text:              "Testing",
-		AsUser:            false,
-		Ts:                time.Time{},
-		DictionaryMessage: dto.DictionaryMessage{},
-		OriginalMessage:   dto.BaseOriginalMessage{},
-	})
-
-	list := GetCurrentConversations()
-	assert.NotEmpty(t, list)
-	assert.NotEmpty(t, list["_test_channel_"])
-}
-
-func TestCleanUpExpiredMessages(t *testing.T) {
-	now := _time.Service.Now()
-	scenario := database.EventScenario{}
-
-	AddConversation(scenario, dto.BaseChatMessage{
-		Channel:           "_test_channel_",
-		Text:              "Testing",
-		AsUser:            false,
-		Ts:                now.Add(-time.Second * 600),
-		DictionaryMessage: dto.DictionaryMessage{},
-		OriginalMessage:   dto.BaseOriginalMessage{},
-	})
-
-	AddConversation(scenario, dto.BaseChatMessage{
-		Channel:           "_test_channel2_",
-		Text:              "Testing",
-		AsUser:            false,
-		Ts:                now.Add(time.Second * 600),
-		DictionaryMessage: dto.DictionaryMessage{},
-		OriginalMessage:   dto.BaseOriginalMessage{},
-	})
-
-	CleanUpExpiredMessages()
-
-	assert.NotEmpty(t, currentConversations)
-	assert.Equal(t, 1, len(currentConversations))
-	assert.NotEmpty(t, currentConversations["_test_channel2_"])
-}
-
-func TestGetConversation(t *testing.T) {
-	now := _time.Service.Now()
-	currentConversations = map[string]Conversation{}
-	scenario := database.EventScenario{}
-
-	AddConversation(scenario, dto.BaseChatMessage{
-		Channel:           "_test_channel_",
-		Text:              "Testing",
-		AsUser:            false,
-		Ts:                now.Add(time.Second * 600),
-		DictionaryMessage: dto.DictionaryMessage{},
-		OriginalMessage:   dto.BaseOriginalMessage{},
-	})
-
-	conversation := GetConversation("_test_channel_")
-
-	assert.NotEmpty(t, conversation)
-	assert.Equal(t, "Testing", conversation.LastQuestion.Text)
-
-	conversation = GetConversation("_test_channel2_")
-	assert.Empty(t, conversation)
 }
 
-func TestDeleteConversation(t *testing.T) {
-	scenario := database.EventScenario{}
-	AddConversation(scenario, dto.BaseChatMessage{
-		Channel:           "_test_channel_",
-		Text:              "Testing",
-		AsUser:            false,
-		Ts:                time.Time{},
-		DictionaryMessage: dto.DictionaryMessage{},
-		OriginalMessage:   dto.BaseOriginalMessage{},
-	})
-
-	assert.NotEmpty(t, currentConversations["_test_channel_"])
-	FinaliseConversation("_test_channel_")
-	assert.Empty(t, currentConversations["_test_channel_"])
+func TestParseControlCommand(t *testing.T) {
+	for text, expected := range map[string]struct {
+		command string
+		number  int
+	}{
+		"back":                {phrasebook.CommandBack, 0},
+		" Review ":            {phrasebook.CommandReview, 0},
+		"<@U0LAN0Z89> back":   {phrasebook.CommandBack, 0},
+		"change 2":            {phrasebook.CommandChange, 2},
+		"CHANGE  10":          {phrasebook.CommandChange, 10},
+		"change":              {"", 0},
+		"change the branch":   {"", 0},
+		"go back to the list": {"", 0},
+		"":                    {"", 0},
+	} {
+		command, number := ParseControlCommand(Key{Channel: "C0LAN2Q65"}, text)
+		assert.Equal(t, expected.command, command, text)
+		assert.Equal(t, expected.number, number, text)
+	}
 }
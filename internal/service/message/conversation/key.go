@@ -0,0 +1,34 @@
+package conversation
+
+import (
+	"fmt"
+
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+// Key the identity of the conversation. Conversations are opened per user in the channel and per thread,
+// so the parallel scenarios in one channel or in different threads do not affect each other
+type Key struct {
+	Channel  string
+	User     string
+	ThreadTS string
+}
+
+// NewKey generates the conversation key for selected message
+func NewKey(message dto.BaseChatMessage) Key {
+	threadTS := message.ThreadTS
+	if threadTS == "" {
+		threadTS = message.OriginalMessage.ThreadTS
+	}
+
+	return Key{
+		Channel:  message.Channel,
+		User:     message.OriginalMessage.User,
+		ThreadTS: threadTS,
+	}
+}
+
+// String retrieves the string representation of the key
+func (k Key) String() string {
+	return fmt.Sprintf("%s:%s:%s", k.Channel, k.User, k.ThreadTS)
+}
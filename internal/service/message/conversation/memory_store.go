@@ -0,0 +1,185 @@
+package conversation
+
+import (
+	"sync"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	_time "github.com/sharovik/devbot/internal/service/time"
+)
+
+// MemoryStore the in-memory conversations store protected by mutex
+type MemoryStore struct {
+	mu            sync.RWMutex
+	conversations map[Key]Conversation
+}
+
+// NewMemoryStore creates the new empty in-memory conversations store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		conversations: map[Key]Conversation{},
+	}
+}
+
+// Add add the new conversation to the list of open conversations. This will be used for scenarios build
+func (s *MemoryStore) Add(scenario database.EventScenario, message dto.BaseChatMessage) {
+	key := NewKey(message)
+	conversation := Conversation{
+		ScenarioID:         message.DictionaryMessage.ScenarioID,
+		EventID:            message.DictionaryMessage.EventID,
+		Question:           message.DictionaryMessage.Question,
+		ScenarioQuestionID: message.DictionaryMessage.QuestionID,
+		Channel:            key.Channel,
+		User:               key.User,
+		ThreadTS:           key.ThreadTS,
+		LastQuestion:       message,
+		ReactionType:       message.DictionaryMessage.ReactionType,
+		Scenario:           scenario,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conversations[key] = conversation.clone()
+}
+
+// Get method retrieve the conversation for selected key
+func (s *MemoryStore) Get(key Key) Conversation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if conversation, ok := s.conversations[key]; ok {
+		return conversation.clone()
+	}
+
+	return Conversation{}
+}
+
+// SetLastQuestion sets the last question to the current conversation
+func (s *MemoryStore) SetLastQuestion(message dto.BaseChatMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := NewKey(message)
+	conv, ok := s.conversations[key]
+	if !ok {
+		conv.Channel = key.Channel
+		conv.User = key.User
+		conv.ThreadTS = key.ThreadTS
+	}
+
+	conv.LastQuestion = message
+
+	s.conversations[key] = conv
+}
+
+// SetVariable sets the value of the scenario required variable by its index
+func (s *MemoryStore) SetVariable(key Key, index int, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.conversations[key]
+	if !ok || index < 0 || index >= len(conv.Scenario.RequiredVariables) {
+		return
+	}
+
+	conv.Scenario.RequiredVariables[index].Value = value
+	conv.Scenario.RequiredVariables[index].Answered = true
+}
+
+// ResetVariable removes the value of the scenario required variable by its index
+func (s *MemoryStore) ResetVariable(key Key, index int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.conversations[key]
+	if !ok || index < 0 || index >= len(conv.Scenario.RequiredVariables) {
+		return
+	}
+
+	conv.Scenario.RequiredVariables[index].Value = ""
+	conv.Scenario.RequiredVariables[index].Answered = false
+}
+
+// SetAwaitingConfirmation marks the conversation as waiting for the confirmation of the answers
+func (s *MemoryStore) SetAwaitingConfirmation(key Key, awaiting bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.conversations[key]
+	if !ok {
+		return
+	}
+
+	conv.AwaitingConfirmation = awaiting
+
+	s.conversations[key] = conv
+}
+
+// MarkReady method set the conversation event ready to be executed
+func (s *MemoryStore) MarkReady(key Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.conversations[key]
+	if !ok {
+		return
+	}
+
+	conv.EventReadyToBeExecuted = true
+
+	s.conversations[key] = conv
+}
+
+// Finalise method delete the conversation for selected key
+func (s *MemoryStore) Finalise(key Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conversations, key)
+}
+
+// List returns the list of current open conversations
+func (s *MemoryStore) List() map[Key]Conversation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[Key]Conversation, len(s.conversations))
+	for key, conversation := range s.conversations {
+		result[key] = conversation.clone()
+	}
+
+	return result
+}
+
+// Expire removes the conversations, which are expired
+func (s *MemoryStore) Expire() {
+	currentTime := _time.Service.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, conversation := range s.conversations {
+		if isExpired(conversation, currentTime) {
+			delete(s.conversations, key)
+		}
+	}
+}
+
+// lookup retrieves the copy of the conversation and the flag, which shows if the conversation exists
+func (s *MemoryStore) lookup(key Key) (Conversation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conversation, ok := s.conversations[key]
+
+	return conversation.clone(), ok
+}
+
+// set puts the conversation for selected key
+func (s *MemoryStore) set(key Key, conversation Conversation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conversations[key] = conversation.clone()
+}
@@ -0,0 +1,320 @@
+package conversation
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	_time "github.com/sharovik/devbot/internal/service/time"
+
+	"github.com/sharovik/devbot/internal/database"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_List(t *testing.T) {
+	store := NewMemoryStore()
+	store.conversations[Key{Channel: "_test_channel_"}] = Conversation{
+		ScenarioID:         0,
+		ScenarioQuestionID: 0,
+		LastQuestion: dto.BaseChatMessage{
+			Channel:           "_test_channel_",
+			Text:              "Testing",
+			AsUser:            false,
+			Ts:                time.Time{},
+			DictionaryMessage: dto.DictionaryMessage{},
+			OriginalMessage:   dto.BaseOriginalMessage{},
+		},
+	}
+
+	store.conversations[Key{Channel: "_test_channel2_"}] = Conversation{
+		ScenarioID:         0,
+		ScenarioQuestionID: 0,
+		LastQuestion: dto.BaseChatMessage{
+			Channel:           "_test_channel2_",
+			Text:              "Testing",
+			AsUser:            false,
+			Ts:                time.Time{},
+			DictionaryMessage: dto.DictionaryMessage{},
+			OriginalMessage:   dto.BaseOriginalMessage{},
+		},
+	}
+
+	list := store.List()
+	assert.NotEmpty(t, list)
+	assert.NotEmpty(t, list[Key{Channel: "_test_channel_"}])
+	assert.NotEmpty(t, list[Key{Channel: "_test_channel2_"}])
+}
+
+func TestMemoryStore_Add(t *testing.T) {
+	store := NewMemoryStore()
+	scenario := database.EventScenario{}
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel:           "_test_channel_",
+		Text:              "Testing",
+		AsUser:            false,
+		Ts:                time.Time{},
+		DictionaryMessage: dto.DictionaryMessage{},
+		OriginalMessage:   dto.BaseOriginalMessage{},
+	})
+
+	list := store.List()
+	assert.NotEmpty(t, list)
+	assert.NotEmpty(t, list[Key{Channel: "_test_channel_"}])
+}
+
+func TestMemoryStore_Expire(t *testing.T) {
+	store := NewMemoryStore()
+	now := _time.Service.Now()
+	scenario := database.EventScenario{}
+
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel:           "_test_channel_",
+		Text:              "Testing",
+		AsUser:            false,
+		Ts:                now.Add(-time.Second * 600),
+		DictionaryMessage: dto.DictionaryMessage{},
+		OriginalMessage:   dto.BaseOriginalMessage{},
+	})
+
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel:           "_test_channel2_",
+		Text:              "Testing",
+		AsUser:            false,
+		Ts:                now.Add(time.Second * 600),
+		DictionaryMessage: dto.DictionaryMessage{},
+		OriginalMessage:   dto.BaseOriginalMessage{},
+	})
+
+	store.Expire()
+
+	assert.NotEmpty(t, store.conversations)
+	assert.Equal(t, 1, len(store.conversations))
+	assert.NotEmpty(t, store.conversations[Key{Channel: "_test_channel2_"}])
+}
+
+func TestMemoryStore_Get(t *testing.T) {
+	store := NewMemoryStore()
+	now := _time.Service.Now()
+	scenario := database.EventScenario{}
+
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel:           "_test_channel_",
+		Text:              "Testing",
+		AsUser:            false,
+		Ts:                now.Add(time.Second * 600),
+		DictionaryMessage: dto.DictionaryMessage{},
+		OriginalMessage:   dto.BaseOriginalMessage{},
+	})
+
+	conversation := store.Get(Key{Channel: "_test_channel_"})
+
+	assert.NotEmpty(t, conversation)
+	assert.Equal(t, "Testing", conversation.LastQuestion.Text)
+
+	conversation = store.Get(Key{Channel: "_test_channel2_"})
+	assert.Empty(t, conversation)
+}
+
+func TestMemoryStore_Finalise(t *testing.T) {
+	store := NewMemoryStore()
+	scenario := database.EventScenario{}
+	store.Add(scenario, dto.BaseChatMessage{
+		Channel:           "_test_channel_",
+		Text:              "Testing",
+		AsUser:            false,
+		Ts:                time.Time{},
+		DictionaryMessage: dto.DictionaryMessage{},
+		OriginalMessage:   dto.BaseOriginalMessage{},
+	})
+
+	assert.NotEmpty(t, store.conversations[Key{Channel: "_test_channel_"}])
+	store.Finalise(Key{Channel: "_test_channel_"})
+	assert.Empty(t, store.conversations[Key{Channel: "_test_channel_"}])
+}
+
+func TestMemoryStore_SetVariable(t *testing.T) {
+	store := NewMemoryStore()
+	store.Add(database.EventScenario{
+		RequiredVariables: []database.ScenarioVariable{
+			{Question: "First question?"},
+			{Question: "Second question?"},
+		},
+	}, dto.BaseChatMessage{
+		Channel: "_test_channel_",
+		Ts:      _time.Service.Now(),
+	})
+
+	conv := store.Get(Key{Channel: "_test_channel_"})
+	conv.Scenario.RequiredVariables[0].Value = "not stored"
+	assert.Equal(t, "", store.Get(Key{Channel: "_test_channel_"}).Scenario.RequiredVariables[0].Value)
+
+	store.SetVariable(Key{Channel: "_test_channel_"}, 1, "Answer")
+	store.SetVariable(Key{Channel: "_test_channel_"}, 5, "Out of range")
+	store.SetVariable(Key{Channel: "_test_channel2_"}, 0, "Unknown channel")
+
+	conv = store.Get(Key{Channel: "_test_channel_"})
+	assert.Equal(t, "", conv.Scenario.RequiredVariables[0].Value)
+	assert.Equal(t, "Answer", conv.Scenario.RequiredVariables[1].Value)
+	assert.False(t, conv.Scenario.RequiredVariables[0].IsAnswered())
+	assert.True(t, conv.Scenario.RequiredVariables[1].IsAnswered())
+	assert.Empty(t, store.Get(Key{Channel: "_test_channel2_"}))
+
+	//The skipped optional variable is answered with the empty value
+	store.SetVariable(Key{Channel: "_test_channel_"}, 0, "")
+	conv = store.Get(Key{Channel: "_test_channel_"})
+	assert.Equal(t, "", conv.Scenario.GetUnAnsweredQuestion())
+}
+
+func TestMemoryStore_ResetVariable(t *testing.T) {
+	store := NewMemoryStore()
+	store.Add(database.EventScenario{
+		RequiredVariables: []database.ScenarioVariable{
+			{Question: "First question?"},
+			{Question: "Second question?"},
+		},
+	}, dto.BaseChatMessage{
+		Channel: "_test_channel_",
+		Ts:      _time.Service.Now(),
+	})
+
+	key := Key{Channel: "_test_channel_"}
+	store.SetVariable(key, 0, "")
+	store.SetVariable(key, 1, "Answer")
+	store.SetAwaitingConfirmation(key, true)
+	assert.True(t, store.Get(key).AwaitingConfirmation)
+
+	store.ResetVariable(key, 0)
+	store.ResetVariable(key, 5)
+	store.SetAwaitingConfirmation(key, false)
+
+	conv := store.Get(key)
+	assert.False(t, conv.AwaitingConfirmation)
+	assert.Equal(t, "First question?", conv.Scenario.GetUnAnsweredQuestion())
+	assert.Equal(t, "Answer", conv.Scenario.RequiredVariables[1].Value)
+}
+
+func TestMemoryStore_MarkReady(t *testing.T) {
+	store := NewMemoryStore()
+	store.Add(database.EventScenario{}, dto.BaseChatMessage{
+		Channel: "_test_channel_",
+		Ts:      _time.Service.Now(),
+	})
+
+	store.MarkReady(Key{Channel: "_test_channel_"})
+	store.MarkReady(Key{Channel: "_test_channel2_"})
+
+	assert.True(t, store.Get(Key{Channel: "_test_channel_"}).EventReadyToBeExecuted)
+	assert.Empty(t, store.Get(Key{Channel: "_test_channel2_"}))
+}
+
+func TestMemoryStore_Concurrency(t *testing.T) {
+	var (
+		store    = NewMemoryStore()
+		wg       sync.WaitGroup
+		channels = []string{"_test_channel_", "_test_channel2_", "_test_channel3_"}
+	)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			channel := channels[i%len(channels)]
+			for j := 0; j < 100; j++ {
+				store.Add(database.EventScenario{
+					RequiredVariables: []database.ScenarioVariable{
+						{Question: "First question?"},
+						{Question: "Second question?"},
+					},
+				}, dto.BaseChatMessage{
+					Channel: channel,
+					Text:    fmt.Sprintf("Message %d", j),
+					Ts:      _time.Service.Now(),
+				})
+				store.SetVariable(Key{Channel: channel}, j%2, "Answer")
+				store.SetLastQuestion(dto.BaseChatMessage{
+					Channel: channel,
+					Text:    "Second question?",
+					Ts:      _time.Service.Now(),
+				})
+				store.MarkReady(Key{Channel: channel})
+
+				conv := store.Get(Key{Channel: channel})
+				for k := range conv.Scenario.RequiredVariables {
+					conv.Scenario.RequiredVariables[k].Value = "local copy"
+				}
+
+				for _, c := range store.List() {
+					_ = c.Scenario.GetUnAnsweredQuestion()
+				}
+
+				store.Expire()
+				if j%10 == 0 {
+					store.Finalise(Key{Channel: channel})
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, conv := range store.List() {
+		for _, variable := range conv.Scenario.RequiredVariables {
+			assert.NotEqual(t, "local copy", variable.Value)
+		}
+	}
+}
+
+func TestMemoryStore_ParallelConversations(t *testing.T) {
+	store := NewMemoryStore()
+	scenario := database.EventScenario{
+		RequiredVariables: []database.ScenarioVariable{
+			{Question: "First question?"},
+		},
+	}
+
+	for _, message := range []dto.BaseChatMessage{
+		{Channel: "_test_channel_", Text: "Alice", OriginalMessage: dto.BaseOriginalMessage{User: "U1"}},
+		{Channel: "_test_channel_", Text: "Bob", OriginalMessage: dto.BaseOriginalMessage{User: "U2"}},
+		{Channel: "_test_channel_", Text: "Alice thread", ThreadTS: "1.1", OriginalMessage: dto.BaseOriginalMessage{User: "U1"}},
+	} {
+		message.Ts = _time.Service.Now()
+		store.Add(scenario, message)
+	}
+
+	assert.Len(t, store.List(), 3)
+
+	store.SetVariable(Key{Channel: "_test_channel_", User: "U1"}, 0, "Alice answer")
+	store.Finalise(Key{Channel: "_test_channel_", User: "U2"})
+
+	conv := store.Get(Key{Channel: "_test_channel_", User: "U1"})
+	assert.Equal(t, "Alice", conv.LastQuestion.Text)
+	assert.Equal(t, "U1", conv.User)
+	assert.Equal(t, "Alice answer", conv.Scenario.RequiredVariables[0].Value)
+
+	conv = store.Get(Key{Channel: "_test_channel_", User: "U1", ThreadTS: "1.1"})
+	assert.Equal(t, "Alice thread", conv.LastQuestion.Text)
+	assert.Equal(t, "1.1", conv.ThreadTS)
+	assert.Equal(t, "", conv.Scenario.RequiredVariables[0].Value)
+
+	assert.Empty(t, store.Get(Key{Channel: "_test_channel_", User: "U2"}))
+}
+
+func TestNewKey(t *testing.T) {
+	assert.Equal(t, Key{Channel: "C1", User: "U1", ThreadTS: "1.1"}, NewKey(dto.BaseChatMessage{
+		Channel:         "C1",
+		ThreadTS:        "1.1",
+		OriginalMessage: dto.BaseOriginalMessage{User: "U1", ThreadTS: "2.2"},
+	}))
+
+	assert.Equal(t, Key{Channel: "C1", User: "U1", ThreadTS: "2.2"}, NewKey(dto.BaseChatMessage{
+		Channel:         "C1",
+		OriginalMessage: dto.BaseOriginalMessage{User: "U1", ThreadTS: "2.2"},
+	}))
+
+	assert.Equal(t, Key{Channel: "C1"}, NewKey(dto.BaseChatMessage{Channel: "C1"}))
+	assert.Equal(t, "C1:U1:1.1", Key{Channel: "C1", User: "U1", ThreadTS: "1.1"}.String())
+}
@@ -0,0 +1,44 @@
+package conversation
+
+import (
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+)
+
+// ConversationStore the interface for the storage of open conversations.
+// Implementations must be safe for the concurrent usage, because the conversations are accessed from the websocket loop,
+// the events execution goroutines and the schedule service at the same time.
+type ConversationStore interface {
+	//Add opens the new conversation for the selected scenario. The key of conversation is generated from the message by NewKey. The existing conversation with the same key will be replaced
+	Add(scenario database.EventScenario, message dto.BaseChatMessage)
+
+	//Get retrieves the copy of the conversation for selected key. Empty Conversation will be returned if there is no open conversation
+	Get(key Key) Conversation
+
+	//SetLastQuestion sets the last question to the current conversation
+	SetLastQuestion(message dto.BaseChatMessage)
+
+	//SetVariable sets the value of the scenario required variable by its index and marks the variable as answered
+	SetVariable(key Key, index int, value string)
+
+	//ResetVariable removes the value of the scenario required variable by its index, so the variable will be asked again
+	ResetVariable(key Key, index int)
+
+	//SetAwaitingConfirmation marks the conversation as waiting for the confirmation of the answers
+	SetAwaitingConfirmation(key Key, awaiting bool)
+
+	//MarkReady marks the conversation event as ready to be executed
+	MarkReady(key Key)
+
+	//Finalise removes the conversation for selected key
+	Finalise(key Key)
+
+	//List retrieves the copy of all open conversations
+	List() map[Key]Conversation
+
+	//Expire removes the conversations, which are expired
+	Expire()
+}
+
+// S the conversations store, which is used by the application
+var S ConversationStore = NewMemoryStore()
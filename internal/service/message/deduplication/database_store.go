@@ -0,0 +1,153 @@
+package deduplication
+
+import (
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+// DatabaseStore the deduplication store, which keeps the processed messages identifiers in the database, so the redelivered messages are dropped after the application restart too.
+// The identifiers are cached in the memory
+type DatabaseStore struct {
+	db     clients.BaseClientInterface
+	ttl    time.Duration
+	memory *MemoryStore
+}
+
+// NewDatabaseStore creates the new database deduplication store for selected database client
+func NewDatabaseStore(db clients.BaseClientInterface, ttl time.Duration, limit int) *DatabaseStore {
+	return &DatabaseStore{
+		db:     db,
+		ttl:    ttl,
+		memory: NewMemoryStore(ttl, limit),
+	}
+}
+
+// InitS switches the deduplication store to the selected TTL. If persisted is true, the database store will be used
+func InitS(db clients.BaseClientInterface, ttl time.Duration, persisted bool) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	if !persisted {
+		S = NewMemoryStore(ttl, DefaultLimit)
+		return
+	}
+
+	S = NewDatabaseStore(db, ttl, DefaultLimit)
+}
+
+// IsDuplicate checks if any of the keys was already processed during the TTL. If not, all keys are remembered as processed in the memory and in the database.
+// If the database is not available, only the memory will be used
+func (s *DatabaseStore) IsDuplicate(keys ...string) bool {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	currentTime := _time.Service.Now()
+	if s.memory.contains(currentTime, keys...) {
+		return true
+	}
+
+	threshold := currentTime.Add(-s.ttl).Unix()
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		processedAt, ok := s.find(key, threshold)
+		if !ok {
+			continue
+		}
+
+		s.memory.add(processedAt, keys...)
+
+		return true
+	}
+
+	s.memory.add(currentTime, keys...)
+	s.save(currentTime, threshold, keys...)
+
+	return false
+}
+
+// find retrieves the time, when selected key was processed after the threshold
+func (s *DatabaseStore) find(key string, threshold int64) (time.Time, bool) {
+	q := new(clients.Query).
+		Select(databasedto.ProcessedEventsModel.GetColumns()).
+		From(databasedto.ProcessedEventsModel).
+		Where(query.Where{
+			First:    "event_key",
+			Operator: "=",
+			Second: query.Bind{
+				Field: "event_key",
+				Value: key,
+			},
+		}).
+		Where(query.Where{
+			First:    "created",
+			Operator: ">=",
+			Second: query.Bind{
+				Field: "created",
+				Value: threshold,
+			},
+		})
+
+	res, err := s.db.Execute(q)
+	if err != nil {
+		log.Logger().AddError(err).Str("key", key).Msg("Failed to find the processed event")
+		return time.Time{}, false
+	}
+
+	if len(res.Items()) == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(res.Items()[0].GetField("created").Value.(int)), 0), true
+}
+
+// save removes the expired keys from the database and stores the new ones
+func (s *DatabaseStore) save(processedAt time.Time, threshold int64, keys ...string) {
+	q := new(clients.Query).
+		Delete().
+		From(databasedto.ProcessedEventsModel).
+		Where(query.Where{
+			First:    "created",
+			Operator: "<",
+			Second: query.Bind{
+				Field: "created",
+				Value: threshold,
+			},
+		})
+	if _, err := s.db.Execute(q); err != nil {
+		log.Logger().AddError(err).Msg("Failed to remove the expired processed events")
+	}
+
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		model := &cdto.BaseModel{
+			TableName: databasedto.ProcessedEventsModel.GetTableName(),
+			Fields: []interface{}{
+				cdto.ModelField{
+					Name:  "event_key",
+					Value: key,
+				},
+				cdto.ModelField{
+					Name:  "created",
+					Value: processedAt.Unix(),
+				},
+			},
+		}
+
+		if _, err := s.db.Execute(new(clients.Query).Insert(model)); err != nil {
+			log.Logger().AddError(err).Str("key", key).Msg("Failed to store the processed event")
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package deduplication
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/devbot/test"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func countProcessedEvents(t *testing.T, db clients.BaseClientInterface) int {
+	res, err := db.Execute(new(clients.Query).Select(databasedto.ProcessedEventsModel.GetColumns()).From(databasedto.ProcessedEventsModel))
+	assert.NoError(t, err)
+
+	return len(res.Items())
+}
+
+func TestDatabaseStore_IsDuplicate(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.ProcessedEventsModel)
+
+	store := NewDatabaseStore(db, time.Hour, DefaultLimit)
+	assert.False(t, store.IsDuplicate("envelope:1", "event:1", ""))
+	assert.True(t, store.IsDuplicate("envelope:2", "event:1"))
+	assert.Equal(t, 2, countProcessedEvents(t, db))
+
+	//The new store simulates the application restart
+	restored := NewDatabaseStore(db, time.Hour, DefaultLimit)
+	assert.True(t, restored.IsDuplicate("envelope:3", "event:1"))
+	assert.True(t, restored.IsDuplicate("envelope:3"))
+	assert.False(t, restored.IsDuplicate("envelope:4", "event:4"))
+	assert.Equal(t, 4, countProcessedEvents(t, db))
+}
+
+func TestDatabaseStore_Expired(t *testing.T) {
+	db := test.InitDatabase(t, databasedto.ProcessedEventsModel)
+
+	_, err := db.Execute(new(clients.Query).Insert(&cdto.BaseModel{
+		TableName: databasedto.ProcessedEventsModel.GetTableName(),
+		Fields: []interface{}{
+			cdto.ModelField{Name: "event_key", Value: "event:1"},
+			cdto.ModelField{Name: "created", Value: _time.Service.Now().Add(-time.Hour * 2).Unix()},
+		},
+	}))
+	assert.NoError(t, err)
+
+	store := NewDatabaseStore(db, time.Hour, DefaultLimit)
+	assert.False(t, store.IsDuplicate("event:1"))
+	assert.True(t, store.IsDuplicate("event:1"))
+
+	//The expired row was replaced by the new one
+	assert.Equal(t, 1, countProcessedEvents(t, db))
+}
+
+func TestDatabaseStore_WithoutTable(t *testing.T) {
+	db := test.InitDatabase(t)
+
+	//The memory cache is still used, if the database is not available
+	store := NewDatabaseStore(db, time.Hour, DefaultLimit)
+	assert.False(t, store.IsDuplicate("event:1"))
+	assert.True(t, store.IsDuplicate("event:1"))
+}
+
+func TestInitS(t *testing.T) {
+	InitS(nil, 0, false)
+	assert.IsType(t, &MemoryStore{}, S)
+	assert.Equal(t, DefaultTTL, S.(*MemoryStore).ttl)
+
+	db := test.InitDatabase(t, databasedto.ProcessedEventsModel)
+	InitS(db, time.Minute, true)
+	assert.IsType(t, &DatabaseStore{}, S)
+	assert.Equal(t, time.Minute, S.(*DatabaseStore).ttl)
+
+	S = NewMemoryStore(DefaultTTL, DefaultLimit)
+}
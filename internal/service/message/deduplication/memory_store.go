@@ -0,0 +1,95 @@
+package deduplication
+
+import (
+	"sync"
+	"time"
+
+	_time "github.com/sharovik/devbot/internal/service/time"
+)
+
+// MemoryStore the in-memory deduplication store. The identifiers are removed after the TTL or, once the limit is reached, starting from the oldest one
+type MemoryStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	limit int
+	keys  map[string]time.Time
+
+	//order the keys in the order of their adding. Because all keys have the same TTL, the first key is always expired first
+	order []string
+}
+
+// NewMemoryStore creates the new in-memory deduplication store with selected TTL and limit of remembered keys
+func NewMemoryStore(ttl time.Duration, limit int) *MemoryStore {
+	return &MemoryStore{
+		ttl:   ttl,
+		limit: limit,
+		keys:  map[string]time.Time{},
+	}
+}
+
+// IsDuplicate checks if any of the keys was already processed during the TTL. If not, all keys are remembered as processed
+func (s *MemoryStore) IsDuplicate(keys ...string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	currentTime := _time.Service.Now()
+	if s.contains(currentTime, keys...) {
+		return true
+	}
+
+	s.add(currentTime, keys...)
+
+	return false
+}
+
+// contains checks if any of the keys was remembered during the TTL
+func (s *MemoryStore) contains(currentTime time.Time, keys ...string) bool {
+	s.prune(currentTime)
+
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		if processedAt, ok := s.keys[key]; ok && currentTime.Sub(processedAt) < s.ttl {
+			return true
+		}
+	}
+
+	return false
+}
+
+// add remembers the keys as processed at selected time
+func (s *MemoryStore) add(processedAt time.Time, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		if _, ok := s.keys[key]; ok {
+			continue
+		}
+
+		s.keys[key] = processedAt
+		s.order = append(s.order, key)
+	}
+
+	s.prune(processedAt)
+}
+
+// prune removes the expired keys and the oldest keys over the limit
+func (s *MemoryStore) prune(currentTime time.Time) {
+	removed := 0
+	for _, key := range s.order {
+		if len(s.keys) <= s.limit && currentTime.Sub(s.keys[key]) < s.ttl {
+			break
+		}
+
+		delete(s.keys, key)
+		removed++
+	}
+
+	if removed > 0 {
+		s.order = append([]string{}, s.order[removed:]...)
+	}
+}
@@ -0,0 +1,78 @@
+package deduplication
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_IsDuplicate(t *testing.T) {
+	store := NewMemoryStore(time.Hour, DefaultLimit)
+
+	assert.False(t, store.IsDuplicate("envelope:1", "event:1", "message:1"))
+
+	//The redelivery has the new envelope, but the same event
+	assert.True(t, store.IsDuplicate("envelope:2", "event:1", "message:1"))
+	assert.True(t, store.IsDuplicate("", "", "message:1"))
+
+	assert.False(t, store.IsDuplicate("envelope:3", "event:3", ""))
+	assert.False(t, store.IsDuplicate("", "", ""))
+	assert.False(t, store.IsDuplicate())
+}
+
+func TestMemoryStore_TTL(t *testing.T) {
+	store := NewMemoryStore(time.Millisecond*50, DefaultLimit)
+
+	assert.False(t, store.IsDuplicate("event:1"))
+	assert.True(t, store.IsDuplicate("event:1"))
+
+	time.Sleep(time.Millisecond * 100)
+
+	assert.False(t, store.IsDuplicate("event:1"))
+	assert.Len(t, store.keys, 1)
+	assert.Len(t, store.order, 1)
+}
+
+func TestMemoryStore_Limit(t *testing.T) {
+	store := NewMemoryStore(time.Hour, 3)
+
+	for i := 0; i < 5; i++ {
+		assert.False(t, store.IsDuplicate(fmt.Sprintf("event:%d", i)))
+	}
+
+	assert.Len(t, store.keys, 3)
+	assert.Len(t, store.order, 3)
+
+	//The oldest keys were removed
+	assert.False(t, store.IsDuplicate("event:0"))
+	assert.True(t, store.IsDuplicate("event:4"))
+}
+
+func TestMemoryStore_Concurrency(t *testing.T) {
+	var (
+		store     = NewMemoryStore(time.Hour, DefaultLimit)
+		wg        sync.WaitGroup
+		processed int64
+	)
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if !store.IsDuplicate(fmt.Sprintf("event:%d", j)) {
+					atomic.AddInt64(&processed, 1)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int64(100), processed)
+}
@@ -0,0 +1,21 @@
+package deduplication
+
+import "time"
+
+const (
+	//DefaultTTL the default time, during which the processed message identifiers are remembered. Slack retries the delivery during few minutes
+	DefaultTTL = time.Hour
+
+	//DefaultLimit the default maximum number of remembered identifiers
+	DefaultLimit = 10000
+)
+
+// Store the interface for the storage of already processed messages identifiers.
+// Implementations must be safe for the concurrent usage
+type Store interface {
+	//IsDuplicate checks if any of the keys was already processed during the TTL. If not, all keys are remembered as processed. Empty keys are ignored
+	IsDuplicate(keys ...string) bool
+}
+
+// S the deduplication store, which is used by the application
+var S Store = NewMemoryStore(DefaultTTL, DefaultLimit)
@@ -0,0 +1,334 @@
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/analiser"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/shutdown"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	mattermostEventPosted = "posted"
+
+	mattermostActionAuthentication = "authentication_challenge"
+)
+
+// MattermostService struct of message service for Mattermost messages API
+type MattermostService struct {
+}
+
+func (MattermostService) fetchMainChannelID() error {
+	availableChannels, statusCode, err := container.C.MessageClient.GetConversationsList()
+	if err != nil {
+		log.Logger().AddError(err).Int("status_code", statusCode).Msg("Failed conversations list fetching")
+		return err
+	}
+
+	var mainChannel dto.Channel
+	for _, channel := range availableChannels.Channels {
+		if channel.Name == container.C.Config.MessagesAPIConfig.MainChannelAlias {
+			mainChannel = channel
+			break
+		}
+	}
+
+	if err = container.C.Config.SetToEnv(config.EnvMainChannelID, mainChannel.ID, true); err != nil {
+		log.Logger().AddError(err).Str("channel_id", mainChannel.ID).Msg("Failed to save main channel ID in .env file")
+		return err
+	}
+
+	container.C.Config.MessagesAPIConfig.MainChannelID = mainChannel.ID
+
+	return nil
+}
+
+func (MattermostService) fetchBotUserID() error {
+	availableUsers, statusCode, err := container.C.MessageClient.GetUsersList()
+	if err != nil {
+		log.Logger().AddError(err).Int("status_code", statusCode).Msg("Failed users list fetching")
+		return err
+	}
+
+	var botMember dto.SlackMember
+	for _, member := range availableUsers.Members {
+		if member.Name == container.C.Config.MessagesAPIConfig.BotName {
+			botMember = member
+			break
+		}
+	}
+
+	if botMember.ID == "" {
+		return errors.New("failed to find the bot user by the bot name")
+	}
+
+	if err = container.C.Config.SetToEnv(config.EnvUserID, botMember.ID, true); err != nil {
+		log.Logger().AddError(err).Str("user_id", botMember.ID).Msg("Failed to save bot user ID in .env file")
+		return err
+	}
+
+	container.C.Config.MessagesAPIConfig.BotUserID = botMember.ID
+
+	return nil
+}
+
+// BeforeWSConnectionStart runs methods before the WS connection start
+func (s MattermostService) BeforeWSConnectionStart() error {
+	if container.C.Config.MessagesAPIConfig.MainChannelID == "" {
+		log.Logger().Info().Msg("Main channel ID wasn't specified. Trying to fetch main channel from API")
+		if err := s.fetchMainChannelID(); err != nil {
+			log.Logger().AddError(err).Msg("Failed to fetch channels")
+			return err
+		}
+	}
+
+	if container.C.Config.MessagesAPIConfig.BotUserID == "" {
+		log.Logger().Info().Msg("Bot user ID wasn't specified. Trying to fetch user ID from API")
+		if err := s.fetchBotUserID(); err != nil {
+			log.Logger().AddError(err).Msg("Failed to fetch user ID")
+			return err
+		}
+	}
+
+	log.Logger().AppendGlobalContext(map[string]interface{}{
+		"main_channel_id":    container.C.Config.MessagesAPIConfig.MainChannelID,
+		"main_channel_alias": container.C.Config.MessagesAPIConfig.MainChannelAlias,
+		"bot_user_id":        container.C.Config.MessagesAPIConfig.BotUserID,
+		"bot_user_name":      container.C.Config.MessagesAPIConfig.BotName,
+	})
+
+	return nil
+}
+
+// InitWebSocketReceiver method for initialization of websocket receiver
+func (s MattermostService) InitWebSocketReceiver() error {
+	if err := s.BeforeWSConnectionStart(); err != nil {
+		log.Logger().AddError(err).Msg("Failed to prepare service for WS connection")
+		return err
+	}
+
+	ws, err := s.wsConnect()
+	if err != nil {
+		log.Logger().AddError(err).Msg("Failed connect to the websocket")
+		return err
+	}
+
+	defer ws.Close()
+
+	release := closeOnStop(ws)
+	defer release()
+
+	for {
+		var event dto.MattermostResponseWebsocketEvent
+
+		//Receive message
+		if err = websocket.JSON.Receive(ws, &event); err != nil {
+			if shutdown.S.IsStopping() {
+				return ErrReceiverStopped
+			}
+
+			log.Logger().AddError(err).Msg("Something went wrong with message receiving from Mattermost websocket")
+			return err
+		}
+
+		if event.Event != mattermostEventPosted {
+			log.Logger().Debug().
+				Str("event", event.Event).
+				Str("status", event.Status).
+				Msg("Received not supported event. Ignoring.")
+			continue
+		}
+
+		var post dto.MattermostResponsePost
+		if err = json.Unmarshal([]byte(event.Data.Post), &post); err != nil {
+			log.Logger().AddError(err).
+				Str("post", event.Data.Post).
+				Msg("Something went wrong with post parsing")
+			continue
+		}
+
+		log.Logger().Debug().
+			Str("post", event.Data.Post).
+			Int("seq", event.Seq).
+			Msg("Received event message")
+
+		botID := ""
+		if post.IsFromBot() {
+			botID = post.UserID
+		}
+
+		if !isValidMessage(MsgAttributes{
+			Type:    getMattermostMessageType(event, post),
+			Channel: post.ChannelID,
+			Text:    post.Message,
+			User:    post.UserID,
+			BotID:   botID,
+		}) {
+			continue
+		}
+
+		if !shutdown.S.Begin() {
+			return ErrReceiverStopped
+		}
+
+		if err = s.ProcessMessage(&post); err != nil {
+			log.Logger().AddError(err).Interface("message_object", &post).Msg("Can't check or answer to the message")
+		}
+
+		conversation.S.Expire()
+		shutdown.S.End()
+	}
+}
+
+// getMattermostMessageType maps the received post to the accepted message types. The bot reacts on all messages in direct and group channels,
+// but in the public and private channels only the messages with the bot mention are accepted
+func getMattermostMessageType(event dto.MattermostResponseWebsocketEvent, post dto.MattermostResponsePost) string {
+	//The system messages, like channel join, have the type
+	if post.Type != "" {
+		return post.Type
+	}
+
+	switch event.Data.ChannelType {
+	case dto.MattermostChannelTypeDirect, dto.MattermostChannelTypeGroup:
+		return eventTypeMessage
+	}
+
+	var mentions []string
+	if event.Data.Mentions != "" {
+		if err := json.Unmarshal([]byte(event.Data.Mentions), &mentions); err != nil {
+			log.Logger().AddError(err).Str("mentions", event.Data.Mentions).Msg("Failed to parse the post mentions")
+		}
+	}
+
+	for _, userID := range mentions {
+		if userID == container.C.Config.MessagesAPIConfig.BotUserID {
+			return eventTypeAppMention
+		}
+	}
+
+	return ""
+}
+
+// ProcessMessage processes the post received from the WS connection
+func (s MattermostService) ProcessMessage(msg interface{}) error {
+	post := msg.(*dto.MattermostResponsePost)
+	log.Logger().Debug().
+		Str("id", post.ID).
+		Str("text", post.Message).
+		Str("root_id", post.RootID).
+		Str("user", post.UserID).
+		Str("channel", post.ChannelID).
+		Msg("Message received")
+
+	//We need to trim the message before all checks
+	post.Message = strings.TrimSpace(post.Message)
+
+	dmAnswer, err := analiser.GetDmAnswer(analiser.Message{
+		Channel:  post.ChannelID,
+		User:     post.UserID,
+		ThreadTS: post.RootID,
+		Text:     post.Message,
+	})
+	if err != nil {
+		log.Logger().AddError(err).Msg("Failed to get dictionary message answer")
+		return err
+	}
+
+	m, err := prepareAnswer(&dto.SlackResponseEventMessage{
+		Channel:      post.ChannelID,
+		ClientMsgID:  post.ID,
+		DisplayAsBot: false,
+		ThreadTS:     post.RootID,
+		Text:         post.Message,
+		Ts:           post.ID,
+		Type:         eventTypeMessage,
+		User:         post.UserID,
+	}, dmAnswer)
+	if err != nil {
+		log.Logger().AddError(err).Msg("Failed to analyse received message")
+		return err
+	}
+
+	if dmAnswer.IsEmpty() {
+		log.Logger().Debug().
+			Str("id", post.ID).
+			Str("text", post.Message).
+			Str("user", post.UserID).
+			Str("channel", post.ChannelID).
+			Msg("No answer found for the received message")
+	} else {
+		//We put a dictionary message into our message object,
+		// so later we can identify what kind of reaction will be executed
+		m.DictionaryMessage = dmAnswer
+	}
+
+	if err = TriggerAnswer(m, true); err != nil {
+		log.Logger().AddError(err).Msg("Failed trigger the answer")
+		return err
+	}
+
+	refreshPreparedMessages()
+	return nil
+}
+
+// wsConnect method opens the websocket connection and authenticates it with the bot token
+func (MattermostService) wsConnect() (*websocket.Conn, error) {
+	wsURL, err := getMattermostWebsocketURL(container.C.Config.MessagesAPIConfig.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := websocket.NewConfig(wsURL, container.C.Config.MessagesAPIConfig.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Header.Set("Authorization", "Bearer "+container.C.Config.MessagesAPIConfig.OAuthToken)
+
+	ws, err := websocket.DialConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = websocket.JSON.Send(ws, dto.MattermostRequestWebsocketAction{
+		Seq:    1,
+		Action: mattermostActionAuthentication,
+		Data: map[string]string{
+			"token": container.C.Config.MessagesAPIConfig.OAuthToken,
+		},
+	}); err != nil {
+		ws.Close()
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// getMattermostWebsocketURL generates the websocket URL from the API base URL. Example: https://mattermost.example.com/api/v4 => wss://mattermost.example.com/api/v4/websocket
+func getMattermostWebsocketURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	default:
+		return "", errors.New("the messages API base URL should start with http:// or https://")
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/websocket"
+
+	return u.String(), nil
+}
@@ -0,0 +1,223 @@
+package message
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/client"
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	mattermostTestToken  = "test-token"
+	mattermostTestBotID  = "bot"
+	mattermostTestUserID = "user1"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+// fakeDictionary the dictionary, which answers only on known messages
+type fakeDictionary struct {
+	database.BaseDatabaseInterface
+	answers map[string]string
+}
+
+func (d fakeDictionary) FindAnswer(message string) (dto.DictionaryMessage, error) {
+	if d.answers[message] == "" {
+		return dto.DictionaryMessage{}, nil
+	}
+
+	return dto.DictionaryMessage{
+		Question: message,
+		Answer:   d.answers[message],
+	}, nil
+}
+
+func (d fakeDictionary) GetQuestionsByScenarioID(int64, bool) ([]database.QuestionObject, error) {
+	return nil, nil
+}
+
+// fakeMattermostServer the local fake of Mattermost API v4 with the websocket endpoint, which sends selected events to the bot
+type fakeMattermostServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	posts  []dto.MattermostRequestCreatePost
+	auth   dto.MattermostRequestWebsocketAction
+	events []dto.MattermostResponseWebsocketEvent
+}
+
+func newFakeMattermostServer(t *testing.T, events []dto.MattermostResponseWebsocketEvent) *fakeMattermostServer {
+	s := &fakeMattermostServer{
+		events: events,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/posts", func(w http.ResponseWriter, r *http.Request) {
+		var post dto.MattermostRequestCreatePost
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&post))
+
+		s.mu.Lock()
+		s.posts = append(s.posts, post)
+		s.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(dto.MattermostResponsePost{ID: "post", ChannelID: post.ChannelID, Message: post.Message})
+	})
+	mux.Handle("/api/v4/websocket", websocket.Handler(func(ws *websocket.Conn) {
+		var auth dto.MattermostRequestWebsocketAction
+		assert.NoError(t, websocket.JSON.Receive(ws, &auth))
+
+		s.mu.Lock()
+		s.auth = auth
+		s.mu.Unlock()
+
+		assert.NoError(t, websocket.JSON.Send(ws, dto.MattermostResponseWebsocketEvent{Status: "OK", SeqReply: auth.Seq}))
+		assert.NoError(t, websocket.JSON.Send(ws, dto.MattermostResponseWebsocketEvent{Event: "hello"}))
+		for _, event := range s.events {
+			assert.NoError(t, websocket.JSON.Send(ws, event))
+		}
+
+		//We give some time to process the events, before the connection close
+		time.Sleep(time.Millisecond * 200)
+	}))
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+mattermostTestToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *fakeMattermostServer) createdPosts() []dto.MattermostRequestCreatePost {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]dto.MattermostRequestCreatePost{}, s.posts...)
+}
+
+func (s *fakeMattermostServer) authAction() dto.MattermostRequestWebsocketAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.auth
+}
+
+func initMattermostContainer(s *fakeMattermostServer) {
+	h := client.HTTPClient{
+		Client:     s.Server.Client(),
+		OAuthToken: mattermostTestToken,
+		BaseURL:    s.URL + "/api/v4",
+	}
+
+	mc := client.MattermostClient{}
+	mc.HTTPClient = &h
+
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				BaseURL:       s.URL + "/api/v4",
+				OAuthToken:    mattermostTestToken,
+				BotUserID:     mattermostTestBotID,
+				BotName:       "devbot",
+				MainChannelID: "channel1",
+				Type:          config.MessagesAPITypeMattermost,
+			},
+		},
+		MessageClient: mc,
+		Dictionary: fakeDictionary{answers: map[string]string{
+			"Hello bot": "Hello human",
+		}},
+	}
+}
+
+func postedEvent(t *testing.T, channelType string, mentions string, post dto.MattermostResponsePost) dto.MattermostResponseWebsocketEvent {
+	encoded, err := json.Marshal(post)
+	assert.NoError(t, err)
+
+	event := dto.MattermostResponseWebsocketEvent{Event: mattermostEventPosted}
+	event.Data.ChannelType = channelType
+	event.Data.Mentions = mentions
+	event.Data.Post = string(encoded)
+
+	return event
+}
+
+func TestMattermostService_InitWebSocketReceiver(t *testing.T) {
+	server := newFakeMattermostServer(t, []dto.MattermostResponseWebsocketEvent{
+		//Direct message, which should be answered
+		postedEvent(t, dto.MattermostChannelTypeDirect, "", dto.MattermostResponsePost{
+			ID: "p1", UserID: mattermostTestUserID, ChannelID: "dm", Message: " Hello bot ",
+		}),
+		//Message from the bot itself
+		postedEvent(t, dto.MattermostChannelTypeDirect, "", dto.MattermostResponsePost{
+			ID: "p2", UserID: mattermostTestBotID, ChannelID: "dm", Message: "Hello bot",
+		}),
+		//Message in the public channel without the bot mention
+		postedEvent(t, dto.MattermostChannelTypeOpen, "", dto.MattermostResponsePost{
+			ID: "p3", UserID: mattermostTestUserID, ChannelID: "channel1", Message: "Hello bot",
+		}),
+		//System message
+		postedEvent(t, dto.MattermostChannelTypeOpen, `["bot"]`, dto.MattermostResponsePost{
+			ID: "p4", UserID: mattermostTestUserID, ChannelID: "channel1", Message: "Hello bot", Type: "system_join_channel",
+		}),
+		//Message with the bot mention in the thread of public channel
+		postedEvent(t, dto.MattermostChannelTypeOpen, `["bot"]`, dto.MattermostResponsePost{
+			ID: "p5", UserID: mattermostTestUserID, ChannelID: "channel1", RootID: "p0", Message: "Hello bot",
+		}),
+	})
+	initMattermostContainer(server)
+
+	//The receiver returns the error, once the fake server closes the connection
+	assert.Error(t, MattermostService{}.InitWebSocketReceiver())
+
+	assert.Equal(t, dto.MattermostRequestWebsocketAction{
+		Seq:    1,
+		Action: mattermostActionAuthentication,
+		Data:   map[string]string{"token": mattermostTestToken},
+	}, server.authAction())
+
+	assert.Equal(t, []dto.MattermostRequestCreatePost{
+		{ChannelID: "dm", Message: "Hello human"},
+		{ChannelID: "channel1", Message: "Hello human", RootID: "p0"},
+	}, server.createdPosts())
+}
+
+func TestMattermostService_InitWebSocketReceiverUnauthorized(t *testing.T) {
+	server := newFakeMattermostServer(t, nil)
+	initMattermostContainer(server)
+	container.C.Config.MessagesAPIConfig.OAuthToken = "wrong-token"
+
+	assert.Error(t, MattermostService{}.InitWebSocketReceiver())
+	assert.Empty(t, server.createdPosts())
+}
+
+func TestGetMattermostWebsocketURL(t *testing.T) {
+	wsURL, err := getMattermostWebsocketURL("https://mattermost.example.com/api/v4/")
+	assert.NoError(t, err)
+	assert.Equal(t, "wss://mattermost.example.com/api/v4/websocket", wsURL)
+
+	wsURL, err = getMattermostWebsocketURL("http://localhost:8065/api/v4")
+	assert.NoError(t, err)
+	assert.Equal(t, "ws://localhost:8065/api/v4/websocket", wsURL)
+
+	_, err = getMattermostWebsocketURL("localhost:8065")
+	assert.Error(t, err)
+}
@@ -1,22 +1,37 @@
 package message
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	_time "github.com/sharovik/devbot/internal/service/time"
 
 	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/service/executor"
 	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/phrasebook"
+	"github.com/sharovik/devbot/internal/service/variables"
 
 	"github.com/sharovik/devbot/internal/service/history"
 
 	"github.com/sharovik/devbot/internal/container"
 	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/event"
 	"github.com/sharovik/devbot/internal/log"
 )
 
-var messagesReceived = map[string]dto.BaseChatMessage{}
+const (
+	unknownQuestionEventAlias = "unknownquestion"
+	learnQuestionEventAlias   = "learnquestion"
+)
+
+var (
+	//The answers are sent by the concurrent event workers, so the access to the received messages is guarded
+	messagesReceivedMu sync.Mutex
+	messagesReceived   = map[string]dto.BaseChatMessage{}
+)
 
 func answerToMessage(m dto.BaseChatMessage) error {
 	response, statusCode, err := container.C.MessageClient.SendMessage(m)
@@ -44,10 +59,16 @@ func SendAnswerForReceivedMessage(message dto.BaseChatMessage) error {
 }
 
 func messageExpired(message dto.BaseChatMessage) {
+	messagesReceivedMu.Lock()
+	defer messagesReceivedMu.Unlock()
+
 	delete(messagesReceived, message.Channel)
 }
 
 func refreshPreparedMessages() {
+	messagesReceivedMu.Lock()
+	defer messagesReceivedMu.Unlock()
+
 	log.Logger().Debug().
 		Interface("answers_prepared", messagesReceived).
 		Msg("Trigger refresh messages")
@@ -66,13 +87,14 @@ func refreshPreparedMessages() {
 func prepareAnswer(message *dto.SlackResponseEventMessage, dm dto.DictionaryMessage) (dto.BaseChatMessage, error) {
 	log.Logger().StartMessage("Answer prepare")
 
-	//If we don't have any answer, we trigger the event for unknown question
+	//If we don't have any answer, we trigger the event for unknown question.
+	//In the learning mode the trainers are asked to teach the bot instead
 	if dm.Answer == "" {
-		if !container.C.Config.LearningEnabled {
-			return triggerUnknownAnswerScenario(message)
+		if dm.IsEmpty() && shouldLearn(message.User) {
+			return triggerLearnScenario(message)
 		}
 
-		//@todo: trigger learn scenario
+		return triggerUnknownAnswerScenario(message)
 	}
 
 	responseMessage := dto.BaseChatMessage{
@@ -92,49 +114,84 @@ func triggerUnknownAnswerScenario(message *dto.SlackResponseEventMessage) (answe
 	message.Text = fmt.Sprintf("similar questions %s", message.Text)
 	return dto.BaseChatMessage{
 		Channel:         message.Channel,
-		Text:            "Hmmm",
+		Text:            phrasebook.For(message.Channel, message.User).Phrase(phrasebook.UnknownQuestion),
+		AsUser:          true,
+		ThreadTS:        message.ThreadTS,
+		Ts:              _time.Service.Now(),
+		OriginalMessage: message.ToBaseOriginalMessage(),
+		DictionaryMessage: dto.DictionaryMessage{
+			ReactionType: unknownQuestionEventAlias,
+		},
+	}, nil
+}
+
+func shouldLearn(userID string) bool {
+	if !container.C.Config.LearningEnabled || !container.C.Config.IsTrainer(userID) {
+		return false
+	}
+
+	if container.C.DefinedEvents[learnQuestionEventAlias] == nil {
+		log.Logger().Warn().
+			Str("event_alias", learnQuestionEventAlias).
+			Msg("The learning mode is enabled, but the event for learning is not defined")
+		return false
+	}
+
+	return true
+}
+
+// triggerLearnScenario triggers the event, which asks the trainer how to react on the received message
+func triggerLearnScenario(message *dto.SlackResponseEventMessage) (dto.BaseChatMessage, error) {
+	return dto.BaseChatMessage{
+		Channel:         message.Channel,
 		AsUser:          true,
 		ThreadTS:        message.ThreadTS,
 		Ts:              _time.Service.Now(),
 		OriginalMessage: message.ToBaseOriginalMessage(),
 		DictionaryMessage: dto.DictionaryMessage{
-			ReactionType: "unknownquestion",
+			ReactionType: learnQuestionEventAlias,
 		},
 	}, nil
 }
 
-// TriggerScenario triggers the scenario for selected channel
-func TriggerScenario(channel string, scenario database.EventScenario, shouldRemember bool) error {
+// TriggerScenario triggers the scenario for selected conversation key
+func TriggerScenario(key conversation.Key, scenario database.EventScenario, shouldRemember bool) error {
 	dmAnswer := dto.DictionaryMessage{
 		ScenarioID:   scenario.ID,
 		EventID:      scenario.EventID,
 		ReactionType: scenario.EventName,
 	}
+	prompt := variables.NextPrompt(phrasebook.For(key.Channel, key.User), scenario)
 
-	conversation.AddConversation(scenario, dto.BaseChatMessage{
-		Channel:           channel,
+	conversation.S.Add(scenario, dto.BaseChatMessage{
+		Channel:           key.Channel,
 		AsUser:            true,
-		Text:              scenario.GetUnAnsweredQuestion(),
+		Text:              prompt,
+		ThreadTS:          key.ThreadTS,
 		Ts:                _time.Service.Now(),
 		DictionaryMessage: dmAnswer,
 		OriginalMessage: dto.BaseOriginalMessage{
-			Text: scenario.GetUnAnsweredQuestion(),
+			Text:     prompt,
+			User:     key.User,
+			Channel:  key.Channel,
+			ThreadTS: key.ThreadTS,
 		},
 	})
 
-	if err := TriggerAnswer(channel, conversation.GetConversation(channel).LastQuestion, shouldRemember); err != nil {
+	if err := TriggerAnswer(conversation.S.Get(key).LastQuestion, shouldRemember); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// TriggerAnswer triggers an answer for received message
-func TriggerAnswer(channel string, answerMessage dto.BaseChatMessage, shouldRemember bool) error {
+// TriggerAnswer triggers an answer for received message. The conversation of the answer is identified by conversation.NewKey
+func TriggerAnswer(answerMessage dto.BaseChatMessage, shouldRemember bool) error {
+	key := conversation.NewKey(answerMessage)
 	if answerMessage.Text != "" {
 		if err := SendAnswerForReceivedMessage(answerMessage); err != nil {
 			log.Logger().AddError(err).Msg("Failed to send prepared answers")
-			conversation.FinaliseConversation(channel)
+			conversation.S.Finalise(key)
 
 			return err
 		}
@@ -144,7 +201,7 @@ func TriggerAnswer(channel string, answerMessage dto.BaseChatMessage, shouldReme
 		return nil
 	}
 
-	conversation.SetLastQuestion(answerMessage)
+	conversation.S.SetLastQuestion(answerMessage)
 
 	if answerMessage.DictionaryMessage.ReactionType == "" || container.C.DefinedEvents[answerMessage.DictionaryMessage.ReactionType] == nil {
 		log.Logger().Warn().
@@ -153,7 +210,7 @@ func TriggerAnswer(channel string, answerMessage dto.BaseChatMessage, shouldReme
 		return nil
 	}
 
-	activeConversation := conversation.GetConversation(channel)
+	activeConversation := conversation.S.Get(key)
 	if activeConversation.ScenarioID != int64(0) && !activeConversation.EventReadyToBeExecuted {
 		log.Logger().Info().
 			Interface("conversation", activeConversation).
@@ -161,11 +218,28 @@ func TriggerAnswer(channel string, answerMessage dto.BaseChatMessage, shouldReme
 		return nil
 	}
 
-	go func() {
-		answer, err := container.C.DefinedEvents[answerMessage.DictionaryMessage.ReactionType].Execute(answerMessage)
+	err := executor.S.Submit(answerMessage.DictionaryMessage.ReactionType, func() {
+		answer, err := executor.S.ExecuteEvent(key, container.C.DefinedEvents[answerMessage.DictionaryMessage.ReactionType], answerMessage)
+		var panicErr *event.PanicError
 		if err != nil {
 			log.Logger().AddError(err).Msg("Failed to execute the event")
-			conversation.FinaliseConversation(channel)
+
+			//The failure is stored before the conversation finalisation, so the history contains the variables of the conversation
+			if shouldRemember && errors.As(err, &panicErr) {
+				history.RememberEventFailure(answerMessage, panicErr.Error())
+			}
+
+			conversation.S.Finalise(key)
+		}
+
+		switch {
+		case errors.As(err, &panicErr):
+			answer.Text = phrasebook.For(key.Channel, key.User).Phrase(phrasebook.EventPanic, answerMessage.DictionaryMessage.ReactionType, panicErr.ReferenceID)
+		case errors.Is(err, executor.ErrEventTimeout):
+			answer.Text = phrasebook.For(key.Channel, key.User).Phrase(phrasebook.EventTimeout, answerMessage.DictionaryMessage.ReactionType)
+		case errors.Is(err, executor.ErrEventCancelled):
+			//The user stopped the event or the application is stopping, so the result is not needed anymore
+			answer.Text = ""
 		}
 
 		if answer.Text != "" {
@@ -178,14 +252,28 @@ func TriggerAnswer(channel string, answerMessage dto.BaseChatMessage, shouldReme
 		//We will trigger the event history save in case when we don't have open conversation
 		//or when we do have open conversation, but it is time to trigger the event execution
 		//so, we can store all variables
-		if shouldRemember && (conversation.GetConversation(answerMessage.Channel).ScenarioID == 0 || conversation.GetConversation(answerMessage.Channel).EventReadyToBeExecuted) {
+		currentConversation := conversation.S.Get(key)
+		if shouldRemember && panicErr == nil && (currentConversation.ScenarioID == 0 || currentConversation.EventReadyToBeExecuted) {
 			history.RememberEventExecution(answerMessage)
 		}
 
-		if conversation.GetConversation(answerMessage.Channel).EventReadyToBeExecuted {
-			conversation.FinaliseConversation(channel)
+		if currentConversation.EventReadyToBeExecuted {
+			conversation.S.Finalise(key)
 		}
-	}()
+	})
+	if err != nil {
+		log.Logger().AddError(err).
+			Str("reaction_type", answerMessage.DictionaryMessage.ReactionType).
+			Msg("Failed to queue the event execution")
+		conversation.S.Finalise(key)
+
+		answerMessage.Text = phrasebook.For(key.Channel, key.User).Phrase(phrasebook.QueueFull)
+		if sendErr := SendAnswerForReceivedMessage(answerMessage); sendErr != nil {
+			log.Logger().AddError(sendErr).Msg("Failed to send the queue full answer")
+		}
+
+		return err
+	}
 
 	return nil
 }
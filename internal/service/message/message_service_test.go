@@ -0,0 +1,181 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/client"
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/event"
+	"github.com/sharovik/devbot/internal/service/executor"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/shutdown"
+	"github.com/stretchr/testify/assert"
+)
+
+type slowEvent struct{}
+
+func (e slowEvent) Help() string   { return "" }
+func (e slowEvent) Alias() string  { return "slow" }
+func (e slowEvent) Install() error { return nil }
+func (e slowEvent) Update() error  { return nil }
+
+func (e slowEvent) Timeout() time.Duration {
+	return time.Millisecond * 10
+}
+
+func (e slowEvent) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	return e.ExecuteContext(context.Background(), message)
+}
+
+func (e slowEvent) ExecuteContext(ctx context.Context, message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	<-ctx.Done()
+	message.Text = "Done"
+
+	return message, ctx.Err()
+}
+
+type panicEvent struct{}
+
+func (e panicEvent) Help() string   { return "" }
+func (e panicEvent) Alias() string  { return "panic" }
+func (e panicEvent) Install() error { return nil }
+func (e panicEvent) Update() error  { return nil }
+
+func (e panicEvent) Execute(message dto.BaseChatMessage) (dto.BaseChatMessage, error) {
+	var variables []string
+	message.Text = variables[1]
+
+	return message, nil
+}
+
+func TestTriggerAnswer_Timeout(t *testing.T) {
+	var output bytes.Buffer
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				BotName: "devbot",
+				Type:    config.MessagesAPITypeConsole,
+			},
+		},
+		MessageClient: client.NewConsoleClient(&output, "devbot", "developer", "console"),
+		DefinedEvents: map[string]event.DefinedEventInterface{
+			"slow": slowEvent{},
+		},
+	}
+
+	executor.S = executor.New(config.EventsExecutorConfig{})
+	shutdown.S = shutdown.New()
+	defer func() {
+		shutdown.S = shutdown.New()
+	}()
+
+	assert.NoError(t, TriggerAnswer(dto.BaseChatMessage{
+		Channel: "console",
+		DictionaryMessage: dto.DictionaryMessage{
+			ReactionType: "slow",
+		},
+		OriginalMessage: dto.BaseOriginalMessage{
+			User: "developer",
+		},
+	}, false))
+
+	assert.NoError(t, shutdown.S.Wait(time.Second))
+	assert.Equal(t, "devbot: Sorry, the `slow` event took too much time, so I stopped it. Please, try again later.\n", output.String())
+}
+
+func TestTriggerAnswer_Panic(t *testing.T) {
+	var output bytes.Buffer
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				BotName: "devbot",
+				Type:    config.MessagesAPITypeConsole,
+			},
+		},
+		MessageClient: client.NewConsoleClient(&output, "devbot", "developer", "console"),
+		DefinedEvents: map[string]event.DefinedEventInterface{
+			"panic": panicEvent{},
+		},
+	}
+
+	executor.S = executor.New(config.EventsExecutorConfig{})
+	shutdown.S = shutdown.New()
+	defer func() {
+		shutdown.S = shutdown.New()
+	}()
+
+	message := dto.BaseChatMessage{
+		Channel: "console",
+		DictionaryMessage: dto.DictionaryMessage{
+			ScenarioID:   1,
+			ReactionType: "panic",
+		},
+		OriginalMessage: dto.BaseOriginalMessage{
+			User: "developer",
+		},
+	}
+	conversation.S.Add(database.EventScenario{}, message)
+	conversation.S.MarkReady(conversation.NewKey(message))
+
+	assert.NoError(t, TriggerAnswer(message, false))
+	assert.NoError(t, shutdown.S.Wait(time.Second))
+
+	assert.Regexp(t, "^devbot: Something went wrong during the `panic` event execution. Please, contact the administrator with the reference ID `[0-9a-f]{12}`.\n$", output.String())
+	assert.Empty(t, conversation.S.Get(conversation.NewKey(message)).ScenarioID)
+}
+
+func TestPrepareAnswer_Learning(t *testing.T) {
+	container.C = container.Main{
+		Config: config.Config{
+			LearningEnabled:  true,
+			LearningTrainers: []string{"trainer"},
+		},
+		DefinedEvents: map[string]event.DefinedEventInterface{
+			learnQuestionEventAlias: slowEvent{},
+		},
+	}
+
+	answer, err := prepareAnswer(&dto.SlackResponseEventMessage{
+		Channel: "console",
+		User:    "trainer",
+		Text:    "ship it",
+	}, dto.DictionaryMessage{})
+	assert.NoError(t, err)
+	assert.Equal(t, learnQuestionEventAlias, answer.DictionaryMessage.ReactionType)
+	assert.Empty(t, answer.Text)
+	assert.Equal(t, "ship it", answer.OriginalMessage.Text)
+
+	//Only the trainers can teach the bot
+	answer, err = prepareAnswer(&dto.SlackResponseEventMessage{
+		Channel: "console",
+		User:    "developer",
+		Text:    "ship it",
+	}, dto.DictionaryMessage{})
+	assert.NoError(t, err)
+	assert.Equal(t, unknownQuestionEventAlias, answer.DictionaryMessage.ReactionType)
+
+	//The learning mode doesn't work without the learning event
+	container.C.DefinedEvents = map[string]event.DefinedEventInterface{}
+	answer, err = prepareAnswer(&dto.SlackResponseEventMessage{
+		Channel: "console",
+		User:    "trainer",
+		Text:    "ship it",
+	}, dto.DictionaryMessage{})
+	assert.NoError(t, err)
+	assert.Equal(t, unknownQuestionEventAlias, answer.DictionaryMessage.ReactionType)
+
+	container.C.Config.LearningEnabled = false
+	answer, err = prepareAnswer(&dto.SlackResponseEventMessage{
+		Channel: "console",
+		User:    "trainer",
+		Text:    "ship it",
+	}, dto.DictionaryMessage{})
+	assert.NoError(t, err)
+	assert.Equal(t, unknownQuestionEventAlias, answer.DictionaryMessage.ReactionType)
+}
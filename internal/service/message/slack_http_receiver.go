@@ -0,0 +1,262 @@
+package message
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/shutdown"
+	_time "github.com/sharovik/devbot/internal/service/time"
+)
+
+const (
+	//SlackEventsPath the path, on which the http receiver accepts the Events API requests
+	SlackEventsPath = "/slack/events"
+
+	slackEventTypeURLVerification = "url_verification"
+	slackEventTypeEventCallback   = "event_callback"
+
+	headerSlackSignature        = "X-Slack-Signature"
+	headerSlackRequestTimestamp = "X-Slack-Request-Timestamp"
+
+	slackSignatureVersion = "v0"
+
+	//slackRequestMaxAge the requests older than this age are rejected, to prevent the replay attacks
+	slackRequestMaxAge = time.Minute * 5
+
+	slackRequestMaxSize  = 1 << 20
+	slackEventsQueueSize = 100
+)
+
+var (
+	errSlackEventsQueueFull   = errors.New("the events queue is full")
+	errSlackEventsQueueClosed = errors.New("the events queue is closed")
+)
+
+// slackEventsQueue the queue of the received events, which are waiting for the processing. The queue is closed, once the http receiver stopped,
+// so its consumer processes the rest of the events and stops as well
+type slackEventsQueue struct {
+	mu     sync.Mutex
+	closed bool
+	events chan *dto.SlackResponseEventAPIMessage
+}
+
+func newSlackEventsQueue(size int) *slackEventsQueue {
+	return &slackEventsQueue{events: make(chan *dto.SlackResponseEventAPIMessage, size)}
+}
+
+// push adds the event to the queue without waiting, so the response is not blocked by the full queue
+func (q *slackEventsQueue) push(message *dto.SlackResponseEventAPIMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errSlackEventsQueueClosed
+	}
+
+	select {
+	case q.events <- message:
+		return nil
+	default:
+		return errSlackEventsQueueFull
+	}
+}
+
+// close stops accepting the new events. The events, which are already in the queue, are still received by the consumer
+func (q *slackEventsQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+}
+
+// initHTTPReceiver starts the http server, which receives the Events API requests instead of Socket Mode connection.
+// The events are processed one by one in the same order, as they were received
+func (s SlackService) initHTTPReceiver() error {
+	if container.C.Config.MessagesAPIConfig.SigningSecret == "" {
+		return errors.New("the signing secret should be specified for the http receiver mode")
+	}
+
+	queue := newSlackEventsQueue(slackEventsQueueSize)
+	go func() {
+		for message := range queue.events {
+			s.handleEvent(message)
+			shutdown.S.End()
+		}
+	}()
+
+	//The consumer stops once the queue is closed, so the failed start does not leave it behind before the next attempt
+	defer queue.close()
+
+	mux := http.NewServeMux()
+	mux.Handle(SlackEventsPath, newSlackEventsHandler(container.C.Config.MessagesAPIConfig.SigningSecret, func(message *dto.SlackResponseEventAPIMessage) bool {
+		if !shutdown.S.Begin() {
+			log.Logger().Warn().
+				Str("event_id", message.Payload.EventID).
+				Msg("The application is stopping. The event is skipped.")
+			return true
+		}
+
+		//The response is not blocked by the full queue, otherwise Slack times out and sends the same event again
+		if err := queue.push(message); err != nil {
+			shutdown.S.End()
+			log.Logger().AddError(err).
+				Str("event_id", message.Payload.EventID).
+				Int("queue_size", slackEventsQueueSize).
+				Msg("The event is rejected.")
+			return false
+		}
+
+		return true
+	}))
+
+	server := &http.Server{
+		Addr:              container.C.Config.MessagesAPIConfig.HTTPAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	//The server stops accepting the new requests and waits for the requests in progress, once the application is stopping
+	finished := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-shutdown.S.Stopping():
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(container.C.Config.ShutdownTimeout)*time.Second)
+			defer cancel()
+
+			log.Logger().Info().Msg("Stop Events API http receiver")
+			if err := server.Shutdown(ctx); err != nil {
+				log.Logger().AddError(err).Msg("Failed to stop Events API http receiver")
+			}
+		case <-finished:
+		}
+	}()
+
+	log.Logger().Info().
+		Str("address", server.Addr).
+		Str("path", SlackEventsPath).
+		Msg("Start Events API http receiver")
+
+	err := server.ListenAndServe()
+	close(finished)
+	if errors.Is(err, http.ErrServerClosed) {
+		<-stopped
+		return ErrReceiverStopped
+	}
+
+	return err
+}
+
+// newSlackEventsHandler creates the handler of Events API requests. The verified event callbacks are passed to the handle function.
+// The request is answered with 503 status, when the handle function did not accept the event, so Slack delivers it again later
+func newSlackEventsHandler(signingSecret string, handle func(message *dto.SlackResponseEventAPIMessage) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, slackRequestMaxSize))
+		if err != nil {
+			log.Logger().AddError(err).Msg("Failed to read the Events API request body")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if err = verifySlackSignature(signingSecret, r.Header, body, _time.Service.Now()); err != nil {
+			log.Logger().AddError(err).
+				Str("remote_address", r.RemoteAddr).
+				Msg("Failed to verify the Events API request")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		//The body of Events API request is the same, as the payload of Socket Mode message
+		var message dto.SlackResponseEventAPIMessage
+		if err = json.Unmarshal(body, &message.Payload); err != nil {
+			log.Logger().AddError(err).
+				RawJSON("message_body", body).
+				Msg("Something went wrong with message parsing")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		message.Type = message.Payload.Type
+		if retryAttempt := r.Header.Get("X-Slack-Retry-Num"); retryAttempt != "" {
+			message.RetryAttempt, _ = strconv.Atoi(retryAttempt)
+			message.RetryReason = r.Header.Get("X-Slack-Retry-Reason")
+		}
+
+		log.Logger().Debug().
+			RawJSON("message_body", body).
+			Str("type", message.Payload.Type).
+			Msg("Received Events API request")
+
+		switch message.Payload.Type {
+		case slackEventTypeURLVerification:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(message.Payload.Challenge))
+		case slackEventTypeEventCallback:
+			if !handle(&message) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+		default:
+			log.Logger().Debug().Str("type", message.Payload.Type).Msg("Received not supported request type. Ignoring.")
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
+
+// verifySlackSignature checks the request signature, which is generated with the signing secret of the Slack application
+func verifySlackSignature(signingSecret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get(headerSlackRequestTimestamp)
+	signature := header.Get(headerSlackSignature)
+	if timestamp == "" || signature == "" {
+		return errors.New("the request signature headers are missing")
+	}
+
+	unixTime, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid request timestamp `%s`", timestamp)
+	}
+
+	age := now.Sub(time.Unix(unixTime, 0))
+	if age > slackRequestMaxAge || age < -slackRequestMaxAge {
+		return errors.New("the request timestamp is too old")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(generateSlackSignature(signingSecret, timestamp, body))) {
+		return errors.New("the request signature is invalid")
+	}
+
+	return nil
+}
+
+// generateSlackSignature generates the signature of the request body. Example: v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503
+func generateSlackSignature(signingSecret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	_, _ = mac.Write([]byte(fmt.Sprintf("%s:%s:%s", slackSignatureVersion, timestamp, body)))
+
+	return fmt.Sprintf("%s=%s", slackSignatureVersion, hex.EncodeToString(mac.Sum(nil)))
+}
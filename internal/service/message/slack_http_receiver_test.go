@@ -0,0 +1,217 @@
+package message
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/client"
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/service/message/deduplication"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/devbot/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const slackTestSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func signedSlackRequest(t *testing.T, body []byte, timestamp time.Time) *http.Request {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+
+	r := httptest.NewRequest(http.MethodPost, SlackEventsPath, bytes.NewReader(body))
+	r.Header.Set(headerSlackRequestTimestamp, ts)
+	r.Header.Set(headerSlackSignature, generateSlackSignature(slackTestSigningSecret, ts, body))
+
+	return r
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	//The example from the Slack documentation
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c")
+	header := http.Header{}
+	header.Set(headerSlackRequestTimestamp, "1531420618")
+	header.Set(headerSlackSignature, "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503")
+
+	now := time.Unix(1531420618, 0).Add(time.Second * 30)
+	assert.NoError(t, verifySlackSignature(slackTestSigningSecret, header, body, now))
+
+	assert.Error(t, verifySlackSignature("wrong-secret", header, body, now))
+	assert.Error(t, verifySlackSignature(slackTestSigningSecret, header, append(body, '&'), now))
+	assert.Error(t, verifySlackSignature(slackTestSigningSecret, header, body, now.Add(time.Hour)))
+	assert.Error(t, verifySlackSignature(slackTestSigningSecret, http.Header{}, body, now))
+
+	header.Set(headerSlackRequestTimestamp, "wrong")
+	assert.Error(t, verifySlackSignature(slackTestSigningSecret, header, body, now))
+}
+
+func TestSlackEventsHandler_URLVerification(t *testing.T) {
+	var handled []*dto.SlackResponseEventAPIMessage
+	handler := newSlackEventsHandler(slackTestSigningSecret, func(message *dto.SlackResponseEventAPIMessage) bool {
+		handled = append(handled, message)
+		return true
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedSlackRequest(t, test.FileToBytes(t, "../../../test/testdata/slack/events_api.url_verification.json"), _time.Service.Now()))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P", w.Body.String())
+	assert.Empty(t, handled)
+}
+
+func TestSlackEventsHandler_InvalidRequests(t *testing.T) {
+	var handled []*dto.SlackResponseEventAPIMessage
+	handler := newSlackEventsHandler(slackTestSigningSecret, func(message *dto.SlackResponseEventAPIMessage) bool {
+		handled = append(handled, message)
+		return true
+	})
+
+	body := test.FileToBytes(t, "../../../test/testdata/slack/events_api.message.im.json")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, SlackEventsPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, SlackEventsPath, bytes.NewReader(body)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r := signedSlackRequest(t, body, _time.Service.Now())
+	r.Header.Set(headerSlackSignature, "v0=wrong")
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, signedSlackRequest(t, body, _time.Service.Now().Add(-time.Hour)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, signedSlackRequest(t, []byte(`{"type":`), _time.Service.Now()))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Empty(t, handled)
+}
+
+func TestSlackEventsHandler_EventCallback(t *testing.T) {
+	var output bytes.Buffer
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				BotUserID:     "U0LAN0Z89",
+				BotName:       "devbot",
+				Type:          config.MessagesAPITypeSlack,
+				ReceiverMode:  config.ReceiverModeHTTP,
+				SigningSecret: slackTestSigningSecret,
+			},
+		},
+		MessageClient: client.NewConsoleClient(&output, "devbot", "", ""),
+		Dictionary: fakeDictionary{answers: map[string]string{
+			"Hello bot":              "Hello human",
+			"<@U0LAN0Z89> Hello bot": "Hello from the thread",
+		}},
+	}
+
+	deduplication.S = deduplication.NewMemoryStore(deduplication.DefaultTTL, deduplication.DefaultLimit)
+
+	var handled []*dto.SlackResponseEventAPIMessage
+	handler := newSlackEventsHandler(slackTestSigningSecret, func(message *dto.SlackResponseEventAPIMessage) bool {
+		handled = append(handled, message)
+		return true
+	})
+
+	for _, fileName := range []string{
+		"events_api.app_mention.json",
+		"events_api.message.im.json",
+		"events_api.message.bot.json",
+	} {
+		w := httptest.NewRecorder()
+		r := signedSlackRequest(t, test.FileToBytes(t, "../../../test/testdata/slack/"+fileName), _time.Service.Now())
+		if fileName == "events_api.message.im.json" {
+			r.Header.Set("X-Slack-Retry-Num", "1")
+			r.Header.Set("X-Slack-Retry-Reason", "http_timeout")
+		}
+
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	assert.Len(t, handled, 3)
+	assert.Equal(t, "event_callback", handled[0].Type)
+	assert.Equal(t, "Ev0LAN670R", handled[0].Payload.EventID)
+	assert.Equal(t, "app_mention", handled[0].Payload.Event.Type)
+	assert.Equal(t, "C0LAN2Q65", handled[0].Payload.Event.Channel)
+	assert.Equal(t, "U061F7AUR", handled[0].Payload.Event.User)
+	assert.Equal(t, "1515449438.000011", handled[0].Payload.Event.ThreadTS)
+	assert.Equal(t, 1, handled[1].RetryAttempt)
+	assert.Equal(t, "http_timeout", handled[1].RetryReason)
+
+	for _, message := range handled {
+		SlackService{}.handleEvent(message)
+	}
+
+	assert.Equal(t, "devbot in #C0LAN2Q65 (thread 1515449438.000011): Hello from the thread\ndevbot in #D024BE91L: Hello human\n", output.String())
+}
+
+func TestSlackEventsHandler_Rejected(t *testing.T) {
+	handler := newSlackEventsHandler(slackTestSigningSecret, func(message *dto.SlackResponseEventAPIMessage) bool {
+		return false
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedSlackRequest(t, test.FileToBytes(t, "../../../test/testdata/slack/events_api.message.im.json"), _time.Service.Now()))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestSlackEventsQueue(t *testing.T) {
+	queue := newSlackEventsQueue(1)
+	assert.NoError(t, queue.push(&dto.SlackResponseEventAPIMessage{}))
+	assert.ErrorIs(t, queue.push(&dto.SlackResponseEventAPIMessage{}), errSlackEventsQueueFull)
+
+	queue.close()
+	queue.close()
+	assert.ErrorIs(t, queue.push(&dto.SlackResponseEventAPIMessage{}), errSlackEventsQueueClosed)
+
+	//The queued event is still received after the close, then the consumer stops
+	var received int
+	for range queue.events {
+		received++
+	}
+	assert.Equal(t, 1, received)
+}
+
+func TestInitHTTPReceiver_FailedStart(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				SigningSecret: slackTestSigningSecret,
+				HTTPAddress:   listener.Addr().String(),
+			},
+		},
+	}
+
+	goroutines := runtime.NumGoroutine()
+
+	//The address is already in use, so every start fails and the next one is retried
+	for i := 0; i < 5; i++ {
+		assert.Error(t, SlackService{}.initHTTPReceiver())
+	}
+
+	//The goroutines are counted without assert.Eventually, because it runs the condition in its own goroutine
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
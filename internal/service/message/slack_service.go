@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/message/deduplication"
+	"github.com/sharovik/devbot/internal/service/shutdown"
 
 	"github.com/sharovik/devbot/internal/client"
 	"github.com/sharovik/devbot/internal/config"
@@ -140,12 +142,19 @@ func (s SlackService) InitWebSocketReceiver() error {
 		return err
 	}
 
+	if container.C.Config.MessagesAPIConfig.ReceiverMode == config.ReceiverModeHTTP {
+		return s.initHTTPReceiver()
+	}
+
 	ws, statusCode, err := s.wsConnect()
 	if err != nil {
 		log.Logger().AddError(err).Int("status_code", statusCode).Msg("Failed connect to the websocket")
 		return err
 	}
 
+	release := closeOnStop(ws)
+	defer release()
+
 	var (
 		event interface{}
 	)
@@ -155,6 +164,10 @@ func (s SlackService) InitWebSocketReceiver() error {
 
 		//Receive message
 		if err = websocket.JSON.Receive(ws, &event); err != nil {
+			if shutdown.S.IsStopping() {
+				return ErrReceiverStopped
+			}
+
 			log.Logger().AddError(err).Msg("Something went wrong with message receiving from EventsAPI")
 			return err
 		}
@@ -181,31 +194,43 @@ func (s SlackService) InitWebSocketReceiver() error {
 			Str("envelope_id", message.EnvelopeID).
 			Msg("Received event message")
 
+		//The message is not acknowledged, once the application is stopping, so Slack will redeliver it to the next connection
+		if !shutdown.S.Begin() {
+			return ErrReceiverStopped
+		}
+
 		if err = acknowledge(ws, message.EnvelopeID); err != nil {
 			log.Logger().AddError(err).
 				RawJSON("message_body", str).
 				Str("envelope_id", message.EnvelopeID).
 				Msg("Failed to acknowledge the message")
 
+			shutdown.S.End()
 			return err
 		}
 
-		if !isValidMessage(MsgAttributes{
-			Type:    message.Payload.Event.Type,
-			Channel: message.Payload.Event.Channel,
-			Text:    message.Payload.Event.Text,
-			User:    message.Payload.Event.User,
-			BotID:   message.Payload.Event.BotID,
-		}) {
-			continue
-		}
+		s.handleEvent(&message)
+		shutdown.S.End()
+	}
+}
 
-		if err = s.ProcessMessage(&message); err != nil {
-			log.Logger().AddError(err).Interface("message_object", &message).Msg("Can't check or answer to the message")
-		}
+// handleEvent validates and processes the received event message. It is used by the socket and http receivers
+func (s SlackService) handleEvent(message *dto.SlackResponseEventAPIMessage) {
+	if !isValidMessage(MsgAttributes{
+		Type:    message.Payload.Event.Type,
+		Channel: message.Payload.Event.Channel,
+		Text:    message.Payload.Event.Text,
+		User:    message.Payload.Event.User,
+		BotID:   message.Payload.Event.BotID,
+	}) {
+		return
+	}
 
-		conversation.CleanUpExpiredMessages()
+	if err := s.ProcessMessage(message); err != nil {
+		log.Logger().AddError(err).Interface("message_object", message).Msg("Can't check or answer to the message")
 	}
+
+	conversation.S.Expire()
 }
 
 func acknowledge(ws *websocket.Conn, envelopeID string) error {
@@ -223,6 +248,24 @@ func acknowledge(ws *websocket.Conn, envelopeID string) error {
 // ProcessMessage processes the message from the WS connection
 func (s SlackService) ProcessMessage(msg interface{}) error {
 	message := msg.(*dto.SlackResponseEventAPIMessage)
+
+	//Slack redelivers the messages, if the acknowledge was slow. We drop these messages to not execute the events twice
+	if deduplication.S.IsDuplicate(
+		prefixedKey("envelope", message.EnvelopeID),
+		prefixedKey("event", message.Payload.EventID),
+		prefixedKey("message", message.Payload.Event.ClientMsgID),
+	) {
+		log.Logger().Warn().
+			Str("envelope_id", message.EnvelopeID).
+			Str("event_id", message.Payload.EventID).
+			Str("client_msg_id", message.Payload.Event.ClientMsgID).
+			Int("retry_attempt", message.RetryAttempt).
+			Str("retry_reason", message.RetryReason).
+			Msg("The message was already processed. Skipping the duplicate.")
+
+		return nil
+	}
+
 	log.Logger().Debug().
 		Str("type", message.Type).
 		Str("text", message.Payload.Event.Text).
@@ -236,9 +279,10 @@ func (s SlackService) ProcessMessage(msg interface{}) error {
 	message.Payload.Event.Text = strings.TrimSpace(message.Payload.Event.Text)
 
 	dmAnswer, err := analiser.GetDmAnswer(analiser.Message{
-		Channel: message.Payload.Event.Channel,
-		User:    message.Payload.Event.User,
-		Text:    message.Payload.Event.Text,
+		Channel:  message.Payload.Event.Channel,
+		User:     message.Payload.Event.User,
+		ThreadTS: message.Payload.Event.ThreadTS,
+		Text:     message.Payload.Event.Text,
 	})
 	if err != nil {
 		log.Logger().AddError(err).Msg("Failed to get dictionary message answer")
@@ -263,8 +307,7 @@ func (s SlackService) ProcessMessage(msg interface{}) error {
 		return err
 	}
 
-	emptyDmMessage := dto.DictionaryMessage{}
-	if dmAnswer == emptyDmMessage {
+	if dmAnswer.IsEmpty() {
 		log.Logger().Debug().
 			Str("type", message.Payload.Event.Type).
 			Str("text", message.Payload.Event.Text).
@@ -279,7 +322,7 @@ func (s SlackService) ProcessMessage(msg interface{}) error {
 		m.DictionaryMessage = dmAnswer
 	}
 
-	if err = TriggerAnswer(message.Payload.Event.Channel, m, true); err != nil {
+	if err = TriggerAnswer(m, true); err != nil {
 		log.Logger().AddError(err).Msg("Failed trigger the answer")
 		return err
 	}
@@ -288,6 +331,15 @@ func (s SlackService) ProcessMessage(msg interface{}) error {
 	return nil
 }
 
+// prefixedKey generates the deduplication key for selected identifier type. The empty identifier generates the empty key
+func prefixedKey(prefix string, id string) string {
+	if id == "" {
+		return ""
+	}
+
+	return prefix + ":" + id
+}
+
 func getWSClient() client.SlackClient {
 	netTransport := &http.Transport{
 		TLSHandshakeTimeout: time.Duration(container.C.Config.HTTPClient.TLSHandshakeTimeout) * time.Second,
@@ -0,0 +1,55 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/client"
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/service/message/deduplication"
+	"github.com/sharovik/devbot/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlackService_ProcessMessageDuplicate(t *testing.T) {
+	var output bytes.Buffer
+	container.C = container.Main{
+		Config: config.Config{
+			MessagesAPIConfig: config.MessagesAPIConfig{
+				BotUserID: "U0LAN0Z89",
+				BotName:   "devbot",
+				Type:      config.MessagesAPITypeSlack,
+			},
+		},
+		MessageClient: client.NewConsoleClient(&output, "devbot", "", ""),
+		Dictionary: fakeDictionary{answers: map[string]string{
+			"Hello bot": "Hello human",
+		}},
+	}
+	deduplication.S = deduplication.NewMemoryStore(deduplication.DefaultTTL, deduplication.DefaultLimit)
+
+	body := test.FileToBytes(t, "../../../test/testdata/slack/events_api.message.im.json")
+
+	var message dto.SlackResponseEventAPIMessage
+	assert.NoError(t, json.Unmarshal(body, &message.Payload))
+	message.EnvelopeID = "envelope-1"
+	assert.NoError(t, SlackService{}.ProcessMessage(&message))
+
+	//The redelivered message has the new envelope, but the same event
+	var redelivered dto.SlackResponseEventAPIMessage
+	assert.NoError(t, json.Unmarshal(body, &redelivered.Payload))
+	redelivered.EnvelopeID = "envelope-2"
+	redelivered.RetryAttempt = 1
+	redelivered.RetryReason = "timeout"
+	assert.NoError(t, SlackService{}.ProcessMessage(&redelivered))
+
+	assert.Equal(t, "devbot in #D024BE91L: Hello human\n", output.String())
+}
+
+func TestPrefixedKey(t *testing.T) {
+	assert.Equal(t, "event:Ev0LAN670R", prefixedKey("event", "Ev0LAN670R"))
+	assert.Equal(t, "", prefixedKey("event", ""))
+}
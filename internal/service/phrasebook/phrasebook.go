@@ -0,0 +1,403 @@
+package phrasebook
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale the locale of the built-in phrasebook
+const DefaultLocale = "en"
+
+// The keys of the phrases. The phrases are the fmt formats, the translations should keep the same verbs in the same order
+const (
+	//Ok the answer, which is sent before the event execution
+	Ok = "ok"
+
+	//Stopped the answer to the stop phrase
+	Stopped = "stopped"
+
+	//EventStopped the answer to the stop phrase, which cancelled the running event
+	EventStopped = "event_stopped"
+
+	//UnknownQuestion the answer to the message, which doesn't match any question
+	UnknownQuestion = "unknown_question"
+
+	//Suggestions the answer to the message, which is similar to several questions. The argument is the list of the questions
+	Suggestions = "suggestions"
+
+	//QueueFull the answer, when the event cannot be queued for the execution
+	QueueFull = "queue_full"
+
+	//EventTimeout the answer, when the event took too much time. The argument is the event alias
+	EventTimeout = "event_timeout"
+
+	//EventPanic the answer, when the event failed unexpectedly. The arguments are the event alias and the reference ID
+	EventPanic = "event_panic"
+
+	//Confirmation the question, which confirms the answers of the scenario. The arguments are the event alias and the answers
+	Confirmation = "confirmation"
+
+	//Declined the answer to the declined confirmation
+	Declined = "declined"
+
+	//Answers the list of the collected answers. The argument is the list
+	Answers = "answers"
+
+	//NoAnswers the answer to the review, when there are no answers
+	NoAnswers = "no_answers"
+
+	//NoPreviousQuestion the answer to the back command on the first question
+	NoPreviousQuestion = "no_previous_question"
+
+	//UnknownAnswerNumber the answer to the change command with the wrong number. The argument is the number
+	UnknownAnswerNumber = "unknown_answer_number"
+
+	//Skipped the value of the skipped variable in the list of answers
+	Skipped = "skipped"
+
+	//SkipHint the hint of the optional variable. The argument is the skip answer
+	SkipHint = "skip_hint"
+
+	//Choices the hint of the enum variable. The argument is the list of choices
+	Choices = "choices"
+
+	//EmptyAnswer the explanation of the empty answer
+	EmptyAnswer = "empty_answer"
+
+	//InvalidInt the explanation of the wrong number. The argument is the answer
+	InvalidInt = "invalid_int"
+
+	//InvalidBool the explanation of the wrong yes or no answer. The argument is the answer
+	InvalidBool = "invalid_bool"
+
+	//InvalidEnum the explanation of the answer, which is not in the list. The arguments are the answer and the list of choices
+	InvalidEnum = "invalid_enum"
+
+	//InvalidDate the explanation of the wrong date. The argument is the answer
+	InvalidDate = "invalid_date"
+
+	//InvalidChannel the explanation of the wrong channel. The argument is the answer
+	InvalidChannel = "invalid_channel"
+
+	//InvalidUser the explanation of the wrong user. The argument is the answer
+	InvalidUser = "invalid_user"
+
+	//InvalidURL the explanation of the wrong link. The argument is the answer
+	InvalidURL = "invalid_url"
+
+	//InvalidPattern the explanation of the answer, which doesn't match the pattern. The arguments are the answer and the pattern
+	InvalidPattern = "invalid_pattern"
+)
+
+// The conversation control commands, which can be written instead of the answer to the scenario question. The words of the commands are taken from the phrasebook
+const (
+	//CommandBack asks the previous question again
+	CommandBack = "back"
+
+	//CommandChange asks again the question of the selected answer. The answers are numbered in the review
+	CommandChange = "change"
+
+	//CommandReview shows the answers collected so far
+	CommandReview = "review"
+)
+
+var verbRegex = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// Phrasebook the phrases of the bot in the selected locale. The missing phrases and answers are taken from the built-in English phrasebook
+type Phrasebook struct {
+	Locale string `yaml:"locale"`
+
+	//StopPhrases the phrases, which stop the open scenario. The message should contain only the phrase, optionally with the bot mention. The case is ignored
+	StopPhrases []string `yaml:"stop_phrases"`
+
+	//StopPatterns the regexes, which stop the open scenario. They are matched as they are written, so the anchors are up to you
+	StopPatterns []string `yaml:"stop_patterns"`
+
+	//Yes the positive answers to the yes or no questions
+	Yes []string `yaml:"yes"`
+
+	//No the negative answers to the yes or no questions
+	No []string `yaml:"no"`
+
+	//Skip the answers, which skip the optional variable. The first one is shown in the hint
+	Skip []string `yaml:"skip"`
+
+	//Back the commands, which ask the previous question again
+	Back []string `yaml:"back"`
+
+	//Change the commands, which ask again the question of the selected answer. The number of the answer follows the command, like `change 2`.
+	//The first one is shown in the confirmation question
+	Change []string `yaml:"change"`
+
+	//Review the commands, which show the collected answers. The first one is shown in the hints
+	Review []string `yaml:"review"`
+
+	//Phrases the system replies by their keys
+	Phrases map[string]string `yaml:"phrases"`
+
+	//File the path of the file, where the phrasebook is defined
+	File string `yaml:"-"`
+
+	stopRegexes  []*regexp.Regexp
+	controlRegex *regexp.Regexp
+}
+
+// Default retrieves the built-in English phrasebook
+func Default() *Phrasebook {
+	book := &Phrasebook{
+		Locale:      DefaultLocale,
+		StopPhrases: []string{"stop!", "stop scenario!", "exit", "stop", "cancel"},
+		Yes:         []string{"yes", "y", "true", "1", "ok", "sure"},
+		No:          []string{"no", "n", "false", "0", "nope"},
+		Skip:        []string{"skip"},
+		Back:        []string{"back"},
+		Change:      []string{"change"},
+		Review:      []string{"review"},
+		Phrases: map[string]string{
+			Ok:                  "Ok",
+			Stopped:             "Ok, no more questions!",
+			EventStopped:        "Ok, I stopped the running event.",
+			UnknownQuestion:     "Hmmm",
+			Suggestions:         "I'm not sure what you mean. Please, write one of these:\n%s",
+			QueueFull:           "I'm too busy right now. Please, try again a bit later.",
+			EventTimeout:        "Sorry, the `%s` event took too much time, so I stopped it. Please, try again later.",
+			EventPanic:          "Something went wrong during the `%s` event execution. Please, contact the administrator with the reference ID `%s`.",
+			Confirmation:        "Run `%s` with these values?\n%s\nPlease, answer yes or no. Write `%s <number>` to change the answer.",
+			Declined:            "Ok, I will not run it.",
+			Answers:             "Here are your answers:\n%s",
+			NoAnswers:           "There are no answers yet.",
+			NoPreviousQuestion:  "There is no previous question.",
+			UnknownAnswerNumber: "There is no answer number %d. Write `%s` to see the answers.",
+			Skipped:             "skipped",
+			SkipHint:            "Write `%s` to skip this question.",
+			Choices:             "Please, choose one of: %s.",
+			EmptyAnswer:         "The answer cannot be empty.",
+			InvalidInt:          "`%s` is not a number. Please, write the number, like `42`.",
+			InvalidBool:         "I didn't get `%s`. Please, answer yes or no.",
+			InvalidEnum:         "`%s` is not in the list. Please, choose one of: %s.",
+			InvalidDate:         "I cannot recognise the date `%s`. Please, write it like `2024-05-01 10:00`, `in 2 hours` or `monday at 10:00`.",
+			InvalidChannel:      "`%s` is not a channel. Please, mention the channel, like `#general`.",
+			InvalidUser:         "`%s` is not a user. Please, mention the user, like `@john`.",
+			InvalidURL:          "`%s` is not a link. Please, write the full link, like `https://example.com`.",
+			InvalidPattern:      "`%s` has the wrong format. The answer should match `%s`.",
+		},
+	}
+
+	_ = book.prepare()
+
+	return book
+}
+
+// Phrase retrieves the phrase by its key, formatted with the arguments. The key is returned for the unknown phrase
+func (b *Phrasebook) Phrase(key string, args ...interface{}) string {
+	phrase, ok := b.Phrases[key]
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return phrase
+	}
+
+	return fmt.Sprintf(phrase, args...)
+}
+
+// IsStop checks if the message stops the open scenario
+func (b *Phrasebook) IsStop(text string) bool {
+	for _, regex := range b.stopRegexes {
+		if regex.MatchString(text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsYes checks if the text is the positive answer
+func (b *Phrasebook) IsYes(text string) bool {
+	return contains(b.Yes, text)
+}
+
+// IsNo checks if the text is the negative answer
+func (b *Phrasebook) IsNo(text string) bool {
+	return contains(b.No, text)
+}
+
+// IsSkip checks if the text skips the optional variable
+func (b *Phrasebook) IsSkip(text string) bool {
+	return contains(b.Skip, text)
+}
+
+// SkipAnswer retrieves the answer, which is shown in the hint of the optional variable
+func (b *Phrasebook) SkipAnswer() string {
+	return first(b.Skip)
+}
+
+// ChangeCommand retrieves the change command, which is shown in the confirmation question
+func (b *Phrasebook) ChangeCommand() string {
+	return first(b.Change)
+}
+
+// ReviewCommand retrieves the review command, which is shown in the hints
+func (b *Phrasebook) ReviewCommand() string {
+	return first(b.Review)
+}
+
+// Command parses the conversation control command. Like the stop phrase, the message should contain only the command, optionally with the bot mention.
+// The number of the answer is retrieved for the change command. Empty command is returned, when the message is not the control command
+func (b *Phrasebook) Command(text string) (command string, number int) {
+	if b.controlRegex == nil {
+		return "", 0
+	}
+
+	matches := b.controlRegex.FindStringSubmatch(text)
+	switch {
+	case len(matches) == 0:
+		return "", 0
+	case matches[1] != "":
+		return CommandBack, 0
+	case matches[2] != "":
+		return CommandReview, 0
+	}
+
+	number, _ = strconv.Atoi(matches[3])
+
+	return CommandChange, number
+}
+
+// problems checks the phrasebook against the built-in one, which contains all known phrases
+func (b *Phrasebook) problems(base *Phrasebook) (problems []string) {
+	if strings.TrimSpace(b.Locale) == "" {
+		problems = append(problems, "the locale cannot be empty")
+	}
+
+	for key, phrase := range b.Phrases {
+		original, ok := base.Phrases[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("the phrase %q is unknown", key))
+			continue
+		}
+
+		if strings.Join(verbRegex.FindAllString(phrase, -1), " ") != strings.Join(verbRegex.FindAllString(original, -1), " ") {
+			problems = append(problems, fmt.Sprintf("the phrase %q should contain the same verbs as %q", key, original))
+		}
+	}
+
+	for _, command := range append(append(append([]string{}, b.Back...), b.Change...), b.Review...) {
+		if strings.TrimSpace(command) == "" {
+			problems = append(problems, "the control command cannot be empty")
+		}
+	}
+
+	for _, pattern := range b.StopPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("the stop pattern %q is invalid: %s", pattern, err))
+		}
+	}
+
+	return problems
+}
+
+// merge fills the missing phrases and answers from the base phrasebook
+func (b *Phrasebook) merge(base *Phrasebook) {
+	if len(b.StopPhrases) == 0 {
+		b.StopPhrases = base.StopPhrases
+	}
+
+	if len(b.StopPatterns) == 0 {
+		b.StopPatterns = base.StopPatterns
+	}
+
+	if len(b.Yes) == 0 {
+		b.Yes = base.Yes
+	}
+
+	if len(b.No) == 0 {
+		b.No = base.No
+	}
+
+	if len(b.Skip) == 0 {
+		b.Skip = base.Skip
+	}
+
+	if len(b.Back) == 0 {
+		b.Back = base.Back
+	}
+
+	if len(b.Change) == 0 {
+		b.Change = base.Change
+	}
+
+	if len(b.Review) == 0 {
+		b.Review = base.Review
+	}
+
+	phrases := make(map[string]string, len(base.Phrases))
+	for key, phrase := range base.Phrases {
+		phrases[key] = phrase
+	}
+
+	for key, phrase := range b.Phrases {
+		phrases[key] = phrase
+	}
+
+	b.Phrases = phrases
+}
+
+// prepare compiles the stop phrases, the stop patterns and the control commands
+func (b *Phrasebook) prepare() error {
+	b.stopRegexes = nil
+	if len(b.StopPhrases) > 0 {
+		//The message should contain only the stop phrase, so the answers like "exit code 1" don't stop the scenario
+		b.stopRegexes = append(b.stopRegexes, regexp.MustCompile(fmt.Sprintf(`(?i)^\s*(?:<@\w+>\s*)?(?:%s)\s*$`, alternatives(b.StopPhrases))))
+	}
+
+	b.controlRegex = nil
+	if len(b.Back) > 0 && len(b.Review) > 0 && len(b.Change) > 0 {
+		//The groups are the back command, the review command and the number of the change command
+		b.controlRegex = regexp.MustCompile(fmt.Sprintf(`(?i)^\s*(?:<@\w+>\s*)?(?:(%s)|(%s)|(?:%s)\s+(\d+))\s*$`,
+			alternatives(b.Back), alternatives(b.Review), alternatives(b.Change)))
+	}
+
+	for _, pattern := range b.StopPatterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+
+		b.stopRegexes = append(b.stopRegexes, regex)
+	}
+
+	return nil
+}
+
+// alternatives retrieves the regex, which matches any of the phrases as they are written
+func alternatives(phrases []string) string {
+	var quoted []string
+	for _, phrase := range phrases {
+		quoted = append(quoted, regexp.QuoteMeta(strings.TrimSpace(phrase)))
+	}
+
+	return strings.Join(quoted, "|")
+}
+
+func first(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+	return items[0]
+}
+
+func contains(items []string, text string) bool {
+	text = strings.TrimSpace(text)
+	for _, item := range items {
+		if strings.EqualFold(item, text) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,160 @@
+package phrasebook
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPhrasebooks = `phrasebooks:
+  - locale: de
+    stop_phrases: [stopp, abbrechen]
+    stop_patterns: ['(?i)^bitte aufhören']
+    "yes": [ja, j]
+    "no": [nein, n]
+    skip: [überspringen]
+    back: [zurück]
+    change: [ändern]
+    review: [übersicht]
+    phrases:
+      stopped: Ok, keine Fragen mehr!
+      invalid_enum: "%s ist nicht in der Liste. Bitte wähle eins von: %s."
+`
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func writePhrasebooks(t *testing.T, dir string, name string, content string) {
+	assert.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0o600))
+}
+
+func TestPhrasebook_Phrase(t *testing.T) {
+	book := Default()
+	assert.Equal(t, "Ok, no more questions!", book.Phrase(Stopped))
+	assert.Equal(t, "There is no answer number 5. Write `review` to see the answers.", book.Phrase(UnknownAnswerNumber, 5, book.ReviewCommand()))
+	assert.Equal(t, "unknown", book.Phrase("unknown"))
+	assert.Equal(t, "skip", book.SkipAnswer())
+	assert.True(t, book.IsYes(" Sure "))
+	assert.True(t, book.IsNo("NO"))
+	assert.False(t, book.IsYes("maybe"))
+}
+
+func TestPhrasebook_IsStop(t *testing.T) {
+	book := Default()
+	for _, text := range []string{"stop", "Stop!", " cancel ", "exit", "stop scenario!", "<@U0LAN0Z89> stop"} {
+		assert.True(t, book.IsStop(text), text)
+	}
+
+	for _, text := range []string{"exit code 1", "stop conversation <#C0LAN2Q65>", "stopped", ""} {
+		assert.False(t, book.IsStop(text), text)
+	}
+}
+
+func TestPhrasebook_Command(t *testing.T) {
+	book := Default()
+	for text, expected := range map[string]struct {
+		command string
+		number  int
+	}{
+		"back":                {CommandBack, 0},
+		" Review ":            {CommandReview, 0},
+		"<@U0LAN0Z89> back":   {CommandBack, 0},
+		"change 2":            {CommandChange, 2},
+		"CHANGE  10":          {CommandChange, 10},
+		"change":              {"", 0},
+		"change the branch":   {"", 0},
+		"go back to the list": {"", 0},
+		"":                    {"", 0},
+	} {
+		command, number := book.Command(text)
+		assert.Equal(t, expected.command, command, text)
+		assert.Equal(t, expected.number, number, text)
+	}
+}
+
+func TestInitS(t *testing.T) {
+	dir := t.TempDir()
+	writePhrasebooks(t, dir, "de.yaml", testPhrasebooks)
+	writePhrasebooks(t, dir, "readme.md", "not a phrasebook")
+
+	assert.NoError(t, InitS(config.PhrasebookConfig{
+		Path:    dir,
+		Locales: map[string]string{"C0LAN2Q65": "de", "U0LAN0Z89": "en", "U0LAN0Z90": "fr"},
+	}))
+
+	book := For("C0LAN2Q65", "U0LAN0Z91")
+	assert.Equal(t, "de", book.Locale)
+	assert.Equal(t, "Ok, keine Fragen mehr!", book.Phrase(Stopped))
+	assert.Equal(t, "a ist nicht in der Liste. Bitte wähle eins von: b.", book.Phrase(InvalidEnum, "a", "b"))
+	assert.True(t, book.IsStop("Stopp"))
+	assert.True(t, book.IsStop("bitte aufhören, danke"))
+	assert.False(t, book.IsStop("stop"))
+	assert.True(t, book.IsYes("ja"))
+	assert.Equal(t, "überspringen", book.SkipAnswer())
+	assert.Equal(t, "übersicht", book.ReviewCommand())
+
+	command, number := book.Command("Ändern 2")
+	assert.Equal(t, CommandChange, command)
+	assert.Equal(t, 2, number)
+
+	command, _ = book.Command("zurück")
+	assert.Equal(t, CommandBack, command)
+
+	//The commands of the locale replace the built-in ones
+	command, _ = book.Command("back")
+	assert.Empty(t, command)
+
+	//The missing phrases are taken from the built-in phrasebook
+	assert.Equal(t, "Ok, I will not run it.", book.Phrase(Declined))
+
+	//The locale of the user overrides the locale of the channel
+	assert.Equal(t, DefaultLocale, For("C0LAN2Q65", "U0LAN0Z89").Locale)
+
+	//The unknown locale falls back to the default one
+	assert.Equal(t, DefaultLocale, For("C0LAN2Q66", "U0LAN0Z90").Locale)
+
+	assert.NoError(t, InitS(config.PhrasebookConfig{Path: dir, DefaultLocale: "de"}))
+	assert.Equal(t, "de", For("C0LAN2Q66", "U0LAN0Z90").Locale)
+
+	//The invalid phrasebooks are not loaded
+	writePhrasebooks(t, dir, "fr.yaml", "phrasebooks:\n  - locale: fr\n    phrases:\n      stopped: \"%s\"\n")
+	assert.Error(t, InitS(config.PhrasebookConfig{Path: dir, DefaultLocale: "de"}))
+	assert.Equal(t, DefaultLocale, For("C0LAN2Q66", "U0LAN0Z90").Locale)
+
+	assert.NoError(t, InitS(config.PhrasebookConfig{}))
+	assert.Equal(t, DefaultLocale, For("C0LAN2Q65", "").Locale)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	//The typos in the field names are reported
+	writePhrasebooks(t, dir, "typo.yaml", "phrasebooks:\n  - locale: de\n    phrazes: {}\n")
+	_, err := Load(dir)
+	assert.Error(t, err)
+
+	_, err = Load(path.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate([]Phrasebook{{Locale: "de", Phrases: map[string]string{Skipped: "übersprungen"}}}))
+
+	for name, book := range map[string]Phrasebook{
+		"empty locale":   {},
+		"unknown phrase": {Locale: "de", Phrases: map[string]string{"hello": "Hallo"}},
+		"missing verb":   {Locale: "de", Phrases: map[string]string{EventPanic: "Fehler im `%s` Event"}},
+		"wrong verb":     {Locale: "de", Phrases: map[string]string{UnknownAnswerNumber: "Keine Antwort %s"}},
+		"invalid regex":  {Locale: "de", StopPatterns: []string{"(?i"}},
+		"empty command":  {Locale: "de", Review: []string{" "}},
+	} {
+		assert.Error(t, Validate([]Phrasebook{book}), name)
+	}
+
+	assert.Error(t, Validate([]Phrasebook{{Locale: "de"}, {Locale: "de"}}))
+}
@@ -0,0 +1,162 @@
+package phrasebook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/log"
+	"gopkg.in/yaml.v3"
+)
+
+// file the structure of the phrasebooks file
+type file struct {
+	Phrasebooks []Phrasebook `yaml:"phrasebooks"`
+}
+
+// Service the service of the loaded phrasebooks
+type Service struct {
+	books         map[string]*Phrasebook
+	defaultLocale string
+	locales       map[string]string
+}
+
+// S the initialised service. Until the initialisation only the built-in phrasebook is used
+var S = Service{books: map[string]*Phrasebook{DefaultLocale: Default()}, defaultLocale: DefaultLocale}
+
+// InitS loads the phrasebooks from the path and selects the locales of the channels and users.
+// The invalid phrasebooks are not loaded at all, so the built-in phrasebook is used instead of them
+func InitS(cfg config.PhrasebookConfig) error {
+	S = Service{
+		books:         map[string]*Phrasebook{DefaultLocale: Default()},
+		defaultLocale: DefaultLocale,
+		locales:       cfg.Locales,
+	}
+
+	if cfg.DefaultLocale != "" {
+		S.defaultLocale = cfg.DefaultLocale
+	}
+
+	if cfg.Path == "" {
+		return nil
+	}
+
+	books, err := Load(cfg.Path)
+	if err != nil {
+		return err
+	}
+
+	if err = Validate(books); err != nil {
+		return err
+	}
+
+	base := S.books[DefaultLocale]
+	for i := range books {
+		book := books[i]
+		book.merge(base)
+		if err = book.prepare(); err != nil {
+			return fmt.Errorf("%s: phrasebook %q: %w", book.File, book.Locale, err)
+		}
+
+		S.books[book.Locale] = &book
+	}
+
+	for id, locale := range S.locales {
+		if S.books[locale] == nil {
+			log.Logger().Warn().Str("id", id).Str("locale", locale).Msg("The phrasebook of the selected locale is not loaded. The default locale will be used")
+		}
+	}
+
+	log.Logger().Info().Str("path", cfg.Path).Int("phrasebooks", len(books)).Msg("Phrasebooks loaded")
+
+	return nil
+}
+
+// Load reads the phrasebooks from the YAML file or from all YAML files of the directory
+func Load(path string) (books []Phrasebook, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files = nil
+		for _, entry := range entries {
+			extension := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (extension == ".yaml" || extension == ".yml") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		//The unknown fields are rejected, so the typos in the phrasebooks are not ignored
+		var parsed file
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(&parsed); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+
+		for _, book := range parsed.Phrasebooks {
+			book.File = f
+			books = append(books, book)
+		}
+	}
+
+	return books, nil
+}
+
+// Validate checks the phrasebooks and returns all found problems
+func Validate(books []Phrasebook) error {
+	var (
+		errs    []error
+		base    = Default()
+		locales = map[string]bool{}
+	)
+	for _, book := range books {
+		for _, problem := range book.problems(base) {
+			errs = append(errs, fmt.Errorf("%s: phrasebook %q: %s", book.File, book.Locale, problem))
+		}
+
+		if locales[book.Locale] {
+			errs = append(errs, fmt.Errorf("%s: phrasebook %q: the locale is defined twice", book.File, book.Locale))
+		}
+
+		locales[book.Locale] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+// For retrieves the phrasebook of the user in the channel. The locale of the user overrides the locale of the channel.
+// The phrasebook of the default locale is used, when the selected locale is not loaded
+func For(channel string, user string) *Phrasebook {
+	for _, id := range []string{user, channel} {
+		if book, ok := S.books[S.locales[id]]; ok && id != "" {
+			return book
+		}
+	}
+
+	if book, ok := S.books[S.defaultLocale]; ok {
+		return book
+	}
+
+	return S.books[DefaultLocale]
+}
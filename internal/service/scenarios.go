@@ -127,6 +127,11 @@ func PrepareScenario(scenarioID int64, reactionType string) (scenario database.E
 		return scenario, err
 	}
 
+	scenario.Confirmation, err = container.C.Dictionary.IsScenarioConfirmationRequired(scenario.ID)
+	if err != nil {
+		return scenario, err
+	}
+
 	scenario.EventName = reactionType
 	scenario.EventID = eventID
 	analiser.SetScenarioQuestions(&scenario, questions)
@@ -167,6 +172,10 @@ func PrepareEventScenario(eventID int64, reactionType string) (scenario database
 	}
 
 	scenario.ID = int64(res.Items()[0].GetField("id").Value.(int))
+	if confirmation, ok := res.Items()[0].GetField("confirmation").Value.(int); ok {
+		scenario.Confirmation = confirmation == 1
+	}
+
 	variables, err := container.C.Dictionary.GetQuestionsByScenarioID(scenario.ID, true)
 	if err != nil {
 		return scenario, err
@@ -0,0 +1,261 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The limit of the next run search. The expression like `0 0 30 2 *` never matches, so we stop searching after this period
+const cronSearchYears = 5
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	cronWeekdayNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronMinute  = cronField{name: "minute", min: 0, max: 59}
+	cronHour    = cronField{name: "hour", min: 0, max: 23}
+	cronDay     = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth   = cronField{name: "month", min: 1, max: 12, names: cronMonthNames}
+	cronWeekday = cronField{name: "day of week", min: 0, max: 7, names: cronWeekdayNames}
+)
+
+// Cron the parsed cron expression. The standard 5 fields are supported: minute, hour, day of month, month and day of week.
+// The day of week also supports the `weekday#N` syntax, which means the N-th weekday of the month, like `1#1` for the first Monday
+type Cron struct {
+	Expression string
+
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+
+	//nthWeekdays the bits of the weekday occurrences in the month by the weekday
+	nthWeekdays [7]uint8
+
+	//When both days of month and weekdays are restricted, the day matches any of them
+	anyDay     bool
+	anyWeekday bool
+}
+
+// ParseCron parses the 5-field cron expression or one of the macros, like `@daily`
+func ParseCron(expression string) (Cron, error) {
+	expression = strings.TrimSpace(expression)
+	cron := Cron{Expression: expression}
+
+	if macro, ok := cronMacros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return Cron{}, fmt.Errorf("the cron expression `%s` should contain 5 fields: minute, hour, day of month, month and day of week", cron.Expression)
+	}
+
+	var err error
+	if cron.minutes, err = cronMinute.parse(fields[0]); err != nil {
+		return Cron{}, err
+	}
+
+	if cron.hours, err = cronHour.parse(fields[1]); err != nil {
+		return Cron{}, err
+	}
+
+	if cron.days, err = cronDay.parse(fields[2]); err != nil {
+		return Cron{}, err
+	}
+
+	if cron.months, err = cronMonth.parse(fields[3]); err != nil {
+		return Cron{}, err
+	}
+
+	if err = cron.parseWeekdays(fields[4]); err != nil {
+		return Cron{}, err
+	}
+
+	cron.anyDay = strings.HasPrefix(fields[2], "*")
+	cron.anyWeekday = strings.HasPrefix(fields[4], "*")
+
+	return cron, nil
+}
+
+// IsEmpty checks if the cron expression is not set
+func (c Cron) IsEmpty() bool {
+	return c.Expression == ""
+}
+
+// Next retrieves the first time after t, which matches the cron expression. The zero time is returned, when there is no such time
+func (c Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if c.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if c.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if c.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c Cron) matchesDay(t time.Time) bool {
+	day := c.days&(1<<uint(t.Day())) != 0
+	weekday := c.weekdays&(1<<uint(t.Weekday())) != 0 || c.nthWeekdays[t.Weekday()]&(1<<uint((t.Day()-1)/7)) != 0
+
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+func (c *Cron) parseWeekdays(text string) error {
+	var ranges []string
+	for _, item := range strings.Split(text, ",") {
+		weekday, nth, found := strings.Cut(item, "#")
+		if !found {
+			ranges = append(ranges, item)
+			continue
+		}
+
+		day, err := cronWeekday.value(weekday)
+		if err != nil {
+			return err
+		}
+
+		number, err := strconv.Atoi(nth)
+		if err != nil || number < 1 || number > 5 {
+			return fmt.Errorf("the weekday occurrence `%s` should be from 1 to 5", nth)
+		}
+
+		c.nthWeekdays[day%7] |= 1 << uint(number-1)
+	}
+
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	weekdays, err := cronWeekday.parse(strings.Join(ranges, ","))
+	if err != nil {
+		return err
+	}
+
+	//Both 0 and 7 mean Sunday
+	if weekdays&(1<<7) != 0 {
+		weekdays |= 1
+	}
+
+	c.weekdays = weekdays
+
+	return nil
+}
+
+// parse converts the field, like `*/15`, `1-5` or `mon,wed`, to the bits of the allowed values
+func (f cronField) parse(text string) (bits uint64, err error) {
+	for _, item := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("the step `%s` of the %s should be the positive number", stepText, f.name)
+			}
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case rangeText == "*":
+		case strings.Contains(rangeText, "-"):
+			from, to, _ := strings.Cut(rangeText, "-")
+			if start, err = f.value(from); err != nil {
+				return 0, err
+			}
+
+			if end, err = f.value(to); err != nil {
+				return 0, err
+			}
+
+			if start > end {
+				return 0, fmt.Errorf("the range `%s` of the %s should go from the lower value to the higher one", rangeText, f.name)
+			}
+		default:
+			if start, err = f.value(rangeText); err != nil {
+				return 0, err
+			}
+
+			//The single value with the step, like `5/15`, runs till the end of the range
+			if !hasStep {
+				end = start
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+
+	return bits, nil
+}
+
+func (f cronField) value(text string) (int, error) {
+	if value, ok := f.names[strings.ToLower(text)]; ok {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(text)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("the %s `%s` should be from %d to %d", f.name, text, f.min, f.max)
+	}
+
+	return value, nil
+}
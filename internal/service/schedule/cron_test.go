@@ -0,0 +1,53 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, expression := range []string{"* * * * *", "30 9 * * 1-5", "*/15 0-6/2 1,15 jan-jun SUN", "0 10 * * mon#1,fri#5", "0 0 * * 7", "@daily", "@Weekly"} {
+		cron, err := ParseCron(expression)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expression, cron.Expression)
+	}
+
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * * 1#6", "* * * foo *", "@often"} {
+		_, err := ParseCron(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	//2024-05-01 is Wednesday
+	from := time.Date(2024, 5, 1, 10, 15, 30, 0, time.UTC)
+
+	cases := map[string]string{
+		"* * * * *":        "2024-05-01 10:16",
+		"*/20 * * * *":     "2024-05-01 10:20",
+		"30 9 * * 1-5":     "2024-05-02 09:30",
+		"30 9 * * sat,0":   "2024-05-04 09:30",
+		"0 10 * * 1#1":     "2024-05-06 10:00",
+		"0 10 * * fri#5":   "2024-05-31 10:00",
+		"0 0 1 * *":        "2024-06-01 00:00",
+		"0 0 13 * 5":       "2024-05-03 00:00",
+		"0 0 29 2 *":       "2028-02-29 00:00",
+		"@hourly":          "2024-05-01 11:00",
+		"@yearly":          "2025-01-01 00:00",
+		"15 10 1 5 *":      "2025-05-01 10:15",
+		"0 12 * dec *":     "2024-12-01 12:00",
+		"5/20 10,11 * * *": "2024-05-01 10:25",
+	}
+
+	for expression, expected := range cases {
+		cron, err := ParseCron(expression)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, cron.Next(from).Format(timeFormat), expression)
+	}
+
+	cron, err := ParseCron("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, cron.Next(from).IsZero())
+}
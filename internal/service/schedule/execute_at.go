@@ -2,7 +2,6 @@ package schedule
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
@@ -12,35 +11,15 @@ import (
 )
 
 const (
-	//DatetimeRegexp regexp for datetime parsing
-	DatetimeRegexp = `(?im)(\d+-\d+-\d+ \d+:\d+)`
-
-	//MinuteRegexp regexp for minutes parsing
-	MinuteRegexp = `(?im)((\d+) minute|minutes)`
-
-	//HourRegexp regexp for hours parsing
-	HourRegexp = `(?im)((\d+) hour|hours)`
-
-	//DayRegexp regexp for hours parsing
-	DayRegexp = `(?im)((\d+) day|days)`
-
 	repeatableRegexp  = `(?im)(?:^|\s)(repeat|every)\s`
 	delayedTimeRegexp = `(?im)(?:^|\s)(in|after)\s`
-	exactTimeRegexp   = `(?im)(\d+):(\d+)`
+
+	//The cron expression is quoted, because it contains the spaces. The chat clients may replace the quotes with the typographic ones
+	cronRegexp = `(?i)(?:^|\s)cron\s+(?:["“”]([^"“”]+)["“”]|(@\w+))`
 
 	timeFormat = "2006-01-02 15:04"
 )
 
-var daysOfWeek = map[string]time.Weekday{
-	"sunday":    time.Sunday,
-	"monday":    time.Monday,
-	"tuesday":   time.Tuesday,
-	"wednesday": time.Wednesday,
-	"thursday":  time.Thursday,
-	"friday":    time.Friday,
-	"saturday":  time.Saturday,
-}
-
 type ExecuteAt struct {
 	Days          int64
 	Minutes       int64
@@ -50,34 +29,46 @@ type ExecuteAt struct {
 	IsDelayed     bool
 	ExactDatetime time.Time
 	IsExactHours  bool
+
+	//Cron the cron expression. When it is set, the other fields are ignored
+	Cron Cron
+
+	//Location the time zone, in which the time is parsed and calculated. The application time zone is used, when it is not set
+	Location *time.Location
 }
 
-func (e *ExecuteAt) parseExactTime(text string) error {
-	res := helper.FindMatches(exactTimeRegexp, text)
-	if len(res) == 0 {
-		return nil
-	}
+// In selects the time zone, in which the time is parsed and calculated
+func (e *ExecuteAt) In(location *time.Location) *ExecuteAt {
+	e.Location = location
 
-	hour, err := strconv.Atoi(res["1"])
-	if err != nil {
-		return err
-	}
+	return e
+}
 
-	minute, err := strconv.Atoi(res["2"])
-	if err != nil {
-		return err
+// timezone retrieves the name of the time zone to store it. The nil is returned, when the application time zone is used
+func (e *ExecuteAt) timezone() interface{} {
+	if e.Location == nil {
+		return nil
 	}
 
-	e.Hours = int64(hour)
-	e.Minutes = int64(minute)
+	return e.Location.String()
+}
 
-	e.IsExactHours = true
+// now retrieves the current time in the time zone of the ExecuteAt
+func (e *ExecuteAt) now() time.Time {
+	if e.Location == nil {
+		return _time.Service.Now()
+	}
 
-	return nil
+	return _time.Service.Now().In(e.Location)
 }
 
 func (e *ExecuteAt) getDatetime() time.Time {
-	t := _time.Service.Now()
+	t := e.now()
+
+	//The current minute is included, so the schedule is triggered during the matched minute
+	if !e.Cron.IsEmpty() {
+		return e.Cron.Next(t.Add(-time.Minute))
+	}
 
 	if e.Days != 0 || e.Minutes != 0 || e.Hours != 0 {
 		hours := t.Hour()
@@ -101,6 +92,52 @@ func (e *ExecuteAt) getDatetime() time.Time {
 	return e.ExactDatetime
 }
 
+// nextAfter retrieves the first time of the repeatable schedule, which is after the selected time. The interval, like `every 5 minutes`,
+// is counted from that time. The schedule with the weekday or the time of the day, like `every monday at 9:10`, is triggered at that time of the matching days.
+// The zero time is returned, when there is no next time
+func (e *ExecuteAt) nextAfter(after time.Time) time.Time {
+	if e.Location != nil {
+		after = after.In(e.Location)
+	}
+
+	if !e.Cron.IsEmpty() {
+		return e.Cron.Next(after)
+	}
+
+	after = truncateMinute(after)
+	if e.isInterval() {
+		interval := time.Duration(e.Hours)*time.Hour + time.Duration(e.Minutes)*time.Minute
+		if e.Days == 0 && interval == 0 {
+			return time.Time{}
+		}
+
+		return after.AddDate(0, 0, int(e.Days)).Add(interval)
+	}
+
+	//The weekday without the time of the day is triggered at the same time of the day, as the previous run
+	hour, minute := after.Hour(), after.Minute()
+	if e.IsExactHours {
+		hour, minute = int(e.Hours), int(e.Minutes)
+	}
+
+	next := time.Date(after.Year(), after.Month(), after.Day(), hour, minute, 0, 0, after.Location())
+	for !next.After(after) || (e.Weekday != nil && next.Weekday() != e.Weekday.(time.Weekday)) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	//The days, like `every 2 days at 10:00`, are counted from the previous run
+	if e.Weekday == nil && e.Days > 1 && after.Hour() == hour && after.Minute() == minute {
+		next = next.AddDate(0, 0, int(e.Days)-1)
+	}
+
+	return next
+}
+
+// isInterval checks if the repeatable time is the interval, like `every 5 minutes`, which is counted from the previous run
+func (e *ExecuteAt) isInterval() bool {
+	return e.IsRepeatable && e.Cron.IsEmpty() && !e.IsExactHours && e.Weekday == nil
+}
+
 func (e *ExecuteAt) generateDays(now time.Time) int {
 	if e.Weekday == nil {
 		days := now.Day()
@@ -121,7 +158,17 @@ func (e *ExecuteAt) generateDays(now time.Time) int {
 }
 
 func (e *ExecuteAt) IsEmpty() bool {
-	return e.Days == 0 && e.Hours == 0 && e.Minutes == 0 && e.ExactDatetime.IsZero()
+	return e.Cron.IsEmpty() && e.Days == 0 && e.Hours == 0 && e.Minutes == 0 && e.ExactDatetime.IsZero()
+}
+
+// Datetime returns the nearest time, which is described by the ExecuteAt
+func (e *ExecuteAt) Datetime() time.Time {
+	return e.getDatetime()
+}
+
+// String returns the text, which describes the ExecuteAt
+func (e *ExecuteAt) String() string {
+	return e.toString()
 }
 
 func (e *ExecuteAt) toString() string {
@@ -129,6 +176,10 @@ func (e *ExecuteAt) toString() string {
 		return ""
 	}
 
+	if !e.Cron.IsEmpty() {
+		return fmt.Sprintf("cron \"%s\"", e.Cron.Expression)
+	}
+
 	if !e.ExactDatetime.IsZero() && !e.IsRepeatable {
 		return e.ExactDatetime.Format(timeFormat)
 	}
@@ -166,117 +217,40 @@ func (e *ExecuteAt) toString() string {
 	return fmt.Sprintf("%s%s", result, strings.Join(res, " and "))
 }
 
-func (e *ExecuteAt) parseDateTime(text string) error {
-	res := helper.FindMatches(DatetimeRegexp, text)
-	if res["1"] == "" {
-		return nil
-	}
-
-	result, err := time.ParseInLocation(timeFormat, text, _time.Service.TimeZone)
-	if err != nil {
-		return err
-	}
+func (e *ExecuteAt) isRepeatable(text string) bool {
+	res := helper.FindMatches(repeatableRegexp, text)
 
-	e.ExactDatetime = result
-	return nil
+	return res["1"] != ""
 }
 
-func (e *ExecuteAt) parse(text string, regex string) (result interface{}, err error) {
-	res := helper.FindMatches(regex, text)
-	if res["2"] == "" {
-		return nil, nil
-	}
+func (e *ExecuteAt) isDelayed(text string) bool {
+	res := helper.FindMatches(delayedTimeRegexp, text)
 
-	return strconv.Atoi(res["2"])
+	return res["1"] != ""
 }
 
-func (e *ExecuteAt) parseDays(text string) error {
-	res := helper.FindMatches(DayRegexp, text)
-	if res["2"] == "" {
+func (e *ExecuteAt) parseCron(text string) error {
+	res := helper.FindMatches(cronRegexp, text)
+	expression := res["1"] + res["2"]
+	if expression == "" {
 		return nil
 	}
 
-	days, err := strconv.Atoi(res["2"])
+	cron, err := ParseCron(expression)
 	if err != nil {
 		return err
 	}
 
-	e.Days = int64(days)
-
-	return nil
-}
-
-func (e *ExecuteAt) parseWeekday(text string) error {
-	var days []string
-	e.Weekday = nil
-	for dayName := range daysOfWeek {
-		days = append(days, dayName)
-	}
-
-	regexStr := fmt.Sprintf("(?i)(%s)", strings.Join(days, "|"))
-	res := helper.FindMatches(regexStr, text)
-	if res["1"] == "" {
-		return nil
-	}
-
-	dayName := strings.ToLower(res["1"])
-
-	e.Weekday = daysOfWeek[dayName]
-
-	return nil
-}
-
-func (e *ExecuteAt) parseHoursAndMinutes(text string) error {
-	var (
-		hours   interface{}
-		minutes interface{}
-		err     error
-	)
-
-	if hours, err = e.parse(text, HourRegexp); err != nil {
-		return err
-	}
-
-	if minutes, err = e.parse(text, MinuteRegexp); err != nil {
-		return err
-	}
-
-	if hours == nil && minutes == nil {
-		return nil
-	}
-
-	//When we receive only hours but not minutes, we convert hours as minutes
-	if hours != nil && minutes == nil {
-		e.Minutes = int64(hours.(int) * 60)
-
-		return nil
-	}
-
-	if hours == nil {
-		e.Hours = 0
-	} else {
-		e.Hours = int64(hours.(int))
-	}
-
-	e.Minutes = int64(minutes.(int))
+	e.Cron = cron
+	e.IsRepeatable = true
 
 	return nil
 }
 
-func (e *ExecuteAt) isRepeatable(text string) bool {
-	res := helper.FindMatches(repeatableRegexp, text)
-
-	return res["1"] != ""
-}
-
-func (e *ExecuteAt) isDelayed(text string) bool {
-	res := helper.FindMatches(delayedTimeRegexp, text)
-
-	return res["1"] != ""
-}
-
+// FromString parses the time from the text, like `tomorrow at 10am`, `next friday 17:00`, `in 1h30m`, `on november 2`,
+// `every monday at 9:10` or `cron "30 9 * * 1-5"`. The time, which is not repeatable, is resolved to the exact datetime
 func (e *ExecuteAt) FromString(text string) (ExecuteAt, error) {
-	if err := e.parseDateTime(text); err != nil {
+	if err := e.parseCron(text); err != nil {
 		return ExecuteAt{}, err
 	}
 
@@ -284,39 +258,41 @@ func (e *ExecuteAt) FromString(text string) (ExecuteAt, error) {
 		return *e, nil
 	}
 
-	if e.isRepeatable(text) {
-		e.IsRepeatable = true
-	}
-
-	if e.isDelayed(text) {
-		e.IsDelayed = true
-	}
-
-	if err := e.parseHoursAndMinutes(text); err != nil {
+	parsed, err := parseNaturalTime(text)
+	if err != nil {
 		return ExecuteAt{}, err
 	}
 
-	if err := e.parseWeekday(text); err != nil {
-		return ExecuteAt{}, err
+	if parsed.isEmpty() {
+		return *e, nil
 	}
 
-	if err := e.parseDays(text); err != nil {
-		return ExecuteAt{}, err
+	e.IsDelayed = e.isDelayed(text)
+	if !e.isRepeatable(text) {
+		e.ExactDatetime = parsed.resolve(e.now())
+		return *e, nil
 	}
 
-	if err := e.parseExactTime(text); err != nil {
-		return ExecuteAt{}, err
+	//The repeatable time keeps the relative parts, so its next time is calculated again after each execution
+	e.IsRepeatable = true
+	e.Days = int64(parsed.days)
+	e.Hours = int64(parsed.duration / time.Hour)
+	e.Minutes = int64(parsed.duration % time.Hour / time.Minute)
+	if parsed.hasWeekday {
+		e.Weekday = parsed.weekday
 	}
 
-	if e.IsDelayed || e.IsRepeatable {
-		e.generateDelayedDate()
+	if parsed.hasClock {
+		e.Hours, e.Minutes, e.IsExactHours = int64(parsed.hour), int64(parsed.minute), true
 	}
 
+	e.generateDelayedDate()
+
 	return *e, nil
 }
 
 func (e *ExecuteAt) generateDelayedDate() {
-	t := _time.Service.Now()
+	t := e.now()
 	days := t.Day()
 	if e.Days != 0 {
 		days += int(e.Days)
@@ -13,6 +13,22 @@ func init() {
 	_time.InitNOW(time.UTC)
 }
 
+// freezeTime replaces the clock of the time service by the fake one, which always returns the same time
+func freezeTime(t *testing.T, now string) time.Time {
+	frozen, err := time.ParseInLocation(timeFormat, now, _time.Service.TimeZone)
+	assert.NoError(t, err)
+
+	_time.Service.Clock = func() time.Time {
+		return frozen
+	}
+
+	t.Cleanup(func() {
+		_time.Service.Clock = nil
+	})
+
+	return frozen
+}
+
 func TestExecuteAt_IsEmpty(t *testing.T) {
 	cases := []ExecuteAt{
 		{
@@ -35,47 +51,37 @@ func TestExecuteAt_IsEmpty(t *testing.T) {
 
 func TestExecuteAt_parseDays(t *testing.T) {
 	var (
-		cases = map[string]int64{
-			"in 2 days":   int64(2),
-			"after 1 day": int64(1),
-			"every day":   int64(0),
+		cases = map[string]int{
+			"in 2 days":   2,
+			"after 1 day": 1,
+			"every day":   0,
 		}
 	)
 
 	for text, expected := range cases {
-		e := ExecuteAt{}
-		err := e.parseDays(text)
+		actual, err := parseNaturalTime(text)
 		assert.NoError(t, err)
-		assert.Equal(t, expected, e.Days)
+		assert.Equal(t, expected, actual.days, text)
 	}
 }
 
 func TestExecuteAt_parse(t *testing.T) {
 	var (
-		cases = map[string]interface{}{
-			"in 2 hours":   2,
-			"after 1 hour": 1,
-			"every hour":   nil,
+		cases = map[string]time.Duration{
+			"in 2 hours":    2 * time.Hour,
+			"after 1 hour":  time.Hour,
+			"every hour":    0,
+			"in few hours":  0,
+			"in 20 minutes": 20 * time.Minute,
+			"every minute":  0,
 		}
 	)
 
 	for text, expected := range cases {
-		e := ExecuteAt{}
-		hours, err := e.parse(text, HourRegexp)
+		actual, err := parseNaturalTime(text)
 		assert.NoError(t, err)
-		assert.Equal(t, expected, hours)
-	}
-
-	cases = map[string]interface{}{
-		"in 20 minutes": 20,
-		"every minute":  nil,
-	}
-
-	for text, expected := range cases {
-		e := ExecuteAt{}
-		minutes, err := e.parse(text, MinuteRegexp)
-		assert.NoError(t, err)
-		assert.Equal(t, expected, minutes)
+		assert.Equal(t, expected, actual.duration, text)
+		assert.Equal(t, expected != 0, actual.hasDuration, text)
 	}
 }
 
@@ -114,7 +120,7 @@ func TestExecuteAt_getDatetime(t *testing.T) {
 	var (
 		actual       ExecuteAt
 		expectedDate time.Time
-		ct           = _time.Service.Now()
+		ct           = freezeTime(t, "2024-05-01 12:30")
 		err          error
 	)
 
@@ -170,7 +176,7 @@ func TestExecuteAt_ParseDays(t *testing.T) {
 	var (
 		actual       ExecuteAt
 		expectedDate time.Time
-		ct           = _time.Service.Now()
+		ct           = freezeTime(t, "2024-05-01 12:30")
 		err          error
 	)
 
@@ -182,7 +188,8 @@ func TestExecuteAt_ParseDays(t *testing.T) {
 	assert.NoError(t, err)
 	expectedDate = time.Date(ct.Year(), ct.Month(), d, 10, 00, 0, 0, ct.Location())
 	assert.Equal(t, expectedDate.Format(timeFormat), actual.getDatetime().Format(timeFormat))
-	assert.Equal(t, "Sunday and at 10:0", actual.toString())
+	//The time, which is not repeatable, is resolved to the exact datetime
+	assert.Equal(t, "2024-05-05 10:00", actual.toString())
 
 	actual, err = new(ExecuteAt).FromString("every monday at 9:10")
 	assert.NoError(t, err)
@@ -194,3 +201,206 @@ func TestExecuteAt_ParseDays(t *testing.T) {
 	assert.Equal(t, expectedDate.Format(timeFormat), actual.getDatetime().Format(timeFormat))
 	assert.Equal(t, "repeat Monday and at 9:10", actual.toString())
 }
+
+func TestExecuteAt_parseCron(t *testing.T) {
+	actual, err := new(ExecuteAt).FromString(`schedule event examplescenario cron "30 9 * * 1-5"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "30 9 * * 1-5", actual.Cron.Expression)
+	assert.True(t, actual.IsRepeatable)
+	assert.False(t, actual.IsEmpty())
+	assert.Equal(t, `cron "30 9 * * 1-5"`, actual.toString())
+
+	actual, err = new(ExecuteAt).FromString("schedule event examplescenario cron @hourly")
+	assert.NoError(t, err)
+	assert.Equal(t, "@hourly", actual.Cron.Expression)
+
+	//The current minute is included
+	ct := _time.Service.Now()
+	actual, err = new(ExecuteAt).FromString("cron “* * * * *”")
+	assert.NoError(t, err)
+	assert.Equal(t, ct.Format(timeFormat), actual.getDatetime().Format(timeFormat))
+
+	_, err = new(ExecuteAt).FromString(`cron "61 * * * *"`)
+	assert.Error(t, err)
+}
+
+func TestExecuteAt_FromString(t *testing.T) {
+	//It is Wednesday
+	freezeTime(t, "2024-05-01 12:30")
+
+	cases := map[string]string{
+		"tomorrow":                              "2024-05-02 12:30",
+		"tomorrow at 10":                        "2024-05-02 10:00",
+		"Tomorrow at 10am":                      "2024-05-02 10:00",
+		"today at 5pm":                          "2024-05-01 17:00",
+		"today at 9":                            "2024-05-01 09:00",
+		"at 9:00":                               "2024-05-02 09:00",
+		"at 13:15":                              "2024-05-01 13:15",
+		"at 12am":                               "2024-05-02 00:00",
+		"at 12 p.m.":                            "2024-05-02 12:00",
+		"at midnight":                           "2024-05-02 00:00",
+		"day after tomorrow at noon":            "2024-05-03 12:00",
+		"next friday 17:00":                     "2024-05-03 17:00",
+		"friday at 5 pm":                        "2024-05-03 17:00",
+		"fri 8:30am":                            "2024-05-03 08:30",
+		"next wednesday at 9":                   "2024-05-08 09:00",
+		"wednesday at 11:00":                    "2024-05-08 11:00",
+		"wednesday at 14:00":                    "2024-05-01 14:00",
+		"next week":                             "2024-05-08 12:30",
+		"in 1h30m":                              "2024-05-01 14:00",
+		"in 1h 15m":                             "2024-05-01 13:45",
+		"in 2d":                                 "2024-05-03 12:30",
+		"in 2 days and 3 hours":                 "2024-05-03 15:30",
+		"in 1 hour and 2 minutes":               "2024-05-01 13:32",
+		"in an hour":                            "2024-05-01 13:30",
+		"in half an hour":                       "2024-05-01 13:00",
+		"after 90 min":                          "2024-05-01 14:00",
+		"in 1 week":                             "2024-05-08 12:30",
+		"in 2 days at 10am":                     "2024-05-03 10:00",
+		"in 1 day at 10:00":                     "2024-05-02 10:00",
+		"on 2026-11-02":                         "2026-11-02 12:30",
+		"on 2026-11-02 at 9:15am":               "2026-11-02 09:15",
+		"2022-12-18 11:22":                      "2022-12-18 11:22",
+		"november 2":                            "2024-11-02 12:30",
+		"on Nov 2nd 2026 at 6pm":                "2026-11-02 18:00",
+		"2nd of November at 8:05 a.m.":          "2024-11-02 08:05",
+		"april 30":                              "2025-04-30 12:30",
+		"feb 29 at 10":                          "2028-02-29 10:00",
+		"schedule event deploy2 tomorrow at 10": "2024-05-02 10:00",
+		"schedule event examplescenario in 5 minutes":                 "2024-05-01 12:35",
+		"<@U02ABC12D> schedule event deploy tomorrow at 10":           "2024-05-02 10:00",
+		"<@U02ABC1H> schedule event examplescenario in 5 minutes":     "2024-05-01 12:35",
+		"<#C01WED5M|general> schedule event deploy at 13:15":          "2024-05-01 13:15",
+		"schedule event deploy2d in 1h see <https://example.com/30m>": "2024-05-01 13:30",
+	}
+
+	for text, expected := range cases {
+		actual, err := new(ExecuteAt).FromString(text)
+		assert.NoError(t, err, text)
+		assert.False(t, actual.IsRepeatable, text)
+		assert.Equal(t, expected, actual.Datetime().Format(timeFormat), text)
+
+		//The stored time is parsed to the same datetime
+		stored, err := new(ExecuteAt).FromString(actual.toString())
+		assert.NoError(t, err, text)
+		assert.Equal(t, expected, stored.Datetime().Format(timeFormat), text)
+	}
+
+	for _, text := range []string{"every hours", "in few hours", "schedule event examplescenario", ""} {
+		actual, err := new(ExecuteAt).FromString(text)
+		assert.NoError(t, err, text)
+		assert.True(t, actual.IsEmpty(), text)
+	}
+
+	for _, text := range []string{"at 25:00", "at 10:75", "at 13pm", "on 2026-02-30", "february 30", "2027 feb 29", "feb 29 2027"} {
+		_, err := new(ExecuteAt).FromString(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestExecuteAt_FromString_Repeatable(t *testing.T) {
+	//It is Wednesday
+	freezeTime(t, "2024-05-01 12:30")
+
+	cases := map[string]struct {
+		datetime string
+		text     string
+	}{
+		"every 1h30m":               {datetime: "2024-05-01 14:00", text: "repeat 1 hours and 30 minutes"},
+		"every 10 minutes":          {datetime: "2024-05-01 12:40", text: "repeat 10 minutes"},
+		"repeat 2 days at 10am":     {datetime: "2024-05-03 10:00", text: "repeat 2 days and at 10:0"},
+		"every friday at 5pm":       {datetime: "2024-05-03 17:00", text: "repeat Friday and at 17:0"},
+		"repeat Friday and at 17:0": {datetime: "2024-05-03 17:00", text: "repeat Friday and at 17:0"},
+	}
+
+	for text, expected := range cases {
+		actual, err := new(ExecuteAt).FromString(text)
+		assert.NoError(t, err, text)
+		assert.True(t, actual.IsRepeatable, text)
+		assert.Equal(t, expected.datetime, actual.Datetime().Format(timeFormat), text)
+		assert.Equal(t, expected.text, actual.toString(), text)
+	}
+}
+
+func TestExecuteAt_FromString_DaylightSaving(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("The time zones database is not available")
+	}
+
+	_time.InitNOW(location)
+	defer _time.InitNOW(time.UTC)
+
+	//The clocks are moved forward during the next night
+	freezeTime(t, "2024-03-30 12:00")
+
+	actual, err := new(ExecuteAt).FromString("in 1 day")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-31 12:00", actual.Datetime().Format(timeFormat))
+
+	actual, err = new(ExecuteAt).FromString("in 24 hours")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-31 13:00", actual.Datetime().Format(timeFormat))
+}
+
+func TestExecuteAt_In(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("The time zones database is not available")
+	}
+
+	//It is 14:30 in Berlin
+	freezeTime(t, "2024-05-01 12:30")
+
+	cases := map[string]string{
+		"tomorrow at 9":       "2024-05-02 09:00",
+		"at 13:00":            "2024-05-02 13:00",
+		"at 15:00":            "2024-05-01 15:00",
+		"in 1 hour":           "2024-05-01 15:30",
+		"2024-06-01 10:00":    "2024-06-01 10:00",
+		`cron "0 9 * * *"`:    "2024-05-02 09:00",
+		"every day at 16:00":  "2024-05-01 16:00",
+		"every 1h30m":         "2024-05-01 16:00",
+		"next friday at noon": "2024-05-03 12:00",
+	}
+
+	for text, expected := range cases {
+		actual, err := new(ExecuteAt).In(location).FromString(text)
+		assert.NoError(t, err, text)
+		assert.Equal(t, expected, actual.Datetime().In(location).Format(timeFormat), text)
+		assert.Equal(t, "Europe/Berlin", actual.timezone(), text)
+	}
+
+	//The same time is 2 hours earlier in UTC
+	actual, err := new(ExecuteAt).In(location).FromString("tomorrow at 9")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-05-02 07:00", actual.Datetime().UTC().Format(timeFormat))
+
+	assert.Nil(t, new(ExecuteAt).timezone())
+}
+
+func TestExecuteAt_nextAfter(t *testing.T) {
+	freezeTime(t, "2024-05-01 12:30")
+
+	cases := []struct {
+		text     string
+		after    string
+		expected string
+	}{
+		{"every 5 minutes", "2024-05-01 12:30", "2024-05-01 12:35"},
+		{"every 2 hours", "2024-05-01 23:00", "2024-05-02 01:00"},
+		{"every day at 9:00", "2024-05-01 08:59", "2024-05-01 09:00"},
+		{"every day at 9:00", "2024-05-01 09:00", "2024-05-02 09:00"},
+		{"every monday at 9:10", "2024-05-01 12:30", "2024-05-06 09:10"},
+		{"every monday at 9:10", "2024-05-06 09:10", "2024-05-13 09:10"},
+		{`cron "0 9 * * *"`, "2024-05-01 09:00", "2024-05-02 09:00"},
+	}
+	for _, c := range cases {
+		executeAt, err := new(ExecuteAt).FromString(c.text)
+		assert.NoError(t, err)
+
+		after, err := time.ParseInLocation(timeFormat, c.after, _time.Service.TimeZone)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, executeAt.nextAfter(after).Format(timeFormat), c.text)
+	}
+}
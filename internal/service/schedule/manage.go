@@ -0,0 +1,270 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+const (
+	//StatusActive the status of the schedule, which is triggered in time
+	StatusActive = "active"
+
+	//StatusPaused the status of the schedule, which is not triggered until it is resumed
+	StatusPaused = "paused"
+
+	//StatusFinished the status of the schedule, which is not repeatable and was already triggered. It is kept for the runs history
+	StatusFinished = "finished"
+)
+
+var (
+	//ErrNotFound the error of the missing schedule
+	ErrNotFound = errors.New("the schedule is not found")
+
+	//ErrForbidden the error of the schedule change by the user, who is not its author
+	ErrForbidden = errors.New("only the author can change the schedule")
+
+	//ErrFinished the error of the pause or resume of the finished schedule
+	ErrFinished = errors.New("the schedule is already finished, please change its time to run it again")
+)
+
+// Filter the filter of the schedules list. The empty fields are not used
+type Filter struct {
+	Author  string
+	Channel string
+}
+
+// IsPaused checks if the schedule is paused
+func (i Item) IsPaused() bool {
+	return i.Status == StatusPaused
+}
+
+// CanBeChangedBy checks if the user can change the schedule. Only the author of the schedule can do it
+func (i Item) CanBeChangedBy(user string) bool {
+	return user != "" && i.Author == user
+}
+
+// IsFinished checks if the schedule was already triggered and it will not be triggered again
+func (i Item) IsFinished() bool {
+	return i.Status == StatusFinished
+}
+
+// NextRun retrieves the time of the next schedule execution. The zero time is returned for the paused and finished schedules
+func (i Item) NextRun() time.Time {
+	if i.IsPaused() || i.IsFinished() {
+		return time.Time{}
+	}
+
+	now := _time.Service.Now()
+	if i.IsRepeatable && i.ExecuteAt.Cron.IsEmpty() {
+		return i.nextRepeat(now)
+	}
+
+	//The overdue schedule, which is not repeatable, is triggered during the next minute
+	next := i.ExecuteAt.Datetime()
+	if !i.IsRepeatable && next.Before(now) {
+		return now
+	}
+
+	return next
+}
+
+// nextRepeat retrieves the first time of the repeatable schedule after its last run, which is not in the past
+func (i Item) nextRepeat(now time.Time) time.Time {
+	minute := truncateMinute(now)
+	after := i.LastRunAt
+	if after.IsZero() {
+		after = handledSince(i.ExecuteAt)
+	}
+
+	next := i.ExecuteAt.nextAfter(after)
+	for !next.IsZero() && next.Before(minute) {
+		next = i.ExecuteAt.nextAfter(next)
+	}
+
+	return next
+}
+
+// List retrieves the schedules, which match the filter
+func (s *Service) List(filter Filter) ([]Item, error) {
+	var wheres []query.Where
+	if filter.Author != "" {
+		wheres = append(wheres, database.WhereEquals("author", filter.Author))
+	}
+
+	if filter.Channel != "" {
+		wheres = append(wheres, database.WhereEquals("channel", filter.Channel))
+	}
+
+	return s.findItems(wheres...)
+}
+
+// Get retrieves the schedule by its ID
+func (s *Service) Get(id int) (Item, error) {
+	items, err := s.findItems(database.WhereEquals("id", id))
+	if err != nil {
+		return Item{}, err
+	}
+
+	if len(items) == 0 {
+		return Item{}, ErrNotFound
+	}
+
+	return items[0], nil
+}
+
+// Pause stops the schedule triggering until it is resumed
+func (s *Service) Pause(id int, user string) (Item, error) {
+	return s.change(id, user, func(item *Item) ([]cdto.ModelField, error) {
+		if item.IsFinished() {
+			return nil, ErrFinished
+		}
+
+		item.Status = StatusPaused
+
+		return []cdto.ModelField{{Name: "status", Value: item.Status}}, nil
+	})
+}
+
+// Resume continues the schedule triggering. The runs, which were planned during the pause, are not executed.
+// The overdue schedule, which is not repeatable, is triggered right away
+func (s *Service) Resume(id int, user string) (Item, error) {
+	return s.change(id, user, func(item *Item) ([]cdto.ModelField, error) {
+		if item.IsFinished() {
+			return nil, ErrFinished
+		}
+
+		item.Status = StatusActive
+
+		return []cdto.ModelField{{Name: "status", Value: item.Status}, handledNow(item)}, nil
+	})
+}
+
+// Reschedule changes the time of the schedule. The schedule becomes repeatable, when the new time is repeatable.
+// The finished schedule becomes active again. The time zone of the schedule is replaced by the time zone of the new time
+func (s *Service) Reschedule(id int, user string, executeAt ExecuteAt) (Item, error) {
+	return s.change(id, user, func(item *Item) ([]cdto.ModelField, error) {
+		item.ExecuteAt = executeAt
+		item.IsRepeatable = executeAt.IsRepeatable || !executeAt.Cron.IsEmpty()
+		if item.IsFinished() {
+			item.Status = StatusActive
+		}
+
+		value, cron := executeAt.toString(), ""
+		if !executeAt.Cron.IsEmpty() {
+			value, cron = "", executeAt.Cron.Expression
+		}
+
+		return []cdto.ModelField{
+			{Name: "execute_at", Value: value},
+			{Name: "cron", Value: cron},
+			{Name: "timezone", Value: executeAt.timezone()},
+			{Name: "is_repeatable", Value: item.IsRepeatable},
+			{Name: "status", Value: item.Status},
+			handledNow(item),
+		}, nil
+	})
+}
+
+// SetMisfirePolicy changes the way, how the runs, which were missed while the bot was down, are handled
+func (s *Service) SetMisfirePolicy(id int, user string, policy string) (Item, error) {
+	if !IsMisfirePolicy(policy) {
+		return Item{}, fmt.Errorf("the misfire policy `%s` is unknown. Please, use one of: %s, %s, %s", policy, MisfireSkip, MisfireRunOnce, MisfireRunAll)
+	}
+
+	return s.change(id, user, func(item *Item) ([]cdto.ModelField, error) {
+		item.MisfirePolicy = policy
+
+		return []cdto.ModelField{{Name: "misfire_policy", Value: item.MisfirePolicy}}, nil
+	})
+}
+
+// Delete removes the schedule
+func (s *Service) Delete(id int, user string) (Item, error) {
+	item, err := s.authorise(id, user)
+	if err != nil {
+		return Item{}, err
+	}
+
+	q := new(clients.Query).Delete().From(databasedto.SchedulesModel).Where(database.WhereEquals("id", id))
+	if _, err = s.DB.Execute(q); err != nil {
+		return Item{}, err
+	}
+
+	s.forget(id)
+
+	log.Logger().Info().Int("item_id", id).Str("user", user).Msg("Schedule deleted")
+
+	return item, nil
+}
+
+// change updates the fields of the schedule, which are returned by the update function
+func (s *Service) change(id int, user string, update func(item *Item) ([]cdto.ModelField, error)) (Item, error) {
+	item, err := s.authorise(id, user)
+	if err != nil {
+		return Item{}, err
+	}
+
+	changed, err := update(&item)
+	if err != nil {
+		return Item{}, err
+	}
+
+	var fields []interface{}
+	for _, field := range changed {
+		fields = append(fields, field)
+	}
+
+	q := new(clients.Query).
+		Update(&cdto.BaseModel{TableName: databasedto.SchedulesModel.GetTableName(), Fields: fields}).
+		Where(database.WhereEquals("id", id))
+	if _, err = s.DB.Execute(q); err != nil {
+		return Item{}, err
+	}
+
+	//The pending runs are dropped, the schedule is planned again with the new values during the next minute
+	s.forget(id)
+
+	log.Logger().Info().Int("item_id", id).Str("user", user).Str("status", item.Status).Msg("Schedule changed")
+
+	return item, nil
+}
+
+func (s *Service) authorise(id int, user string) (Item, error) {
+	item, err := s.Get(id)
+	if err != nil {
+		return Item{}, err
+	}
+
+	if !item.CanBeChangedBy(user) {
+		return Item{}, ErrForbidden
+	}
+
+	return item, nil
+}
+
+// handledNow marks the runs of the schedule till the current minute as handled, so they are not treated as missed
+func handledNow(item *Item) cdto.ModelField {
+	item.LastRunAt = handledSince(item.ExecuteAt)
+
+	return cdto.ModelField{Name: "last_run_at", Value: item.LastRunAt.Format(timeFormat)}
+}
+
+// handledSince retrieves the minute, till which the runs of the new or changed schedule are handled. The runs of the current minute are executed too,
+// except the interval, like `every 5 minutes`, which is counted from the current minute
+func handledSince(executeAt ExecuteAt) time.Time {
+	minute := truncateMinute(_time.Service.Now())
+	if executeAt.isInterval() {
+		return minute
+	}
+
+	return minute.Add(-time.Minute)
+}
@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+}
+
+func initTestService(t *testing.T) {
+	InitS(config.Config{}, test.InitDatabase(t, databasedto.SchedulesModel, databasedto.ScheduleRunsModel), nil)
+}
+
+func TestService_Manage(t *testing.T) {
+	initTestService(t)
+
+	executeAt, err := new(ExecuteAt).FromString(`cron "30 9 * * 1-5"`)
+	assert.NoError(t, err)
+
+	for _, item := range []Item{
+		{Author: "U1", Channel: "C1", ReactionType: "deploy", ScenarioID: 1, EventID: 1, ExecuteAt: executeAt},
+		{Author: "U2", Channel: "C1", ReactionType: "about", ScenarioID: 2, EventID: 2, ExecuteAt: ExecuteAt{Minutes: 1, IsRepeatable: true}, IsRepeatable: true},
+		{Author: "U1", Channel: "C2", ReactionType: "about", ScenarioID: 2, EventID: 2, ExecuteAt: ExecuteAt{Hours: 1}},
+	} {
+		assert.NoError(t, S.Schedule(item))
+	}
+
+	items, err := S.List(Filter{Author: "U1"})
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "30 9 * * 1-5", items[0].ExecuteAt.Cron.Expression)
+	assert.True(t, items[0].IsRepeatable)
+	assert.Equal(t, StatusActive, items[0].Status)
+
+	items, err = S.List(Filter{Channel: "C1"})
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	_, err = S.Get(10)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	//Only the author can change the schedule
+	_, err = S.Pause(1, "U2")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	item, err := S.Pause(1, "U1")
+	assert.NoError(t, err)
+	assert.True(t, item.IsPaused())
+	assert.True(t, item.NextRun().IsZero())
+
+	item, err = S.Get(1)
+	assert.NoError(t, err)
+	assert.True(t, item.IsPaused())
+	assert.Len(t, S.getSchedules(), 3)
+
+	item, err = S.Resume(1, "U1")
+	assert.NoError(t, err)
+	assert.False(t, item.IsPaused())
+	assert.False(t, item.NextRun().IsZero())
+
+	executeAt, err = new(ExecuteAt).FromString("2030-01-02 10:00")
+	assert.NoError(t, err)
+	_, err = S.Reschedule(1, "U1", executeAt)
+	assert.NoError(t, err)
+
+	item, err = S.Get(1)
+	assert.NoError(t, err)
+	assert.True(t, item.ExecuteAt.Cron.IsEmpty())
+	assert.False(t, item.IsRepeatable)
+	assert.Equal(t, "2030-01-02 10:00", item.NextRun().Format(timeFormat))
+
+	_, err = S.Delete(3, "U2")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = S.Delete(3, "U1")
+	assert.NoError(t, err)
+
+	_, err = S.Get(3)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestItem_NextRun(t *testing.T) {
+	now := freezeTime(t, "2024-05-01 12:30")
+
+	cases := []struct {
+		text      string
+		lastRunAt time.Time
+		expected  string
+	}{
+		{"every day at 9:00", now.Add(-27 * time.Hour).Add(-30 * time.Minute), "2024-05-02 09:00"},
+		{"every day at 13:00", time.Time{}, "2024-05-01 13:00"},
+		{"every monday at 9:10", now.Add(-time.Hour), "2024-05-06 09:10"},
+		{"every 5 minutes", now.Add(-30 * time.Minute), "2024-05-01 12:30"},
+		{"every 5 minutes", now.Add(-3 * time.Minute), "2024-05-01 12:32"},
+	}
+	for _, c := range cases {
+		executeAt, err := new(ExecuteAt).FromString(c.text)
+		assert.NoError(t, err)
+
+		item := Item{ExecuteAt: executeAt, IsRepeatable: true, Status: StatusActive, LastRunAt: c.lastRunAt}
+		assert.Equal(t, c.expected, item.NextRun().Format(timeFormat), c.text)
+	}
+}
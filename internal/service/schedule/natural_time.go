@@ -0,0 +1,386 @@
+package schedule
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tokenWord = iota
+	tokenNumber
+	tokenClock
+	tokenDate
+	tokenDuration
+)
+
+// The text is split to the dates, like `2026-11-02`, the clock times, like `17:00`, the compact durations, like `1h30m`,
+// the numbers, which may have the ordinal suffix, like `2nd`, and the words. The digits inside the words, like `deploy2d`, are not the time parts
+var naturalTokenRegexp = regexp.MustCompile(`(?i)\b(\d{4}-\d{1,2}-\d{1,2})|\b(\d{1,2}:\d{1,2})|\b((?:\d+[dhm])+)\b|\b(\d+)(?:st|nd|rd|th)?|([a-z]+)`)
+
+// The mentions of the users and channels and the links in the chat format, like `<@U02ABC12D>` or `<#C01ABC|general>`, are not the time parts
+var chatReferenceRegexp = regexp.MustCompile(`<[^<>]*>`)
+
+var compactDurationRegexp = regexp.MustCompile(`(\d+)([dhm])`)
+
+var naturalUnits = map[string]time.Duration{
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"mins":    time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hr":      time.Hour,
+	"hrs":     time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       24 * time.Hour,
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+}
+
+var naturalWeekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+var naturalMonths = map[string]time.Month{
+	"january": time.January, "february": time.February, "march": time.March, "april": time.April,
+	"may": time.May, "june": time.June, "july": time.July, "august": time.August,
+	"september": time.September, "october": time.October, "november": time.November, "december": time.December,
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April, "jun": time.June, "jul": time.July,
+	"aug": time.August, "sep": time.September, "sept": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+type naturalToken struct {
+	kind int
+	text string
+}
+
+// naturalTime the parts of the time, which were found in the text, like `next friday at 5pm` or `in 1h30m`
+type naturalTime struct {
+	//days and duration the relative time, like `in 2 days and 3 hours`. The days are kept apart, so the daylight saving time changes do not shift the time of the day
+	days        int
+	duration    time.Duration
+	hasDuration bool
+
+	//date the exact date, like `2026-11-02` or `november 2`. The year is not set, when it was not specified
+	date     time.Time
+	hasDate  bool
+	hasYear  bool
+	dayShift int
+
+	//hasDayShift the day is set relatively to today, like `tomorrow` or `next week`
+	hasDayShift bool
+
+	weekday    time.Weekday
+	hasWeekday bool
+
+	//isNextWeekday the weekday is not today, like `next friday`
+	isNextWeekday bool
+
+	hour     int
+	minute   int
+	hasClock bool
+}
+
+// isEmpty checks if no time parts were found
+func (n naturalTime) isEmpty() bool {
+	return !n.hasDuration && !n.hasDate && !n.hasDayShift && !n.hasWeekday && !n.hasClock
+}
+
+func tokenize(text string) (tokens []naturalToken) {
+	text = chatReferenceRegexp.ReplaceAllString(text, " ")
+	for _, match := range naturalTokenRegexp.FindAllStringSubmatch(text, -1) {
+		for kind, value := range match[1:] {
+			if value == "" {
+				continue
+			}
+
+			token := naturalToken{text: strings.ToLower(value)}
+			switch kind {
+			case 0:
+				token.kind = tokenDate
+			case 1:
+				token.kind = tokenClock
+			case 2:
+				token.kind = tokenDuration
+			case 3:
+				token.kind = tokenNumber
+			default:
+				token.kind = tokenWord
+			}
+
+			tokens = append(tokens, token)
+			break
+		}
+	}
+
+	return tokens
+}
+
+// parseNaturalTime finds the time parts in the text. The unknown words, like the event name, are ignored
+func parseNaturalTime(text string) (n naturalTime, err error) {
+	tokens := tokenize(text)
+
+	word := func(i int) string {
+		if i < 0 || i >= len(tokens) || tokens[i].kind != tokenWord {
+			return ""
+		}
+
+		return tokens[i].text
+	}
+
+	number := func(i int) (int, bool) {
+		if i < 0 || i >= len(tokens) || tokens[i].kind != tokenNumber {
+			return 0, false
+		}
+
+		value, err := strconv.Atoi(tokens[i].text)
+		return value, err == nil
+	}
+
+	//meridiem checks if the token is `am`, `pm`, `a.m.` or `p.m.` and retrieves the number of its tokens
+	meridiem := func(i int) (isPM bool, size int) {
+		switch word(i) {
+		case "am":
+			return false, 1
+		case "pm":
+			return true, 1
+		case "a", "p":
+			if word(i+1) == "m" {
+				return word(i) == "p", 2
+			}
+		}
+
+		return false, 0
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch token.kind {
+		case tokenDate:
+			date, err := time.Parse("2006-1-2", token.text)
+			if err != nil {
+				return naturalTime{}, fmt.Errorf("the date `%s` is invalid", token.text)
+			}
+
+			n.date, n.hasDate, n.hasYear = date, true, true
+		case tokenClock:
+			hour, minute, _ := strings.Cut(token.text, ":")
+			n.hour, _ = strconv.Atoi(hour)
+			n.minute, _ = strconv.Atoi(minute)
+
+			isPM, size := meridiem(i + 1)
+			if err = n.setClock(token.text, size > 0, isPM); err != nil {
+				return naturalTime{}, err
+			}
+
+			i += size
+		case tokenDuration:
+			for _, part := range compactDurationRegexp.FindAllStringSubmatch(token.text, -1) {
+				value, _ := strconv.Atoi(part[1])
+				n.addDuration(value, naturalUnits[part[2]])
+			}
+		case tokenNumber:
+			value, _ := number(i)
+
+			if unit, ok := naturalUnits[word(i+1)]; ok {
+				n.addDuration(value, unit)
+				i++
+				continue
+			}
+
+			if isPM, size := meridiem(i + 1); size > 0 {
+				n.hour, n.minute = value, 0
+				if err = n.setClock(token.text, true, isPM); err != nil {
+					return naturalTime{}, err
+				}
+
+				i += size
+				continue
+			}
+
+			//The day of month before the month name, like `2 november` or `2nd of november`
+			monthIndex := i + 1
+			if word(monthIndex) == "of" {
+				monthIndex++
+			}
+
+			if month, ok := naturalMonths[word(monthIndex)]; ok {
+				i = monthIndex
+				if err = n.setDate(month, value, number, &i); err != nil {
+					return naturalTime{}, err
+				}
+
+				continue
+			}
+
+			//The hour without minutes, like `at 10`
+			if word(i-1) == "at" {
+				n.hour, n.minute = value, 0
+				if err = n.setClock(token.text, false, false); err != nil {
+					return naturalTime{}, err
+				}
+			}
+		case tokenWord:
+			if weekday, ok := naturalWeekdays[token.text]; ok {
+				n.weekday, n.hasWeekday = weekday, true
+				n.isNextWeekday = word(i-1) == "next"
+				continue
+			}
+
+			if month, ok := naturalMonths[token.text]; ok {
+				//The month name is followed by the day of month, like `november 2` or `nov 2nd 2026`
+				if day, ok := number(i + 1); ok {
+					i++
+					if err = n.setDate(month, day, number, &i); err != nil {
+						return naturalTime{}, err
+					}
+				}
+
+				continue
+			}
+
+			switch token.text {
+			case "a", "an":
+				if unit, ok := naturalUnits[word(i+1)]; ok {
+					n.addDuration(1, unit)
+					i++
+				}
+			case "half":
+				if word(i+1) == "an" && word(i+2) == "hour" {
+					n.addDuration(30, time.Minute)
+					i += 2
+				}
+			case "today":
+				n.hasDayShift = true
+			case "tomorrow":
+				n.dayShift, n.hasDayShift = n.dayShift+1, true
+			case "day":
+				if word(i+1) == "after" && word(i+2) == "tomorrow" {
+					n.dayShift, n.hasDayShift = n.dayShift+2, true
+					i += 2
+				}
+			case "week":
+				if word(i-1) == "next" {
+					n.dayShift, n.hasDayShift = n.dayShift+7, true
+				}
+			case "noon":
+				n.hour, n.minute, n.hasClock = 12, 0, true
+			case "midnight":
+				n.hour, n.minute, n.hasClock = 0, 0, true
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (n *naturalTime) addDuration(value int, unit time.Duration) {
+	const day = 24 * time.Hour
+	if unit%day == 0 {
+		n.days += value * int(unit/day)
+	} else {
+		n.duration += time.Duration(value) * unit
+	}
+
+	n.hasDuration = true
+}
+
+// setClock validates the already set hour and minute. The hour is converted from the 12-hour clock, when the meridiem is set
+func (n *naturalTime) setClock(text string, hasMeridiem bool, isPM bool) error {
+	if hasMeridiem {
+		if n.hour < 1 || n.hour > 12 {
+			return fmt.Errorf("the time `%s` is invalid, the hour should be from 1 to 12 with am or pm", text)
+		}
+
+		n.hour %= 12
+		if isPM {
+			n.hour += 12
+		}
+	}
+
+	if n.hour > 23 || n.minute > 59 {
+		return fmt.Errorf("the time `%s` is invalid", text)
+	}
+
+	n.hasClock = true
+
+	return nil
+}
+
+// setDate sets the date by the month and the day of month. The year is taken from the next token, when it is there
+func (n *naturalTime) setDate(month time.Month, day int, number func(i int) (int, bool), i *int) error {
+	year, hasYear := number(*i + 1)
+	if hasYear && year >= 1000 {
+		*i++
+	} else {
+		year, hasYear = 2000, false
+	}
+
+	//The leap year is used, so the 29th of February is valid, when the year is not specified
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if day < 1 || date.Month() != month {
+		return fmt.Errorf("the date `%s %d` is invalid", month, day)
+	}
+
+	n.date, n.hasDate, n.hasYear = date, true, hasYear
+
+	return nil
+}
+
+// resolve calculates the exact time relatively to now. The time of the day is not changed, when it was not specified.
+// The time, which is already in the past, is moved to the next matching day, when the day was not specified
+func (n naturalTime) resolve(now time.Time) time.Time {
+	now = time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, now.Location())
+	hour, minute := now.Hour(), now.Minute()
+	if n.hasClock {
+		hour, minute = n.hour, n.minute
+	}
+
+	year, month, day := now.Date()
+	switch {
+	case n.hasDate:
+		month, day = n.date.Month(), n.date.Day()
+		if n.hasYear {
+			year = n.date.Year()
+		}
+	case n.hasWeekday:
+		days := int(7+n.weekday-now.Weekday()) % 7
+		if days == 0 && n.isNextWeekday {
+			days = 7
+		}
+
+		day += days
+	}
+
+	if n.hasDayShift {
+		day += n.dayShift
+	}
+
+	result := time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+
+	//The date without the year is the nearest one, which is not in the past. The 29th of February waits for the leap year
+	for !n.hasYear && n.hasDate && (result.Before(now) || result.Month() != month) && year < now.Year()+8 {
+		year++
+		result = time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+	}
+
+	//The passed time of today is moved to the next day. The relative days, like `in 2 days at 10am`, are already counted from today
+	if !n.hasDate && !n.hasDayShift && !n.hasDuration && n.hasClock && result.Before(now) {
+		if n.hasWeekday {
+			result = result.AddDate(0, 0, 7)
+		} else {
+			result = result.AddDate(0, 0, 1)
+		}
+	}
+
+	return result.AddDate(0, 0, n.days).Add(n.duration)
+}
@@ -0,0 +1,386 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+const (
+	//MisfireSkip the missed runs are not executed
+	MisfireSkip = "skip"
+
+	//MisfireRunOnce only the latest missed run is executed
+	MisfireRunOnce = "run_once"
+
+	//MisfireRunAll all missed runs are executed one by one
+	MisfireRunAll = "run_all"
+
+	//RunSucceeded the status of the run, which was executed without errors
+	RunSucceeded = "succeeded"
+
+	//RunFailed the status of the run, which execution returned the error
+	RunFailed = "failed"
+
+	//RunMissed the status of the run, which was missed and skipped by the misfire policy
+	RunMissed = "missed"
+
+	//RunPending the status of the run, which failed or was postponed, and which waits for its next attempt
+	RunPending = "pending"
+
+	//The run is missed, when it is late more than this time. So the small delays of the schedule service are not treated as misfires
+	misfireThreshold = time.Minute
+
+	//The maximum number of the missed runs, which can be executed or recorded at once. The older runs are dropped
+	maxMissedRuns = 100
+
+	runTimeFormat = "2006-01-02 15:04:05"
+)
+
+// Run the attempt of the scheduled event execution
+type Run struct {
+	ID         int
+	ScheduleID int
+
+	//ScheduledAt the time, when the run was planned by the schedule
+	ScheduledAt time.Time
+
+	//StartedAt the time, when the run was started
+	StartedAt time.Time
+	Duration  time.Duration
+
+	//Attempt the number of the attempt, starting from 1. It is 0 for the missed runs
+	Attempt int
+	Status  string
+	Error   string
+
+	//RetryAt the time of the next attempt of the pending run
+	RetryAt time.Time
+}
+
+// plannedRun the run, which should be executed during the current minute
+type plannedRun struct {
+	item        Item
+	scheduledAt time.Time
+	attempt     int
+}
+
+// IsMisfirePolicy checks if the text is the known misfire policy
+func IsMisfirePolicy(policy string) bool {
+	return policy == MisfireSkip || policy == MisfireRunOnce || policy == MisfireRunAll
+}
+
+// ActualMisfirePolicy retrieves the misfire policy of the schedule. By default, the missed runs of the repeatable schedule are skipped
+// and the missed schedule, which is not repeatable, is executed once
+func (i Item) ActualMisfirePolicy() string {
+	if i.MisfirePolicy != "" {
+		return i.MisfirePolicy
+	}
+
+	if i.IsRepeatable {
+		return MisfireSkip
+	}
+
+	return MisfireRunOnce
+}
+
+// Runs retrieves the latest runs of the schedule, starting from the newest one
+func (s *Service) Runs(scheduleID int, limit int) (runs []Run, err error) {
+	q := new(clients.Query).
+		Select(databasedto.ScheduleRunsModel.GetColumns()).
+		From(databasedto.ScheduleRunsModel).
+		Where(database.WhereEquals("schedule_id", scheduleID)).
+		OrderBy("id", query.OrderDirectionDesc).
+		Limit(query.Limit{From: 0, To: int64(limit)})
+
+	result, err := s.DB.Execute(q)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range result.Items() {
+		runs = append(runs, runFromModel(model))
+	}
+
+	return runs, nil
+}
+
+func runFromModel(model cdto.ModelInterface) Run {
+	run := Run{
+		ID:         model.GetField("id").Value.(int),
+		ScheduleID: model.GetField("schedule_id").Value.(int),
+		Duration:   time.Duration(model.GetField("duration").Value.(int)) * time.Millisecond,
+		Attempt:    model.GetField("attempt").Value.(int),
+		Status:     model.GetField("status").Value.(string),
+	}
+
+	location := _time.Service.Now().Location()
+	run.ScheduledAt, _ = time.ParseInLocation(runTimeFormat, model.GetField("scheduled_at").Value.(string), location)
+	run.StartedAt, _ = time.ParseInLocation(runTimeFormat, model.GetField("started_at").Value.(string), location)
+	run.Error, _ = model.GetField("error").Value.(string)
+	if retryAt, ok := model.GetField("retry_at").Value.(string); ok && retryAt != "" {
+		run.RetryAt, _ = time.ParseInLocation(runTimeFormat, retryAt, location)
+	}
+
+	return run
+}
+
+// dueRuns retrieves the runs of the schedule, which should be executed till the current minute.
+// The runs, which were missed while the bot was down, are handled by the misfire policy of the schedule
+func (s *Service) dueRuns(item Item, now time.Time) (runs []plannedRun) {
+	minute := truncateMinute(now)
+
+	//The schedule is handled since the last minute, when it was checked
+	handledTill := item.LastRunAt
+	if handledTill.IsZero() {
+		handledTill = minute.Add(-time.Minute)
+	}
+
+	//The repeatable time is matched in the time zone of the schedule
+	if item.ExecuteAt.Location != nil {
+		handledTill = handledTill.In(item.ExecuteAt.Location)
+	}
+
+	var (
+		due     []time.Time
+		dropped int
+	)
+	if !item.IsRepeatable {
+		at := item.ExecuteAt.Datetime()
+		if at.IsZero() || at.After(minute) {
+			return nil
+		}
+
+		due = append(due, at)
+	} else {
+		for at := item.ExecuteAt.nextAfter(handledTill); !at.IsZero() && !at.After(minute); at = item.ExecuteAt.nextAfter(at) {
+			due = append(due, at)
+			if len(due) > maxMissedRuns {
+				due = due[1:]
+				dropped++
+			}
+		}
+	}
+
+	//The interval, like `every 5 minutes`, is counted from its previous run, so only the planned runs move it.
+	//The interval of the schedule, which was created before the last run column, is counted from its first check
+	lastRunAt, isInterval := minute, item.ExecuteAt.isInterval()
+	if isInterval && len(due) > 0 {
+		lastRunAt = due[len(due)-1].In(minute.Location())
+	}
+
+	if len(due) == 0 && (!isInterval || !item.LastRunAt.IsZero()) {
+		return nil
+	}
+
+	if dropped > 0 {
+		log.Logger().Warn().Int("item_id", item.ID).Int("dropped", dropped).Msg("Too many missed runs. The oldest ones are dropped")
+	}
+
+	var (
+		policy     = item.ActualMisfirePolicy()
+		missedTill = minute.Add(-misfireThreshold)
+		missed     []time.Time
+	)
+	for i, at := range due {
+		//The schedule, which time was already in the past during its creation, is not missed
+		isMissed := at.Before(missedTill) && at.After(handledTill)
+		isLatestMissed := i == len(due)-1 || !due[i+1].Before(missedTill)
+		if isMissed && (policy == MisfireSkip || (policy == MisfireRunOnce && !isLatestMissed)) {
+			missed = append(missed, at)
+			continue
+		}
+
+		runs = append(runs, plannedRun{item: item, scheduledAt: at, attempt: 1})
+	}
+
+	//The schedule, which is not repeatable, is finished once it is planned. So it is not planned again during the next minute
+	fields := []interface{}{cdto.ModelField{Name: "last_run_at", Value: lastRunAt.Format(timeFormat)}}
+	if !item.IsRepeatable {
+		fields = append(fields, cdto.ModelField{Name: "status", Value: StatusFinished})
+	}
+
+	q := new(clients.Query).
+		Update(&cdto.BaseModel{TableName: databasedto.SchedulesModel.GetTableName(), Fields: fields}).
+		Where(database.WhereEquals("id", item.ID))
+	if _, err := s.DB.Execute(q); err != nil {
+		//The runs are not executed, otherwise they will be executed again during the next minute
+		log.Logger().AddError(err).Int("item_id", item.ID).Msg("Failed to update the last run of the schedule")
+		return nil
+	}
+
+	for _, at := range missed {
+		s.recordRun(Run{ScheduleID: item.ID, ScheduledAt: at, StartedAt: now, Status: RunMissed})
+	}
+
+	if len(missed) > 0 {
+		log.Logger().Info().Int("item_id", item.ID).Int("missed", len(missed)).Str("policy", policy).Msg("The missed runs of the schedule are skipped")
+	}
+
+	return runs
+}
+
+// retry plans the failed run again. Each next attempt waits twice longer than the previous one
+func (s *Service) retry(r plannedRun) {
+	backoff := s.Config.Schedules.RetryBackoff
+	if backoff <= 0 {
+		backoff = 1
+	}
+
+	delay := time.Duration(backoff<<(r.attempt-1)) * time.Minute
+	r.attempt++
+
+	log.Logger().Info().
+		Int("item_id", r.item.ID).
+		Int("attempt", r.attempt).
+		Dur("delay", delay).
+		Msg("Retry the failed scheduled event")
+
+	s.postpone([]plannedRun{r}, delay)
+}
+
+// postpone plans the runs to the later time
+func (s *Service) postpone(runs []plannedRun, delay time.Duration) {
+	retriesMutex.Lock()
+	defer retriesMutex.Unlock()
+
+	s.postponeRuns(runs, delay)
+}
+
+// postponeRuns does the same as postpone, but it should be called under retriesMutex.
+// The runs are stored as pending, so they are not lost, when the bot is restarted
+func (s *Service) postponeRuns(runs []plannedRun, delay time.Duration) {
+	now := _time.Service.Now()
+	for _, r := range runs {
+		s.recordRun(Run{
+			ScheduleID:  r.item.ID,
+			ScheduledAt: r.scheduledAt,
+			StartedAt:   now,
+			Attempt:     r.attempt,
+			Status:      RunPending,
+			RetryAt:     truncateMinute(now.Add(delay)),
+		})
+	}
+}
+
+// takeRetries removes the pending runs, which time has come, and retrieves them. It should be called under retriesMutex
+func (s *Service) takeRetries(now time.Time) (runs []plannedRun) {
+	q := new(clients.Query).
+		Select(databasedto.ScheduleRunsModel.GetColumns()).
+		From(databasedto.ScheduleRunsModel).
+		Where(database.WhereEquals("status", RunPending)).
+		OrderBy("id", query.OrderDirectionAsc)
+
+	result, err := s.DB.Execute(q)
+	if err != nil {
+		log.Logger().AddError(err).Msg("Failed to retrieve the pending runs")
+		return nil
+	}
+
+	for _, model := range result.Items() {
+		pending := runFromModel(model)
+		if pending.RetryAt.After(now) {
+			continue
+		}
+
+		//The pending run of the deleted schedule is dropped. After the other errors it is taken again during the next minute
+		item, err := s.Get(pending.ScheduleID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			log.Logger().AddError(err).Int("item_id", pending.ScheduleID).Msg("Failed to retrieve the schedule of the pending run")
+			continue
+		}
+
+		//The run is removed before the execution, so it is not executed twice
+		q := new(clients.Query).Delete().From(databasedto.ScheduleRunsModel).Where(database.WhereEquals("id", pending.ID))
+		if _, err = s.DB.Execute(q); err != nil {
+			log.Logger().AddError(err).Int("run_id", pending.ID).Msg("Failed to remove the pending run")
+			continue
+		}
+
+		if item.ID == 0 {
+			continue
+		}
+
+		runs = append(runs, plannedRun{item: item, scheduledAt: pending.ScheduledAt, attempt: pending.Attempt})
+	}
+
+	return runs
+}
+
+// forget removes the pending runs of the schedule, so the changed or deleted schedule is not executed by the old plan
+func (s *Service) forget(id int) {
+	retriesMutex.Lock()
+	defer retriesMutex.Unlock()
+
+	q := new(clients.Query).
+		Delete().
+		From(databasedto.ScheduleRunsModel).
+		Where(database.WhereEquals("schedule_id", id)).
+		Where(database.WhereEquals("status", RunPending))
+	if _, err := s.DB.Execute(q); err != nil {
+		log.Logger().AddError(err).Int("item_id", id).Msg("Failed to remove the pending runs of the schedule")
+	}
+}
+
+func (s *Service) recordRun(run Run) {
+	//The runs are stored in the application time zone, whatever the time zone of the schedule is
+	location := _time.Service.Now().Location()
+
+	var errorText, retryAt interface{}
+	if run.Error != "" {
+		errorText = run.Error
+	}
+
+	if !run.RetryAt.IsZero() {
+		retryAt = run.RetryAt.In(location).Format(runTimeFormat)
+	}
+
+	q := new(clients.Query).Insert(&cdto.BaseModel{
+		TableName: databasedto.ScheduleRunsModel.GetTableName(),
+		Fields: []interface{}{
+			cdto.ModelField{Name: "schedule_id", Value: run.ScheduleID},
+			cdto.ModelField{Name: "scheduled_at", Value: run.ScheduledAt.In(location).Format(runTimeFormat)},
+			cdto.ModelField{Name: "started_at", Value: run.StartedAt.In(location).Format(runTimeFormat)},
+			cdto.ModelField{Name: "duration", Value: int(run.Duration / time.Millisecond)},
+			cdto.ModelField{Name: "attempt", Value: run.Attempt},
+			cdto.ModelField{Name: "status", Value: run.Status},
+			cdto.ModelField{Name: "error", Value: errorText},
+			cdto.ModelField{Name: "retry_at", Value: retryAt},
+		},
+	})
+	if _, err := s.DB.Execute(q); err != nil {
+		log.Logger().AddError(err).Interface("run", run).Msg("Failed to record the schedule run")
+	}
+}
+
+func truncateMinute(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+}
+
+// String returns the text, which describes the run
+func (r Run) String() string {
+	text := fmt.Sprintf("%s %s", r.ScheduledAt.Format(timeFormat), r.Status)
+	if r.Attempt > 1 {
+		text += fmt.Sprintf(", attempt %d", r.Attempt)
+	}
+
+	if r.Status == RunPending {
+		text += fmt.Sprintf(", next attempt at %s", r.RetryAt.Format(timeFormat))
+	} else if r.Status != RunMissed {
+		text += fmt.Sprintf(", started %s, took %s", r.StartedAt.Format(runTimeFormat), r.Duration.Round(time.Millisecond))
+	}
+
+	if r.Error != "" {
+		text += fmt.Sprintf(": %s", r.Error)
+	}
+
+	return text
+}
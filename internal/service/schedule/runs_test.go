@@ -0,0 +1,261 @@
+package schedule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/config"
+	"github.com/sharovik/devbot/internal/service/message/conversation"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/stretchr/testify/assert"
+)
+
+func scheduledTimes(runs []plannedRun) (times []string) {
+	for _, r := range runs {
+		times = append(times, r.scheduledAt.Format(timeFormat))
+	}
+
+	return times
+}
+
+func TestService_dueRuns(t *testing.T) {
+	initTestService(t)
+
+	executeAt, err := new(ExecuteAt).FromString("cron @hourly")
+	assert.NoError(t, err)
+	assert.NoError(t, S.Schedule(Item{Author: "U1", Channel: "C1", ReactionType: "deploy", ExecuteAt: executeAt}))
+
+	item, err := S.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, MisfireSkip, item.ActualMisfirePolicy())
+
+	//The bot was down since 05:00, so the runs from 06:00 till 09:00 are missed
+	now := time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC)
+	item.LastRunAt = time.Date(2024, 5, 1, 5, 0, 0, 0, time.UTC)
+
+	cases := map[string][]string{
+		MisfireSkip:    {"2024-05-01 10:00"},
+		MisfireRunOnce: {"2024-05-01 09:00", "2024-05-01 10:00"},
+		MisfireRunAll:  {"2024-05-01 06:00", "2024-05-01 07:00", "2024-05-01 08:00", "2024-05-01 09:00", "2024-05-01 10:00"},
+	}
+	for policy, expected := range cases {
+		item.MisfirePolicy = policy
+		assert.Equal(t, expected, scheduledTimes(S.dueRuns(item, now)), policy)
+	}
+
+	runs, err := S.Runs(1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 7)
+	assert.Equal(t, RunMissed, runs[0].Status)
+	assert.Equal(t, "2024-05-01 08:00 missed", runs[0].String())
+
+	runs, err = S.Runs(1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+
+	//The runs are handled till the current minute
+	item, err = S.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-05-01 10:00", item.LastRunAt.Format(timeFormat))
+	assert.Empty(t, S.dueRuns(item, now.Add(time.Minute)))
+}
+
+func TestService_dueRuns_NotRepeatable(t *testing.T) {
+	initTestService(t)
+
+	now := time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC)
+	item := Item{ID: 1, ExecuteAt: ExecuteAt{ExactDatetime: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)}}
+	assert.Equal(t, MisfireRunOnce, item.ActualMisfirePolicy())
+
+	//The schedule time was in the future during the last check, so it was missed
+	item.LastRunAt = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2024-05-01 09:00"}, scheduledTimes(S.dueRuns(item, now)))
+
+	item.MisfirePolicy = MisfireSkip
+	assert.Empty(t, S.dueRuns(item, now))
+
+	//The schedule time was already in the past during its creation, so it is executed
+	item.LastRunAt = time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2024-05-01 09:00"}, scheduledTimes(S.dueRuns(item, now)))
+
+	item.ExecuteAt.ExactDatetime = time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC)
+	assert.Empty(t, S.dueRuns(item, now))
+}
+
+func TestService_dueRuns_Repeatable(t *testing.T) {
+	initTestService(t)
+
+	for _, text := range []string{"every 5 minutes", "every day at 9:00"} {
+		executeAt, err := new(ExecuteAt).FromString(text)
+		assert.NoError(t, err)
+		assert.NoError(t, S.Schedule(Item{Author: "U1", Channel: "C1", ReactionType: "deploy", ExecuteAt: executeAt, IsRepeatable: true}))
+	}
+
+	//The bot was down since 10:00, so the interval runs till 10:15 are missed
+	now := time.Date(2024, 5, 1, 10, 20, 30, 0, time.UTC)
+	item, err := S.Get(1)
+	assert.NoError(t, err)
+	item.LastRunAt = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := map[string][]string{
+		MisfireSkip:    {"2024-05-01 10:20"},
+		MisfireRunOnce: {"2024-05-01 10:15", "2024-05-01 10:20"},
+		MisfireRunAll:  {"2024-05-01 10:05", "2024-05-01 10:10", "2024-05-01 10:15", "2024-05-01 10:20"},
+	}
+	for policy, expected := range cases {
+		item.MisfirePolicy = policy
+		assert.Equal(t, expected, scheduledTimes(S.dueRuns(item, now)), policy)
+	}
+
+	//The interval is counted from the last planned run
+	item, err = S.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-05-01 10:20", item.LastRunAt.Format(timeFormat))
+	assert.Empty(t, S.dueRuns(item, now.Add(4*time.Minute)))
+	assert.Equal(t, []string{"2024-05-01 10:25"}, scheduledTimes(S.dueRuns(item, now.Add(5*time.Minute))))
+
+	//The interval of the schedule, which was never checked, is counted from its first check
+	item.LastRunAt = time.Time{}
+	assert.Empty(t, S.dueRuns(item, now))
+	item, err = S.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-05-01 10:20", item.LastRunAt.Format(timeFormat))
+
+	//The bot was down for three days, so only the latest daily run is executed by run_once policy
+	item, err = S.Get(2)
+	assert.NoError(t, err)
+	item.LastRunAt = time.Date(2024, 4, 28, 9, 0, 0, 0, time.UTC)
+	item.MisfirePolicy = MisfireRunOnce
+	assert.Equal(t, []string{"2024-05-01 09:00"}, scheduledTimes(S.dueRuns(item, now)))
+
+	runs, err := S.Runs(2, 10)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.Equal(t, "2024-04-30 09:00 missed", runs[0].String())
+}
+
+func TestService_retry(t *testing.T) {
+	initTestService(t)
+	S.Config.Schedules = config.SchedulesConfig{Retries: 2, RetryBackoff: 2}
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, S.Schedule(Item{Author: "U1", Channel: "C1", ReactionType: "deploy", ExecuteAt: ExecuteAt{Hours: 1}}))
+	}
+
+	item, err := S.Get(1)
+	assert.NoError(t, err)
+
+	now := _time.Service.Now()
+	scheduledAt := truncateMinute(now)
+	S.retry(plannedRun{item: item, scheduledAt: scheduledAt, attempt: 1})
+	assert.Empty(t, S.takeRetries(now))
+
+	//The pending run is stored, so it is kept after the restart
+	stored, err := S.Runs(1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, stored, 1)
+	assert.Equal(t, RunPending, stored[0].Status)
+	assert.Equal(t, fmt.Sprintf("%s pending, attempt 2, next attempt at %s", scheduledAt.Format(timeFormat), truncateMinute(now.Add(2*time.Minute)).Format(timeFormat)), stored[0].String())
+
+	InitS(S.Config, S.DB, nil)
+	runs := S.takeRetries(now.Add(2 * time.Minute))
+	assert.Len(t, runs, 1)
+	assert.Equal(t, 2, runs[0].attempt)
+	assert.Equal(t, 1, runs[0].item.ID)
+	assert.Equal(t, scheduledAt.Format(timeFormat), runs[0].scheduledAt.Format(timeFormat))
+
+	//The taken run is not pending anymore
+	stored, err = S.Runs(1, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, stored)
+
+	//Each next retry waits twice longer
+	S.retry(runs[0])
+	assert.Empty(t, S.takeRetries(now.Add(3*time.Minute)))
+	assert.Len(t, S.takeRetries(now.Add(4*time.Minute)), 1)
+
+	//The pending runs of the changed schedule are forgotten
+	item, err = S.Get(2)
+	assert.NoError(t, err)
+	S.retry(plannedRun{item: item, scheduledAt: scheduledAt, attempt: 1})
+	_, err = S.Pause(2, "U1")
+	assert.NoError(t, err)
+	assert.Empty(t, S.takeRetries(now.Add(time.Hour)))
+
+	//The pending runs of the deleted schedule are dropped
+	S.postpone([]plannedRun{{item: Item{ID: 3}, scheduledAt: scheduledAt, attempt: 1}}, time.Minute)
+	assert.Empty(t, S.takeRetries(now.Add(time.Hour)))
+	stored, err = S.Runs(3, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, stored)
+}
+
+func TestService_execute(t *testing.T) {
+	initTestService(t)
+	S.Config.Schedules = config.SchedulesConfig{Retries: 1}
+
+	key := conversation.Key{Channel: "C1", User: "U1"}
+	S.execute(key, plannedRun{item: Item{ID: 1, ReactionType: "unknown"}, scheduledAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), attempt: 1})
+
+	runs, err := S.Runs(1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, RunFailed, runs[0].Status)
+	assert.Equal(t, 1, runs[0].Attempt)
+	assert.Equal(t, "the event `unknown` is not defined", runs[0].Error)
+
+	//The missing event is not retried
+	assert.Empty(t, S.takeRetries(time.Now().Add(time.Hour)))
+}
+
+func TestService_Timezone(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("The time zones database is not available")
+	}
+
+	initTestService(t)
+
+	executeAt, err := new(ExecuteAt).In(location).FromString(`cron "0 9 * * *"`)
+	assert.NoError(t, err)
+	assert.NoError(t, S.Schedule(Item{Author: "U1", Channel: "C1", ReactionType: "deploy", ExecuteAt: executeAt}))
+
+	executeAt, err = new(ExecuteAt).FromString("2024-05-01 09:00")
+	assert.NoError(t, err)
+	assert.NoError(t, S.Schedule(Item{Author: "U1", Channel: "C1", ReactionType: "deploy", ExecuteAt: executeAt}))
+
+	//The time zone is stored together with the schedule
+	item, err := S.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", item.ExecuteAt.Location.String())
+
+	item, err = S.Get(2)
+	assert.NoError(t, err)
+	assert.Nil(t, item.ExecuteAt.Location)
+
+	//It is 09:00 in Tokyo, when it is 00:00 in UTC
+	item, err = S.Get(1)
+	assert.NoError(t, err)
+	item.LastRunAt = time.Date(2024, 4, 30, 23, 58, 0, 0, time.UTC)
+	runs := S.dueRuns(item, time.Date(2024, 5, 1, 0, 0, 20, 0, time.UTC))
+	assert.Len(t, runs, 1)
+	assert.Equal(t, "2024-05-01 00:00", runs[0].scheduledAt.UTC().Format(timeFormat))
+
+	//The runs are stored in the application time zone
+	S.recordRun(Run{ScheduleID: 1, ScheduledAt: runs[0].scheduledAt, StartedAt: runs[0].scheduledAt, Status: RunSucceeded, Attempt: 1})
+	stored, err := S.Runs(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-05-01 00:00", stored[0].ScheduledAt.UTC().Format(timeFormat))
+
+	//The new time of the schedule brings its own time zone
+	executeAt, err = new(ExecuteAt).FromString("tomorrow at 10")
+	assert.NoError(t, err)
+	item, err = S.Reschedule(1, "U1", executeAt)
+	assert.NoError(t, err)
+
+	item, err = S.Get(1)
+	assert.NoError(t, err)
+	assert.Nil(t, item.ExecuteAt.Location)
+	assert.True(t, item.ExecuteAt.Cron.IsEmpty())
+}
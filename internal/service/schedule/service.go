@@ -1,6 +1,7 @@
 package schedule
 
 import (
+	"errors"
 	"fmt"
 	"github.com/sharovik/devbot/internal/config"
 	"github.com/sharovik/devbot/internal/database"
@@ -8,12 +9,16 @@ import (
 	"github.com/sharovik/devbot/internal/dto/databasedto"
 	"github.com/sharovik/devbot/internal/dto/event"
 	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/executor"
+	"github.com/sharovik/devbot/internal/service/history"
 	"github.com/sharovik/devbot/internal/service/message/conversation"
+	"github.com/sharovik/devbot/internal/service/shutdown"
 	_time "github.com/sharovik/devbot/internal/service/time"
 	"github.com/sharovik/orm/clients"
 	cdto "github.com/sharovik/orm/dto"
 	"github.com/sharovik/orm/query"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -30,8 +35,10 @@ const (
 )
 
 var (
-	S            Service
-	toBeExecuted = map[string][]Item{}
+	S Service
+
+	//retriesMutex guards the pending runs, because the schedules can be changed from the chat during the triggering
+	retriesMutex sync.Mutex
 )
 
 // Item the item struct for schedule object
@@ -61,6 +68,15 @@ type Item struct {
 	ExecuteAt ExecuteAt
 	//IsRepeatable if it is set to true, that means we want to repeat it
 	IsRepeatable bool
+
+	//Status - the status of the schedule. The paused and finished schedules are not triggered
+	Status string
+
+	//MisfirePolicy - the way, how the runs, which were missed while the bot was down, are handled. See ActualMisfirePolicy for the default one
+	MisfirePolicy string
+
+	//LastRunAt - the minute, till which the runs of the schedule are handled
+	LastRunAt time.Time
 }
 
 func InitS(cfg config.Config, db clients.BaseClientInterface, definedEvents map[string]event.DefinedEventInterface) {
@@ -71,18 +87,28 @@ func InitS(cfg config.Config, db clients.BaseClientInterface, definedEvents map[
 	}
 }
 
-// Run runs the schedule service in goroutine
+// Run runs the schedule service in goroutine. The service stops triggering the events, once the application is stopping
 func (s *Service) Run() (err error) {
 	log.Logger().Debug().Msg("Start schedule service")
 	go func() {
 		lastExecutedStr := ""
 		for {
 			if lastExecutedStr == time.Now().Format("2006-01-02T15:04") {
-				time.Sleep(time.Second)
+				select {
+				case <-shutdown.S.Stopping():
+				case <-time.After(time.Second):
+				}
+
 				continue
 			}
 
+			if !shutdown.S.Begin() {
+				log.Logger().Debug().Msg("Stop schedule service")
+				return
+			}
+
 			s.triggerEvents()
+			shutdown.S.End()
 			lastExecutedStr = time.Now().Format("2006-01-02T15:04")
 		}
 	}()
@@ -92,62 +118,82 @@ func (s *Service) Run() (err error) {
 	return err
 }
 
-func alreadyExists(item Item) bool {
-	//Check if the entry already exists. If so, false will be returned
-	for _, scheduledTimeSlot := range toBeExecuted {
-		for _, entry := range scheduledTimeSlot {
-			if generateItemID(entry) == generateItemID(item) {
-				//it's already exists
-				return true
+func (s *Service) triggerEvents() {
+	retriesMutex.Lock()
+	defer retriesMutex.Unlock()
+
+	//The runs of the same schedule are executed one by one, so they are grouped by the schedule
+	var (
+		now     = _time.Service.Now()
+		planned = map[int][]plannedRun{}
+		order   []int
+	)
+	plan := func(runs ...plannedRun) {
+		for _, r := range runs {
+			if _, ok := planned[r.item.ID]; !ok {
+				order = append(order, r.item.ID)
 			}
+
+			planned[r.item.ID] = append(planned[r.item.ID], r)
 		}
 	}
 
-	return false
-}
-
-func (s *Service) triggerEvents() {
-	now := _time.Service.Now()
 	for _, item := range s.getSchedules() {
-		if !item.IsRepeatable && now.After(item.ExecuteAt.getDatetime()) {
-			if alreadyExists(item) {
-				continue
-			}
-
-			toBeExecuted[now.Format(timeFormat)] = append(toBeExecuted[now.Format(timeFormat)], item)
+		if item.Status != StatusActive {
 			continue
 		}
 
-		if alreadyExists(item) {
-			continue
-		}
+		plan(s.dueRuns(item, now)...)
+	}
 
-		toBeExecuted[item.ExecuteAt.getDatetime().Format(timeFormat)] = append(toBeExecuted[item.ExecuteAt.getDatetime().Format(timeFormat)], item)
+	plan(s.takeRetries(now)...)
+	for _, id := range order {
+		s.trigger(planned[id])
 	}
+}
+
+// trigger executes the runs of the schedule one by one. It should be called under retriesMutex
+func (s *Service) trigger(runs []plannedRun) {
+	item := runs[0].item
+	log.Logger().Info().Interface("item", item).Int("runs", len(runs)).Msg("Trigger scheduled event")
+
+	key := conversation.Key{Channel: item.Channel, User: item.Author}
+	if conversation.S.Get(key).ScenarioID != 0 {
+		log.Logger().Debug().
+			Str("channel", item.Channel).
+			Interface("item", item).
+			Msg("There is open conversation for selected channel and author. Postponed.")
+		s.postponeRuns(runs, time.Minute)
 
-	tStr := now.Format(timeFormat)
-	for _, item := range toBeExecuted[tStr] {
-		s.trigger(item)
+		return
 	}
 
-	//We clean up the already executed events
-	for timeStr := range toBeExecuted {
-		targ, err := time.ParseInLocation(timeFormat, timeStr, _time.Service.TimeZone)
-		if err != nil {
-			log.Logger().AddError(err).Msg("Failed to parse time")
-			continue
-		}
+	openConversation(key, item)
+	err := executor.S.Submit(item.ReactionType, func() {
+		for i, r := range runs {
+			if i > 0 {
+				openConversation(key, item)
+			}
 
-		if now.After(targ) {
-			delete(toBeExecuted, timeStr)
+			s.execute(key, r)
+			conversation.S.Finalise(key)
 		}
+	})
+	if err != nil {
+		log.Logger().AddError(err).
+			Interface("item", item).
+			Msg("Failed to queue the scheduled event execution. Postponed.")
+		conversation.S.Finalise(key)
+		s.postponeRuns(runs, time.Minute)
+
+		return
 	}
 
-	delete(toBeExecuted, now.Format(timeFormat))
+	log.Logger().Info().Interface("item", item).Msg("Scheduled event has been queued")
 }
 
-func (s *Service) trigger(item Item) {
-	log.Logger().Info().Interface("item", item).Msg("Trigger scheduled event")
+// openConversation prepares the conversation with the answers of the schedule, which is used by the event execution
+func openConversation(key conversation.Key, item Item) {
 	scenario := database.EventScenario{
 		ID:        item.ScenarioID,
 		EventName: item.ReactionType,
@@ -160,16 +206,8 @@ func (s *Service) trigger(item Item) {
 		})
 	}
 
-	if conversation.GetConversation(item.Channel).ScenarioID != 0 {
-		log.Logger().Debug().
-			Str("channel", item.Channel).
-			Interface("item", item).
-			Msg("There is open conversation for selected channel. Skipping.")
-		return
-	}
-
-	conversation.AddConversation(scenario, dto.BaseChatMessage{
-		Channel: item.Channel,
+	conversation.S.Add(scenario, dto.BaseChatMessage{
+		Channel: key.Channel,
 		AsUser:  true,
 		Ts:      _time.Service.Now(),
 		DictionaryMessage: dto.DictionaryMessage{
@@ -177,46 +215,64 @@ func (s *Service) trigger(item Item) {
 			EventID:      item.EventID,
 			ReactionType: item.ReactionType,
 		},
-		OriginalMessage: dto.BaseOriginalMessage{},
+		OriginalMessage: dto.BaseOriginalMessage{
+			User: key.User,
+		},
 	})
+}
 
-	go func() {
-		if s.DefinedEvents[item.ReactionType] == nil {
-			log.Logger().Error().
-				Str("reaction_type", item.ReactionType).
-				Msg("Reaction type not exists")
+// execute executes the run and records its result. The failed run is retried, when the retries are configured
+func (s *Service) execute(key conversation.Key, r plannedRun) {
+	var (
+		err          error
+		started      = _time.Service.Now()
+		definedEvent = s.DefinedEvents[r.item.ReactionType]
+		message      = conversation.S.Get(key).LastQuestion
+	)
+
+	if definedEvent == nil {
+		err = fmt.Errorf("the event `%s` is not defined", r.item.ReactionType)
+	} else {
+		_, err = executor.S.ExecuteEvent(key, definedEvent, message)
+	}
 
-			return
-		}
+	run := Run{
+		ScheduleID:  r.item.ID,
+		ScheduledAt: r.scheduledAt,
+		StartedAt:   started,
+		Duration:    _time.Service.Now().Sub(started),
+		Attempt:     r.attempt,
+		Status:      RunSucceeded,
+	}
 
-		if _, err := s.DefinedEvents[item.ReactionType].Execute(conversation.GetConversation(item.Channel).LastQuestion); err != nil {
-			log.Logger().AddError(err).Msg("Failed to execute event")
+	if err != nil {
+		log.Logger().AddError(err).Interface("item", r.item).Int("attempt", r.attempt).Msg("Failed to execute event")
+
+		//There is no user, who waits for the answer, so the failure is only stored in the history
+		var panicErr *event.PanicError
+		if errors.As(err, &panicErr) {
+			history.RememberEventFailure(message, panicErr.Error())
 		}
 
-		conversation.FinaliseConversation(item.Channel)
-	}()
+		run.Status, run.Error = RunFailed, err.Error()
 
-	if !item.IsRepeatable {
-		q := new(clients.Query).Delete().From(databasedto.SchedulesModel).Where(query.Where{
-			First:    "id",
-			Operator: "=",
-			Second: query.Bind{
-				Field: "id",
-				Value: item.ID,
-			},
-		})
-		if _, err := s.DB.Execute(q); err != nil {
-			log.Logger().
-				AddError(err).
-				Int("item_id", item.ID).
-				Msg("Failed to delete scheduled item from the database")
+		//The missing event cannot be fixed by the retry
+		if definedEvent != nil && r.attempt <= s.Config.Schedules.Retries {
+			s.retry(r)
 		}
 	}
 
-	log.Logger().Info().Interface("item", item).Msg("Scheduled event has been executed")
+	s.recordRun(run)
 }
 
 func (s *Service) Schedule(item Item) (err error) {
+	//The cron schedule is stored in its own column, so the execute_at column keeps only the old format
+	executeAt, cron := item.ExecuteAt.toString(), ""
+	if !item.ExecuteAt.Cron.IsEmpty() {
+		executeAt, cron = "", item.ExecuteAt.Cron.Expression
+		item.IsRepeatable = true
+	}
+
 	model := databasedto.SchedulesModel
 	model.AddModelField(cdto.ModelField{
 		Name:  "author",
@@ -244,12 +300,32 @@ func (s *Service) Schedule(item Item) (err error) {
 	})
 	model.AddModelField(cdto.ModelField{
 		Name:  "execute_at",
-		Value: item.ExecuteAt.toString(),
+		Value: executeAt,
+	})
+	model.AddModelField(cdto.ModelField{
+		Name:  "cron",
+		Value: cron,
 	})
 	model.AddModelField(cdto.ModelField{
 		Name:  "variables",
 		Value: item.Variables,
 	})
+	model.AddModelField(cdto.ModelField{
+		Name:  "status",
+		Value: StatusActive,
+	})
+	model.AddModelField(cdto.ModelField{
+		Name:  "misfire_policy",
+		Value: item.MisfirePolicy,
+	})
+	model.AddModelField(cdto.ModelField{
+		Name:  "timezone",
+		Value: item.ExecuteAt.timezone(),
+	})
+	model.AddModelField(cdto.ModelField{
+		Name:  "last_run_at",
+		Value: handledSince(item.ExecuteAt).Format(timeFormat),
+	})
 	q := new(clients.Query).
 		Insert(model)
 
@@ -263,46 +339,98 @@ func (s *Service) Schedule(item Item) (err error) {
 }
 
 func (s *Service) getSchedules() (items []Item) {
+	items, err := s.findItems()
+	if err != nil {
+		log.Logger().AddError(err).Msg("Failed to retrieve schedule list")
+		return nil
+	}
+
+	return items
+}
+
+// findItems retrieves the schedules, which match all the conditions. The schedules with the broken time are skipped
+func (s *Service) findItems(wheres ...query.Where) (items []Item, err error) {
 	q := new(clients.Query).
 		Select(databasedto.SchedulesModel.GetColumns()).
 		From(databasedto.SchedulesModel)
+	for _, where := range wheres {
+		q.Where(where)
+	}
 
-	result, err := s.DB.Execute(q)
+	result, err := s.DB.Execute(q.OrderBy("id", query.OrderDirectionAsc))
 	if err != nil {
-		log.Logger().AddError(err).Msg("Failed to retrieve schedule list")
-		return nil
+		return nil, err
 	}
 
-	for _, item := range result.Items() {
-		executeAt, err := new(ExecuteAt).FromString(item.GetField("execute_at").Value.(string))
+	for _, model := range result.Items() {
+		item, err := itemFromModel(model)
 		if err != nil {
-			log.Logger().AddError(err).Msg("Failed to parse execute_at")
-			return nil
+			log.Logger().AddError(err).Interface("item_id", model.GetField("id").Value).Msg("Failed to parse the schedule")
+			continue
 		}
 
-		isRepeatable := false
-		if item.GetField("is_repeatable").Value.(int) == 1 {
-			isRepeatable = true
-			executeAt.IsRepeatable = isRepeatable
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func itemFromModel(model cdto.ModelInterface) (Item, error) {
+	//The schedules, which were created before the timezone column, use the application time zone
+	var location *time.Location
+	if name, ok := model.GetField("timezone").Value.(string); ok && name != "" {
+		var err error
+		if location, err = time.LoadLocation(name); err != nil {
+			return Item{}, err
 		}
+	}
 
-		items = append(items, Item{
-			ID:           item.GetField("id").Value.(int),
-			Author:       item.GetField("author").Value.(string),
-			Channel:      item.GetField("channel").Value.(string),
-			ScenarioID:   int64(item.GetField("scenario_id").Value.(int)),
-			EventID:      int64(item.GetField("event_id").Value.(int)),
-			ReactionType: item.GetField("reaction_type").Value.(string),
-			Scenario:     database.EventScenario{},
-			ExecuteAt:    executeAt,
-			IsRepeatable: isRepeatable,
-			Variables:    item.GetField("variables").Value.(string),
-		})
+	executeAt, err := new(ExecuteAt).In(location).FromString(model.GetField("execute_at").Value.(string))
+	if err != nil {
+		return Item{}, err
 	}
 
-	return items
-}
+	if cron, ok := model.GetField("cron").Value.(string); ok && cron != "" {
+		if executeAt.Cron, err = ParseCron(cron); err != nil {
+			return Item{}, err
+		}
+	}
+
+	isRepeatable := false
+	if model.GetField("is_repeatable").Value.(int) == 1 {
+		isRepeatable = true
+		executeAt.IsRepeatable = isRepeatable
+	}
+
+	//The schedules, which were created before the status column, are active
+	status, _ := model.GetField("status").Value.(string)
+	if status == "" {
+		status = StatusActive
+	}
+
+	author, _ := model.GetField("author").Value.(string)
+	misfirePolicy, _ := model.GetField("misfire_policy").Value.(string)
+
+	var lastRunAt time.Time
+	if value, ok := model.GetField("last_run_at").Value.(string); ok && value != "" {
+		if lastRunAt, err = time.ParseInLocation(timeFormat, value, _time.Service.TimeZone); err != nil {
+			return Item{}, err
+		}
+	}
 
-func generateItemID(item Item) string {
-	return fmt.Sprintf("%d-%s-%s", item.ID, item.Channel, item.ReactionType)
+	return Item{
+		ID:            model.GetField("id").Value.(int),
+		Author:        author,
+		Channel:       model.GetField("channel").Value.(string),
+		ScenarioID:    int64(model.GetField("scenario_id").Value.(int)),
+		EventID:       int64(model.GetField("event_id").Value.(int)),
+		ReactionType:  model.GetField("reaction_type").Value.(string),
+		Scenario:      database.EventScenario{},
+		ExecuteAt:     executeAt,
+		IsRepeatable:  isRepeatable,
+		Variables:     model.GetField("variables").Value.(string),
+		Status:        status,
+		MisfirePolicy: misfirePolicy,
+		LastRunAt:     lastRunAt,
+	}, nil
 }
@@ -0,0 +1,147 @@
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Service tracks the work in progress, which should be finished before the application stop.
+// The messages receivers and schedule service use it to stop accepting the new work, once the application is stopping
+type Service struct {
+	mu       sync.Mutex
+	running  int
+	stopping bool
+	stopped  chan struct{}
+	idle     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// S the shutdown service object
+var S = New()
+
+// New creates the new shutdown service
+func New() *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Service{
+		stopped: make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Begin marks the start of the work. It returns false, once the application is stopping and the new work cannot be started.
+// Each successful Begin call should be finished by End call
+func (s *Service) Begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopping {
+		return false
+	}
+
+	s.running++
+
+	return true
+}
+
+// Track marks the start of the work, which was created by the running work. It is tracked even when the application is stopping.
+// Each Track call should be finished by End call
+func (s *Service) Track() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running++
+}
+
+// End marks the finish of the work, which was started by Begin, Track or Go
+func (s *Service) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.running--
+	if s.running == 0 && s.idle != nil {
+		close(s.idle)
+		s.idle = nil
+	}
+}
+
+// Go runs the function in the goroutine and tracks it until the function will be finished.
+// It is used by the running work, so it is started even when the application is stopping
+func (s *Service) Go(fn func()) {
+	s.Track()
+
+	go func() {
+		defer s.End()
+
+		fn()
+	}()
+}
+
+// Stop notifies the receivers, that the application is stopping. The new work will not be started after this call
+func (s *Service) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopping {
+		return
+	}
+
+	s.stopping = true
+	close(s.stopped)
+}
+
+// Stopping returns the channel, which is closed once the application is stopping
+func (s *Service) Stopping() <-chan struct{} {
+	return s.stopped
+}
+
+// Context returns the parent context for the running work. It is cancelled by Cancel method
+func (s *Service) Context() context.Context {
+	return s.ctx
+}
+
+// Cancel cancels the context of the running work. It is used, when the running work was not finished in time during the application stop
+func (s *Service) Cancel() {
+	s.cancel()
+}
+
+// IsStopping returns true, once the application is stopping
+func (s *Service) IsStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stopping
+}
+
+// Wait waits until all running work will be finished. If the work was not finished during the timeout, the error is returned
+func (s *Service) Wait(timeout time.Duration) error {
+	s.mu.Lock()
+	if s.running == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+
+	if s.idle == nil {
+		s.idle = make(chan struct{})
+	}
+
+	idle := s.idle
+	s.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-idle:
+		return nil
+	case <-timer.C:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return fmt.Errorf("the running work was not finished in %s, %d still running", timeout, s.running)
+	}
+}
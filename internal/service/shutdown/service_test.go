@@ -0,0 +1,78 @@
+package shutdown
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_Wait(t *testing.T) {
+	s := New()
+	assert.NoError(t, s.Wait(time.Millisecond))
+
+	var finished int64
+	assert.True(t, s.Begin())
+	s.Go(func() {
+		time.Sleep(time.Millisecond * 50)
+
+		//The nested work is started, even when the application is stopping
+		s.Go(func() {
+			time.Sleep(time.Millisecond * 50)
+			atomic.AddInt64(&finished, 1)
+		})
+
+		atomic.AddInt64(&finished, 1)
+	})
+
+	s.Stop()
+	assert.False(t, s.Begin())
+	s.End()
+
+	assert.NoError(t, s.Wait(time.Second))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&finished))
+}
+
+func TestService_WaitTimeout(t *testing.T) {
+	s := New()
+
+	release := make(chan struct{})
+	s.Go(func() {
+		<-release
+	})
+
+	s.Stop()
+	err := s.Wait(time.Millisecond * 10)
+	assert.EqualError(t, err, "the running work was not finished in 10ms, 1 still running")
+
+	close(release)
+	assert.NoError(t, s.Wait(time.Second))
+}
+
+func TestService_Stop(t *testing.T) {
+	s := New()
+	assert.False(t, s.IsStopping())
+
+	select {
+	case <-s.Stopping():
+		t.Fatal("The service should not be stopped")
+	default:
+	}
+
+	s.Stop()
+	s.Stop()
+
+	assert.True(t, s.IsStopping())
+	_, opened := <-s.Stopping()
+	assert.False(t, opened)
+}
+
+func TestService_Cancel(t *testing.T) {
+	s := New()
+	assert.NoError(t, s.Context().Err())
+
+	s.Cancel()
+	assert.ErrorIs(t, s.Context().Err(), context.Canceled)
+}
@@ -10,6 +10,9 @@ var Service TimeService
 
 type TimeService struct {
 	TimeZone *time.Location
+
+	//Clock returns the current time. It is replaced by the fake clock in the tests. The real time is used, when it is not set
+	Clock func() time.Time
 }
 
 func (s TimeService) Now() time.Time {
@@ -17,6 +20,10 @@ func (s TimeService) Now() time.Time {
 		s.TimeZone = config.DefaultTimezone
 	}
 
+	if s.Clock != nil {
+		return s.Clock().In(s.TimeZone)
+	}
+
 	return time.Now().In(s.TimeZone)
 }
 
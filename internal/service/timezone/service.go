@@ -0,0 +1,251 @@
+package timezone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+// The chat profiles are fetched again after this time, so the changed time zones of the profiles are used
+const profilesTTL = time.Hour
+
+// UsersClient the client, which retrieves the chat users with their profiles
+type UsersClient interface {
+	GetUsersList() (dto.SlackResponseUsersList, int, error)
+}
+
+// Service resolves the time zones of the users. The time zone selected by the user has the priority over the time zone of the chat profile.
+// The application time zone is used, when both are not set
+type Service struct {
+	mu    sync.Mutex
+	db    clients.BaseClientInterface
+	users UsersClient
+
+	profiles         map[string]*time.Location
+	profilesLoadedAt time.Time
+
+	//The time zones selected by the users. The nil value means, that the user did not select the time zone
+	selected map[string]*time.Location
+}
+
+// S the initialised service. Until the initialisation the application time zone is used for all users
+var S = &Service{}
+
+// InitS initialises the service with the database, where the selected time zones are stored, and the chat client, which retrieves the profiles
+func InitS(db clients.BaseClientInterface, users UsersClient) {
+	S = &Service{
+		db:       db,
+		users:    users,
+		selected: map[string]*time.Location{},
+	}
+}
+
+// Default retrieves the application time zone
+func Default() *time.Location {
+	return _time.Service.Now().Location()
+}
+
+// Now retrieves the current time in the time zone
+func Now(location *time.Location) time.Time {
+	return _time.Service.Now().In(location)
+}
+
+// Parse finds the time zone by its name, like `Europe/Berlin` or `america/new_york`
+func Parse(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.EqualFold(name, "local") {
+		return nil, fmt.Errorf("the time zone `%s` is unknown. Please, use the name like `Europe/Berlin` or `UTC`", name)
+	}
+
+	if location, err := time.LoadLocation(name); err == nil {
+		return location, nil
+	}
+
+	//The names are case-sensitive, so we try the usual capitalisation, like `America/New_York`
+	if location, err := time.LoadLocation(capitalise(name)); err == nil {
+		return location, nil
+	}
+
+	return nil, fmt.Errorf("the time zone `%s` is unknown. Please, use the name like `Europe/Berlin` or `UTC`", name)
+}
+
+// Location retrieves the time zone of the user
+func (s *Service) Location(user string) *time.Location {
+	location, err := s.Selected(user)
+	if err != nil {
+		log.Logger().AddError(err).Str("user", user).Msg("Failed to retrieve the selected time zone of the user")
+	}
+
+	if location != nil {
+		return location
+	}
+
+	if location = s.profile(user); location != nil {
+		return location
+	}
+
+	return Default()
+}
+
+// Selected retrieves the time zone, which was selected by the user. The nil is returned, when the user did not select it.
+// The selected time zones are cached, so the database is not queried for each answer
+func (s *Service) Selected(user string) (*time.Location, error) {
+	if s.db == nil || user == "" {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if location, ok := s.selected[user]; ok {
+		return location, nil
+	}
+
+	q := new(clients.Query).
+		Select(databasedto.UserTimezonesModel.GetColumns()).
+		From(databasedto.UserTimezonesModel).
+		Where(database.WhereEquals("user", user)).
+		Limit(query.Limit{From: 0, To: 1})
+
+	res, err := s.db.Execute(q)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Items()) == 0 {
+		s.selected[user] = nil
+		return nil, nil
+	}
+
+	location, err := Parse(res.Items()[0].GetField("timezone").Value.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	s.selected[user] = location
+
+	return location, nil
+}
+
+// Select saves the time zone selected by the user
+func (s *Service) Select(user string, name string) (*time.Location, error) {
+	if s.db == nil {
+		return nil, errors.New("the time zones storage is not initialised")
+	}
+
+	location, err := Parse(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.Reset(user); err != nil {
+		return nil, err
+	}
+
+	q := new(clients.Query).Insert(&cdto.BaseModel{
+		TableName: databasedto.UserTimezonesModel.GetTableName(),
+		Fields: []interface{}{
+			cdto.ModelField{Name: "user", Value: user},
+			cdto.ModelField{Name: "timezone", Value: location.String()},
+		},
+	})
+	if _, err = s.db.Execute(q); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.selected[user] = location
+	s.mu.Unlock()
+
+	log.Logger().Info().Str("user", user).Str("timezone", location.String()).Msg("Time zone selected")
+
+	return location, nil
+}
+
+// Reset removes the time zone selected by the user, so the time zone of the chat profile is used again
+func (s *Service) Reset(user string) error {
+	if s.db == nil {
+		return errors.New("the time zones storage is not initialised")
+	}
+
+	q := new(clients.Query).Delete().From(databasedto.UserTimezonesModel).Where(database.WhereEquals("user", user))
+	_, err := s.db.Execute(q)
+
+	//The cached time zone is removed, so it is retrieved from the database again
+	s.mu.Lock()
+	delete(s.selected, user)
+	s.mu.Unlock()
+
+	return err
+}
+
+// profile retrieves the time zone from the chat profile of the user. The profiles are cached, so the chat API is not called for each message
+func (s *Service) profile(user string) *time.Location {
+	if s.users == nil || user == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _time.Service.Now().Sub(s.profilesLoadedAt) > profilesTTL {
+		//The failed request is not repeated until the cache expires, so the chat API is not flooded
+		s.profilesLoadedAt = _time.Service.Now()
+
+		users, statusCode, err := s.users.GetUsersList()
+		if err != nil {
+			log.Logger().AddError(err).Int("status_code", statusCode).Msg("Failed to fetch the users time zones")
+			return s.profiles[user]
+		}
+
+		s.profiles = map[string]*time.Location{}
+		for _, member := range users.Members {
+			name, _ := member.Tz.(string)
+			if name == "" {
+				continue
+			}
+
+			location, err := Parse(name)
+			if err != nil {
+				log.Logger().Debug().Str("user", member.ID).Str("timezone", name).Msg("The time zone of the user profile is unknown")
+				continue
+			}
+
+			s.profiles[member.ID] = location
+		}
+	}
+
+	return s.profiles[user]
+}
+
+// capitalise converts the name to the usual capitalisation of the time zones, like `America/New_York`. The short names, like `utc`, are upper-cased
+func capitalise(name string) string {
+	if len(name) <= 4 {
+		return strings.ToUpper(name)
+	}
+
+	var result strings.Builder
+	isWordStart := true
+	for _, r := range strings.ToLower(name) {
+		if isWordStart {
+			result.WriteString(strings.ToUpper(string(r)))
+		} else {
+			result.WriteRune(r)
+		}
+
+		isWordStart = r == '/' || r == '_' || r == '-'
+	}
+
+	return result.String()
+}
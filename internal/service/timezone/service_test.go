@@ -0,0 +1,167 @@
+package timezone
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/dto"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/devbot/internal/log"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/sharovik/devbot/test"
+	"github.com/sharovik/orm/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	_ = log.Init(log.Config{Env: "testing"})
+	_time.InitNOW(time.UTC)
+}
+
+type fakeUsersClient struct {
+	calls int
+	err   error
+}
+
+func (c *fakeUsersClient) GetUsersList() (dto.SlackResponseUsersList, int, error) {
+	c.calls++
+	if c.err != nil {
+		return dto.SlackResponseUsersList{}, http.StatusInternalServerError, c.err
+	}
+
+	return dto.SlackResponseUsersList{
+		Ok: true,
+		Members: []dto.SlackMember{
+			{ID: "U1", Tz: "Europe/Berlin"},
+			{ID: "U2", Tz: "Unknown/Zone"},
+			{ID: "U3"},
+		},
+	}, http.StatusOK, nil
+}
+
+func initTestService(t *testing.T, users UsersClient) clients.BaseClientInterface {
+	db := test.InitDatabase(t, databasedto.UserTimezonesModel)
+	InitS(db, users)
+
+	return db
+}
+
+func TestParse(t *testing.T) {
+	cases := map[string]string{
+		"Europe/Berlin":    "Europe/Berlin",
+		"america/new_york": "America/New_York",
+		"utc":              "UTC",
+		" Asia/Tokyo ":     "Asia/Tokyo",
+	}
+
+	for name, expected := range cases {
+		location, err := Parse(name)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+
+		assert.Equal(t, expected, location.String(), name)
+	}
+
+	for _, name := range []string{"", "Local", "Mars/Olympus", "../../etc/passwd"} {
+		_, err := Parse(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestService_Location(t *testing.T) {
+	users := &fakeUsersClient{}
+	initTestService(t, users)
+
+	//The time zone of the chat profile is used
+	assert.Equal(t, "Europe/Berlin", S.Location("U1").String())
+
+	//The unknown and missing time zones of the profiles are replaced by the application one
+	assert.Equal(t, "UTC", S.Location("U2").String())
+	assert.Equal(t, "UTC", S.Location("U3").String())
+
+	//The selected time zone has the priority
+	location, err := S.Select("U1", "asia/tokyo")
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", location.String())
+	assert.Equal(t, "Asia/Tokyo", S.Location("U1").String())
+
+	_, err = S.Select("U1", "America/New_York")
+	assert.NoError(t, err)
+	assert.Equal(t, "America/New_York", S.Location("U1").String())
+
+	_, err = S.Select("U1", "Mars/Olympus")
+	assert.Error(t, err)
+	assert.Equal(t, "America/New_York", S.Location("U1").String())
+
+	assert.NoError(t, S.Reset("U1"))
+	selected, err := S.Selected("U1")
+	assert.NoError(t, err)
+	assert.Nil(t, selected)
+	assert.Equal(t, "Europe/Berlin", S.Location("U1").String())
+
+	//The profiles are cached
+	assert.Equal(t, 1, users.calls)
+}
+
+func TestService_Location_ProfilesCache(t *testing.T) {
+	users := &fakeUsersClient{err: errors.New("the chat is not available")}
+	initTestService(t, users)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	_time.Service.Clock = func() time.Time {
+		return now
+	}
+	defer func() {
+		_time.Service.Clock = nil
+	}()
+
+	//The failed request is not repeated until the cache expires
+	assert.Equal(t, "UTC", S.Location("U1").String())
+	assert.Equal(t, "UTC", S.Location("U1").String())
+	assert.Equal(t, 1, users.calls)
+
+	users.err = nil
+	now = now.Add(profilesTTL + time.Minute)
+	assert.Equal(t, "Europe/Berlin", S.Location("U1").String())
+	assert.Equal(t, 2, users.calls)
+}
+
+func TestService_Selected_Cache(t *testing.T) {
+	db := initTestService(t, nil)
+
+	_, err := S.Select("U1", "Asia/Tokyo")
+	assert.NoError(t, err)
+
+	//The selected time zone is taken from the cache, so the database is not queried again
+	_, err = db.Execute(new(clients.Query).Delete().From(databasedto.UserTimezonesModel))
+	assert.NoError(t, err)
+
+	selected, err := S.Selected("U1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", selected.String())
+
+	//The users without selected time zone are cached as well
+	selected, err = S.Selected("U2")
+	assert.NoError(t, err)
+	assert.Nil(t, selected)
+
+	_, err = S.Select("U2", "Europe/Berlin")
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", S.Location("U2").String())
+
+	assert.NoError(t, S.Reset("U2"))
+	assert.Equal(t, "UTC", S.Location("U2").String())
+}
+
+func TestService_NotInitialised(t *testing.T) {
+	S = &Service{}
+
+	assert.Equal(t, "UTC", S.Location("U1").String())
+
+	_, err := S.Select("U1", "Europe/Berlin")
+	assert.Error(t, err)
+	assert.Error(t, S.Reset("U1"))
+}
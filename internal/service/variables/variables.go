@@ -0,0 +1,356 @@
+package variables
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/service/phrasebook"
+	"github.com/sharovik/devbot/internal/service/schedule"
+)
+
+// DateFormat the format of the date variables values. The value is followed by the name of the time zone of the user, like `2024-05-01 10:00 Europe/Berlin`
+const DateFormat = "2006-01-02 15:04"
+
+var (
+	channelMentionRegex = regexp.MustCompile(`^(?:<|&lt;)#(\w+)(?:\|[^>]*)?(?:>|&gt;)$`)
+	channelNameRegex    = regexp.MustCompile(`^#([\w.-]+)$`)
+	userMentionRegex    = regexp.MustCompile(`^(?:<|&lt;)@(\w+)(?:\|[^>]*)?(?:>|&gt;)$`)
+	userNameRegex       = regexp.MustCompile(`^@([\w.-]+)$`)
+	linkRegex           = regexp.MustCompile(`^(?:<|&lt;)([^|>&]+)(?:\|[^>]*)?(?:>|&gt;)$`)
+)
+
+// InvalidAnswerError the answer doesn't satisfy the variable rules. The error contains the explanation for the user
+type InvalidAnswerError struct {
+	Explanation string
+}
+
+// Error retrieves the explanation of the error
+func (e InvalidAnswerError) Error() string {
+	return e.Explanation
+}
+
+func invalid(book *phrasebook.Phrasebook, phrase string, args ...interface{}) error {
+	return InvalidAnswerError{Explanation: book.Phrase(phrase, args...)}
+}
+
+// Check validates the rules of the variable, so the invalid rules are found during the installation instead of the conversation
+func Check(rules database.VariableRules) error {
+	switch rules.Type {
+	case "", database.VariableTypeString, database.VariableTypeInt, database.VariableTypeBool, database.VariableTypeDate,
+		database.VariableTypeChannel, database.VariableTypeUser, database.VariableTypeURL:
+	case database.VariableTypeEnum:
+		if len(rules.Choices) == 0 {
+			return fmt.Errorf("the choices of the enum variable cannot be empty")
+		}
+	case database.VariableTypeRegex:
+		if rules.Pattern == "" {
+			return fmt.Errorf("the pattern of the regex variable cannot be empty")
+		}
+
+		if _, err := regexp.Compile(rules.Pattern); err != nil {
+			return fmt.Errorf("the pattern of the regex variable is invalid: %w", err)
+		}
+	default:
+		return fmt.Errorf("the variable type %s is not supported", rules.Type)
+	}
+
+	if len(rules.Choices) != 0 && rules.Type != database.VariableTypeEnum {
+		return fmt.Errorf("the choices can be used only by the enum variable")
+	}
+
+	if rules.Pattern != "" && rules.Type != database.VariableTypeRegex {
+		return fmt.Errorf("the pattern can be used only by the regex variable")
+	}
+
+	for _, transition := range rules.Transitions {
+		if err := checkTransition(rules, transition); err != nil {
+			return err
+		}
+	}
+
+	if rules.Default == "" {
+		return nil
+	}
+
+	if !rules.Optional {
+		return fmt.Errorf("the default value can be used only by the optional variable")
+	}
+
+	//The default values are written in the built-in locale, like the values of the answers
+	if _, err := Parse(phrasebook.Default(), nil, database.ScenarioVariable{VariableRules: database.VariableRules{
+		Type:    rules.Type,
+		Choices: rules.Choices,
+		Pattern: rules.Pattern,
+	}}, rules.Default); err != nil {
+		return fmt.Errorf("the default value is invalid: %w", err)
+	}
+
+	return nil
+}
+
+func checkTransition(rules database.VariableRules, transition database.Transition) error {
+	if (transition.Next == "") == !transition.End {
+		return fmt.Errorf("the transition should have either the next question or the end of the scenario")
+	}
+
+	if transition.Matches != "" {
+		if _, err := regexp.Compile(transition.Matches); err != nil {
+			return fmt.Errorf("the regex of the transition is invalid: %w", err)
+		}
+	}
+
+	if transition.Equals != "" && rules.Type == database.VariableTypeEnum && !contains(rules.Choices, transition.Equals) {
+		return fmt.Errorf("the transition value %s is not one of the choices", transition.Equals)
+	}
+
+	return nil
+}
+
+// CheckTransitions validates the transitions between the variables of the scenario. The next questions should exist in the scenario
+// and the transitions cannot make the loop, because the answered variables are not asked again
+func CheckTransitions(list []database.ScenarioVariable) error {
+	indexes := map[string]int{}
+	for i, variable := range list {
+		indexes[variable.Question] = i
+	}
+
+	edges := make([][]int, len(list))
+	for i, variable := range list {
+		hasDefault := true
+		for _, transition := range variable.Transitions {
+			if transition.Equals == "" && transition.Matches == "" {
+				hasDefault = false
+			}
+
+			if transition.End {
+				continue
+			}
+
+			next, ok := indexes[transition.Next]
+			if !ok {
+				return fmt.Errorf("the next question %q of the variable %q is not defined in the scenario", transition.Next, variable.Question)
+			}
+
+			edges[i] = append(edges[i], next)
+		}
+
+		//Without the matched transition the next variable of the list is asked
+		if hasDefault && i+1 < len(list) {
+			edges[i] = append(edges[i], i+1)
+		}
+	}
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+
+	states := make([]int, len(list))
+	var walk func(i int) error
+	walk = func(i int) error {
+		states[i] = inProgress
+		for _, next := range edges[i] {
+			switch states[next] {
+			case inProgress:
+				return fmt.Errorf("the transitions of the variable %q make the loop", list[i].Question)
+			case unvisited:
+				if err := walk(next); err != nil {
+					return err
+				}
+			}
+		}
+
+		states[i] = done
+		return nil
+	}
+
+	for i := range list {
+		if states[i] == unvisited {
+			if err := walk(i); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Parse validates the answer by the variable rules and returns the normalised value. The answers and explanations of the phrasebook are used.
+// The dates are parsed in the time zone of the user, the application time zone is used when the location is nil.
+// The InvalidAnswerError is returned for the invalid answer
+func Parse(book *phrasebook.Phrasebook, location *time.Location, variable database.ScenarioVariable, answer string) (string, error) {
+	text := strings.TrimSpace(answer)
+	if variable.Optional && book.IsSkip(text) {
+		return variable.Default, nil
+	}
+
+	if text == "" {
+		return "", invalid(book, phrasebook.EmptyAnswer)
+	}
+
+	switch variable.Type {
+	case database.VariableTypeInt:
+		number, err := strconv.Atoi(text)
+		if err != nil {
+			return "", invalid(book, phrasebook.InvalidInt, text)
+		}
+
+		return strconv.Itoa(number), nil
+	case database.VariableTypeBool:
+		//The value is the same in all locales, so the events don't depend on the language of the user
+		switch {
+		case book.IsYes(text):
+			return "yes", nil
+		case book.IsNo(text):
+			return "no", nil
+		}
+
+		return "", invalid(book, phrasebook.InvalidBool, text)
+	case database.VariableTypeEnum:
+		for _, choice := range variable.Choices {
+			if strings.EqualFold(choice, text) {
+				return choice, nil
+			}
+		}
+
+		return "", invalid(book, phrasebook.InvalidEnum, text, choicesList(variable.Choices))
+	case database.VariableTypeDate:
+		executeAt, err := new(schedule.ExecuteAt).In(location).FromString(text)
+		if err != nil || executeAt.IsEmpty() {
+			return "", invalid(book, phrasebook.InvalidDate, text)
+		}
+
+		return formatDate(executeAt.Datetime()), nil
+	case database.VariableTypeChannel:
+		if match := firstMatch(text, channelMentionRegex, channelNameRegex); match != "" {
+			return match, nil
+		}
+
+		return "", invalid(book, phrasebook.InvalidChannel, text)
+	case database.VariableTypeUser:
+		if match := firstMatch(text, userMentionRegex, userNameRegex); match != "" {
+			return match, nil
+		}
+
+		return "", invalid(book, phrasebook.InvalidUser, text)
+	case database.VariableTypeURL:
+		//The links can be formatted by the messages API, like <https://example.com|example.com>
+		link := text
+		if match := firstMatch(text, linkRegex); match != "" {
+			link = match
+		}
+
+		u, err := url.ParseRequestURI(link)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", invalid(book, phrasebook.InvalidURL, text)
+		}
+
+		return link, nil
+	case database.VariableTypeRegex:
+		matched, err := regexp.MatchString(variable.Pattern, text)
+		if err != nil || !matched {
+			return "", invalid(book, phrasebook.InvalidPattern, text, variable.Pattern)
+		}
+
+		return text, nil
+	}
+
+	//The string variables keep the original answer
+	return answer, nil
+}
+
+// ParseDate parses the value of the date variable in the time zone, which is written in the value
+func ParseDate(value string) (time.Time, error) {
+	separator := strings.LastIndex(value, " ")
+	if separator == -1 {
+		return time.Time{}, fmt.Errorf("the date %q has no time zone", value)
+	}
+
+	location, err := time.LoadLocation(value[separator+1:])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.ParseInLocation(DateFormat, value[:separator], location)
+}
+
+// formatDate formats the date in its time zone and adds the name of the time zone, so the events know, in which time zone the answer was given
+func formatDate(date time.Time) string {
+	return fmt.Sprintf("%s %s", date.Format(DateFormat), date.Location())
+}
+
+// Prompt retrieves the question of the variable with the hints of the phrasebook, how it can be answered
+func Prompt(book *phrasebook.Phrasebook, variable database.ScenarioVariable) string {
+	prompt := variable.Question
+	if variable.Type == database.VariableTypeEnum {
+		prompt += "\n" + book.Phrase(phrasebook.Choices, choicesList(variable.Choices))
+	}
+
+	if variable.Optional {
+		prompt += "\n" + book.Phrase(phrasebook.SkipHint, book.SkipAnswer())
+	}
+
+	return prompt
+}
+
+// NextPrompt retrieves the prompt of the variable, which should be asked next. Empty string is returned, when the scenario is finished
+func NextPrompt(book *phrasebook.Phrasebook, scenario database.EventScenario) string {
+	index, ok := scenario.NextVariable()
+	if !ok {
+		return ""
+	}
+
+	return Prompt(book, scenario.RequiredVariables[index])
+}
+
+// Review retrieves the numbered list of the answered variables in the order, in which they were asked. The numbers are used by the change command
+func Review(book *phrasebook.Phrasebook, scenario database.EventScenario) string {
+	var lines []string
+	for i, index := range scenario.AnsweredVariables() {
+		variable := scenario.RequiredVariables[index]
+
+		value := book.Phrase(phrasebook.Skipped)
+		if variable.Value != "" {
+			value = fmt.Sprintf("`%s`", variable.Value)
+		}
+
+		lines = append(lines, fmt.Sprintf("%d. %s %s", i+1, variable.Question, value))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func contains(items []string, text string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func choicesList(choices []string) string {
+	var items []string
+	for _, choice := range choices {
+		items = append(items, fmt.Sprintf("`%s`", choice))
+	}
+
+	return strings.Join(items, ", ")
+}
+
+func firstMatch(text string, regexes ...*regexp.Regexp) string {
+	for _, re := range regexes {
+		if matches := re.FindStringSubmatch(text); len(matches) == 2 {
+			return matches[1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,215 @@
+package variables
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sharovik/devbot/internal/database"
+	"github.com/sharovik/devbot/internal/service/phrasebook"
+	_time "github.com/sharovik/devbot/internal/service/time"
+	"github.com/stretchr/testify/assert"
+)
+
+var book = phrasebook.Default()
+
+func init() {
+	_time.InitNOW(time.UTC)
+}
+
+func variable(rules database.VariableRules) database.ScenarioVariable {
+	return database.ScenarioVariable{Question: "Question?", VariableRules: rules}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		rules    database.VariableRules
+		answer   string
+		expected string
+	}{
+		{database.VariableRules{}, " any text ", " any text "},
+		{database.VariableRules{Type: database.VariableTypeInt}, " 042 ", "42"},
+		{database.VariableRules{Type: database.VariableTypeBool}, "Yes", "yes"},
+		{database.VariableRules{Type: database.VariableTypeBool}, "n", "no"},
+		{database.VariableRules{Type: database.VariableTypeEnum, Choices: []string{"staging", "Production"}}, "production", "Production"},
+		{database.VariableRules{Type: database.VariableTypeDate}, "2024-05-01 10:00", "2024-05-01 10:00 UTC"},
+		{database.VariableRules{Type: database.VariableTypeChannel}, "<#C123|general>", "C123"},
+		{database.VariableRules{Type: database.VariableTypeChannel}, "&lt;#C123&gt;", "C123"},
+		{database.VariableRules{Type: database.VariableTypeChannel}, "#general", "general"},
+		{database.VariableRules{Type: database.VariableTypeUser}, "<@U123>", "U123"},
+		{database.VariableRules{Type: database.VariableTypeUser}, "@john.doe", "john.doe"},
+		{database.VariableRules{Type: database.VariableTypeURL}, "https://example.com/path?q=1", "https://example.com/path?q=1"},
+		{database.VariableRules{Type: database.VariableTypeURL}, "<https://example.com|example.com>", "https://example.com"},
+		{database.VariableRules{Type: database.VariableTypeRegex, Pattern: `^[A-Z]+-\d+$`}, "DEV-42", "DEV-42"},
+		{database.VariableRules{Type: database.VariableTypeInt, Optional: true, Default: "1"}, "Skip", "1"},
+		{database.VariableRules{Optional: true}, "skip", ""},
+	}
+
+	for _, c := range cases {
+		actual, err := Parse(book, nil, variable(c.rules), c.answer)
+		assert.NoError(t, err, c.answer)
+		assert.Equal(t, c.expected, actual, c.answer)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := []struct {
+		rules  database.VariableRules
+		answer string
+	}{
+		{database.VariableRules{}, "  "},
+		{database.VariableRules{Type: database.VariableTypeInt}, "forty two"},
+		{database.VariableRules{Type: database.VariableTypeBool}, "maybe"},
+		{database.VariableRules{Type: database.VariableTypeEnum, Choices: []string{"staging"}}, "production"},
+		{database.VariableRules{Type: database.VariableTypeDate}, "some day"},
+		{database.VariableRules{Type: database.VariableTypeChannel}, "general"},
+		{database.VariableRules{Type: database.VariableTypeUser}, "john"},
+		{database.VariableRules{Type: database.VariableTypeURL}, "example.com"},
+		{database.VariableRules{Type: database.VariableTypeURL}, "ftp://example.com"},
+		{database.VariableRules{Type: database.VariableTypeRegex, Pattern: `^[A-Z]+-\d+$`}, "dev 42"},
+		{database.VariableRules{Type: database.VariableTypeInt}, "skip"},
+	}
+
+	for _, c := range cases {
+		_, err := Parse(book, nil, variable(c.rules), c.answer)
+
+		var invalidAnswer InvalidAnswerError
+		assert.True(t, errors.As(err, &invalidAnswer), c.answer)
+		assert.NotEmpty(t, invalidAnswer.Explanation, c.answer)
+	}
+}
+
+func TestParse_DelayedDate(t *testing.T) {
+	actual, err := Parse(book, nil, variable(database.VariableRules{Type: database.VariableTypeDate}), "in 2 hours")
+	assert.NoError(t, err)
+
+	parsed, err := ParseDate(actual)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, _time.Service.Now().Add(2*time.Hour), parsed, time.Minute)
+}
+
+func TestParse_DateInUserTimezone(t *testing.T) {
+	_time.Service.Clock = func() time.Time {
+		return time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC)
+	}
+	defer func() {
+		_time.Service.Clock = nil
+	}()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	//It is already the 2nd of May in Berlin, so the tomorrow of the user is the 3rd of May
+	actual, err := Parse(book, berlin, variable(database.VariableRules{Type: database.VariableTypeDate}), "tomorrow at 10")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-05-03 10:00 Europe/Berlin", actual)
+
+	parsed, err := ParseDate(actual)
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2024, 5, 3, 8, 0, 0, 0, time.UTC).Equal(parsed))
+
+	actual, err = Parse(book, berlin, variable(database.VariableRules{Type: database.VariableTypeDate}), "2024-05-01 10:00")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-05-01 10:00 Europe/Berlin", actual)
+}
+
+func TestParseDate_Invalid(t *testing.T) {
+	for _, value := range []string{"2024-05-01", "2024-05-01 10:00 Mars/Base", "tomorrow UTC"} {
+		_, err := ParseDate(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	valid := []database.VariableRules{
+		{},
+		{Type: database.VariableTypeDate},
+		{Type: database.VariableTypeEnum, Choices: []string{"a", "b"}, Optional: true, Default: "a"},
+		{Type: database.VariableTypeRegex, Pattern: `\d+`},
+		{Type: database.VariableTypeEnum, Choices: []string{"a", "b"}, Transitions: []database.Transition{{Equals: "a", Next: "Second?"}, {End: true}}},
+	}
+
+	for _, rules := range valid {
+		assert.NoError(t, Check(rules), rules.Type)
+	}
+
+	invalid := []database.VariableRules{
+		{Type: "float"},
+		{Type: database.VariableTypeEnum},
+		{Type: database.VariableTypeRegex},
+		{Type: database.VariableTypeRegex, Pattern: `(\d+`},
+		{Type: database.VariableTypeInt, Choices: []string{"1"}},
+		{Pattern: `\d+`},
+		{Type: database.VariableTypeInt, Default: "1"},
+		{Type: database.VariableTypeInt, Optional: true, Default: "one"},
+		{Transitions: []database.Transition{{Equals: "a"}}},
+		{Transitions: []database.Transition{{Next: "Second?", End: true}}},
+		{Transitions: []database.Transition{{Matches: "(a", Next: "Second?"}}},
+		{Type: database.VariableTypeEnum, Choices: []string{"a"}, Transitions: []database.Transition{{Equals: "b", End: true}}},
+	}
+
+	for _, rules := range invalid {
+		assert.Error(t, Check(rules), rules.Type)
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	assert.Equal(t, "Question?", Prompt(book, variable(database.VariableRules{Type: database.VariableTypeBool})))
+	assert.Equal(t, "Question?\nPlease, choose one of: `a`, `b`.\nWrite `skip` to skip this question.",
+		Prompt(book, variable(database.VariableRules{Type: database.VariableTypeEnum, Choices: []string{"a", "b"}, Optional: true})))
+
+	scenario := database.EventScenario{RequiredVariables: []database.ScenarioVariable{
+		{Question: "First?", Answered: true},
+		{Question: "Second?", VariableRules: database.VariableRules{Optional: true}},
+	}}
+	assert.Equal(t, "Second?\nWrite `skip` to skip this question.", NextPrompt(book, scenario))
+
+	scenario.RequiredVariables[1].Answered = true
+	assert.Empty(t, NextPrompt(book, scenario))
+}
+
+func TestCheckTransitions(t *testing.T) {
+	linear := []database.ScenarioVariable{{Question: "First?"}, {Question: "Second?"}}
+	assert.NoError(t, CheckTransitions(linear))
+
+	branching := []database.ScenarioVariable{
+		{Question: "First?", VariableRules: database.VariableRules{Transitions: []database.Transition{
+			{Equals: "yes", Next: "Third?"},
+		}}},
+		{Question: "Second?", VariableRules: database.VariableRules{Transitions: []database.Transition{{End: true}}}},
+		{Question: "Third?"},
+	}
+	assert.NoError(t, CheckTransitions(branching))
+
+	unknown := []database.ScenarioVariable{
+		{Question: "First?", VariableRules: database.VariableRules{Transitions: []database.Transition{{Next: "Unknown?"}}}},
+	}
+	assert.Error(t, CheckTransitions(unknown))
+
+	//The second variable is followed by the third one, which returns to the second
+	loop := []database.ScenarioVariable{
+		{Question: "First?"},
+		{Question: "Second?"},
+		{Question: "Third?", VariableRules: database.VariableRules{Transitions: []database.Transition{{Equals: "again", Next: "Second?"}}}},
+	}
+	assert.Error(t, CheckTransitions(loop))
+}
+
+func TestReview(t *testing.T) {
+	scenario := database.EventScenario{RequiredVariables: []database.ScenarioVariable{
+		{
+			Question: "Which repository?",
+			Value:    "devbot",
+			VariableRules: database.VariableRules{Transitions: []database.Transition{
+				{Equals: "monorepo", Next: "Which service?"},
+				{Next: "Which branch?"},
+			}},
+		},
+		{Question: "Which service?", Value: "billing"},
+		{Question: "Which branch?", Answered: true},
+		{Question: "Which environment?"},
+	}}
+
+	assert.Equal(t, "1. Which repository? `devbot`\n2. Which branch? skipped", Review(book, scenario))
+	assert.Empty(t, Review(book, database.EventScenario{}))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/log"
+	"github.com/sharovik/devbot/internal/service/dictionary"
+)
+
+const (
+	actionExport = "export"
+	actionImport = "import"
+
+	descriptionAction = "The action, which should be executed: export or import"
+	descriptionFile   = "The path to the dictionary document. The .json files are in JSON format, the other files are in YAML format"
+	descriptionDryRun = "Show the changes of the import without applying them"
+)
+
+func main() {
+	if err := run(); err != nil {
+		log.Logger().AddError(err).Msg("Failed to process the dictionary")
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	action := flag.String("action", "", descriptionAction)
+	file := flag.String("file", "", descriptionFile)
+	dryRun := flag.Bool("dry-run", false, descriptionDryRun)
+	flag.Parse()
+
+	if *file == "" {
+		return fmt.Errorf("the file cannot be empty")
+	}
+
+	cnt, err := container.Init()
+	if err != nil {
+		return err
+	}
+
+	container.C = cnt
+	defer func() {
+		if err := container.C.Dictionary.CloseDatabaseConnection(); err != nil {
+			log.Logger().AddError(err).Msg("Failed to close connection")
+		}
+	}()
+
+	switch *action {
+	case actionExport:
+		return export(*file)
+	case actionImport:
+		return importDocument(*file, *dryRun)
+	}
+
+	return fmt.Errorf("the action %q is not supported, please use %s or %s", *action, actionExport, actionImport)
+}
+
+func export(file string) error {
+	doc, err := dictionary.Export()
+	if err != nil {
+		return err
+	}
+
+	data, err := dictionary.Marshal(doc, dictionary.FormatByPath(file))
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("The dictionary with %d events is exported to %s\n", len(doc.Events), file)
+	return nil
+}
+
+func importDocument(file string, dryRun bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	doc, err := dictionary.Unmarshal(data, dictionary.FormatByPath(file))
+	if err != nil {
+		return err
+	}
+
+	changes, err := dictionary.Import(doc, dryRun)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(changes) == 0:
+		fmt.Println("The dictionary is up to date")
+	case dryRun:
+		fmt.Printf("%d changes will be applied. Run the import without --dry-run to apply them\n", len(changes))
+	default:
+		fmt.Printf("%d changes are applied. The running bot loads them within MATCHING_CACHE_TTL seconds\n", len(changes))
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sharovik/devbot/internal/dto/databasedto"
 	"github.com/sharovik/devbot/internal/service/definedevents"
+	"github.com/sharovik/devbot/internal/service/definitions"
 	"github.com/sharovik/orm/clients"
 	"github.com/sharovik/orm/dto"
 
@@ -147,6 +148,12 @@ func run() error {
 		}
 	}
 
+	//The scenario definitions are attached to the installed events, so they are synced at the end
+	if err = definitions.InitS(cfg.ScenariosPath, container.C.DefinedEvents); err != nil {
+		log.Logger().AddError(err).Str("path", cfg.ScenariosPath).Msg("Failed to sync the scenario definitions")
+		return err
+	}
+
 	log.Logger().Info().Msg("Done")
 	return nil
 }
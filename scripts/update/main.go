@@ -21,6 +21,21 @@ var (
 		migrations.ExampleMigration{},
 		migrations.EventsTriggersHistoryMigration{},
 		migrations.UpdateEventsTriggersHistoryMigration{},
+		migrations.CreateConversationsMigration{},
+		migrations.UpdateConversationsMigration{},
+		migrations.CreateProcessedEventsMigration{},
+		migrations.AddEventsTriggersHistoryStatusMigration{},
+		migrations.AddQuestionsRegexPriorityMigration{},
+		migrations.CreateLearnedQuestionsMigration{},
+		migrations.AddQuestionsVariableRulesMigration{},
+		migrations.AddScenariosConfirmationMigration{},
+		migrations.AddSchedulesCronMigration{},
+		migrations.AddSchedulesStatusMigration{},
+		migrations.CreateScheduleRunsMigration{},
+		migrations.AddSchedulesMisfireMigration{},
+		migrations.CreateUserTimezonesMigration{},
+		migrations.AddSchedulesTimezoneMigration{},
+		migrations.AddScheduleRunsRetryMigration{},
 	}
 )
 
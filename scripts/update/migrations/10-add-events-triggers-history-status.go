@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+type AddEventsTriggersHistoryStatusMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m AddEventsTriggersHistoryStatusMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m AddEventsTriggersHistoryStatusMigration) GetName() string {
+	return "10-add-events-triggers-history-status"
+}
+
+func (m AddEventsTriggersHistoryStatusMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//The table, which was created from the current schema, already has these columns
+	q := new(clients.Query).
+		Select([]interface{}{"status", "error"}).
+		From(databasedto.EventTriggerHistoryModel).
+		Limit(query.Limit{From: 0, To: 1})
+	if _, err := client.Execute(q); err == nil {
+		return nil
+	}
+
+	for _, column := range []dto.ModelField{
+		{
+			Name:    "status",
+			Type:    dto.VarcharColumnType,
+			Length:  255,
+			Default: "success",
+		},
+		{
+			Name:       "error",
+			Type:       dto.VarcharColumnType,
+			Length:     255,
+			IsNullable: true,
+		},
+	} {
+		q = new(clients.Query).
+			Alter(databasedto.EventTriggerHistoryModel).
+			AddColumn(column)
+		if _, err := client.Execute(q); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Failed to add %s column to %s table", column.Name, databasedto.EventTriggerHistoryModel.GetTableName()))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+type AddQuestionsRegexPriorityMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m AddQuestionsRegexPriorityMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m AddQuestionsRegexPriorityMigration) GetName() string {
+	return "11-add-questions-regex-priority"
+}
+
+func (m AddQuestionsRegexPriorityMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//The table, which was created from the current schema, already has this column
+	q := new(clients.Query).
+		Select([]interface{}{"priority"}).
+		From(databasedto.QuestionsRegexModel).
+		Limit(query.Limit{From: 0, To: 1})
+	if _, err := client.Execute(q); err == nil {
+		return nil
+	}
+
+	q = new(clients.Query).
+		Alter(databasedto.QuestionsRegexModel).
+		AddColumn(dto.ModelField{
+			Name:    "priority",
+			Type:    dto.IntegerColumnType,
+			Default: 0,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to add priority column to %s table", databasedto.QuestionsRegexModel.GetTableName()))
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+)
+
+type CreateLearnedQuestionsMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m CreateLearnedQuestionsMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m CreateLearnedQuestionsMigration) GetName() string {
+	return "12-create-learned-questions"
+}
+
+func (m CreateLearnedQuestionsMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//Create learned questions table
+	q := new(clients.Query).
+		Create(databasedto.LearnedQuestionsModel).
+		IfNotExists()
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to create %s table", databasedto.LearnedQuestionsModel.GetTableName()))
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+type AddQuestionsVariableRulesMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m AddQuestionsVariableRulesMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m AddQuestionsVariableRulesMigration) GetName() string {
+	return "13-add-questions-variable-rules"
+}
+
+func (m AddQuestionsVariableRulesMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//The table, which was created from the current schema, already has this column
+	q := new(clients.Query).
+		Select([]interface{}{"variable_rules"}).
+		From(databasedto.QuestionsModel).
+		Limit(query.Limit{From: 0, To: 1})
+	if _, err := client.Execute(q); err == nil {
+		return nil
+	}
+
+	q = new(clients.Query).
+		Alter(databasedto.QuestionsModel).
+		AddColumn(dto.ModelField{
+			Name:       "variable_rules",
+			Type:       dto.VarcharColumnType,
+			IsNullable: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to add variable_rules column to %s table", databasedto.QuestionsModel.GetTableName()))
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+type AddScenariosConfirmationMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m AddScenariosConfirmationMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m AddScenariosConfirmationMigration) GetName() string {
+	return "14-add-scenarios-confirmation"
+}
+
+func (m AddScenariosConfirmationMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//The table, which was created from the current schema, already has this column
+	q := new(clients.Query).
+		Select([]interface{}{"confirmation"}).
+		From(databasedto.ScenariosModel).
+		Limit(query.Limit{From: 0, To: 1})
+	if _, err := client.Execute(q); err == nil {
+		return nil
+	}
+
+	q = new(clients.Query).
+		Alter(databasedto.ScenariosModel).
+		AddColumn(dto.ModelField{
+			Name:       "confirmation",
+			Type:       dto.BooleanColumnType,
+			IsNullable: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to add confirmation column to %s table", databasedto.ScenariosModel.GetTableName()))
+	}
+
+	return nil
+}
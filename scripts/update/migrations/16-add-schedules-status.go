@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+type AddSchedulesStatusMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m AddSchedulesStatusMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m AddSchedulesStatusMigration) GetName() string {
+	return "16-add-schedules-status"
+}
+
+func (m AddSchedulesStatusMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//The table, which was created from the current schema, already has this column
+	q := new(clients.Query).
+		Select([]interface{}{"status"}).
+		From(databasedto.SchedulesModel).
+		Limit(query.Limit{From: 0, To: 1})
+	if _, err := client.Execute(q); err == nil {
+		return nil
+	}
+
+	q = new(clients.Query).
+		Alter(databasedto.SchedulesModel).
+		AddColumn(dto.ModelField{
+			Name:       "status",
+			Type:       dto.VarcharColumnType,
+			Length:     32,
+			IsNullable: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to add status column to %s table", databasedto.SchedulesModel.GetTableName()))
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+)
+
+type CreateScheduleRunsMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m CreateScheduleRunsMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m CreateScheduleRunsMigration) GetName() string {
+	return "17-create-schedule-runs"
+}
+
+func (m CreateScheduleRunsMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//Create schedule runs table
+	q := new(clients.Query).
+		Create(databasedto.ScheduleRunsModel).
+		IfNotExists().
+		AddIndex(dto.Index{
+			Name:   "schedule_runs_schedule_id_index",
+			Target: databasedto.ScheduleRunsModel.GetTableName(),
+			Key:    "schedule_id",
+			Unique: false,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to create %s table", databasedto.ScheduleRunsModel.GetTableName()))
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+type AddSchedulesMisfireMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m AddSchedulesMisfireMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m AddSchedulesMisfireMigration) GetName() string {
+	return "18-add-schedules-misfire"
+}
+
+func (m AddSchedulesMisfireMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	for _, column := range []string{"misfire_policy", "last_run_at"} {
+		//The table, which was created from the current schema, already has this column
+		q := new(clients.Query).
+			Select([]interface{}{column}).
+			From(databasedto.SchedulesModel).
+			Limit(query.Limit{From: 0, To: 1})
+		if _, err := client.Execute(q); err == nil {
+			continue
+		}
+
+		q = new(clients.Query).
+			Alter(databasedto.SchedulesModel).
+			AddColumn(dto.ModelField{
+				Name:       column,
+				Type:       dto.VarcharColumnType,
+				Length:     255,
+				IsNullable: true,
+			})
+		if _, err := client.Execute(q); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Failed to add %s column to %s table", column, databasedto.SchedulesModel.GetTableName()))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+)
+
+type CreateUserTimezonesMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m CreateUserTimezonesMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m CreateUserTimezonesMigration) GetName() string {
+	return "19-create-user-timezones"
+}
+
+func (m CreateUserTimezonesMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//Create user timezones table
+	q := new(clients.Query).
+		Create(databasedto.UserTimezonesModel).
+		IfNotExists().
+		AddIndex(dto.Index{
+			Name:   "user_timezones_user_index",
+			Target: databasedto.UserTimezonesModel.GetTableName(),
+			Key:    "user",
+			Unique: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to create %s table", databasedto.UserTimezonesModel.GetTableName()))
+	}
+
+	return nil
+}
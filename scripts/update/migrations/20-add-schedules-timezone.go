@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+type AddSchedulesTimezoneMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m AddSchedulesTimezoneMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m AddSchedulesTimezoneMigration) GetName() string {
+	return "20-add-schedules-timezone"
+}
+
+func (m AddSchedulesTimezoneMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//The table, which was created from the current schema, already has this column
+	q := new(clients.Query).
+		Select([]interface{}{"timezone"}).
+		From(databasedto.SchedulesModel).
+		Limit(query.Limit{From: 0, To: 1})
+	if _, err := client.Execute(q); err == nil {
+		return nil
+	}
+
+	q = new(clients.Query).
+		Alter(databasedto.SchedulesModel).
+		AddColumn(dto.ModelField{
+			Name:       "timezone",
+			Type:       dto.VarcharColumnType,
+			Length:     64,
+			IsNullable: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to add timezone column to %s table", databasedto.SchedulesModel.GetTableName()))
+	}
+
+	return nil
+}
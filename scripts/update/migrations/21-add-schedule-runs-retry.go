@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+	"github.com/sharovik/orm/query"
+)
+
+type AddScheduleRunsRetryMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m AddScheduleRunsRetryMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m AddScheduleRunsRetryMigration) GetName() string {
+	return "21-add-schedule-runs-retry"
+}
+
+func (m AddScheduleRunsRetryMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//The table, which was created from the current schema, already has this column
+	q := new(clients.Query).
+		Select([]interface{}{"retry_at"}).
+		From(databasedto.ScheduleRunsModel).
+		Limit(query.Limit{From: 0, To: 1})
+	if _, err := client.Execute(q); err == nil {
+		return nil
+	}
+
+	q = new(clients.Query).
+		Alter(databasedto.ScheduleRunsModel).
+		AddColumn(dto.ModelField{
+			Name:       "retry_at",
+			Type:       dto.VarcharColumnType,
+			Length:     255,
+			IsNullable: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to add retry_at column to %s table", databasedto.ScheduleRunsModel.GetTableName()))
+	}
+
+	return nil
+}
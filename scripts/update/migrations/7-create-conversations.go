@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+)
+
+type CreateConversationsMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m CreateConversationsMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m CreateConversationsMigration) GetName() string {
+	return "7-create-conversations"
+}
+
+func (m CreateConversationsMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//Create conversations table
+	q := new(clients.Query).
+		Create(databasedto.ConversationsModel).
+		IfNotExists().
+		AddIndex(dto.Index{
+			Name:   "conversations_channel_uindex",
+			Target: databasedto.ConversationsModel.GetTableName(),
+			Key:    "channel",
+			Unique: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to create %s table", databasedto.ConversationsModel.GetTableName()))
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+)
+
+type UpdateConversationsMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m UpdateConversationsMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m UpdateConversationsMigration) GetName() string {
+	return "8-update-conversations-schema"
+}
+
+func (m UpdateConversationsMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//The conversations are keyed by channel, user and thread now, so we recreate the table
+	q := new(clients.Query).Drop(databasedto.ConversationsModel)
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to drop %s table", databasedto.ConversationsModel.GetTableName()))
+	}
+
+	q = new(clients.Query).
+		Create(databasedto.ConversationsModel).
+		AddIndex(dto.Index{
+			Name:   "conversations_key_uindex",
+			Target: databasedto.ConversationsModel.GetTableName(),
+			Key:    "channel, user, thread_ts",
+			Unique: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to create %s table", databasedto.ConversationsModel.GetTableName()))
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sharovik/devbot/internal/container"
+	"github.com/sharovik/devbot/internal/dto/databasedto"
+	"github.com/sharovik/orm/clients"
+	"github.com/sharovik/orm/dto"
+)
+
+type CreateProcessedEventsMigration struct {
+	Client clients.BaseClientInterface
+}
+
+func (m CreateProcessedEventsMigration) SetClient(client clients.BaseClientInterface) {
+	m.Client = client
+}
+
+func (m CreateProcessedEventsMigration) GetName() string {
+	return "9-create-processed-events"
+}
+
+func (m CreateProcessedEventsMigration) Execute() error {
+	client := container.C.Dictionary.GetDBClient()
+
+	//Create processed events table
+	q := new(clients.Query).
+		Create(databasedto.ProcessedEventsModel).
+		IfNotExists().
+		AddIndex(dto.Index{
+			Name:   "processed_events_event_key_uindex",
+			Target: databasedto.ProcessedEventsModel.GetTableName(),
+			Key:    "event_key",
+			Unique: true,
+		})
+	if _, err := client.Execute(q); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Failed to create %s table", databasedto.ProcessedEventsModel.GetTableName()))
+	}
+
+	return nil
+}
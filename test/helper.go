@@ -2,7 +2,12 @@ package test
 
 import (
 	"io/ioutil"
+	"os"
+	"path"
 	"testing"
+
+	"github.com/sharovik/orm/clients"
+	cdto "github.com/sharovik/orm/dto"
 )
 
 // FileToBytes reads fileName and returns the file contents as a byte array
@@ -14,3 +19,35 @@ func FileToBytes(t *testing.T, fileName string) (bytes []byte) {
 
 	return
 }
+
+// InitDatabase opens the temporary sqlite database with the tables of the models. The connection is closed, once the test is finished
+func InitDatabase(tb testing.TB, models ...cdto.ModelInterface) clients.BaseClientInterface {
+	tb.Helper()
+
+	dbPath := path.Join(tb.TempDir(), "devbot.sqlite")
+	f, err := os.Create(dbPath)
+	if err != nil {
+		tb.Fatalf("test.InitDatabase: failed creating %s: %s", dbPath, err)
+	}
+	_ = f.Close()
+
+	db, err := clients.InitClient(clients.DatabaseConfig{
+		Type: clients.DatabaseTypeSqlite,
+		Host: dbPath,
+	})
+	if err != nil {
+		tb.Fatalf("test.InitDatabase: failed connecting %s: %s", dbPath, err)
+	}
+
+	tb.Cleanup(func() {
+		_ = db.Disconnect()
+	})
+
+	for _, model := range models {
+		if _, err = db.Execute(new(clients.Query).Create(model).IfNotExists()); err != nil {
+			tb.Fatalf("test.InitDatabase: failed creating %s table: %s", model.GetTableName(), err)
+		}
+	}
+
+	return db
+}